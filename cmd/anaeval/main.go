@@ -0,0 +1,322 @@
+// Command anaeval replays golden datasets of architect questions against the
+// configured models, scores the answers and compares runs.
+//
+//	anaeval run -dataset eval/datasets/architect.jsonl -model assistant -judge agent
+//	anaeval list -dataset architect
+//	anaeval compare 20250613-101500-architect-cerebras_qwen-3-32b 20250614-090000-architect-ollama_llama3.1_8b
+//
+// Models are configured as for the server, through ANA_MODELS, OPENAI_BASE_URL,
+// OLLAMA_BASE_URL and CEREBRAS_API_KEY.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/eval"
+	"github.com/lyffseba/ana/internal/prompts"
+)
+
+const usage = `Usage: anaeval <command> [flags]
+
+Commands:
+  run      replay a dataset against a model and save the scored run
+  list     list saved runs
+  compare  compare two saved runs case by case
+
+Run "anaeval <command> -h" for the flags of a command.
+`
+
+func main() {
+	// The providers read their keys from the environment, as the server does
+	_ = godotenv.Load()
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = runCommand(os.Args[2:], os.Stdout)
+	case "list":
+		err = listCommand(os.Args[2:], os.Stdout)
+	case "compare":
+		err = compareCommand(os.Args[2:], os.Stdout)
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	var failed errBelowThreshold
+	switch {
+	case errors.As(err, &failed):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	case err != nil:
+		log.Fatalf("anaeval %s: %v", os.Args[1], err)
+	}
+}
+
+// errBelowThreshold fails a run whose pass rate is under -fail-under
+type errBelowThreshold struct {
+	passRate, threshold float64
+}
+
+func (e errBelowThreshold) Error() string {
+	return fmt.Sprintf("pass rate %.1f%% is below %.1f%%", e.passRate*100, e.threshold*100)
+}
+
+// runCommand replays a dataset and saves the run
+func runCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	datasetPath := flags.String("dataset", "eval/datasets/architect.jsonl", "JSONL dataset to replay")
+	modelSpec := flags.String("model", config.ModelAssistant, "logical model, or provider:model, to evaluate")
+	judgeSpec := flags.String("judge", "", "logical model, or provider:model, grading judge checks; judge checks fail without one")
+	promptsDir := flags.String("prompts", os.Getenv("ANA_PROMPTS_DIR"), "directory of prompt templates overriding the built-in ones")
+	tags := flags.String("tags", "", "comma-separated tags; only cases with one of them are run")
+	parallel := flags.Int("parallel", 4, "cases answered at once")
+	resultsDir := flags.String("results", "eval/results", "directory runs are saved in")
+	failUnder := flags.Float64("fail-under", 0, "exit with status 1 if the pass rate is below this share, e.g. 0.8")
+	flags.Parse(args)
+
+	dataset, err := eval.LoadDataset(*datasetPath)
+	if err != nil {
+		return err
+	}
+	if *tags != "" {
+		dataset = dataset.Filter(strings.Split(*tags, ",")...)
+		if len(dataset.Cases) == 0 {
+			return fmt.Errorf("no cases tagged %s", *tags)
+		}
+	}
+
+	llmConfig, err := config.LLMConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid model configuration: %w", err)
+	}
+	modelName, llmConfig, err := bindModel(llmConfig, "eval", *modelSpec)
+	if err != nil {
+		return err
+	}
+	judgeName := ""
+	if *judgeSpec != "" {
+		if judgeName, llmConfig, err = bindModel(llmConfig, "eval_judge", *judgeSpec); err != nil {
+			return err
+		}
+	}
+	factory, err := ai.NewModelFactoryFromConfig(llmConfig)
+	if err != nil {
+		return err
+	}
+	// Every case must reach the model
+	factory.SetResponseCache(nil, 0)
+
+	runner := &eval.Runner{Parallel: *parallel, Options: map[string]string{}}
+	model, err := factory.ChatModel(modelName)
+	if err != nil {
+		return err
+	}
+	runner.Model, runner.ModelName = model, model.Label()
+	if judgeName != "" {
+		judge, err := factory.ChatModel(judgeName)
+		if err != nil {
+			return err
+		}
+		runner.Judge, runner.JudgeName = judge, judge.Label()
+	} else if hasJudgeChecks(dataset) {
+		log.Printf("Warning: %s has judge checks but no -judge model was given; they will fail", dataset.Name)
+	}
+	if *promptsDir != "" {
+		runner.Prompts = prompts.NewRegistry(prompts.DirSource{Dir: *promptsDir})
+		if err := runner.Prompts.Reload(context.Background()); err != nil {
+			return err
+		}
+		runner.Options["prompts"] = *promptsDir
+	}
+	if *tags != "" {
+		runner.Options["tags"] = *tags
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(out, "Running %d cases of %s against %s\n", len(dataset.Cases), dataset.Name, runner.ModelName)
+	store := eval.FileStore{Dir: *resultsDir}
+	previous, err := store.Latest(dataset.Name)
+	if err != nil && !errors.Is(err, eval.ErrRunNotFound) {
+		return err
+	}
+	run, err := runner.Run(ctx, dataset)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(run); err != nil {
+		return err
+	}
+
+	printRun(out, run)
+	fmt.Fprintf(out, "Saved run %s\n", run.ID)
+	if previous != nil {
+		fmt.Fprintln(out)
+		printComparison(out, eval.Compare(previous, run))
+	}
+	if run.Summary.PassRate < *failUnder {
+		return errBelowThreshold{passRate: run.Summary.PassRate, threshold: *failUnder}
+	}
+	return nil
+}
+
+// bindModel returns the logical model to use for spec. A logical model is
+// used as configured; "provider:model" is bound to name with the generation
+// parameters of the assistant.
+func bindModel(llmConfig config.LLMConfig, name, spec string) (string, config.LLMConfig, error) {
+	if _, ok := llmConfig.Models[spec]; ok {
+		return spec, llmConfig, nil
+	}
+	bindings, err := config.ParseModels(name + "=" + spec)
+	if err != nil {
+		return "", llmConfig, fmt.Errorf("model %q: expected a logical model or provider:model", spec)
+	}
+	model := bindings[name]
+	assistant := llmConfig.Models[config.ModelAssistant]
+	model.Temperature, model.MaxTokens = assistant.Temperature, assistant.MaxTokens
+	return name, llmConfig.Merge(config.LLMConfig{Models: map[string]config.ModelConfig{name: model}}), nil
+}
+
+// hasJudgeChecks reports whether any case needs a judge model
+func hasJudgeChecks(dataset eval.Dataset) bool {
+	for _, c := range dataset.Cases {
+		for _, check := range c.Checks {
+			if check.Type == eval.CheckJudge {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listCommand lists the saved runs
+func listCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	dataset := flags.String("dataset", "", "only list runs of this dataset")
+	resultsDir := flags.String("results", "eval/results", "directory runs are saved in")
+	flags.Parse(args)
+
+	runs, err := eval.FileStore{Dir: *resultsDir}.List(*dataset)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tMODEL\tPROMPTS\tCASES\tPASSED\tSCORE")
+	for _, run := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.1f%%\t%.3f\n", run.ID, run.Model, promptList(run.Summary.Prompts),
+			run.Summary.Cases, run.Summary.PassRate*100, run.Summary.Score)
+	}
+	return w.Flush()
+}
+
+// compareCommand compares two saved runs
+func compareCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	resultsDir := flags.String("results", "eval/results", "directory runs are saved in")
+	asJSON := flags.Bool("json", false, "print the comparison as JSON")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		return errors.New("expected the IDs of the base and head runs")
+	}
+
+	store := eval.FileStore{Dir: *resultsDir}
+	base, err := store.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	head, err := store.Load(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	comparison := eval.Compare(base, head)
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparison)
+	}
+	printComparison(out, comparison)
+	return nil
+}
+
+// printRun prints each case's outcome and the summary of a run
+func printRun(out io.Writer, run *eval.Run) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, result := range run.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		detail := result.Error
+		for _, check := range result.Checks {
+			if detail == "" && !check.Passed {
+				detail = check.Type + ": " + check.Detail
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%dms\t%s\n", status, result.ID, result.Score, result.LatencyMS, truncate(detail, 100))
+	}
+	w.Flush()
+	s := run.Summary
+	fmt.Fprintf(out, "\n%d/%d passed (%.1f%%), mean score %.3f, %d errors, prompts %s\n",
+		s.Passed, s.Cases, s.PassRate*100, s.Score, s.Errors, promptList(s.Prompts))
+}
+
+// printComparison prints how head changed from base
+func printComparison(out io.Writer, c eval.Comparison) {
+	fmt.Fprintf(out, "Compared to %s: score %+.3f, pass rate %+.1f points\n", c.Base, c.ScoreDelta, c.PassDelta*100)
+	for _, diff := range c.Regressions {
+		fmt.Fprintf(out, "  regressed  %s (%.2f -> %.2f)\n", diff.ID, diff.BaseScore, diff.HeadScore)
+	}
+	for _, diff := range c.Improvements {
+		fmt.Fprintf(out, "  improved   %s (%.2f -> %.2f)\n", diff.ID, diff.BaseScore, diff.HeadScore)
+	}
+	for _, id := range c.Added {
+		fmt.Fprintf(out, "  new        %s\n", id)
+	}
+	for _, id := range c.Removed {
+		fmt.Fprintf(out, "  removed    %s\n", id)
+	}
+}
+
+// promptList lists the prompt template versions of a run
+func promptList(counts map[string]int) string {
+	refs := make([]string, 0, len(counts))
+	for ref := range counts {
+		refs = append(refs, ref)
+	}
+	if len(refs) == 0 {
+		return "-"
+	}
+	sort.Strings(refs)
+	return strings.Join(refs, ",")
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
@@ -4,89 +4,336 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/processors"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/handlers"
 	"github.com/lyffseba/ana/internal/googleauth"
+	"github.com/lyffseba/ana/internal/lifecycle"
+	"github.com/lyffseba/ana/internal/logging"
+	"github.com/lyffseba/ana/internal/metrics"
 	"github.com/lyffseba/ana/internal/models"
 	"github.com/lyffseba/ana/internal/monitoring"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/rag"
 	"github.com/lyffseba/ana/internal/repositories"
 	"github.com/lyffseba/ana/internal/server"
+	"github.com/lyffseba/ana/internal/session"
+	"github.com/lyffseba/ana/internal/usage"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
+// defaultConfigPath is read when it exists and no other file is given
+const defaultConfigPath = "config.yaml"
+
+// overrideFlags collects the repeated -set flags
+type overrideFlags []string
+
+func (o *overrideFlags) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrideFlags) Set(value string) error {
+	*o = append(*o, value)
+	return nil
+}
+
 func main() {
+	// Load environment variables from .env file before they are read
+	envErr := godotenv.Load()
+
+	configPath := flag.String("config", os.Getenv("ANA_CONFIG"), "YAML configuration file (default "+defaultConfigPath+" if it exists, or $ANA_CONFIG)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	port := flag.Int("port", 0, "port to listen on, overriding server.port")
+	var overrides overrideFlags
+	flag.Var(&overrides, "set", "override a setting, e.g. -set ai.cache.backend=disk (repeatable)")
+	flag.Parse()
+
+	path := *configPath
+	if path == "" {
+		if _, err := os.Stat(defaultConfigPath); err == nil {
+			path = defaultConfigPath
+		}
+	}
+	if *port != 0 {
+		overrides = append(overrides, "server.port="+strconv.Itoa(*port))
+	}
+	cfg, err := config.Load(config.LoadOptions{Path: path, Overrides: overrides})
+	if *printConfig {
+		if cfg != nil {
+			out, _ := yaml.Marshal(cfg.Redacted())
+			os.Stdout.Write(out)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	// Initialize Logger
-	logger, err := zap.NewProduction() // Or zap.NewDevelopment() for more verbose logs
+	appLogger, err := logging.NewLogger(&cfg.Logging)
 	if err != nil {
-		zap.L().Fatal("can't initialize zap logger", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "can't initialize zap logger: %v\n", err)
+		os.Exit(1)
 	}
+	logger := appLogger.Logger
 	defer logger.Sync() // flushes buffer, if any
 	sugar := logger.Sugar()
+	if envErr != nil {
+		sugar.Warnw(".env file not found or could not be loaded. Using environment variables.", "error", envErr)
+	}
+	if path != "" {
+		sugar.Infof("Loaded configuration from %s", path)
+	}
+
+	// Resources are stopped on SIGINT or SIGTERM in the reverse order they
+	// are registered in; a second signal exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	lifecycleManager := lifecycle.New()
+
+	// Connect to the storage backend selected by database.driver
+	openCtx, cancelOpen := context.WithTimeout(context.Background(), 15*time.Second)
+	repos, err := repositories.Open(openCtx, cfg.Database)
+	cancelOpen()
+	if err != nil {
+		sugar.Fatalf("Failed to open %s database: %v", cfg.Database.Driver, err)
+	}
+	lifecycleManager.OnShutdown("database", repos.Close)
+	sugar.Infof("Using %s storage backend", cfg.Database.Driver)
+	if cfg.AI.HistoryTokenBudget > 0 {
+		handlers.SetHistoryTokenBudget(cfg.AI.HistoryTokenBudget)
+	}
+
+	// Token ledger and budgets; the budget endpoints need auth.admin_token
+	handlers.SetAdminToken(cfg.Auth.AdminToken)
+	if cfg.Auth.AdminToken == "" {
+		sugar.Warn("No admin token set; usage budgets cannot be managed")
+	}
+
+	// Per-operation deadlines and the questions each IP may ask
+	handlers.SetTimeouts(cfg.Timeouts)
+	handlers.SetRateLimit(cfg.AI.RateLimit.RequestsPerMinute, cfg.AI.RateLimit.Burst)
+
+	// LLM providers and the logical models handlers ask for
+	ai.SetClientConfig(cfg.AI)
+	llmConfig := cfg.AI.ResolvedLLM()
+	modelFactory, err := ai.NewModelFactoryFromConfig(llmConfig)
+	if err != nil {
+		sugar.Fatalf("Failed to set up LLM providers: %v", err)
+	}
+	lifecycleManager.OnShutdown("response cache", func(context.Context) error {
+		return modelFactory.Close()
+	})
+	for name, model := range llmConfig.Models {
+		sugar.Infof("Model %s served by %s (%s)", name, model.Provider, model.Model)
+	}
+
+	// Documents the assistant grounds its answers in, indexed in memory
+	embedder, err := modelFactory.Embedder(llmConfig.Embeddings)
+	if err != nil {
+		sugar.Fatalf("Failed to set up the embedding model: %v", err)
+	}
+	documentStore := rag.NewStore(repos.Documents, embedder)
+	loadCtx, cancelLoad := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := documentStore.Load(loadCtx); err != nil {
+		sugar.Warnf("Failed to load documents: %v", err)
+	}
+	cancelLoad()
+	sugar.Infof("Indexed %d documents with %s", len(documentStore.Documents()), embedder.Name())
 
-	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		sugar.Warnw(".env file not found or could not be loaded. Using environment variables.", "error", err)
+	// System prompt templates: built in, overridden by the files in
+	// prompts.dir and the versions stored in the database, and reloaded
+	// every prompts.reload
+	var promptSources []prompts.Source
+	if cfg.Prompts.Dir != "" {
+		promptSources = append(promptSources, prompts.DirSource{Dir: cfg.Prompts.Dir})
 	}
+	promptSources = append(promptSources, prompts.RepositorySource{Repo: repos.Prompts})
+	promptRegistry := prompts.NewRegistry(promptSources...)
+	promptCtx, cancelPrompts := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := promptRegistry.Reload(promptCtx); err != nil {
+		sugar.Warnf("Failed to load prompt templates, using the built-in ones: %v", err)
+	}
+	cancelPrompts()
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		promptRegistry.Watch(jobsCtx, cfg.Prompts.Reload)
+	}()
+	lifecycleManager.OnShutdown("background jobs", func(context.Context) error {
+		stopJobs()
+		jobs.Wait()
+		return nil
+	})
 
-	// Set default port if not specified
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Models run by name through /api/v1/ai/process
+	aiService := ai.NewAIService(cfg, modelFactory)
+	if err := aiService.InitializeModels(context.Background()); err != nil {
+		sugar.Fatalf("Failed to initialize AI models: %v", err)
 	}
 
-	// MongoDB: Connection is now handled internally by repositories/database package.
-	sugar.Info("MongoDB connection will be established by repository/database package as needed.")
+	// Processors served by /api/v1/ai/processors and the WebSocket endpoint,
+	// configured by the file in ai.processors_config
+	processorManager := processors.NewProcessorManager()
+	cerebrasConfig, err := processors.LoadConfig(cfg.AI.ProcessorsConfig, "cerebras")
+	if err != nil {
+		sugar.Warnf("Failed to load processor configuration, using defaults: %v", err)
+		cerebrasConfig = &processors.Config{ModelID: "qwen-3-32b", MaxTokens: 1000, Temperature: 0.7}
+	}
+	// The processor shares the cerebras provider, its circuit breaker and the
+	// usage recorder with the models. Without one, a provider is created
+	// from the processor's settings and registered so there is still only
+	// one cerebras circuit breaker.
+	if _, ok := modelFactory.Provider(config.ProviderCerebras); !ok {
+		sugar.Warnf("No %s provider configured; using the endpoint and key of the processor configuration", config.ProviderCerebras)
+		provider, err := ai.NewProvider(config.ProviderCerebras, config.ProviderConfig{
+			Type:     config.ProviderCerebras,
+			Endpoint: cerebrasConfig.Endpoint,
+			APIKey:   cerebrasConfig.APIKey,
+		})
+		if err != nil {
+			sugar.Fatalf("Failed to create the %s provider: %v", config.ProviderCerebras, err)
+		}
+		modelFactory.RegisterProvider(provider)
+	}
+	cerebrasModel, err := modelFactory.ProviderModel(config.ProviderCerebras, config.ModelConfig{})
+	if err != nil {
+		sugar.Fatalf("Failed to create the cerebras processor: %v", err)
+	}
+	processorManager.RegisterProcessor("cerebras", processors.NewCerebrasProcessor(cerebrasConfig, cerebrasModel))
 
-	// Create task repository and seed initial data if needed
-	taskRepo := repositories.NewTaskRepository()
-	if err := seedInitialData(taskRepo); err != nil {
+	// Seed initial data if needed
+	seedCtx, cancelSeed := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := seedInitialData(seedCtx, repos.Tasks, repos.Projects); err != nil {
 		sugar.Warnf("Failed to seed initial data: %v", err)
 	}
+	cancelSeed()
 	
 	// Initialize monitoring
 	sugar.Info("Initializing monitoring system...")
 	monitoring.Init()
-	
-	// Set up initial monitoring stats
-	setupInitialMonitoringStats()
 
 	// Initialize Google OAuth Service
 	sugar.Info("Initializing Google OAuth Service...")
-	// IMPORTANT: Ensure credentials.json is in the 'config' directory at the project root.
-	// Add 'config/credentials.json' to your .gitignore file!
-	credPath := "config/credentials.json"
-	// This redirectURL must match exactly one of the Authorized redirect URIs in your Google Cloud Console
-	redirectURL := "http://localhost:8080/api/auth/google/callback"
-	scopes := []string{
-		"https://www.googleapis.com/auth/calendar",       // Example: full calendar access
-		"https://www.googleapis.com/auth/gmail.readonly", // Example: read-only gmail access
-		// Add other scopes as needed, e.g., user profile:
-		// "https://www.googleapis.com/auth/userinfo.email",
-		// "https://www.googleapis.com/auth/userinfo.profile",
-	}
-	authService, err := googleauth.NewOAuthService(credPath, redirectURL, logger, scopes)
+	// IMPORTANT: Keep the credentials file out of version control.
+	// The redirect URL must match exactly one of the Authorized redirect URIs in your Google Cloud Console
+	google := cfg.Auth.Google
+	authService, err := googleauth.NewOAuthService(google.CredentialsPath, google.RedirectURL, logger, google.Scopes)
 	if err != nil {
 		sugar.Fatalf("Failed to initialize Google OAuth Service: %v", err)
 	}
 
+	// Signed session cookies of the users who sign in with Google
+	sessionSecret := []byte(cfg.Auth.SessionSecret)
+	if len(sessionSecret) == 0 {
+		sugar.Warn("No session secret set; users are signed out when the server restarts")
+		sessionSecret = make([]byte, 32)
+		if _, err := rand.Read(sessionSecret); err != nil {
+			sugar.Fatalf("Failed to generate a session secret: %v", err)
+		}
+	}
+	sessions := session.NewManager(sessionSecret, cfg.Auth.SessionMaxAge)
+
+	// Encryption of the stored Google tokens
+	tokenKey, err := cfg.Auth.DecodeTokenKey()
+	if err != nil {
+		sugar.Fatalf("Invalid token key: %v", err)
+	}
+	if tokenKey == nil {
+		sugar.Warn("No token key set; users must sign in again to reconnect Google after the server restarts")
+		tokenKey = make([]byte, 32)
+		if _, err := rand.Read(tokenKey); err != nil {
+			sugar.Fatalf("Failed to generate a token key: %v", err)
+		}
+	}
+	tokenCipher, err := repositories.NewTokenCipher(tokenKey)
+	if err != nil {
+		sugar.Fatalf("Failed to create the token cipher: %v", err)
+	}
+
 	// Initialize and start the server
-	r := server.SetupRouter(authService) // Pass authService to SetupRouter
-	sugar.Infof("Server starting on port %s...", port)
-	if err := r.Run(":" + port); err != nil {
-		sugar.Fatalf("Failed to start server: %v", err)
+	r := server.New(server.Dependencies{
+		Repositories: repos,
+		Models:       modelFactory,
+		Usage:        usage.NewLedger(repos.Usage),
+		Documents:    documentStore,
+		Prompts:      promptRegistry,
+		AIService:    aiService,
+		Processors:   processorManager,
+		Metrics:      metrics.Default(),
+		Auth:         authService,
+		Sessions:     sessions,
+		TokenCipher:  tokenCipher,
+		Lifecycle:    lifecycleManager,
+
+		AllowedOrigins: cfg.Server.AllowedOrigins,
+	})
+	srv := &http.Server{
+		Addr:        net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)),
+		Handler:     r,
+		ReadTimeout: cfg.Server.ReadTimeout,
+		// Streams and batches may write for as long as their deadline allows
+		WriteTimeout: max(cfg.Server.WriteTimeout, cfg.Timeouts.Longest()),
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		sugar.Errorf("Failed to start server: %v", err)
+		if err := lifecycleManager.Shutdown(context.Background()); err != nil {
+			sugar.Errorf("Shutdown: %v", err)
+		}
+		logger.Sync()
+		os.Exit(1)
+	}
+	sugar.Infof("Server starting on %s...", srv.Addr)
+	err = lifecycleManager.Serve(ctx, srv, ln, lifecycle.Options{
+		Delay:   cfg.Server.ShutdownDelay,
+		Timeout: cfg.Server.ShutdownTimeout,
+	})
+	if err != nil {
+		sugar.Errorf("Server stopped with errors: %v", err)
+		logger.Sync()
+		os.Exit(1)
+	}
+	sugar.Info("Server stopped")
 }
 
 // seedInitialData adds default data if the database is empty
-func seedInitialData(taskRepo *repositories.TaskRepository) error {
+func seedInitialData(ctx context.Context, taskRepo repositories.TaskRepository, projectRepo repositories.ProjectRepository) error {
 	// Get a logger instance, assuming sugar is not accessible here or prefer direct logger
 	logger, _ := zap.NewProduction() // Basic logger for this function
 	defer logger.Sync()
 
 	// Check if we have tasks, if not create sample tasks
-	tasks, err := taskRepo.FindAll()
+	tasks, err := taskRepo.FindAll(ctx)
 	if err != nil {
 		return err
 	}
@@ -94,6 +341,20 @@ func seedInitialData(taskRepo *repositories.TaskRepository) error {
 	// If no tasks exist, seed with default data
 	if len(tasks) == 0 {
 		logger.Info("No tasks found, seeding initial data...")
+
+		// Create a sample project the seeded tasks belong to
+		sampleProject := models.Project{
+			Name:      "Edificio Residencial Chapinero",
+			Client:    "Constructora Andina",
+			Address:   "Calle 60 # 9-45, Bogotá",
+			Phase:     models.ProjectPhaseSchematicDesign,
+			StartDate: time.Now(),
+			EndDate:   time.Now().AddDate(1, 0, 0),
+			Status:    models.ProjectStatusActive,
+		}
+		if err := projectRepo.Create(ctx, &sampleProject); err != nil {
+			return err
+		}
 		
 		// Create sample tasks for development
 		sampleTasks := []models.Task{
@@ -102,7 +363,7 @@ func seedInitialData(taskRepo *repositories.TaskRepository) error {
 				Description: "Discutir requisitos del proyecto para el nuevo edificio residencial",
 				DueDate:     time.Now().AddDate(0, 0, 1), // Tomorrow
 				Priority:    "High",
-				ProjectID:   1,
+				ProjectID:   sampleProject.ID,
 				Status:      "To-Do",
 			},
 			{
@@ -110,13 +371,13 @@ func seedInitialData(taskRepo *repositories.TaskRepository) error {
 				Description: "Completar la versión final de los documentos del plano",
 				DueDate:     time.Now().AddDate(0, 0, 3), // In 3 days
 				Priority:    "Medium",
-				ProjectID:   1,
+				ProjectID:   sampleProject.ID,
 				Status:      "To-Do",
 			},
 		}
 		
 		for _, task := range sampleTasks {
-			if err := taskRepo.Create(&task); err != nil {
+			if err := taskRepo.Create(ctx, &task); err != nil {
 				return err
 			}
 		}
@@ -126,43 +387,3 @@ func seedInitialData(taskRepo *repositories.TaskRepository) error {
 	
 	return nil
 }
-
-// setupInitialMonitoringStats initializes monitoring statistics
-func setupInitialMonitoringStats() {
-	// Get a logger instance
-	logger, _ := zap.NewProduction() // Basic logger for this function
-	defer logger.Sync()
-
-	// Initialize AI service stats
-	aiStats := monitoring.ServiceStats{
-		CacheStats: monitoring.CacheStats{
-			Size:         0,
-			HitRate:      0.0,
-			HitCount:     0,
-			MissCount:    0,
-			AvgValueSize: 0,
-		},
-		CircuitStats: monitoring.CircuitStats{
-			State:        "closed",
-			FailureCount: 0,
-			ResetTimeout: "60s",
-		},
-		PerformanceStats: monitoring.PerformanceStats{
-			AvgResponseTime: 0.0,
-			RequestCount:    0,
-		},
-		ErrorStats: monitoring.ErrorStats{
-			ErrorCount:          0,
-			CircuitBreakerOpens: 0,
-			RateLimitRejections: 0,
-		},
-	}
-	
-	// Register AI service stats
-	monitoring.UpdateServiceStats("cerebras", aiStats)
-	
-	// Set circuit breaker state
-	monitoring.SetCircuitBreakerState("cerebras", false)
-	
-	logger.Info("Monitoring system initialized with initial stats")
-}
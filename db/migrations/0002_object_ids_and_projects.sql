@@ -0,0 +1,43 @@
+-- Up migration
+-- Tasks and projects use 24-character hex ObjectIDs so records keep the same
+-- identifiers on the MongoDB and PostgreSQL backends.
+CREATE TABLE projects (
+  id CHAR(24) PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  client VARCHAR(255),
+  address TEXT,
+  phase VARCHAR(50),
+  start_date TIMESTAMP,
+  end_date TIMESTAMP,
+  budget NUMERIC(18, 2) NOT NULL DEFAULT 0,
+  status VARCHAR(50),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE tasks ALTER COLUMN id DROP DEFAULT;
+ALTER TABLE tasks ALTER COLUMN id TYPE CHAR(24) USING lpad(to_hex(id), 24, '0');
+DROP SEQUENCE IF EXISTS tasks_id_seq;
+
+-- Integer project references cannot be mapped to projects, so they are cleared
+ALTER TABLE tasks ALTER COLUMN project_id TYPE CHAR(24) USING NULL::CHAR(24);
+ALTER TABLE tasks ADD CONSTRAINT fk_tasks_project FOREIGN KEY (project_id) REFERENCES projects(id);
+
+-- Sort keys must not be NULL for keyset pagination; the zero time matches
+-- how MongoDB stores an unset Go time.Time
+UPDATE tasks SET due_date = '0001-01-01 00:00:00' WHERE due_date IS NULL;
+UPDATE tasks SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+UPDATE tasks SET updated_at = CURRENT_TIMESTAMP WHERE updated_at IS NULL;
+ALTER TABLE tasks ALTER COLUMN due_date SET NOT NULL;
+ALTER TABLE tasks ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE tasks ALTER COLUMN updated_at SET NOT NULL;
+
+-- Keyset pagination indexes matching the task sort orders
+CREATE INDEX idx_tasks_due_date_id ON tasks(due_date, id);
+CREATE INDEX idx_tasks_created_at_id ON tasks(created_at, id);
+CREATE INDEX idx_tasks_updated_at_id ON tasks(updated_at, id);
+CREATE INDEX idx_tasks_title_id ON tasks(title, id);
+
+-- Down migration (rollback)
+-- ALTER TABLE tasks DROP CONSTRAINT fk_tasks_project;
+-- DROP TABLE projects;
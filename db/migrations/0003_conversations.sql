@@ -0,0 +1,17 @@
+-- Up migration
+-- Assistant conversations; messages are stored as a JSON array
+CREATE TABLE conversations (
+  id CHAR(24) PRIMARY KEY,
+  owner_id VARCHAR(255) NOT NULL,
+  title VARCHAR(255) NOT NULL,
+  messages JSONB NOT NULL DEFAULT '[]',
+  summary TEXT NOT NULL DEFAULT '',
+  summarized_count INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_conversations_owner_updated ON conversations(owner_id, updated_at DESC);
+
+-- Down migration (rollback)
+-- DROP TABLE conversations;
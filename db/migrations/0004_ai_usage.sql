@@ -0,0 +1,34 @@
+-- Up migration
+-- Token ledger: one row per model completion
+CREATE TABLE ai_usage (
+  id CHAR(24) PRIMARY KEY,
+  user_id VARCHAR(255) NOT NULL,
+  project_id VARCHAR(255) NOT NULL DEFAULT '',
+  provider VARCHAR(100) NOT NULL,
+  model VARCHAR(255) NOT NULL,
+  prompt_tokens INTEGER NOT NULL DEFAULT 0,
+  completion_tokens INTEGER NOT NULL DEFAULT 0,
+  estimated BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ai_usage_created ON ai_usage(created_at);
+CREATE INDEX idx_ai_usage_user_created ON ai_usage(user_id, created_at);
+CREATE INDEX idx_ai_usage_project_created ON ai_usage(project_id, created_at);
+
+-- Daily or monthly token limits for everyone, a user or a project
+CREATE TABLE ai_budgets (
+  id CHAR(24) PRIMARY KEY,
+  scope VARCHAR(20) NOT NULL,
+  subject VARCHAR(255) NOT NULL DEFAULT '',
+  period VARCHAR(20) NOT NULL,
+  token_limit BIGINT NOT NULL,
+  warn_ratio DOUBLE PRECISION NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (scope, subject, period)
+);
+
+-- Down migration (rollback)
+-- DROP TABLE ai_budgets;
+-- DROP TABLE ai_usage;
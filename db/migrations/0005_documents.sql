@@ -0,0 +1,29 @@
+-- Up migration
+-- Documents the assistant retrieves passages from, e.g. NSR-10 or a project brief
+CREATE TABLE documents (
+  id CHAR(24) PRIMARY KEY,
+  title VARCHAR(255) NOT NULL,
+  source VARCHAR(255) NOT NULL DEFAULT '',
+  kind VARCHAR(20) NOT NULL,
+  project_id CHAR(24),
+  embedder VARCHAR(255) NOT NULL,
+  chunk_count INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Passages of a document with their embeddings; the vector index is built in memory
+CREATE TABLE document_chunks (
+  id CHAR(24) PRIMARY KEY,
+  document_id CHAR(24) NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+  chunk_index INTEGER NOT NULL,
+  page INTEGER NOT NULL DEFAULT 0,
+  section TEXT NOT NULL DEFAULT '',
+  text TEXT NOT NULL,
+  embedding REAL[] NOT NULL
+);
+
+CREATE INDEX idx_document_chunks_document ON document_chunks(document_id, chunk_index);
+
+-- Down migration (rollback)
+-- DROP TABLE document_chunks;
+-- DROP TABLE documents;
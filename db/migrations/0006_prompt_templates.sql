@@ -0,0 +1,18 @@
+-- Up migration
+-- Versions of the prompt templates sent to the models
+CREATE TABLE prompt_templates (
+  id CHAR(24) PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  version INTEGER NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  text TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (name, version)
+);
+
+-- The prompt template version each completion was made with
+ALTER TABLE ai_usage ADD COLUMN prompt VARCHAR(120) NOT NULL DEFAULT '';
+
+-- Down migration (rollback)
+-- ALTER TABLE ai_usage DROP COLUMN prompt;
+-- DROP TABLE prompt_templates;
@@ -0,0 +1,15 @@
+-- Up migration
+-- Accounts of the people who sign in with Google
+CREATE TABLE users (
+  id CHAR(24) PRIMARY KEY,
+  google_id VARCHAR(255) NOT NULL UNIQUE,
+  email VARCHAR(320) NOT NULL DEFAULT '',
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  picture TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Down migration (rollback)
+-- DROP TABLE users;
@@ -0,0 +1,10 @@
+-- Up migration
+-- Google OAuth tokens of each user, encrypted by the application
+CREATE TABLE oauth_tokens (
+  user_id CHAR(24) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  token BYTEA NOT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Down migration (rollback)
+-- DROP TABLE oauth_tokens;
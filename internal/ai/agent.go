@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAgentMaxIterations = 5
+	defaultPendingActionTTL   = 15 * time.Minute
+)
+
+var (
+	// ErrToolLoopLimit is returned when the model keeps calling tools past the iteration limit
+	ErrToolLoopLimit = errors.New("tool call iteration limit reached")
+	// ErrPendingActionNotFound is returned for unknown or expired confirmations
+	ErrPendingActionNotFound = errors.New("pending action not found or expired")
+	// ErrPendingActionForbidden is returned when someone other than the
+	// owner of a pending action tries to resolve it
+	ErrPendingActionForbidden = errors.New("pending action belongs to another user")
+)
+
+// ChatCompleter sends chat completion requests; *CerebrasClient implements it
+type ChatCompleter interface {
+	CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error)
+}
+
+// PendingCall is a mutating tool call waiting for confirmation
+type PendingCall struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Summary   string          `json:"summary"`
+
+	callID string
+}
+
+// PendingAction groups the mutating calls of one model turn. Confirming it
+// runs them and resumes the conversation; rejecting it tells the model they
+// were not run.
+type PendingAction struct {
+	ID        string        `json:"id"`
+	Calls     []PendingCall `json:"calls"`
+	ExpiresAt time.Time     `json:"expires_at"`
+
+	// owner is the user whose turn proposed the calls; only they may resolve them
+	owner    string
+	messages []Message
+}
+
+// AgentResult is the outcome of an agent turn. Either Response is set, or
+// Pending holds calls the user must confirm first.
+type AgentResult struct {
+	Response  string         `json:"response,omitempty"`
+	Pending   *PendingAction `json:"pending_action,omitempty"`
+	ToolsUsed []string       `json:"tools_used,omitempty"`
+}
+
+// Agent runs a multi-turn tool-calling loop against a chat model
+type Agent struct {
+	client        ChatCompleter
+	tools         *ToolRegistry
+	model         string
+	maxIterations int
+	pendingTTL    time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*PendingAction
+}
+
+// NewAgent creates an agent that offers the registry's tools to the model
+func NewAgent(client ChatCompleter, tools *ToolRegistry, model string) *Agent {
+	return &Agent{
+		client:        client,
+		tools:         tools,
+		model:         model,
+		maxIterations: defaultAgentMaxIterations,
+		pendingTTL:    defaultPendingActionTTL,
+		pending:       make(map[string]*PendingAction),
+	}
+}
+
+// SetMaxIterations sets how many model calls one turn may make
+func (a *Agent) SetMaxIterations(n int) {
+	if n > 0 {
+		a.maxIterations = n
+	}
+}
+
+// Run answers the last message of owner's conversation, calling tools as
+// needed. Pending actions it returns can only be confirmed by owner.
+func (a *Agent) Run(ctx context.Context, owner string, messages []Message) (AgentResult, error) {
+	return a.loop(ctx, owner, append([]Message(nil), messages...), nil)
+}
+
+// Confirm resolves a pending action of owner. When approve is true its calls
+// run; otherwise the model is told the user declined them. The conversation
+// then continues from where it stopped.
+func (a *Agent) Confirm(ctx context.Context, owner string, id string, approve bool) (AgentResult, error) {
+	action, err := a.takePending(owner, id)
+	if err != nil {
+		return AgentResult{}, err
+	}
+
+	messages := action.messages
+	var used []string
+	for _, call := range action.Calls {
+		var content string
+		if approve {
+			tool, _ := a.tools.Get(call.Tool)
+			content = a.execute(ctx, tool, call.Arguments)
+			used = append(used, call.Tool)
+		} else {
+			content = `{"error":"El usuario rechazó esta acción; no se ejecutó."}`
+		}
+		messages = append(messages, Message{Role: "tool", ToolCallID: call.callID, Content: content})
+	}
+	return a.loop(ctx, owner, messages, used)
+}
+
+// loop calls the model until it answers without tool calls, a mutating call
+// needs confirmation or the iteration limit is reached
+func (a *Agent) loop(ctx context.Context, owner string, messages []Message, used []string) (AgentResult, error) {
+	for i := 0; i < a.maxIterations; i++ {
+		resp, err := a.client.CreateChatCompletion(ctx, ChatCompletionRequest{
+			Model:       a.model,
+			Messages:    messages,
+			Temperature: 0.2,
+			MaxTokens:   800,
+			Tools:       a.tools.Definitions(),
+			ToolChoice:  "auto",
+		})
+		if err != nil {
+			return AgentResult{ToolsUsed: used}, err
+		}
+
+		reply := resp.Choices[0].Message
+		if len(reply.ToolCalls) == 0 {
+			return AgentResult{Response: removeThinkingTags(reply.Content), ToolsUsed: used}, nil
+		}
+		messages = append(messages, Message{Role: "assistant", Content: reply.Content, ToolCalls: reply.ToolCalls})
+
+		// Read-only calls run now; mutating calls wait for confirmation
+		var deferred []PendingCall
+		for _, call := range reply.ToolCalls {
+			args := json.RawMessage(call.FunctionCall.Arguments)
+			tool, ok := a.tools.Get(call.FunctionCall.Name)
+			switch {
+			case !ok:
+				messages = append(messages, Message{Role: "tool", ToolCallID: call.ID,
+					Content: toolError(fmt.Errorf("unknown tool: %s", call.FunctionCall.Name))})
+			case tool.Mutating:
+				deferred = append(deferred, PendingCall{
+					Tool:      call.FunctionCall.Name,
+					Arguments: args,
+					Summary:   tool.describe(ctx, args),
+					callID:    call.ID,
+				})
+			default:
+				messages = append(messages, Message{Role: "tool", ToolCallID: call.ID, Content: a.execute(ctx, tool, args)})
+				used = append(used, call.FunctionCall.Name)
+			}
+		}
+		if len(deferred) > 0 {
+			return AgentResult{Pending: a.storePending(owner, deferred, messages), ToolsUsed: used}, nil
+		}
+	}
+	return AgentResult{ToolsUsed: used}, ErrToolLoopLimit
+}
+
+// execute runs a tool and encodes its result or error for the model
+func (a *Agent) execute(ctx context.Context, tool RegisteredTool, args json.RawMessage) string {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	result, err := tool.Handler(ctx, args)
+	if err != nil {
+		log.Printf("Tool %s failed: %v", tool.Function.Name, err)
+		return toolError(err)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return toolError(err)
+	}
+	return string(encoded)
+}
+
+// toolError encodes an error as a tool result so the model can react to it
+func toolError(err error) string {
+	encoded, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(encoded)
+}
+
+// storePending saves the calls and conversation state until owner confirms them
+func (a *Agent) storePending(owner string, calls []PendingCall, messages []Message) *PendingAction {
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+	action := &PendingAction{
+		ID:        hex.EncodeToString(idBytes),
+		Calls:     calls,
+		ExpiresAt: time.Now().Add(a.pendingTTL),
+		owner:     owner,
+		messages:  messages,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for id, p := range a.pending {
+		if now.After(p.ExpiresAt) {
+			delete(a.pending, id)
+		}
+	}
+	a.pending[action.ID] = action
+	return action
+}
+
+// takePending removes and returns a pending action of owner that has not
+// expired. Actions of other users are left in place.
+func (a *Agent) takePending(owner string, id string) (*PendingAction, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	action, ok := a.pending[id]
+	if !ok {
+		return nil, ErrPendingActionNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(action.owner), []byte(owner)) != 1 {
+		return nil, ErrPendingActionForbidden
+	}
+	delete(a.pending, id)
+	if time.Now().After(action.ExpiresAt) {
+		return nil, ErrPendingActionNotFound
+	}
+	return action, nil
+}
@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// scriptedCompleter returns canned responses and records the requests it received
+type scriptedCompleter struct {
+	responses []ResponseMessage
+	requests  []ChatCompletionRequest
+}
+
+func (s *scriptedCompleter) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	s.requests = append(s.requests, req)
+	if len(s.requests) > len(s.responses) {
+		return nil, errors.New("unexpected request")
+	}
+	return &ChatCompletionResponse{Choices: []Choice{{Message: s.responses[len(s.requests)-1]}}}, nil
+}
+
+// toolCallMessage builds an assistant message calling one tool
+func toolCallMessage(id, name, args string) ResponseMessage {
+	return ResponseMessage{Role: "assistant", ToolCalls: []ToolCall{{ID: id, Type: "function", FunctionCall: FunctionCall{Name: name, Arguments: args}}}}
+}
+
+// newTestRegistry registers a read-only "lookup" tool and a mutating "write" tool
+func newTestRegistry(t *testing.T, writes *[]string) *ToolRegistry {
+	registry := NewToolRegistry()
+	err := registry.Register(RegisteredTool{
+		Function: Function{Name: "lookup"},
+		Handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+			return map[string]int{"overdue": 2}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.Register(RegisteredTool{
+		Function: Function{Name: "write"},
+		Mutating: true,
+		Handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+			*writes = append(*writes, string(args))
+			return map[string]bool{"ok": true}, nil
+		},
+		Describe: func(ctx context.Context, args json.RawMessage) string { return "Escribir " + string(args) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// TestAgentRunsReadOnlyTools tests that read-only tools run immediately and their results reach the model
+func TestAgentRunsReadOnlyTools(t *testing.T) {
+	var writes []string
+	completer := &scriptedCompleter{responses: []ResponseMessage{
+		toolCallMessage("call_1", "lookup", `{}`),
+		{Role: "assistant", Content: "<think>contar</think>Tienes 2 tareas vencidas."},
+	}}
+	agent := NewAgent(completer, newTestRegistry(t, &writes), "test-model")
+
+	result, err := agent.Run(context.Background(), "ana", []Message{{Role: "user", Content: "¿Qué tengo vencido?"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Response != "Tienes 2 tareas vencidas." || result.Pending != nil {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(completer.requests[0].Tools) != 2 {
+		t.Errorf("Expected both tools to be offered, got %d", len(completer.requests[0].Tools))
+	}
+	second := completer.requests[1].Messages
+	last := second[len(second)-1]
+	if last.Role != "tool" || last.ToolCallID != "call_1" || last.Content != `{"overdue":2}` {
+		t.Errorf("Tool result not sent back to the model: %+v", last)
+	}
+}
+
+// TestAgentRequiresConfirmation tests that mutating tools wait for confirmation
+func TestAgentRequiresConfirmation(t *testing.T) {
+	var writes []string
+	completer := &scriptedCompleter{responses: []ResponseMessage{
+		toolCallMessage("call_1", "write", `{"title":"Revisar planos"}`),
+		{Role: "assistant", Content: "Listo, creé la tarea."},
+	}}
+	agent := NewAgent(completer, newTestRegistry(t, &writes), "test-model")
+
+	result, err := agent.Run(context.Background(), "ana", []Message{{Role: "user", Content: "Créame una tarea"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Pending == nil || len(result.Pending.Calls) != 1 {
+		t.Fatalf("Expected a pending action, got %+v", result)
+	}
+	if len(writes) != 0 {
+		t.Fatalf("Mutating tool ran before confirmation")
+	}
+	if got := result.Pending.Calls[0].Summary; got != `Escribir {"title":"Revisar planos"}` {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	// Only the user who asked for the change can confirm it
+	if _, err := agent.Confirm(context.Background(), "mallory", result.Pending.ID, true); !errors.Is(err, ErrPendingActionForbidden) {
+		t.Errorf("Expected ErrPendingActionForbidden, got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Fatalf("Mutating tool ran for another user")
+	}
+
+	confirmed, err := agent.Confirm(context.Background(), "ana", result.Pending.ID, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(writes) != 1 || confirmed.Response != "Listo, creé la tarea." {
+		t.Errorf("Unexpected confirmation result %+v, writes %v", confirmed, writes)
+	}
+
+	// An action can only be confirmed once
+	if _, err := agent.Confirm(context.Background(), "ana", result.Pending.ID, true); !errors.Is(err, ErrPendingActionNotFound) {
+		t.Errorf("Expected ErrPendingActionNotFound, got %v", err)
+	}
+}
+
+// TestAgentRejectedAction tests that rejected actions do not run and the model is told so
+func TestAgentRejectedAction(t *testing.T) {
+	var writes []string
+	completer := &scriptedCompleter{responses: []ResponseMessage{
+		toolCallMessage("call_1", "write", `{}`),
+		{Role: "assistant", Content: "De acuerdo, no hice cambios."},
+	}}
+	agent := NewAgent(completer, newTestRegistry(t, &writes), "test-model")
+
+	result, err := agent.Run(context.Background(), "ana", []Message{{Role: "user", Content: "Borra todo"}})
+	if err != nil || result.Pending == nil {
+		t.Fatalf("Expected a pending action, got %+v, %v", result, err)
+	}
+	if _, err := agent.Confirm(context.Background(), "ana", result.Pending.ID, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("Rejected action was executed")
+	}
+	msgs := completer.requests[1].Messages
+	if last := msgs[len(msgs)-1]; last.Role != "tool" || last.ToolCallID != "call_1" {
+		t.Errorf("Model was not told about the rejection: %+v", last)
+	}
+}
+
+// TestAgentIterationLimit tests that a model that keeps calling tools is stopped
+func TestAgentIterationLimit(t *testing.T) {
+	var writes []string
+	completer := &scriptedCompleter{}
+	for i := 0; i < 3; i++ {
+		completer.responses = append(completer.responses, toolCallMessage("call", "lookup", `{}`))
+	}
+	agent := NewAgent(completer, newTestRegistry(t, &writes), "test-model")
+	agent.SetMaxIterations(3)
+
+	_, err := agent.Run(context.Background(), "ana", []Message{{Role: "user", Content: "?"}})
+	if !errors.Is(err, ErrToolLoopLimit) {
+		t.Fatalf("Expected ErrToolLoopLimit, got %v", err)
+	}
+	if len(completer.requests) != 3 {
+		t.Errorf("Expected 3 model calls, got %d", len(completer.requests))
+	}
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// BatchRequest is one query of a batch, such as a task to summarize or a
+// description to translate
+type BatchRequest struct {
+	// ID is echoed in the result to match it with the request
+	ID    string `json:"id,omitempty"`
+	Query string `json:"query"`
+	// Context is sent before the query, e.g. a system prompt
+	Context []Message `json:"context,omitempty"`
+}
+
+// BatchResult is the answer to one BatchRequest. Err is set instead of
+// Response when the request failed; the other requests are unaffected.
+type BatchResult struct {
+	ID       string `json:"id,omitempty"`
+	Response string `json:"response,omitempty"`
+	// Cached is set when the answer came from the response cache
+	Cached bool  `json:"cached,omitempty"`
+	Err    error `json:"-"`
+}
+
+// GenerateBatch answers every request with model, sending at most as many
+// requests at once as the client's concurrency limit. Identical requests are
+// sent once and cached answers are reused. Results are in input order.
+func (c *CerebrasClient) GenerateBatch(ctx context.Context, model string, requests []BatchRequest) []BatchResult {
+	return generateBatch(ctx, requests, c.concurrencyLimiter, func(ctx context.Context, messages []Message) (string, bool, error) {
+		request := textRequest(model, messages, false)
+		if answer, ok := c.getCachedResponse(ctx, request); ok {
+			return answer, true, nil
+		}
+		resp, err := c.CreateChatCompletion(ctx, request)
+		if err != nil {
+			return "", false, err
+		}
+		answer := removeThinkingTags(resp.Choices[0].Message.Content)
+		c.setCachedResponse(ctx, request, answer)
+		return answer, false, nil
+	})
+}
+
+// GenerateBatch answers every request with the chain, falling back as
+// Answer does. Requests are limited as for CerebrasClient.GenerateBatch when
+// the primary model is served by Cerebras, and to defaultMaxConcurrent at
+// once otherwise.
+func (c *ModelChain) GenerateBatch(ctx context.Context, requests []BatchRequest) []BatchResult {
+	limiter := semaphore.NewWeighted(defaultMaxConcurrent)
+	if client, ok := c.Primary().Provider.(*CerebrasClient); ok {
+		limiter = client.concurrencyLimiter
+	}
+	return generateBatch(ctx, requests, limiter, func(ctx context.Context, messages []Message) (string, bool, error) {
+		if answer, ok := c.CachedAnswer(ctx, messages); ok {
+			return answer, true, nil
+		}
+		answer, err := c.Answer(ctx, messages)
+		return answer, false, err
+	})
+}
+
+// generateBatch answers the distinct requests concurrently, each holding a
+// slot of limiter, and copies the answers to the duplicates
+func generateBatch(ctx context.Context, requests []BatchRequest, limiter *semaphore.Weighted, answer func(context.Context, []Message) (string, bool, error)) []BatchResult {
+	batchSize.Observe(float64(len(requests)))
+
+	results := make([]BatchResult, len(requests))
+	groups := make(map[string][]int)
+	var order []string
+	for i, request := range requests {
+		results[i].ID = request.ID
+		key := batchKey(request)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range order {
+		indexes := groups[key]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result BatchResult
+			if err := limiter.Acquire(ctx, 1); err != nil {
+				result.Err = err
+			} else {
+				result.Response, result.Cached, result.Err = answer(ctx, withUserQuery(requests[indexes[0]].Context, requests[indexes[0]].Query))
+				limiter.Release(1)
+			}
+			if result.Err != nil {
+				errorCounter.WithLabelValues("batch").Inc()
+			}
+			for _, i := range indexes {
+				results[i].Response, results[i].Cached, results[i].Err = result.Response, result.Cached, result.Err
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// batchKey identifies identical requests of a batch
+func batchKey(request BatchRequest) string {
+	data, _ := json.Marshal(withUserQuery(request.Context, request.Query))
+	return string(data)
+}
@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/cache"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// TestGenerateBatch tests fanning out a batch with bounded concurrency
+func TestGenerateBatch(t *testing.T) {
+	var calls, inFlight, maxInFlight atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var request ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		query := request.Messages[len(request.Messages)-1].Content
+		if query == "falla" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message": "bad request"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": "%s"}, "finish_reason": "stop"}]}`, strings.ToUpper(query))
+	}))
+	defer server.Close()
+
+	client := newCerebrasClient(cache.NewLRU(cache.Config{}))
+	client.apiURL, client.apiKey = server.URL, "test-key"
+	chain := NewModelChain(NewChatModel("batch", NewOpenAIProvider("local", server.URL, ""),
+		config.ModelConfig{Model: "local-model"}, cache.NewLRU(cache.Config{}), time.Minute))
+
+	batches := map[string]func([]BatchRequest) []BatchResult{
+		"client": func(requests []BatchRequest) []BatchResult {
+			return client.GenerateBatch(context.Background(), "qwen-3-32b", requests)
+		},
+		"chain": func(requests []BatchRequest) []BatchResult {
+			return chain.GenerateBatch(context.Background(), requests)
+		},
+	}
+	for name, generate := range batches {
+		calls.Store(0)
+		maxInFlight.Store(0)
+
+		var requests []BatchRequest
+		for i := 0; i < 30; i++ {
+			requests = append(requests, BatchRequest{ID: fmt.Sprint(i), Query: fmt.Sprintf("tarea %s %d", name, i)})
+		}
+		requests = append(requests,
+			BatchRequest{ID: "dup", Query: "tarea " + name + " 0"},
+			BatchRequest{ID: "bad", Query: "falla"},
+		)
+		results := generate(requests)
+
+		if len(results) != len(requests) {
+			t.Fatalf("%s: expected %d results, got %d", name, len(requests), len(results))
+		}
+		for i, result := range results[:30] {
+			if result.ID != fmt.Sprint(i) || result.Response != strings.ToUpper(requests[i].Query) || result.Err != nil {
+				t.Errorf("%s: unexpected result %d: %+v", name, i, result)
+			}
+		}
+		if dup := results[30]; dup.ID != "dup" || dup.Response != results[0].Response {
+			t.Errorf("%s: expected the duplicate to share the first answer, got %+v", name, dup)
+		}
+		var apiErr *APIError
+		if bad := results[31]; bad.ID != "bad" || !errors.As(bad.Err, &apiErr) {
+			t.Errorf("%s: expected the failed item to carry its error, got %+v", name, bad)
+		}
+		if n := calls.Load(); n != 31 {
+			t.Errorf("%s: expected 31 requests for 31 distinct queries, got %d", name, n)
+		}
+		if n := maxInFlight.Load(); n > defaultMaxConcurrent {
+			t.Errorf("%s: expected at most %d requests at once, got %d", name, defaultMaxConcurrent, n)
+		}
+
+		// Answers are reused from the cache
+		calls.Store(0)
+		results = generate(requests[:2])
+		if calls.Load() != 0 || !results[0].Cached || !results[1].Cached {
+			t.Errorf("%s: expected cached answers, got %+v after %d calls", name, results, calls.Load())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range chain.GenerateBatch(ctx, []BatchRequest{{Query: "cancelada"}}) {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %+v", result)
+		}
+	}
+}
@@ -5,62 +5,47 @@
 package ai
 
 import (
-	"bytes"
-	"crypto/md5"
+	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"math/rand"
-	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/cache"
+	"github.com/lyffseba/ana/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/sync/semaphore"
 )
 
 const (
-	defaultCerebrasAPIURL = "https://api.cerebras.ai/v1/chat/completions"
-	defaultTimeout        = 30 * time.Second
-	defaultCacheTTL       = 15 * time.Minute
-	defaultMaxRetries     = 3
-	defaultMaxConcurrent  = 10
+	defaultCerebrasAPIURL  = "https://api.cerebras.ai/v1/chat/completions"
+	defaultTimeout         = 30 * time.Second
+	defaultCacheTTL        = 15 * time.Minute
+	defaultCacheMaxEntries = 1000
+	defaultCacheMaxBytes   = 32 << 20
+	defaultCacheDir        = "data/cache"
+	defaultMaxRetries      = 3
+	defaultMaxConcurrent   = 10
+	defaultVisionModel     = "llama-4-scout-17b-16e-instruct"
 )
 
-// CachedResponse represents a cached API response
-type CachedResponse struct {
-	Response string
-	Expiry   time.Time
-}
-
-// CircuitBreakerState tracks the circuit breaker state
-type CircuitBreakerState struct {
-	Failures       int
-	LastFailure    time.Time
-	Open           bool
-	OpenUntil      time.Time
-	ThresholdCount int
-	ResetTimeout   time.Duration
-	mutex          sync.RWMutex
-}
-
 // CerebrasClient handles communication with the Cerebras AI API
 type CerebrasClient struct {
 	apiKey             string
 	apiURL             string
 	httpClient         *retryablehttp.Client
-	cache              map[string]CachedResponse
+	cache              cache.ResponseCache
 	cacheTTL           time.Duration
-	cacheMutex         sync.RWMutex
-	circuitBreaker     CircuitBreakerState
+	circuitBreaker     *CircuitBreaker
 	concurrencyLimiter *semaphore.Weighted
 	metricsEnabled     bool
+	visionModel        string
 }
 
 // metrics for monitoring client performance
@@ -82,20 +67,6 @@ var (
 		[]string{"type"},
 	)
 
-	cacheHits = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "cerebras_cache_hits_total",
-			Help: "Number of cache hits",
-		},
-	)
-
-	cacheMisses = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "cerebras_cache_misses_total",
-			Help: "Number of cache misses",
-		},
-	)
-
 	errorCounter = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "cerebras_errors_total",
@@ -113,10 +84,40 @@ var (
 	)
 )
 
-// Message represents a chat message
+// Message represents a chat message.
+// Assistant messages may carry tool calls; tool messages answer one of them
+// through ToolCallID. When Parts is set it is sent as the content instead of
+// Content, which is how images reach the vision model.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	Parts      []ContentPart `json:"-"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON encodes Parts as the message content when present
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plainMessage Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(plainMessage(m))
+	}
+	return json.Marshal(struct {
+		plainMessage
+		Content []ContentPart `json:"content"`
+	}{plainMessage(m), m.Parts})
+}
+
+// ContentPart is one part of a multimodal message: text or an image
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL points at an image, usually a base64 data URL
+type ImageURL struct {
+	URL string `json:"url"`
 }
 
 // FunctionParameters represents the parameters for a function
@@ -234,132 +235,199 @@ type ChatCompletionResponse struct {
 
 // NewCerebrasClient creates a new client for the Cerebras API
 func NewCerebrasClient() *CerebrasClient {
-	apiKey := getEnv("CEREBRAS_API_KEY", "")
-	if apiKey == "" {
+	return newCerebrasClient(newResponseCache())
+}
+
+var (
+	clientConfigMu sync.RWMutex
+	// clientConfig holds the settings of the clients and response caches
+	// created from now on; nil reads them from the environment
+	clientConfig *config.AIConfig
+)
+
+// SetClientConfig sets the endpoint, key, concurrency limit, circuit breaker
+// and response cache of the Cerebras clients created afterwards. Until it is
+// called they are configured from the environment.
+func SetClientConfig(cfg config.AIConfig) {
+	clientConfigMu.Lock()
+	defer clientConfigMu.Unlock()
+	clientConfig = &cfg
+}
+
+// currentClientConfig returns the settings of new clients
+func currentClientConfig() config.AIConfig {
+	clientConfigMu.RLock()
+	defer clientConfigMu.RUnlock()
+	if clientConfig != nil {
+		return *clientConfig
+	}
+	// Invalid variables keep their defaults, as they did before
+	cfg, _ := config.FromEnv()
+	return cfg.AI
+}
+
+// newCerebrasClient creates a client configured by SetClientConfig or the
+// environment that caches answers in responseCache, which may be nil
+func newCerebrasClient(responseCache cache.ResponseCache) *CerebrasClient {
+	cfg := currentClientConfig()
+	if cfg.Cerebras.APIKey == "" {
 		log.Println("Warning: CEREBRAS_API_KEY not set. AI assistant functionality will not work")
 	}
 
 	// Create retry client for more robust error handling
-	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = defaultMaxRetries
-	retryClient.RetryWaitMin = 1 * time.Second
-	retryClient.RetryWaitMax = 5 * time.Second
-	retryClient.Logger = nil // Disable default logger
-	standardClient := retryClient.StandardClient()
-	standardClient.Timeout = defaultTimeout
-
-	// Create circuit breaker
-	circuitBreaker := CircuitBreakerState{
-		ThresholdCount: 5,
-		ResetTimeout:   60 * time.Second,
-	}
+	retryClient := newProviderHTTPClient(defaultTimeout)
 
-	// Enable metrics based on env var
-	metricsEnabled := getEnv("ENABLE_CEREBRAS_METRICS", "false") == "true"
+	// Create circuit breaker; unset values keep the defaults
+	breaker := DefaultCircuitBreakerConfig()
+	settings := cfg.Cerebras.CircuitBreaker
+	breaker.FailureThreshold = orDefault(settings.FailureThreshold, breaker.FailureThreshold)
+	breaker.Window = orDefault(settings.Window, breaker.Window)
+	breaker.OpenTimeout = orDefault(settings.OpenTimeout, breaker.OpenTimeout)
+	breaker.HalfOpenRequests = orDefault(settings.HalfOpenRequests, breaker.HalfOpenRequests)
 
 	return &CerebrasClient{
-		apiKey:             apiKey,
-		apiURL:             getEnv("CEREBRAS_API_URL", defaultCerebrasAPIURL),
+		apiKey:             cfg.Cerebras.APIKey,
+		apiURL:             orDefault(cfg.Cerebras.Endpoint, defaultCerebrasAPIURL),
 		httpClient:         retryClient,
-		cache:              make(map[string]CachedResponse),
-		cacheTTL:           parseDuration(getEnv("CEREBRAS_CACHE_TTL", "15m"), defaultCacheTTL),
-		circuitBreaker:     circuitBreaker,
-		concurrencyLimiter: semaphore.NewWeighted(int64(defaultMaxConcurrent)),
-		metricsEnabled:     metricsEnabled,
+		cache:              responseCache,
+		cacheTTL:           cacheTTL(cfg.Cache),
+		circuitBreaker:     NewCircuitBreaker(breaker),
+		concurrencyLimiter: semaphore.NewWeighted(int64(orDefault(cfg.Cerebras.MaxConcurrent, defaultMaxConcurrent))),
+		metricsEnabled:     cfg.Cerebras.Metrics,
+		visionModel:        orDefault(cfg.Cerebras.VisionModel, defaultVisionModel),
+	}
+}
+
+// newResponseCache opens the response cache selected by SetClientConfig or
+// CEREBRAS_CACHE_BACKEND (memory, disk or redis), or returns nil when caching
+// is disabled. If the backend cannot be opened the client falls back to an
+// in-memory cache so answers still get cached.
+func newResponseCache() cache.ResponseCache {
+	settings := currentClientConfig().Cache
+	if !settings.Enabled {
+		return nil
+	}
+	cfg := cache.Config{
+		Backend:    orDefault(settings.Backend, cache.BackendMemory),
+		Service:    "cerebras",
+		MaxEntries: orDefault(settings.MaxEntries, defaultCacheMaxEntries),
+		MaxBytes:   orDefault(settings.MaxBytes, defaultCacheMaxBytes),
+		Dir:        orDefault(settings.Dir, defaultCacheDir),
+		RedisURL:   settings.RedisURL,
+	}
+
+	responseCache, err := cache.New(cfg)
+	if err != nil {
+		log.Printf("Warning: could not open %s response cache, using memory instead: %v", cfg.Backend, err)
+		return cache.NewLRU(cfg)
 	}
+	return responseCache
 }
 
-// parseDuration parses a duration string and returns a fallback if parsing fails
-func parseDuration(durationStr string, fallback time.Duration) time.Duration {
-	duration, err := time.ParseDuration(durationStr)
-	if err != nil {
+// cacheTTL returns how long cached answers are kept
+func cacheTTL(settings config.CacheConfig) time.Duration {
+	return orDefault(settings.Duration, defaultCacheTTL)
+}
+
+// orDefault returns value, or fallback when value is the zero value
+func orDefault[T comparable](value, fallback T) T {
+	var zero T
+	if value == zero {
 		return fallback
 	}
-	return duration
+	return value
 }
 
-// computeCacheKey generates a unique key for caching based on the request
-func computeCacheKey(model string, messages []Message) string {
-	// Marshal only what matters for caching
+// textRequest builds the completion request for a text query, so generated,
+// streamed and cached answers agree on the generation parameters
+func textRequest(model string, messages []Message, stream bool) ChatCompletionRequest {
+	return ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   500,
+		Stream:      stream,
+	}
+}
+
+// computeCacheKey generates a unique key for caching based on the request.
+// Every field that changes the answer is included; streaming does not.
+func computeCacheKey(request ChatCompletionRequest) string {
 	data := struct {
-		Model    string    `json:"model"`
-		Messages []Message `json:"messages"`
+		Model          string          `json:"model"`
+		Messages       []Message       `json:"messages"`
+		Temperature    float64         `json:"temperature"`
+		MaxTokens      int             `json:"max_tokens"`
+		TopP           float64         `json:"top_p"`
+		Seed           int             `json:"seed"`
+		Stop           []string        `json:"stop"`
+		ResponseFormat *ResponseFormat `json:"response_format"`
 	}{
-		Model:    model,
-		Messages: messages,
+		Model:          request.Model,
+		Messages:       request.Messages,
+		Temperature:    request.Temperature,
+		MaxTokens:      request.MaxTokens,
+		TopP:           request.TopP,
+		Seed:           request.Seed,
+		Stop:           request.Stop,
+		ResponseFormat: request.ResponseFormat,
 	}
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		// If marshaling fails, use a simpler approach
-		var key string
-		key = model
-		for _, msg := range messages {
+		key := fmt.Sprintf("%s:%g:%d", request.Model, request.Temperature, request.MaxTokens)
+		for _, msg := range request.Messages {
 			key += ":" + msg.Role + ":" + msg.Content
 		}
-		return key
+		jsonData = []byte(key)
 	}
 
-	hash := md5.Sum(jsonData)
+	hash := sha256.Sum256(jsonData)
 	return hex.EncodeToString(hash[:])
 }
 
-// GetCachedResponse retrieves a response from cache if available
-func (c *CerebrasClient) GetCachedResponse(model string, messages []Message) (string, bool) {
-	if !isCacheable(messages) {
-		return "", false
-	}
-
-	cacheKey := computeCacheKey(model, messages)
-
-	c.cacheMutex.RLock()
-	defer c.cacheMutex.RUnlock()
+// CachedTextResponse returns the cached answer to a text query, if any.
+// The key covers the query, the conversation context, the model and the
+// generation parameters used by GenerateTextResponse.
+func (c *CerebrasClient) CachedTextResponse(ctx context.Context, userQuery string, model string, conversationContext []Message) (string, bool) {
+	return c.getCachedResponse(ctx, textRequest(model, withUserQuery(conversationContext, userQuery), false))
+}
 
-	if cached, exists := c.cache[cacheKey]; exists {
-		if time.Now().Before(cached.Expiry) {
-			if c.metricsEnabled {
-				cacheHits.Inc()
-			}
-			return cached.Response, true
-		}
+// getCachedResponse retrieves a response from cache if available
+func (c *CerebrasClient) getCachedResponse(ctx context.Context, request ChatCompletionRequest) (string, bool) {
+	if c.cache == nil || !isCacheable(request.Messages) {
+		return "", false
 	}
 
-	if c.metricsEnabled {
-		cacheMisses.Inc()
+	response, ok, err := c.cache.Get(ctx, computeCacheKey(request))
+	if err != nil {
+		log.Printf("Error reading response cache: %v", err)
+		return "", false
 	}
-	return "", false
+	return response, ok
 }
 
-// SetCachedResponse stores a response in the cache
-func (c *CerebrasClient) SetCachedResponse(model string, messages []Message, response string) {
-	if !isCacheable(messages) {
+// setCachedResponse stores a successful response in the cache
+func (c *CerebrasClient) setCachedResponse(ctx context.Context, request ChatCompletionRequest, response string) {
+	if c.cache == nil || response == "" || !isCacheable(request.Messages) {
 		return
 	}
 
-	cacheKey := computeCacheKey(model, messages)
-
-	c.cacheMutex.Lock()
-	defer c.cacheMutex.Unlock()
-
-	c.cache[cacheKey] = CachedResponse{
-		Response: response,
-		Expiry:   time.Now().Add(c.cacheTTL),
-	}
-
-	// Prune expired entries occasionally
-	if rand.Intn(100) < 5 { // 5% chance to clean up on each set
-		c.pruneExpiredCache()
+	if err := c.cache.Set(ctx, computeCacheKey(request), response, c.cacheTTL); err != nil {
+		log.Printf("Error writing response cache: %v", err)
 	}
 }
 
-// pruneExpiredCache removes expired entries from the cache
-func (c *CerebrasClient) pruneExpiredCache() {
-	now := time.Now()
-	for key, cached := range c.cache {
-		if now.After(cached.Expiry) {
-			delete(c.cache, key)
-		}
-	}
+// withUserQuery returns the conversation context followed by the user's query.
+// The context is copied so the caller's slice is never written to.
+func withUserQuery(conversationContext []Message, userQuery string) []Message {
+	messages := make([]Message, 0, len(conversationContext)+1)
+	messages = append(messages, conversationContext...)
+	return append(messages, Message{
+		Role:    "user",
+		Content: userQuery,
+	})
 }
 
 // isCacheable determines if a request should be cached
@@ -403,237 +471,105 @@ func isCacheable(messages []Message) bool {
 	return true
 }
 
-// CheckCircuitBreaker determines if requests should be allowed based on failure history
+// CheckCircuitBreaker reports whether the circuit breaker would currently let
+// a request through. The client checks again for every request it sends.
 func (c *CerebrasClient) CheckCircuitBreaker() error {
-	c.circuitBreaker.mutex.RLock()
-	defer c.circuitBreaker.mutex.RUnlock()
-
-	if c.circuitBreaker.Open {
-		if time.Now().Before(c.circuitBreaker.OpenUntil) {
-			return fmt.Errorf("circuit breaker is open until %v", c.circuitBreaker.OpenUntil)
-		}
-	}
-
-	return nil
-}
-
-// RecordSuccess resets the failure counter on successful API calls
-func (c *CerebrasClient) RecordSuccess() {
-	c.circuitBreaker.mutex.Lock()
-	defer c.circuitBreaker.mutex.Unlock()
-
-	c.circuitBreaker.Failures = 0
-	c.circuitBreaker.Open = false
-}
-
-// RecordFailure tracks API failures and opens circuit breaker if threshold is exceeded
-func (c *CerebrasClient) RecordFailure() {
-	c.circuitBreaker.mutex.Lock()
-	defer c.circuitBreaker.mutex.Unlock()
-
-	c.circuitBreaker.Failures++
-	c.circuitBreaker.LastFailure = time.Now()
-
-	if c.circuitBreaker.Failures >= c.circuitBreaker.ThresholdCount {
-		c.circuitBreaker.Open = true
-		c.circuitBreaker.OpenUntil = time.Now().Add(c.circuitBreaker.ResetTimeout)
-		log.Printf("Circuit breaker opened until %v after %d failures",
-			c.circuitBreaker.OpenUntil, c.circuitBreaker.Failures)
-	}
+	return c.circuitBreaker.Ready()
 }
 
-// GenerateTextResponse generates a response to a text-only query
-func (c *CerebrasClient) GenerateTextResponse(userQuery string, model string, conversationContext []Message) (string, error) {
-	if c.apiKey == "" {
-		return "Lo sentimos, el asistente de arquitectura no está disponible en este momento. Por favor contacta al administrador para activar esta funcionalidad.", nil
-	}
-
-	// Create a context with user's query
-	messages := append(conversationContext, Message{
-		Role:    "user",
-		Content: userQuery,
-	})
-
-	// Create the request body
-	requestBody := ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   500,
-	}
+// GenerateTextResponse generates a response to a text-only query.
+// The request is abandoned when ctx is cancelled or its deadline passes.
+// Failures are returned as errors: ErrMissingAPIKey without an API key, and
+// *APIError, matching ErrRateLimited or ErrUnavailable, for non-200 answers.
+func (c *CerebrasClient) GenerateTextResponse(ctx context.Context, userQuery string, model string, conversationContext []Message) (string, error) {
+	// Create the request body with the user's query after the context
+	request := textRequest(model, withUserQuery(conversationContext, userQuery), false)
 
-	// Convert to JSON
-	requestBytes, err := json.Marshal(requestBody)
+	completionResponse, err := c.CreateChatCompletion(ctx, request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
 
-	// Create the HTTP request
-	req, err := http.NewRequest("POST", c.apiURL, bytes.NewBuffer(requestBytes))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
+	// Remove thinking tags if present
+	responseContent := removeThinkingTags(completionResponse.Choices[0].Message.Content)
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	// Only real answers are cached
+	c.setCachedResponse(ctx, request, responseContent)
 
-	// Convert standard request to retryable request
-	retryReq, err := retryablehttp.FromRequest(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to create retry request: %w", err)
-	}
+	return responseContent, nil
+}
 
-	// Make the request
-	resp, err := c.httpClient.Do(retryReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+// CreateChatCompletion sends a raw chat completion request and returns the
+// decoded response. Non-200 answers are returned as *APIError so callers can
+// decide how to present them.
+func (c *CerebrasClient) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if c.apiKey == "" {
+		return nil, ErrMissingAPIKey
 	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
+	resp, err := c.endpoint().complete(ctx, request)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API error: status code %d, body: %s", resp.StatusCode, string(body))
-
-		// Return user-friendly error messages based on status code
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return "No se pudo autenticar con el servicio de IA. Por favor verifica la configuración del asistente arquitectónico.", nil
-		case http.StatusForbidden:
-			return "No tienes permisos para utilizar el asistente arquitectónico. Por favor contacta al administrador.", nil
-		case http.StatusTooManyRequests:
-			return "El asistente arquitectónico está experimentando mucho tráfico. Por favor intenta de nuevo en unos momentos.", nil
-		case http.StatusServiceUnavailable:
-			return "El servicio de asistencia arquitectónica no está disponible temporalmente. Por favor intenta más tarde.", nil
-		default:
-			return "Hubo un problema al procesar tu consulta arquitectónica. Por favor intenta reformularla o contacta soporte técnico.", nil
-		}
-	}
-
-	var completionResponse ChatCompletionResponse
-	if err := json.Unmarshal(body, &completionResponse); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, err
 	}
+	tokenUsage.WithLabelValues("prompt").Add(float64(resp.Usage.PromptTokens))
+	tokenUsage.WithLabelValues("completion").Add(float64(resp.Usage.CompletionTokens))
+	return resp, nil
+}
 
-	if len(completionResponse.Choices) == 0 {
-		return "", fmt.Errorf("no completions returned")
+// StreamChatCompletion sends a streaming request and passes each piece of
+// content to onDelta as it arrives, <think> sections included
+func (c *CerebrasClient) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest, onDelta func(string) error) error {
+	if c.apiKey == "" {
+		return ErrMissingAPIKey
 	}
+	return c.endpoint().stream(ctx, request, onDelta)
+}
 
-	// Extract the response content
-	responseContent := completionResponse.Choices[0].Message.Content
-
-	// Remove thinking tags if present
-	responseContent = removeThinkingTags(responseContent)
-
-	return responseContent, nil
+// Name identifies the client as an LLMProvider
+func (c *CerebrasClient) Name() string {
+	return config.ProviderCerebras
 }
 
-// GenerateVisionResponse generates a response to a query with an image
-func (c *CerebrasClient) GenerateVisionResponse(userQuery string, imageBase64 string, conversationContext []Message) (string, error) {
-	if c.apiKey == "" {
-		return "Lo sentimos, el asistente de visión arquitectónica no está disponible en este momento. Por favor contacta al administrador para activar esta funcionalidad.", nil
+// endpoint returns the client's chat completions endpoint
+func (c *CerebrasClient) endpoint() chatEndpoint {
+	return chatEndpoint{
+		url:        c.apiURL,
+		apiKey:     c.apiKey,
+		httpClient: c.httpClient,
+		breaker:    c.circuitBreaker,
 	}
+}
 
+// GenerateVisionResponse generates a response to a query about an image.
+// mimeType is the image's detected type (see PrepareImage) and imageBase64
+// its standard base64 encoding. The request is abandoned when ctx is
+// cancelled or its deadline passes. Failures are returned as errors, as for
+// GenerateTextResponse.
+func (c *CerebrasClient) GenerateVisionResponse(ctx context.Context, userQuery string, mimeType string, imageBase64 string, conversationContext []Message) (string, error) {
 	if imageBase64 == "" {
 		return "", fmt.Errorf("image data is required for vision model")
 	}
 
-	// Determine image format (simple heuristic)
-	var imageFormat string
-	if len(imageBase64) > 0 {
-		imageFormat = "jpeg" // default assumption
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("unsupported image MIME type %q", mimeType)
 	}
 
-	// Create content with image
-	imageContent := fmt.Sprintf(
-		`%s\n\n![Imagen arquitectónica](data:image/%s;base64,%s)`,
-		userQuery,
-		imageFormat,
-		imageBase64,
-	)
+	// Send the query and the image as separate content parts
+	messages := append(append([]Message{}, conversationContext...), ImageMessage(userQuery, mimeType, imageBase64))
 
-	// Create a context with user's query and image
-	messages := append(conversationContext, Message{
-		Role:    "user",
-		Content: imageContent,
-	})
+	model := c.visionModel
+	if model == "" {
+		model = defaultVisionModel
+	}
 
-	// Create the request body - specifically use vision model
-	// Note: Check Cerebras documentation for current vision model availability
-	requestBody := ChatCompletionRequest{
-		Model:       "llama-4-scout-17b-16e-instruct", // Use appropriate vision-capable model
+	// Create the request body for the vision-capable model
+	completionResponse, err := c.CreateChatCompletion(ctx, ChatCompletionRequest{
+		Model:       model,
 		Messages:    messages,
 		Temperature: 0.7,
 		MaxTokens:   800, // Higher for vision descriptions
-	}
-
-	// Convert to JSON
-	requestBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal vision request: %w", err)
-	}
-
-	// Create the HTTP request
-	req, err := http.NewRequest("POST", c.apiURL, bytes.NewBuffer(requestBytes))
-	if err != nil {
-		return "", fmt.Errorf("failed to create vision request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Convert standard request to retryable request
-	retryReq, err := retryablehttp.FromRequest(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to create retry request for vision: %w", err)
-	}
-
-	// Make the request
-	resp, err := c.httpClient.Do(retryReq)
-	if err != nil {
-		return "", fmt.Errorf("failed to send vision request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read vision response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Vision API error: status code %d, body: %s", resp.StatusCode, string(body))
-
-		// Return user-friendly error messages based on status code
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return "No se pudo autenticar con el servicio de visión AI. Por favor verifica la configuración del asistente.", nil
-		case http.StatusForbidden:
-			return "No tienes permisos para utilizar el análisis de imágenes. Por favor contacta al administrador.", nil
-		case http.StatusTooManyRequests:
-			return "El servicio de análisis de imágenes está experimentando mucho tráfico. Por favor intenta de nuevo en unos momentos.", nil
-		case http.StatusServiceUnavailable:
-			return "El servicio de análisis de imágenes no está disponible temporalmente. Por favor intenta más tarde.", nil
-		case http.StatusRequestEntityTooLarge:
-			return "La imagen es demasiado grande. Por favor utiliza una imagen más pequeña (máximo 5MB).", nil
-		default:
-			return "Hubo un problema al procesar tu imagen arquitectónica. Por favor intenta con otra imagen o contacta soporte técnico.", nil
-		}
-	}
-
-	var completionResponse ChatCompletionResponse
-	if err := json.Unmarshal(body, &completionResponse); err != nil {
-		return "", fmt.Errorf("failed to unmarshal vision response: %w", err)
-	}
-
-	if len(completionResponse.Choices) == 0 {
-		return "No se pudo generar un análisis de la imagen. Por favor intenta con otra imagen más clara o con mejor iluminación.", nil
+		return "", err
 	}
 
 	return completionResponse.Choices[0].Message.Content, nil
@@ -641,16 +577,8 @@ func (c *CerebrasClient) GenerateVisionResponse(userQuery string, imageBase64 st
 
 // GenerateAssistantResponse is a legacy function that calls GenerateTextResponse with default model
 // Kept for backward compatibility
-func (c *CerebrasClient) GenerateAssistantResponse(userQuery string, conversationContext []Message) (string, error) {
-	return c.GenerateTextResponse(userQuery, "qwen-3-32b", conversationContext)
-}
-
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
+func (c *CerebrasClient) GenerateAssistantResponse(ctx context.Context, userQuery string, conversationContext []Message) (string, error) {
+	return c.GenerateTextResponse(ctx, userQuery, "qwen-3-32b", conversationContext)
 }
 
 // GetAPIStatus returns the status of the API authentication
@@ -662,21 +590,27 @@ func (c *CerebrasClient) GetAPIStatus() string {
 }
 
 // GetCacheSize returns the current number of items in the cache
-func (c *CerebrasClient) GetCacheSize() int {
-	c.cacheMutex.RLock()
-	defer c.cacheMutex.RUnlock()
-	return len(c.cache)
+func (c *CerebrasClient) GetCacheSize(ctx context.Context) int {
+	if c.cache == nil {
+		return 0
+	}
+	size, err := c.cache.Len(ctx)
+	if err != nil {
+		log.Printf("Error reading response cache size: %v", err)
+		return 0
+	}
+	return size
 }
 
-// GetCircuitState returns the current state of the circuit breaker
+// GetCircuitState returns the current state of the circuit breaker:
+// closed, open or half_open
 func (c *CerebrasClient) GetCircuitState() string {
-	c.circuitBreaker.mutex.RLock()
-	defer c.circuitBreaker.mutex.RUnlock()
+	return c.circuitBreaker.State()
+}
 
-	if c.circuitBreaker.Open {
-		return "open"
-	}
-	return "closed"
+// RemoveThinkingTags removes the <think> sections of reasoning models from an answer
+func RemoveThinkingTags(content string) string {
+	return removeThinkingTags(content)
 }
 
 // removeThinkingTags removes <think>...</think> tags and their content from the response
@@ -5,14 +5,16 @@ package ai
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
-	"sync"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/cache"
 )
 
 // MockRoundTripper is a mock implementation of http.RoundTripper for testing
@@ -83,15 +85,15 @@ func TestGenerateTextResponse(t *testing.T) {
 		apiKey:            "test-api-key",
 		apiURL:            "https://test-url.com",
 		httpClient:        retryClient,
-		cache:             make(map[string]CachedResponse),
+		cache:             cache.NewLRU(cache.Config{}),
 		cacheTTL:          15 * time.Minute,
-		circuitBreaker:    CircuitBreakerState{mutex: sync.RWMutex{}},
+		circuitBreaker:    NewCircuitBreaker(CircuitBreakerConfig{Service: "test"}),
 		concurrencyLimiter: nil, // Not needed for tests
 		metricsEnabled:    false,
 	}
 	
 	// Test with a simple query
-	response, err := client.GenerateTextResponse("Test query", "test-model", []Message{
+	response, err := client.GenerateTextResponse(context.Background(), "Test query", "test-model", []Message{
 		{Role: "system", Content: "You are a test assistant"},
 	})
 	
@@ -118,7 +120,7 @@ func TestGenerateVisionResponse(t *testing.T) {
 			body, _ := io.ReadAll(req.Body)
 			req.Body = io.NopCloser(bytes.NewBuffer(body)) // Reset the body for further reading
 			
-			if !strings.Contains(string(body), "base64,test-image-data") {
+			if !strings.Contains(string(body), `"image_url":{"url":"data:image/jpeg;base64,test-image-data"}`) {
 				t.Error("Request body doesn't contain the image data")
 			}
 			
@@ -163,15 +165,16 @@ func TestGenerateVisionResponse(t *testing.T) {
 		apiKey:            "test-api-key",
 		apiURL:            "https://test-url.com",
 		httpClient:        retryClient,
-		cache:             make(map[string]CachedResponse),
+		cache:             cache.NewLRU(cache.Config{}),
 		cacheTTL:          15 * time.Minute,
-		circuitBreaker:    CircuitBreakerState{mutex: sync.RWMutex{}},
+		circuitBreaker:    NewCircuitBreaker(CircuitBreakerConfig{Service: "test"}),
 		concurrencyLimiter: nil, // Not needed for tests
 		metricsEnabled:    false,
+		visionModel:       "cerebras/QWen-2.5-Vision",
 	}
 	
 	// Test with a query and image
-	response, err := client.GenerateVisionResponse("Analyze this image", "test-image-data", []Message{
+	response, err := client.GenerateVisionResponse(context.Background(), "Analyze this image", "image/jpeg", "test-image-data", []Message{
 		{Role: "system", Content: "You are a vision assistant"},
 	})
 	
@@ -212,25 +215,28 @@ func TestErrorHandling(t *testing.T) {
 		apiKey:            "test-api-key",
 		apiURL:            "https://test-url.com",
 		httpClient:        retryClient,
-		cache:             make(map[string]CachedResponse),
+		cache:             cache.NewLRU(cache.Config{}),
 		cacheTTL:          15 * time.Minute,
-		circuitBreaker:    CircuitBreakerState{mutex: sync.RWMutex{}},
+		circuitBreaker:    NewCircuitBreaker(CircuitBreakerConfig{Service: "test"}),
 		concurrencyLimiter: nil, // Not needed for tests
 		metricsEnabled:    false,
 	}
 	
 	// Test with a simple query
-	response, err := client.GenerateTextResponse("Test query", "test-model", []Message{
+	response, err := client.GenerateTextResponse(context.Background(), "Test query", "test-model", []Message{
 		{Role: "system", Content: "You are a test assistant"},
 	})
 	
-	// Should return a user-friendly error message without throwing an error
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
+	// Should return the status as an error, not a message posing as an answer
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected APIError with status 401, got %v", err)
 	}
-	
-	if !strings.Contains(response, "No se pudo autenticar") {
-		t.Errorf("Expected authentication error message, got '%s'", response)
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected 401 to match neither ErrRateLimited nor ErrUnavailable, got %v", err)
+	}
+	if response != "" {
+		t.Errorf("Expected no response, got '%s'", response)
 	}
 }
 
@@ -245,29 +251,21 @@ func TestNoAPIKey(t *testing.T) {
 		apiKey:            "",
 		apiURL:            "https://test-url.com",
 		httpClient:        retryClient,
-		cache:             make(map[string]CachedResponse),
+		cache:             cache.NewLRU(cache.Config{}),
 		cacheTTL:          15 * time.Minute,
-		circuitBreaker:    CircuitBreakerState{mutex: sync.RWMutex{}},
+		circuitBreaker:    NewCircuitBreaker(CircuitBreakerConfig{Service: "test"}),
 		concurrencyLimiter: nil, // Not needed for tests
 		metricsEnabled:    false,
 	}
 	
 	// Test text response
-	textResponse, err := client.GenerateTextResponse("Test query", "test-model", nil)
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
-	}
-	if !strings.Contains(textResponse, "Lo sentimos, el asistente de arquitectura no está disponible") {
-		t.Errorf("Expected service unavailable message, got '%s'", textResponse)
+	if _, err := client.GenerateTextResponse(context.Background(), "Test query", "test-model", nil); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey, got %v", err)
 	}
 	
 	// Test vision response
-	visionResponse, err := client.GenerateVisionResponse("Test query", "test-image", nil)
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
-	}
-	if !strings.Contains(visionResponse, "Lo sentimos, el asistente de visión arquitectónica no está disponible") {
-		t.Errorf("Expected vision service unavailable message, got '%s'", visionResponse)
+	if _, err := client.GenerateVisionResponse(context.Background(), "Test query", "image/jpeg", "test-image", nil); !errors.Is(err, ErrMissingAPIKey) {
+		t.Errorf("Expected ErrMissingAPIKey for vision, got %v", err)
 	}
 }
 
@@ -281,15 +279,15 @@ func TestVisionNoImage(t *testing.T) {
 		apiKey:            "test-api-key",
 		apiURL:            "https://test-url.com",
 		httpClient:        retryClient,
-		cache:             make(map[string]CachedResponse),
+		cache:             cache.NewLRU(cache.Config{}),
 		cacheTTL:          15 * time.Minute,
-		circuitBreaker:    CircuitBreakerState{mutex: sync.RWMutex{}},
+		circuitBreaker:    NewCircuitBreaker(CircuitBreakerConfig{Service: "test"}),
 		concurrencyLimiter: nil, // Not needed for tests
 		metricsEnabled:    false,
 	}
 	
 	// Test vision response with empty image
-	_, err := client.GenerateVisionResponse("Test query", "", nil)
+	_, err := client.GenerateVisionResponse(context.Background(), "Test query", "image/jpeg", "", nil)
 	if err == nil {
 		t.Error("Expected error for missing image, got nil")
 	}
@@ -298,3 +296,104 @@ func TestVisionNoImage(t *testing.T) {
 	}
 }
 
+// TestGenerateTextResponseHonoursContext tests that a cancelled context aborts the API call
+func TestGenerateTextResponseHonoursContext(t *testing.T) {
+	// Transport that only returns once the request's context is done
+	mockTransport := &MockRoundTripper{
+		RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		},
+	}
+
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient = &http.Client{Transport: mockTransport}
+	retryClient.RetryMax = 0
+	retryClient.Logger = nil
+
+	client := &CerebrasClient{
+		apiKey:     "test-api-key",
+		apiURL:     "https://test-url.com",
+		httpClient: retryClient,
+		cache:      cache.NewLRU(cache.Config{}),
+		cacheTTL:   15 * time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GenerateTextResponse(ctx, "Test query", "test-model", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Request was not abandoned promptly, took %v", elapsed)
+	}
+}
+
+// TestResponseCaching tests that answers are cached per query and that
+// failed requests are never cached
+func TestResponseCaching(t *testing.T) {
+	calls := 0
+	status := http.StatusOK
+	mockTransport := &MockRoundTripper{
+		RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return &http.Response{
+				StatusCode: status,
+				Body: io.NopCloser(bytes.NewBufferString(`{
+					"choices": [{"index": 0, "message": {"role": "assistant", "content": "Respuesta"}}]
+				}`)),
+			}, nil
+		},
+	}
+
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient = &http.Client{Transport: mockTransport}
+	retryClient.RetryMax = 0
+	retryClient.Logger = nil
+
+	client := &CerebrasClient{
+		apiKey:     "test-api-key",
+		apiURL:     "https://test-url.com",
+		httpClient: retryClient,
+		cache:      cache.NewLRU(cache.Config{}),
+		cacheTTL:   15 * time.Minute,
+	}
+	ctx := context.Background()
+	history := []Message{{Role: "system", Content: "Eres un asistente"}}
+
+	if _, ok := client.CachedTextResponse(ctx, "¿Qué es la NSR-10?", "test-model", history); ok {
+		t.Fatal("Expected an empty cache")
+	}
+	if _, err := client.GenerateTextResponse(ctx, "¿Qué es la NSR-10?", "test-model", history); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response, ok := client.CachedTextResponse(ctx, "¿Qué es la NSR-10?", "test-model", history); !ok || response != "Respuesta" {
+		t.Errorf("Expected cached 'Respuesta', got '%s' (hit %v)", response, ok)
+	}
+
+	// The query, the model and the context are all part of the key
+	if _, ok := client.CachedTextResponse(ctx, "¿Qué es el POT?", "test-model", history); ok {
+		t.Error("Expected a miss for a different query")
+	}
+	if _, ok := client.CachedTextResponse(ctx, "¿Qué es la NSR-10?", "other-model", history); ok {
+		t.Error("Expected a miss for a different model")
+	}
+	if _, ok := client.CachedTextResponse(ctx, "¿Qué es la NSR-10?", "test-model", nil); ok {
+		t.Error("Expected a miss for a different context")
+	}
+
+	// Errors are returned as errors and not cached
+	status = http.StatusUnauthorized
+	if _, err := client.GenerateTextResponse(ctx, "¿Qué es el POT?", "test-model", history); err == nil {
+		t.Fatal("Expected an error for a 401 answer")
+	}
+	if _, ok := client.CachedTextResponse(ctx, "¿Qué es el POT?", "test-model", history); ok {
+		t.Error("Expected error responses not to be cached")
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 API calls, got %d", calls)
+	}
+}
@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/cache"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// ChatModel is a logical model such as "assistant" or "vision": a model
+// served by a provider, with its default generation parameters. Answers are
+// cached per provider and model, and <think> sections are removed.
+//
+// Failures are returned as errors; presenting them to users is left to the
+// caller.
+type ChatModel struct {
+	// Name is the logical name the model was requested by
+	Name     string
+	Provider LLMProvider
+	Config   config.ModelConfig
+
+	cache    cache.ResponseCache
+	cacheTTL time.Duration
+	usage    UsageRecorder
+}
+
+// NewChatModel creates a model served by provider. responseCache may be nil
+// to disable caching.
+func NewChatModel(name string, provider LLMProvider, cfg config.ModelConfig, responseCache cache.ResponseCache, cacheTTL time.Duration) *ChatModel {
+	return &ChatModel{
+		Name:     name,
+		Provider: provider,
+		Config:   cfg,
+		cache:    responseCache,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Request builds a request for messages with the model's parameters
+func (m *ChatModel) Request(messages []Message) ChatCompletionRequest {
+	return m.withDefaults(ChatCompletionRequest{Messages: messages})
+}
+
+// withDefaults fills in the model and the parameters the request leaves unset
+func (m *ChatModel) withDefaults(request ChatCompletionRequest) ChatCompletionRequest {
+	if request.Model == "" {
+		request.Model = m.Config.Model
+	}
+	if request.Temperature == 0 {
+		request.Temperature = m.Config.Temperature
+	}
+	if request.MaxTokens == 0 {
+		request.MaxTokens = m.Config.MaxTokens
+	}
+	return request
+}
+
+// CreateChatCompletion sends a request to the provider, filling in the
+// model's parameters where the request leaves them unset. It is not cached,
+// so ChatModel can drive the agent and history summaries.
+func (m *ChatModel) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	request = m.withDefaults(request)
+	resp, err := m.Provider.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	m.recordUsage(ctx, m.responseUsage(request, resp))
+	return resp, nil
+}
+
+// CachedAnswer returns the cached answer to messages, if any
+func (m *ChatModel) CachedAnswer(ctx context.Context, messages []Message) (string, bool) {
+	request := m.Request(messages)
+	if m.cache == nil || !m.cacheable(request) {
+		return "", false
+	}
+	response, ok, err := m.cache.Get(ctx, m.cacheKey(request))
+	if err != nil {
+		log.Printf("Error reading response cache: %v", err)
+		return "", false
+	}
+	return response, ok
+}
+
+// Answer returns the model's answer to messages without <think> sections
+func (m *ChatModel) Answer(ctx context.Context, messages []Message) (string, error) {
+	request := m.Request(messages)
+	resp, err := m.Provider.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	m.recordUsage(ctx, m.responseUsage(request, resp))
+	answer := removeThinkingTags(resp.Choices[0].Message.Content)
+	m.store(ctx, request, answer)
+	return answer, nil
+}
+
+// StreamAnswer passes each piece of the answer's visible text to onToken as
+// it arrives. Streaming stops when ctx is done or onToken returns an error.
+// Streams do not report usage, so the tokens of whatever was received are
+// estimated, <think> sections included.
+func (m *ChatModel) StreamAnswer(ctx context.Context, messages []Message, onToken func(string) error) error {
+	request := m.Request(messages)
+
+	// The visible text is collected so a complete answer can be cached
+	var filter ThinkFilter
+	var answer, received strings.Builder
+	err := m.Provider.StreamChatCompletion(ctx, request, func(delta string) error {
+		received.WriteString(delta)
+		if visible := filter.Write(delta); visible != "" {
+			answer.WriteString(visible)
+			return onToken(visible)
+		}
+		return nil
+	})
+	if received.Len() > 0 {
+		m.recordUsage(ctx, TokenUsage{
+			Provider:         m.Provider.Name(),
+			Model:            request.Model,
+			PromptTokens:     EstimateTokens(request.Messages),
+			CompletionTokens: EstimateTokens([]Message{{Content: received.String()}}) - messageOverheadTokens,
+			Estimated:        true,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if rest := filter.Flush(); rest != "" {
+		answer.WriteString(rest)
+		if err := onToken(rest); err != nil {
+			return err
+		}
+	}
+
+	m.store(ctx, request, answer.String())
+	return nil
+}
+
+// CheckCircuitBreaker reports whether the provider would accept a request
+func (m *ChatModel) CheckCircuitBreaker() error {
+	return m.Provider.CheckCircuitBreaker()
+}
+
+// store caches a complete answer
+func (m *ChatModel) store(ctx context.Context, request ChatCompletionRequest, answer string) {
+	if m.cache == nil || answer == "" || !m.cacheable(request) {
+		return
+	}
+	if err := m.cache.Set(ctx, m.cacheKey(request), answer, m.cacheTTL); err != nil {
+		log.Printf("Error writing response cache: %v", err)
+	}
+}
+
+// cacheable reports whether answers to request may be cached. Questions
+// about images are never cached.
+func (m *ChatModel) cacheable(request ChatCompletionRequest) bool {
+	for _, msg := range request.Messages {
+		if len(msg.Parts) > 0 {
+			return false
+		}
+	}
+	return isCacheable(request.Messages)
+}
+
+// Label identifies the model as provider/model in logs and metrics
+func (m *ChatModel) Label() string {
+	return m.Provider.Name() + "/" + m.Config.Model
+}
+
+// cacheKey keys answers by provider as well, since several providers may
+// serve a model under the same name
+func (m *ChatModel) cacheKey(request ChatCompletionRequest) string {
+	request.Model = m.Provider.Name() + "/" + request.Model
+	return computeCacheKey(request)
+}
+
+// ImageMessage returns a user message asking query about an image.
+// mimeType is the image's detected type (see PrepareImage) and imageBase64
+// its standard base64 encoding.
+func ImageMessage(query string, mimeType string, imageBase64 string) Message {
+	return Message{
+		Role: "user",
+		Parts: []ContentPart{
+			{Type: "text", Text: query},
+			{Type: "image_url", ImageURL: &ImageURL{URL: "data:" + mimeType + ";base64," + imageBase64}},
+		},
+	}
+}
@@ -0,0 +1,312 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreakerConfig configures a CircuitBreaker
+type CircuitBreakerConfig struct {
+	// Service labels the published metrics and stats
+	Service string
+	// FailureThreshold failures within Window open the circuit
+	FailureThreshold int
+	Window           time.Duration
+	// OpenTimeout is how long the circuit stays open before trial requests
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial requests let through while
+	// half-open; the circuit closes once all of them succeed
+	HalfOpenRequests int
+}
+
+// DefaultCircuitBreakerConfig returns the settings used by the Cerebras client
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Service:          "cerebras",
+		FailureThreshold: 5,
+		Window:           60 * time.Second,
+		OpenTimeout:      60 * time.Second,
+		HalfOpenRequests: 3,
+	}
+}
+
+// CircuitBreaker stops calls to a failing service.
+//
+// While closed, every request is allowed and failures are counted over a
+// rolling window. Reaching the threshold opens the circuit and requests are
+// rejected with ErrCircuitOpen. After OpenTimeout the circuit is half-open
+// and lets a limited number of trial requests through: one failure opens it
+// again, and HalfOpenRequests successes close it. A request only counts
+// toward the state that let it through: one still running when the state
+// changes is not counted.
+//
+// Only server errors (5xx), timeouts and failures to reach the service count
+// as failures. Client errors (4xx) show the service is answering, and
+// cancelled requests say nothing about it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	cfg       CircuitBreakerConfig
+	state     string
+	failures  []time.Time // failure times within the window, oldest first
+	openUntil time.Time
+	lastFail  time.Time
+	inFlight  int // trial requests in progress while half-open
+	successes int // successful trial requests while half-open
+	// generation changes with the state, so outcomes of requests let
+	// through by an earlier state are told apart
+	generation uint64
+	opens      int64
+	now        func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker and publishes its state
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	defaults := DefaultCircuitBreakerConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaults.Window
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaults.OpenTimeout
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = defaults.HalfOpenRequests
+	}
+
+	b := &CircuitBreaker{cfg: cfg, state: CircuitClosed, now: time.Now}
+	b.publish()
+	return b
+}
+
+// Ready reports whether a request would currently be allowed, without
+// reserving a trial request. A nil breaker is always ready.
+func (b *CircuitBreaker) Ready() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case CircuitOpen:
+		return fmt.Errorf("%w until %v", ErrCircuitOpen, b.openUntil.Format(time.RFC3339))
+	case CircuitHalfOpen:
+		if b.inFlight >= b.cfg.HalfOpenRequests {
+			return fmt.Errorf("%w: trial requests in progress", ErrCircuitOpen)
+		}
+	}
+	return nil
+}
+
+// Allow reports whether a request may be sent. An allowed request must be
+// followed by exactly one call to done with its outcome.
+func (b *CircuitBreaker) Allow() (done func(error), err error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case CircuitOpen:
+		return nil, fmt.Errorf("%w until %v", ErrCircuitOpen, b.openUntil.Format(time.RFC3339))
+	case CircuitHalfOpen:
+		if b.inFlight >= b.cfg.HalfOpenRequests {
+			return nil, fmt.Errorf("%w: trial requests in progress", ErrCircuitOpen)
+		}
+		b.inFlight++
+	}
+	generation := b.generation
+	return func(err error) { b.record(generation, err) }, nil
+}
+
+// record reports the outcome of a request let through while the breaker
+// was at generation. Requests from an earlier state are ignored.
+func (b *CircuitBreaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.currentState()
+	if generation != b.generation {
+		return
+	}
+	if state == CircuitHalfOpen {
+		b.inFlight--
+	}
+
+	switch classifyFailure(err) {
+	case outcomeFailure:
+		now := b.now()
+		b.lastFail = now
+		if state == CircuitHalfOpen {
+			b.open(now)
+			return
+		}
+		b.failures = append(b.pruneFailures(now), now)
+		if state == CircuitClosed && len(b.failures) >= b.cfg.FailureThreshold {
+			b.open(now)
+			return
+		}
+	case outcomeSuccess:
+		if state == CircuitHalfOpen {
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenRequests {
+				b.setState(CircuitClosed)
+				return
+			}
+		}
+	}
+	b.publish()
+}
+
+// State returns the current state: closed, open or half_open
+func (b *CircuitBreaker) State() string {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// Stats returns the breaker's statistics
+func (b *CircuitBreaker) Stats() monitoring.CircuitStats {
+	if b == nil {
+		return monitoring.CircuitStats{State: CircuitClosed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.currentState()
+	return b.stats()
+}
+
+// currentState moves an open circuit whose timeout has passed to half-open.
+// b.mu must be held.
+func (b *CircuitBreaker) currentState() string {
+	if b.state == CircuitOpen && !b.now().Before(b.openUntil) {
+		b.setState(CircuitHalfOpen)
+	}
+	return b.state
+}
+
+// open opens the circuit for OpenTimeout. b.mu must be held.
+func (b *CircuitBreaker) open(now time.Time) {
+	b.openUntil = now.Add(b.cfg.OpenTimeout)
+	b.opens++
+	b.setState(CircuitOpen)
+}
+
+// setState changes the state, resets the counters of the new state and
+// publishes the change. b.mu must be held.
+func (b *CircuitBreaker) setState(state string) {
+	log.Printf("Circuit breaker %s: %s -> %s", b.cfg.Service, b.state, state)
+	b.state = state
+	b.generation++
+	b.inFlight = 0
+	b.successes = 0
+	if state == CircuitClosed {
+		b.failures = nil
+	}
+	b.publish()
+}
+
+// pruneFailures drops failures that are older than the window. b.mu must be held.
+func (b *CircuitBreaker) pruneFailures(now time.Time) []time.Time {
+	cutoff := now.Add(-b.cfg.Window)
+	i := 0
+	for i < len(b.failures) && !b.failures[i].After(cutoff) {
+		i++
+	}
+	b.failures = b.failures[i:]
+	return b.failures
+}
+
+// stats builds the published statistics. b.mu must be held.
+func (b *CircuitBreaker) stats() monitoring.CircuitStats {
+	stats := monitoring.CircuitStats{
+		State:        b.state,
+		FailureCount: len(b.pruneFailures(b.now())),
+		ResetTimeout: b.cfg.OpenTimeout.String(),
+		LastFailure:  b.lastFail,
+		Opens:        b.opens,
+	}
+	if b.state == CircuitOpen {
+		stats.OpenUntil = b.openUntil
+	}
+	return stats
+}
+
+// publish exports the state to the circuit breaker gauge and /stats.
+// b.mu must be held.
+func (b *CircuitBreaker) publish() {
+	monitoring.SetCircuitBreakerState(b.cfg.Service, b.state)
+	monitoring.UpdateCircuitStats(b.cfg.Service, b.stats())
+}
+
+// failureOutcome is how a request's result affects the breaker
+type failureOutcome int
+
+const (
+	outcomeIgnored failureOutcome = iota
+	outcomeSuccess
+	outcomeFailure
+)
+
+// classifyFailure decides whether err counts against the service
+func classifyFailure(err error) failureOutcome {
+	if err == nil {
+		return outcomeSuccess
+	}
+	if errors.Is(err, context.Canceled) {
+		return outcomeIgnored
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return outcomeFailure
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return outcomeFailure
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode >= 500 {
+			return outcomeFailure
+		}
+		return outcomeSuccess
+	}
+	// The service could not be reached at all
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return outcomeFailure
+	}
+	return outcomeIgnored
+}
+
+// statusError turns an HTTP exchange into the error the breaker classifies
+func statusError(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return &APIError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
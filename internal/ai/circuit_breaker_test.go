@@ -0,0 +1,237 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestBreaker returns a breaker for service with a clock the test controls
+func newTestBreaker(service string, halfOpenRequests int) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2025, 5, 28, 9, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(CircuitBreakerConfig{
+		Service:          service,
+		FailureThreshold: 3,
+		Window:           time.Minute,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: halfOpenRequests,
+	})
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+// fail records a server error through the breaker
+func fail(t *testing.T, b *CircuitBreaker) {
+	t.Helper()
+	allow(t, b)(&APIError{StatusCode: http.StatusBadGateway})
+}
+
+// allow lets a request through the breaker and returns its done function
+func allow(t *testing.T, b *CircuitBreaker) func(error) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected request to be allowed, got %v", err)
+	}
+	return done
+}
+
+// TestCircuitBreakerTransitions tests closed -> open -> half-open -> closed
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b, now := newTestBreaker("cb-transitions", 2)
+	gauge := monitoring.CircuitBreakerState.WithLabelValues("cb-transitions")
+
+	fail(t, b)
+	fail(t, b)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("Expected closed below the threshold, got %s", state)
+	}
+	fail(t, b)
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("Expected open at the threshold, got %s", state)
+	}
+	if value := testutil.ToFloat64(gauge); value != 1 {
+		t.Errorf("Expected gauge 1 while open, got %v", value)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	// After the timeout a limited number of trial requests get through
+	*now = now.Add(30 * time.Second)
+	if err := b.Ready(); err != nil {
+		t.Fatalf("Expected half-open breaker to be ready, got %v", err)
+	}
+	if value := testutil.ToFloat64(gauge); value != 2 {
+		t.Errorf("Expected gauge 2 while half-open, got %v", value)
+	}
+	trials := []func(error){allow(t, b), allow(t, b)}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected extra trial request to be rejected, got %v", err)
+	}
+
+	trials[0](nil)
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("Expected half-open until every trial succeeds, got %s", state)
+	}
+	trials[1](nil)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("Expected closed after successful trials, got %s", state)
+	}
+	if value := testutil.ToFloat64(gauge); value != 0 {
+		t.Errorf("Expected gauge 0 while closed, got %v", value)
+	}
+
+	stats, ok := monitoring.GetServiceStats("cb-transitions")
+	if !ok {
+		t.Fatal("Expected circuit stats to be published")
+	}
+	if stats.CircuitStats.State != CircuitClosed || stats.CircuitStats.Opens != 1 || stats.ErrorStats.CircuitBreakerOpens != 1 {
+		t.Errorf("Unexpected published stats: %+v", stats)
+	}
+}
+
+// TestCircuitBreakerHalfOpenFailure tests that a failed trial reopens the circuit
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	b, now := newTestBreaker("cb-half-open", 1)
+	for i := 0; i < 3; i++ {
+		fail(t, b)
+	}
+
+	*now = now.Add(time.Minute)
+	fail(t, b)
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("Expected a failed trial to reopen the circuit, got %s", state)
+	}
+	if stats := b.Stats(); stats.Opens != 2 || !stats.OpenUntil.Equal(now.Add(30*time.Second)) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestCircuitBreakerStaleRequests tests that requests let through before the
+// circuit opened do not take trial slots or change the half-open state
+func TestCircuitBreakerStaleRequests(t *testing.T) {
+	b, now := newTestBreaker("cb-stale", 1)
+	slow := []func(error){allow(t, b), allow(t, b)}
+	for i := 0; i < 3; i++ {
+		fail(t, b)
+	}
+
+	*now = now.Add(30 * time.Second)
+	trial := allow(t, b)
+
+	// A slow failure neither frees the trial slot nor reopens the circuit
+	slow[0](&APIError{StatusCode: http.StatusBadGateway})
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("Expected a stale failure to be ignored, got %s", state)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the trial slot to stay taken, got %v", err)
+	}
+
+	// Nor does a slow success count toward closing it
+	slow[1](nil)
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("Expected a stale success to be ignored, got %s", state)
+	}
+
+	trial(nil)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("Expected the trial to close the circuit, got %s", state)
+	}
+}
+
+// TestCircuitBreakerRollingWindow tests that old failures stop counting
+func TestCircuitBreakerRollingWindow(t *testing.T) {
+	b, now := newTestBreaker("cb-window", 1)
+	fail(t, b)
+	fail(t, b)
+
+	*now = now.Add(61 * time.Second)
+	fail(t, b)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("Expected failures outside the window to be forgotten, got %s", state)
+	}
+	if count := b.Stats().FailureCount; count != 1 {
+		t.Errorf("Expected 1 failure in the window, got %d", count)
+	}
+}
+
+// TestClassifyFailure tests which errors count against the service
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want failureOutcome
+	}{
+		{"success", nil, outcomeSuccess},
+		{"server error", &APIError{StatusCode: http.StatusServiceUnavailable}, outcomeFailure},
+		{"client error", &APIError{StatusCode: http.StatusBadRequest}, outcomeSuccess},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, outcomeSuccess},
+		{"deadline", fmt.Errorf("failed to send request: %w", context.DeadlineExceeded), outcomeFailure},
+		{"cancelled", fmt.Errorf("failed to send request: %w", context.Canceled), outcomeIgnored},
+		{"unreachable", &url.Error{Op: "Post", URL: "https://test-url.com", Err: errors.New("connection refused")}, outcomeFailure},
+		{"other", errors.New("failed to marshal request"), outcomeIgnored},
+	}
+	for _, tt := range tests {
+		if got := classifyFailure(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestClientUsesCircuitBreaker tests that server errors open the circuit and
+// later requests are rejected without calling the API
+func TestClientUsesCircuitBreaker(t *testing.T) {
+	calls := 0
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient = &http.Client{Transport: &MockRoundTripper{
+		RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+			calls++
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(bytes.NewBufferString(`{"error": "internal"}`)),
+			}, nil
+		},
+	}}
+	retryClient.RetryMax = 0
+	retryClient.Logger = nil
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+	breaker, _ := newTestBreaker("cb-client", 1)
+	client := &CerebrasClient{
+		apiKey:         "test-api-key",
+		apiURL:         "https://test-url.com",
+		httpClient:     retryClient,
+		circuitBreaker: breaker,
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GenerateTextResponse(context.Background(), "Test query", "test-model", nil); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("Expected ErrUnavailable for a server error, got %v", err)
+		}
+	}
+	if state := client.GetCircuitState(); state != CircuitOpen {
+		t.Fatalf("Expected open circuit, got %s", state)
+	}
+
+	if _, err := client.GenerateTextResponse(context.Background(), "Test query", "test-model", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if err := client.CheckCircuitBreaker(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected CheckCircuitBreaker to report ErrCircuitOpen, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 API calls, got %d", calls)
+	}
+}
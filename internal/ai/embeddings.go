@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// defaultHashDimensions is the size of the vectors of the built-in embedder
+const defaultHashDimensions = 512
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// related the texts are
+type Embedder interface {
+	// Name identifies the embedding model; vectors of different models
+	// cannot be compared
+	Name() string
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embeddingProvider is a provider that also serves embedding models
+type embeddingProvider interface {
+	LLMProvider
+	embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// providerEmbedder embeds texts with a model served by a provider
+type providerEmbedder struct {
+	provider embeddingProvider
+	model    string
+}
+
+func (e providerEmbedder) Name() string {
+	return e.provider.Name() + ":" + e.model
+}
+
+func (e providerEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := e.provider.embed(ctx, e.model, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding model %s returned %d vectors for %d texts", e.Name(), len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// Embedder returns the embedding model described by cfg. Without a provider
+// the built-in hashing embedder is used, which needs no model server but
+// only matches shared words.
+func (f *ModelFactory) Embedder(cfg config.ModelConfig) (Embedder, error) {
+	if cfg.Provider == "" {
+		return NewHashEmbedder(defaultHashDimensions), nil
+	}
+	provider, ok := f.Provider(cfg.Provider)
+	if !ok {
+		return nil, fmt.Errorf("embeddings: provider not found: %s", cfg.Provider)
+	}
+	embedding, ok := provider.(embeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("embeddings: provider %s does not serve embedding models", cfg.Provider)
+	}
+	return providerEmbedder{provider: embedding, model: cfg.Model}, nil
+}
+
+// embed calls the OpenAI-compatible /embeddings endpoint next to the chat
+// completions endpoint
+func (p *OpenAIProvider) embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	url := strings.TrimSuffix(p.endpoint.url, "/chat/completions") + "/embeddings"
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	request := map[string]interface{}{"model": model, "input": texts}
+	if err := postJSON(ctx, p.endpoint.httpClient, p.endpoint.breaker, url, p.endpoint.apiKey, request, &response); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(response.Data))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index out of range: %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// embed calls Ollama's /api/embed endpoint
+func (p *OllamaProvider) embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	url := strings.TrimSuffix(p.url, "/api/chat") + "/api/embed"
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	request := map[string]interface{}{"model": model, "input": texts}
+	if err := postJSON(ctx, p.httpClient, p.breaker, url, "", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Embeddings, nil
+}
+
+// postJSON sends a JSON request through a provider's circuit breaker and
+// decodes the JSON response into out
+func postJSON(ctx context.Context, client *retryablehttp.Client, breaker *CircuitBreaker, url, apiKey string, request, out interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := retryablehttp.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	done, err := breaker.Allow()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	done(statusError(resp, err))
+	if err != nil {
+		return sendError(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("API error from %s: status code %d, body: %s", url, resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// HashEmbedder is a local embedder that hashes the words and word pairs of
+// a text into a fixed number of dimensions. It needs no model server and is
+// deterministic, but only relates texts that share words.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder creates a hashing embedder producing vectors of the given size
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+// Name identifies the embedder and its vector size
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", e.dimensions)
+}
+
+// Embed returns the normalised hashed word counts of each text
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	words := embeddingWords(text)
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The sign bit spreads collisions out instead of piling them up
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vector[sum%uint64(e.dimensions)] += weight
+	}
+	for i, word := range words {
+		add(word, 1)
+		if i > 0 {
+			add(words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
+
+// embeddingAccents removes Spanish accents so "sísmica" matches "sismica"
+var embeddingAccents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// embeddingStopWords are frequent Spanish words that carry no meaning for retrieval
+var embeddingStopWords = map[string]bool{
+	"el": true, "la": true, "los": true, "las": true, "un": true, "una": true, "de": true, "del": true,
+	"en": true, "y": true, "o": true, "a": true, "al": true, "que": true, "se": true, "por": true,
+	"para": true, "con": true, "es": true, "son": true, "su": true, "sus": true, "lo": true, "como": true,
+	"cual": true, "cuales": true, "debe": true, "deben": true, "mas": true, "no": true, "si": true,
+}
+
+// embeddingWords splits text into lower case words without accents or stop
+// words, with a plural "s" removed
+func embeddingWords(text string) []string {
+	text = embeddingAccents.Replace(strings.ToLower(text))
+	fields := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	words := fields[:0]
+	for _, word := range fields {
+		if embeddingStopWords[word] {
+			continue
+		}
+		if len(word) > 4 && strings.HasSuffix(word, "s") {
+			word = strings.TrimSuffix(word, "s")
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if they differ in size or either is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
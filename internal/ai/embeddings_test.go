@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// TestHashEmbedder tests that texts sharing words are more similar than unrelated ones
+func TestHashEmbedder(t *testing.T) {
+	embedder := NewHashEmbedder(256)
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"Recubrimiento mínimo del refuerzo en columnas de concreto",
+		"¿Qué recubrimiento necesitan las columnas?",
+		"Horario de atención de la curaduría urbana",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(vectors) != 4 || len(vectors[0]) != 256 {
+		t.Fatalf("Expected 4 vectors of 256 dimensions, got %d", len(vectors))
+	}
+	related := CosineSimilarity(vectors[0], vectors[1])
+	unrelated := CosineSimilarity(vectors[0], vectors[2])
+	if related <= unrelated || related < 0.3 {
+		t.Errorf("Expected related texts to be closer: related %.2f, unrelated %.2f", related, unrelated)
+	}
+	if got := CosineSimilarity(vectors[0], vectors[3]); got != 0 {
+		t.Errorf("Expected 0 similarity with an empty text, got %.2f", got)
+	}
+	if embedder.Name() != "hash-256" {
+		t.Errorf("Expected name hash-256, got %s", embedder.Name())
+	}
+}
+
+// TestProviderEmbedder tests embedding through an OpenAI-compatible endpoint
+func TestProviderEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("Expected /v1/embeddings, got %s", r.URL.Path)
+		}
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if request.Model != "text-embedding-3-small" || len(request.Input) != 2 {
+			t.Errorf("Unexpected request: %+v", request)
+		}
+		// Results may come back out of order
+		fmt.Fprint(w, `{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`)
+	}))
+	defer server.Close()
+
+	factory := NewModelFactory()
+	factory.RegisterProvider(NewOpenAIProvider("openai", server.URL+"/v1/", ""))
+	embedder, err := factory.Embedder(config.ModelConfig{Provider: "openai", Model: "text-embedding-3-small"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if embedder.Name() != "openai:text-embedding-3-small" {
+		t.Errorf("Unexpected name %s", embedder.Name())
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("Expected vectors in input order, got %v", vectors)
+	}
+
+	if _, err := factory.Embedder(config.ModelConfig{Provider: "missing", Model: "x"}); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+	if embedder, err := factory.Embedder(config.ModelConfig{}); err != nil || embedder.Name() != "hash-512" {
+		t.Errorf("Expected the built-in embedder by default, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrMissingAPIKey is returned when CEREBRAS_API_KEY is not configured
+var ErrMissingAPIKey = errors.New("CEREBRAS_API_KEY is not set")
+
+// ErrCircuitOpen is returned when the circuit breaker rejects a request
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrRateLimited is matched by errors for requests the provider rejected
+// with 429 Too Many Requests
+var ErrRateLimited = errors.New("rate limited by provider")
+
+// ErrUnavailable is matched by errors for providers that answered with a
+// server error or could not be reached
+var ErrUnavailable = errors.New("provider unavailable")
+
+// APIError is returned when a provider answers with a non-200 status.
+// It matches ErrRateLimited for 429 and ErrUnavailable for 5xx statuses.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: status code %d", e.StatusCode)
+}
+
+// Unwrap returns the typed error for the status, if any
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// sendError wraps a failure to send a request. Failures caused by the
+// caller's context keep only the context's error; the rest mean the provider
+// could not be reached and match ErrUnavailable.
+func sendError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	return fmt.Errorf("%w: failed to send request: %w", ErrUnavailable, err)
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultHistoryTokenBudget is the number of tokens of conversation history
+// sent to the model with each question
+const DefaultHistoryTokenBudget = 3000
+
+// messageOverheadTokens approximates the role and formatting tokens of a message
+const messageOverheadTokens = 4
+
+// EstimateTokens approximates the number of tokens in messages using about
+// four characters per token. It errs on the high side for Spanish text.
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageOverheadTokens + (utf8.RuneCountInString(msg.Content)+3)/4
+	}
+	return total
+}
+
+// HistoryWindow returns the index of the first message that fits in budget
+// tokens when the most recent messages are kept. The window always starts at
+// a user message so a question is never separated from its answer.
+func HistoryWindow(history []Message, budget int) int {
+	used := 0
+	start := len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		used += EstimateTokens(history[i : i+1])
+		if used > budget {
+			break
+		}
+		start = i
+	}
+	for start < len(history) && history[start].Role != "user" {
+		start++
+	}
+	return start
+}
+
+// SummarizeHistory asks the model to fold messages into the running summary
+// of a conversation so older turns can be dropped from the prompt.
+func SummarizeHistory(ctx context.Context, client ChatCompleter, model string, previousSummary string, messages []Message) (string, error) {
+	var transcript strings.Builder
+	if previousSummary != "" {
+		fmt.Fprintf(&transcript, "Resumen previo:\n%s\n\n", previousSummary)
+	}
+	transcript.WriteString("Conversación:\n")
+	for _, msg := range messages {
+		role := "Usuario"
+		if msg.Role == "assistant" {
+			role = "Asistente"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, msg.Content)
+	}
+
+	resp, err := client.CreateChatCompletion(ctx, ChatCompletionRequest{
+		Model: model,
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: "Resume la conversación entre un arquitecto y su asistente en español, en un máximo de 150 palabras. Conserva nombres de proyectos, cifras, normas citadas, decisiones y preguntas pendientes. Responde solo con el resumen.",
+			},
+			{Role: "user", Content: transcript.String()},
+		},
+		Temperature: 0.2,
+		MaxTokens:   400,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(removeThinkingTags(resp.Choices[0].Message.Content))
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestHistoryWindow tests that the most recent turns are kept within the budget
+func TestHistoryWindow(t *testing.T) {
+	long := strings.Repeat("a", 400) // ~100 tokens
+	history := []Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+	}
+
+	if got := HistoryWindow(history, 1000); got != 0 {
+		t.Errorf("Expected everything to fit, window starts at %d", got)
+	}
+	// Room for three messages, but the window must start on a user message
+	if got := HistoryWindow(history, 320); got != 2 {
+		t.Errorf("Expected window to start at 2, got %d", got)
+	}
+	if got := HistoryWindow(history, 10); got != len(history) {
+		t.Errorf("Expected nothing to fit, got %d", got)
+	}
+}
+
+// TestSummarizeHistory tests that the transcript and previous summary reach the model
+func TestSummarizeHistory(t *testing.T) {
+	completer := &scriptedCompleter{responses: []ResponseMessage{
+		{Role: "assistant", Content: "<think>...</think> El usuario pregunta por la NSR-10."},
+	}}
+	summary, err := SummarizeHistory(context.Background(), completer, "test-model", "Proyecto Casa Usaquén", []Message{
+		{Role: "user", Content: "¿Qué exige la NSR-10?"},
+		{Role: "assistant", Content: "Diseño sismo resistente."},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if summary != "El usuario pregunta por la NSR-10." {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+	prompt := completer.requests[0].Messages[1].Content
+	for _, want := range []string{"Proyecto Casa Usaquén", "Usuario: ¿Qué exige la NSR-10?", "Asistente: Diseño sismo resistente."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Errors returned by PrepareImage
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type")
+	ErrImageTooLarge    = errors.New("image too large")
+	ErrImageTooSmall    = errors.New("image too small")
+	ErrInvalidImage     = errors.New("invalid image")
+)
+
+// visionImageTypes are the detected MIME types accepted for analysis.
+// JPEG and PNG are sent as they are; the others are always re-encoded.
+var visionImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// ImageLimits bounds the images accepted by the vision model
+type ImageLimits struct {
+	// MaxBytes is the largest upload accepted
+	MaxBytes int64
+	// MaxPixels caps width*height; it is checked before the image is decoded
+	MaxPixels int
+	// MinDimension is the shortest side that can still be analysed
+	MinDimension int
+	// MaxDimension is the longest side sent to the model; larger images are downscaled
+	MaxDimension int
+}
+
+// DefaultImageLimits returns the limits used when none are configured
+func DefaultImageLimits() ImageLimits {
+	return ImageLimits{
+		MaxBytes:     10 << 20,
+		MaxPixels:    50_000_000,
+		MinDimension: 32,
+		MaxDimension: 2048,
+	}
+}
+
+// PreparedImage is an image ready to be sent to the vision model
+type PreparedImage struct {
+	MIMEType string
+	Data     []byte
+	Width    int
+	Height   int
+	// Resized reports whether the image was downscaled
+	Resized bool
+}
+
+// Base64 returns the image data encoded for a data URL
+func (p *PreparedImage) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// PrepareImage validates an uploaded image and downscales it when its longest
+// side exceeds limits.MaxDimension. The type is detected from the bytes, not
+// from the file name or the client's Content-Type.
+func PrepareImage(data []byte, limits ImageLimits) (*PreparedImage, error) {
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrImageTooLarge, len(data), limits.MaxBytes)
+	}
+	mimeType := http.DetectContentType(data)
+	if !visionImageTypes[mimeType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, mimeType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > limits.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrImageTooLarge, cfg.Width, cfg.Height, limits.MaxPixels)
+	}
+	if min(cfg.Width, cfg.Height) < limits.MinDimension {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooSmall, cfg.Width, cfg.Height)
+	}
+
+	longest := max(cfg.Width, cfg.Height)
+	native := mimeType == "image/jpeg" || mimeType == "image/png"
+	if native && longest <= limits.MaxDimension {
+		return &PreparedImage{MIMEType: mimeType, Data: data, Width: cfg.Width, Height: cfg.Height}, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	width, height := cfg.Width, cfg.Height
+	if longest > limits.MaxDimension {
+		scale := float64(limits.MaxDimension) / float64(longest)
+		width = max(1, int(math.Round(float64(width)*scale)))
+		height = max(1, int(math.Round(float64(height)*scale)))
+	}
+
+	// Transparent areas become white, which is how plans are printed
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	// Drawings keep lossless PNG so thin lines and dimensions stay legible
+	var buf bytes.Buffer
+	outType := "image/jpeg"
+	if mimeType == "image/png" || mimeType == "image/gif" {
+		outType = "image/png"
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encoding resized image: %w", err)
+	}
+
+	return &PreparedImage{
+		MIMEType: outType,
+		Data:     buf.Bytes(),
+		Width:    width,
+		Height:   height,
+		Resized:  width != cfg.Width || height != cfg.Height,
+	}, nil
+}
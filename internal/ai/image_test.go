@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encodeTestImage returns a solid image of the given size in the given format
+func encodeTestImage(t *testing.T, width, height int, format string) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.Black)
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestPrepareImageKeepsSmallImages tests that images within limits are sent unchanged
+func TestPrepareImageKeepsSmallImages(t *testing.T) {
+	data := encodeTestImage(t, 800, 600, "jpeg")
+	prepared, err := PrepareImage(data, DefaultImageLimits())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if prepared.MIMEType != "image/jpeg" || prepared.Resized || !bytes.Equal(prepared.Data, data) {
+		t.Errorf("Expected the JPEG unchanged, got %s resized=%v", prepared.MIMEType, prepared.Resized)
+	}
+}
+
+// TestPrepareImageDownscales tests that large plans are downscaled and stay PNG
+func TestPrepareImageDownscales(t *testing.T) {
+	limits := DefaultImageLimits()
+	limits.MaxDimension = 1000
+	prepared, err := PrepareImage(encodeTestImage(t, 3000, 1500, "png"), limits)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !prepared.Resized || prepared.Width != 1000 || prepared.Height != 500 {
+		t.Errorf("Expected 1000x500 after resizing, got %dx%d resized=%v", prepared.Width, prepared.Height, prepared.Resized)
+	}
+	if prepared.MIMEType != "image/png" {
+		t.Errorf("Expected PNG output, got %s", prepared.MIMEType)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(prepared.Data))
+	if err != nil || format != "png" || cfg.Width != 1000 {
+		t.Errorf("Resized data does not match: %v %s %d", err, format, cfg.Width)
+	}
+}
+
+// TestPrepareImageLimits tests the rejected inputs
+func TestPrepareImageLimits(t *testing.T) {
+	limits := DefaultImageLimits()
+	limits.MaxPixels = 1000 * 1000
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not an image", []byte("%PDF-1.7 plano"), ErrUnsupportedImage},
+		{"too small", encodeTestImage(t, 10, 200, "png"), ErrImageTooSmall},
+		{"too many pixels", encodeTestImage(t, 2000, 1000, "png"), ErrImageTooLarge},
+		{"corrupt", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 20)...), ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PrepareImage(tt.data, limits); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	limits.MaxBytes = 100
+	if _, err := PrepareImage(encodeTestImage(t, 200, 200, "jpeg"), limits); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge for the upload size, got %v", err)
+	}
+}
@@ -5,8 +5,11 @@ package ai
 
 import (
     "context"
+    "encoding/json"
+    "errors"
     "fmt"
     "log"
+    "sort"
     "sync"
     "time"
 
@@ -14,56 +17,104 @@ import (
     "github.com/lyffseba/ana/internal/config"
 )
 
+// ErrModelNotFound is returned for tasks naming a model that is not configured
+var ErrModelNotFound = errors.New("model not found")
+
+// ErrInvalidTask is returned for tasks whose input or options cannot be sent to a model
+var ErrInvalidTask = errors.New("invalid task")
+
+// ErrInvalidOutput is returned when a model asked for JSON answers with something else
+var ErrInvalidOutput = errors.New("model output is not valid JSON")
+
 // AIService handles AI model integration and processing
 type AIService struct {
     config     *config.Config
+    factory    *ModelFactory
     models     map[string]*Model
     mu         sync.RWMutex
 }
 
 // Model represents an AI model configuration
 type Model struct {
-    Name       string
-    Version    string
-    Endpoint   string
-    Parameters map[string]interface{}
+    // Name is the logical model name tasks ask for, e.g. "assistant"
+    Name       string                 `json:"name"`
+    // Version is the provider's model, e.g. "qwen-3-32b"
+    Version    string                 `json:"version"`
+    Provider   string                 `json:"provider"`
+    Endpoint   string                 `json:"endpoint,omitempty"`
+    Parameters map[string]interface{} `json:"parameters,omitempty"`
+
+    executor ModelExecutor
 }
 
-// NewAIService creates a new AI service instance
-func NewAIService(cfg *config.Config) *AIService {
+// NewAIService creates a new AI service instance whose models are served by
+// factory, the factory created from the same configuration for the rest of
+// the server, so they share its providers, circuit breakers, response cache
+// and usage recorder
+func NewAIService(cfg *config.Config, factory *ModelFactory) *AIService {
     return &AIService{
         config:     cfg,
+        factory:    factory,
         models:     make(map[string]*Model),
     }
 }
 
-// InitializeModels sets up AI models
+// InitializeModels creates the models of the ai section of the configuration
+// with the service's factory
 func (s *AIService) InitializeModels(ctx context.Context) error {
     s.mu.Lock()
     defer s.mu.Unlock()
 
-    // Initialize Cerebras models
-    if err := s.initCerebrasModels(ctx); err != nil {
-        return fmt.Errorf("failed to initialize Cerebras models: %w", err)
+    if s.factory == nil {
+        return errors.New("failed to initialize models: no model factory")
     }
-
-    // Initialize custom models
-    if err := s.initCustomModels(ctx); err != nil {
-        return fmt.Errorf("failed to initialize custom models: %w", err)
+    llm := s.config.AI.ResolvedLLM()
+    models := make(map[string]*Model, len(llm.Models))
+    for name, m := range llm.Models {
+        executor, err := s.factory.CreateModel(name)
+        if err != nil {
+            return fmt.Errorf("failed to initialize model %s: %w", name, err)
+        }
+        provider := llm.Providers[m.Provider]
+        models[name] = &Model{
+            Name:     name,
+            Version:  m.Model,
+            Provider: m.Provider,
+            Endpoint: provider.Endpoint,
+            Parameters: map[string]interface{}{
+                "temperature": m.Temperature,
+                "max_tokens":  m.MaxTokens,
+            },
+            executor: NewMonitoredModel(executor, name, provider.Type),
+        }
     }
+    s.models = models
 
-    log.Printf("AI models initialized successfully")
+    log.Printf("AI models initialized successfully: %d models", len(models))
     return nil
 }
 
-// ProcessTask processes an AI task
+// Models lists the configured models by name
+func (s *AIService) Models() []Model {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    models := make([]Model, 0, len(s.models))
+    for _, m := range s.models {
+        models = append(models, *m)
+    }
+    sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+    return models
+}
+
+// ProcessTask sends a task's input to its model and returns the answer
 func (s *AIService) ProcessTask(ctx context.Context, task *Task) (*Result, error) {
     start := time.Now()
     defer s.recordMetrics("process_task", task.ModelName, start)
 
     model, err := s.getModel(task.ModelName)
     if err != nil {
-        return nil, fmt.Errorf("failed to get model: %w", err)
+        return nil, err
     }
 
     result, err := s.executeModel(ctx, model, task)
@@ -75,88 +126,154 @@ func (s *AIService) ProcessTask(ctx context.Context, task *Task) (*Result, error
     return result, nil
 }
 
-// Task represents an AI processing task
+// Task represents an AI processing task.
+//
+// Input holds either "messages", a list of {"role", "content"} objects, or
+// a "prompt" string with an optional "system" string. Any other input is
+// sent to the model as JSON. Options may set "temperature", "max_tokens"
+// and "response_format": "text", "json_object" or an object such as
+// {"type": "json_schema", "json_schema": {...}}.
 type Task struct {
-    ModelName string
-    Input     map[string]interface{}
-    Options   map[string]interface{}
+    ModelName string                 `json:"model"`
+    Input     map[string]interface{} `json:"input"`
+    Options   map[string]interface{} `json:"options,omitempty"`
 }
 
-// Result represents the AI processing result
+// Result represents the AI processing result.
+// Output is the answer as text, or the decoded JSON value when the task
+// asked for a JSON response format.
 type Result struct {
-    Output     interface{}
-    Confidence float64
-    Duration   time.Duration
-    Metadata   map[string]interface{}
+    Output     interface{}            `json:"output"`
+    Duration   time.Duration          `json:"duration_ns"`
+    Metadata   map[string]interface{} `json:"metadata"`
 }
 
-func (s *AIService) initCerebrasModels(ctx context.Context) error {
-    // Initialize Cerebras model configurations
-    models := []Model{
-        {
-            Name:     "cerebras_large",
-            Version:  "v2",
-            Endpoint: s.config.AI.Cerebras.Endpoint,
-            Parameters: map[string]interface{}{
-                "temperature": s.config.AI.Cerebras.Temperature,
-                "max_tokens":  s.config.AI.Cerebras.MaxTokens,
-            },
-        },
-        // Add more models as needed
-    }
+func (s *AIService) getModel(name string) (*Model, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
 
-    for _, model := range models {
-        s.models[model.Name] = &model
+    model, exists := s.models[name]
+    if !exists {
+        return nil, fmt.Errorf("%w: %s", ErrModelNotFound, name)
     }
 
-    return nil
+    return model, nil
 }
 
-func (s *AIService) initCustomModels(ctx context.Context) error {
-    // Initialize custom model configurations
-    models := []Model{
-        {
-            Name:     "ana_base",
-            Version:  "v1",
-            Endpoint: "http://localhost:placeholder_custom_ai_endpoint",
-            Parameters: map[string]interface{}{
-                "batch_size": 32,
-                "timeout":    30,
-            },
-        },
-        // Add more models as needed
+func (s *AIService) executeModel(ctx context.Context, model *Model, task *Task) (*Result, error) {
+    request, err := taskRequest(task)
+    if err != nil {
+        return nil, err
     }
 
-    for _, model := range models {
-        s.models[model.Name] = &model
+    response, err := model.executor.Execute(ctx, request)
+    if err != nil {
+        return nil, err
     }
 
-    return nil
+    var answer string
+    if err := json.Unmarshal(response.Output, &answer); err != nil {
+        return nil, fmt.Errorf("failed to decode model output: %w", err)
+    }
+    result := &Result{
+        Output:   answer,
+        Duration: response.Duration,
+        Metadata: response.Metadata,
+    }
+    result.Metadata["model_name"] = model.Name
+    if request.ResponseFormat != nil && request.ResponseFormat.Type != "text" {
+        var output interface{}
+        if err := json.Unmarshal([]byte(answer), &output); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidOutput, err)
+        }
+        result.Output = output
+    }
+    return result, nil
 }
 
-func (s *AIService) getModel(name string) (*Model, error) {
-    s.mu.RLock()
-    defer s.mu.RUnlock()
+// taskRequest converts a task's input and options to a chat completion request
+func taskRequest(task *Task) (ChatCompletionRequest, error) {
+    var request ChatCompletionRequest
+    if len(task.Input) == 0 {
+        return request, fmt.Errorf("%w: input is required", ErrInvalidTask)
+    }
 
-    model, exists := s.models[name]
-    if !exists {
-        return nil, fmt.Errorf("model not found: %s", name)
+    switch {
+    case task.Input["messages"] != nil:
+        if err := convert(task.Input["messages"], &request.Messages); err != nil || len(request.Messages) == 0 {
+            return request, fmt.Errorf("%w: messages must be a list of {\"role\", \"content\"} objects", ErrInvalidTask)
+        }
+    case task.Input["prompt"] != nil:
+        prompt, ok := task.Input["prompt"].(string)
+        if !ok || prompt == "" {
+            return request, fmt.Errorf("%w: prompt must be a non-empty string", ErrInvalidTask)
+        }
+        if system, ok := task.Input["system"].(string); ok && system != "" {
+            request.Messages = append(request.Messages, Message{Role: "system", Content: system})
+        }
+        request.Messages = append(request.Messages, Message{Role: "user", Content: prompt})
+    default:
+        data, err := json.Marshal(task.Input)
+        if err != nil {
+            return request, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+        }
+        request.Messages = []Message{{Role: "user", Content: string(data)}}
     }
 
-    return model, nil
+    for name, value := range task.Options {
+        switch name {
+        case "temperature":
+            temperature, ok := value.(float64)
+            if !ok || temperature < 0 || temperature > 2 {
+                return request, fmt.Errorf("%w: temperature must be a number between 0 and 2", ErrInvalidTask)
+            }
+            request.Temperature = temperature
+        case "max_tokens":
+            maxTokens, ok := value.(float64)
+            if !ok || maxTokens < 1 || maxTokens != float64(int(maxTokens)) {
+                return request, fmt.Errorf("%w: max_tokens must be a positive integer", ErrInvalidTask)
+            }
+            request.MaxTokens = int(maxTokens)
+        case "response_format":
+            format, err := responseFormat(value)
+            if err != nil {
+                return request, err
+            }
+            request.ResponseFormat = format
+        default:
+            return request, fmt.Errorf("%w: unknown option %q", ErrInvalidTask, name)
+        }
+    }
+    return request, nil
 }
 
-func (s *AIService) executeModel(ctx context.Context, model *Model, task *Task) (*Result, error) {
-    // Implement model execution logic here
-    // This is a placeholder for the actual implementation
-    result := &Result{
-        Output:     nil,
-        Confidence: 0.0,
-        Duration:   0,
-        Metadata:   make(map[string]interface{}),
+// responseFormat reads the response_format option
+func responseFormat(value interface{}) (*ResponseFormat, error) {
+    format := &ResponseFormat{}
+    if name, ok := value.(string); ok {
+        format.Type = name
+    } else if err := convert(value, format); err != nil {
+        return nil, fmt.Errorf("%w: response_format must be a string or an object", ErrInvalidTask)
+    }
+    switch format.Type {
+    case "text", "json_object":
+    case "json_schema":
+        if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+            return nil, fmt.Errorf("%w: response_format json_schema requires a json_schema object with a schema", ErrInvalidTask)
+        }
+    default:
+        return nil, fmt.Errorf("%w: unsupported response_format %q", ErrInvalidTask, format.Type)
     }
+    return format, nil
+}
 
-    return result, nil
+// convert copies a decoded JSON value into out
+func convert(value interface{}, out interface{}) error {
+    data, err := json.Marshal(value)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, out)
 }
 
 func (s *AIService) recordMetrics(operation string, modelName string, start time.Time) {
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// TestProcessTask tests dispatching tasks to the models of the ai configuration
+func TestProcessTask(t *testing.T) {
+	// OpenAI-compatible endpoints take max_tokens rather than max_completion_tokens
+	var got struct {
+		ChatCompletionRequest
+		MaxTokens int `json:"max_tokens"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.ChatCompletionRequest, got.MaxTokens = ChatCompletionRequest{}, 0
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		content := "Hola"
+		if got.ResponseFormat != nil {
+			content = `{\"material\": \"ladrillo\"}`
+		}
+		fmt.Fprintf(w, `{"id": "chatcmpl-1", "model": "test-model", "system_fingerprint": "fp_1", "choices": [{"message": {"role": "assistant", "content": "%s"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}}`, content)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{AI: config.AIConfig{LLM: config.LLMConfig{
+		Providers: map[string]config.ProviderConfig{"local": {Type: config.ProviderOpenAI, Endpoint: server.URL + "/v1/"}},
+		Models:    map[string]config.ModelConfig{"extract": {Provider: "local", Model: "test-model", Temperature: 0.2, MaxTokens: 100}},
+	}}}
+	factory, err := NewModelFactoryFromConfig(cfg.AI.ResolvedLLM())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer factory.Close()
+	var recorded []TokenUsage
+	factory.SetUsageRecorder(func(ctx context.Context, usage TokenUsage) {
+		recorded = append(recorded, usage)
+	})
+	service := NewAIService(cfg, factory)
+	if err := service.InitializeModels(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	found := false
+	for _, m := range service.Models() {
+		if m.Name == "extract" {
+			found = m.Version == "test-model" && m.Provider == "local"
+		}
+	}
+	if !found {
+		t.Errorf("Expected the configured model to be listed, got %+v", service.Models())
+	}
+
+	result, err := service.ProcessTask(context.Background(), &Task{
+		ModelName: "extract",
+		Input:     map[string]interface{}{"system": "Responde en español", "prompt": "Saluda"},
+		Options:   map[string]interface{}{"temperature": 0.7, "max_tokens": float64(20)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Output != "Hola" || result.Duration <= 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Metadata["total_tokens"] != 7 || result.Metadata["fingerprint"] != "fp_1" || result.Metadata["model_name"] != "extract" {
+		t.Errorf("Unexpected metadata: %v", result.Metadata)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Temperature != 0.7 || got.MaxTokens != 20 {
+		t.Errorf("Unexpected request: %+v", got)
+	}
+	if len(recorded) != 1 || recorded[0].Provider != "local" || recorded[0].Model != "test-model" || recorded[0].PromptTokens != 5 || recorded[0].CompletionTokens != 2 {
+		t.Errorf("Expected the completion's usage to be recorded with the factory's recorder, got %+v", recorded)
+	}
+
+	result, err = service.ProcessTask(context.Background(), &Task{
+		ModelName: "extract",
+		Input:     map[string]interface{}{"messages": []interface{}{map[string]interface{}{"role": "user", "content": "Material"}}},
+		Options:   map[string]interface{}{"response_format": "json_object"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output, ok := result.Output.(map[string]interface{}); !ok || output["material"] != "ladrillo" {
+		t.Errorf("Expected decoded JSON output, got %#v", result.Output)
+	}
+	if got.Temperature != 0.2 || got.MaxTokens != 100 {
+		t.Errorf("Expected the configured parameters, got %+v", got)
+	}
+
+	for _, task := range []Task{
+		{ModelName: "extract"},
+		{ModelName: "extract", Input: map[string]interface{}{"prompt": 3}},
+		{ModelName: "extract", Input: map[string]interface{}{"messages": "Hola"}},
+		{ModelName: "extract", Input: map[string]interface{}{"prompt": "Hola"}, Options: map[string]interface{}{"temperature": 3.0}},
+		{ModelName: "extract", Input: map[string]interface{}{"prompt": "Hola"}, Options: map[string]interface{}{"max_tokens": 1.5}},
+		{ModelName: "extract", Input: map[string]interface{}{"prompt": "Hola"}, Options: map[string]interface{}{"response_format": "xml"}},
+		{ModelName: "extract", Input: map[string]interface{}{"prompt": "Hola"}, Options: map[string]interface{}{"top_k": 3.0}},
+	} {
+		if _, err := service.ProcessTask(context.Background(), &task); !errors.Is(err, ErrInvalidTask) {
+			t.Errorf("Expected ErrInvalidTask for %+v, got %v", task, err)
+		}
+	}
+	if _, err := service.ProcessTask(context.Background(), &Task{ModelName: "missing", Input: map[string]interface{}{"prompt": "Hola"}}); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+}
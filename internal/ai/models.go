@@ -7,7 +7,12 @@ import (
     "context"
     "encoding/json"
     "fmt"
+    "sort"
+    "sync"
     "time"
+
+    "github.com/lyffseba/ana/internal/cache"
+    "github.com/lyffseba/ana/internal/config"
 )
 
 // ModelType represents the type of AI model
@@ -16,14 +21,21 @@ type ModelType string
 const (
     ModelTypeCerebras ModelType = "cerebras"
     ModelTypeCustom   ModelType = "custom"
+    ModelTypeOpenAI   ModelType = "openai"
+    ModelTypeOllama   ModelType = "ollama"
 )
 
 // ModelConfig represents the configuration for an AI model
 type ModelConfig struct {
-    Type       ModelType
-    Name       string
-    Version    string
-    Endpoint   string
+    Type    ModelType
+    Name    string
+    Version string
+    // Provider names a provider registered with the factory. Without one,
+    // custom and openai models use the OpenAI-compatible API at Endpoint and
+    // ollama models the Ollama server at Endpoint.
+    Provider string
+    Endpoint string
+    // Parameters may set "temperature" and "max_tokens"
     Parameters map[string]interface{}
 }
 
@@ -43,7 +55,10 @@ type ModelExecutor interface {
 
 // BaseModel provides common functionality for all models
 type BaseModel struct {
-    config ModelConfig
+    config   ModelConfig
+    provider LLMProvider
+    // factory records the usage of the model's completions
+    factory *ModelFactory
 }
 
 func (m *BaseModel) Configure(config ModelConfig) error {
@@ -51,10 +66,100 @@ func (m *BaseModel) Configure(config ModelConfig) error {
     return nil
 }
 
+// execute sends input to the model's provider. input may be a string, which
+// is sent as the user message, a []Message, a ChatCompletionRequest or any
+// other value, which is sent as JSON. Output is the answer as a JSON string.
+func (m *BaseModel) execute(ctx context.Context, input interface{}) (*ModelResponse, error) {
+    if m.provider == nil {
+        return nil, fmt.Errorf("model %s has no provider", m.config.Name)
+    }
+
+    request, err := m.request(input)
+    if err != nil {
+        return nil, err
+    }
+
+    start := time.Now()
+    resp, err := m.provider.CreateChatCompletion(ctx, request)
+    if err != nil {
+        return nil, err
+    }
+
+    if m.factory != nil {
+        m.factory.recordUsage(ctx, TokenUsage{
+            Provider:         m.provider.Name(),
+            Model:            request.Model,
+            PromptTokens:     resp.Usage.PromptTokens,
+            CompletionTokens: resp.Usage.CompletionTokens,
+        })
+    }
+
+    choice := resp.Choices[0]
+    output, err := json.Marshal(removeThinkingTags(choice.Message.Content))
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode model output: %w", err)
+    }
+    return &ModelResponse{
+        Output:   output,
+        Duration: time.Since(start),
+        Metadata: map[string]interface{}{
+            "provider":          m.provider.Name(),
+            "model":             request.Model,
+            "finish_reason":     choice.FinishReason,
+            "prompt_tokens":     resp.Usage.PromptTokens,
+            "completion_tokens": resp.Usage.CompletionTokens,
+            "total_tokens":      resp.Usage.TotalTokens,
+            "response_id":       resp.ID,
+            "fingerprint":       resp.SystemFingerprint,
+        },
+    }, nil
+}
+
+// request converts an Execute input into a chat completion request
+func (m *BaseModel) request(input interface{}) (ChatCompletionRequest, error) {
+    var request ChatCompletionRequest
+    switch v := input.(type) {
+    case ChatCompletionRequest:
+        request = v
+    case []Message:
+        request.Messages = v
+    case string:
+        request.Messages = []Message{{Role: "user", Content: v}}
+    default:
+        data, err := json.Marshal(v)
+        if err != nil {
+            return request, fmt.Errorf("failed to encode model input: %w", err)
+        }
+        request.Messages = []Message{{Role: "user", Content: string(data)}}
+    }
+
+    if request.Model == "" {
+        request.Model = m.config.Name
+    }
+    if request.Temperature == 0 {
+        request.Temperature = floatParameter(m.config.Parameters, "temperature")
+    }
+    if request.MaxTokens == 0 {
+        request.MaxTokens = int(floatParameter(m.config.Parameters, "max_tokens"))
+    }
+    return request, nil
+}
+
+// floatParameter reads a numeric parameter, 0 if unset
+func floatParameter(parameters map[string]interface{}, name string) float64 {
+    switch v := parameters[name].(type) {
+    case float64:
+        return v
+    case int:
+        return float64(v)
+    default:
+        return 0
+    }
+}
+
 // CerebrasModel implements Cerebras-specific model execution
 type CerebrasModel struct {
     BaseModel
-    // Add Cerebras-specific fields
 }
 
 func NewCerebrasModel(config ModelConfig) *CerebrasModel {
@@ -64,14 +169,13 @@ func NewCerebrasModel(config ModelConfig) *CerebrasModel {
 }
 
 func (m *CerebrasModel) Execute(ctx context.Context, input interface{}) (*ModelResponse, error) {
-    // Implement Cerebras model execution
-    return nil, fmt.Errorf("not implemented")
+    return m.execute(ctx, input)
 }
 
-// CustomModel implements custom model execution
+// CustomModel implements model execution against an OpenAI-compatible
+// endpoint or a local Ollama server
 type CustomModel struct {
     BaseModel
-    // Add custom model fields
 }
 
 func NewCustomModel(config ModelConfig) *CustomModel {
@@ -81,37 +185,247 @@ func NewCustomModel(config ModelConfig) *CustomModel {
 }
 
 func (m *CustomModel) Execute(ctx context.Context, input interface{}) (*ModelResponse, error) {
-    // Implement custom model execution
-    return nil, fmt.Errorf("not implemented")
+    return m.execute(ctx, input)
 }
 
-// ModelFactory creates model instances
+// ModelFactory creates model instances.
+// Models are registered under logical names such as "assistant" and are
+// served by providers registered with the factory.
 type ModelFactory struct {
-    configs map[string]ModelConfig
+    mu        sync.RWMutex
+    configs   map[string]ModelConfig
+    providers map[string]LLMProvider
+    // endpoints holds the providers of models registered with an endpoint
+    // instead of a provider, by model name
+    endpoints map[string]LLMProvider
+    fallbacks map[string][]config.ModelConfig
+    routing   config.RoutingConfig
+    cache     cache.ResponseCache
+    cacheTTL  time.Duration
+    usage     UsageRecorder
 }
 
 func NewModelFactory() *ModelFactory {
     return &ModelFactory{
-        configs: make(map[string]ModelConfig),
+        configs:   make(map[string]ModelConfig),
+        providers: make(map[string]LLMProvider),
+        endpoints: make(map[string]LLMProvider),
+        fallbacks: make(map[string][]config.ModelConfig),
+    }
+}
+
+// NewModelFactoryFromConfig creates the providers in cfg and registers its
+// models, fallback chains and routing rules. Answers from ChatModel are
+// cached in the cache selected by SetClientConfig or CEREBRAS_CACHE_BACKEND.
+func NewModelFactoryFromConfig(cfg config.LLMConfig) (*ModelFactory, error) {
+    if err := cfg.Validate(); err != nil {
+        return nil, err
+    }
+
+    f := NewModelFactory()
+    f.SetResponseCache(newResponseCache(), cacheTTL(currentClientConfig().Cache))
+
+    for name, providerConfig := range cfg.Providers {
+        provider, err := NewProvider(name, providerConfig)
+        if err != nil {
+            return nil, err
+        }
+        f.RegisterProvider(provider)
     }
+
+    for name, m := range cfg.Models {
+        f.RegisterModel(name, ModelConfig{
+            Type:     ModelType(cfg.Providers[m.Provider].Type),
+            Name:     m.Model,
+            Provider: m.Provider,
+            Parameters: map[string]interface{}{
+                "temperature": m.Temperature,
+                "max_tokens":  m.MaxTokens,
+            },
+        })
+    }
+
+    for name, chain := range cfg.Fallbacks {
+        fallbacks := make([]config.ModelConfig, 0, len(chain))
+        for _, entry := range chain {
+            m, err := cfg.ResolveFallback(name, entry)
+            if err != nil {
+                return nil, err
+            }
+            fallbacks = append(fallbacks, m)
+        }
+        f.SetFallbacks(name, fallbacks...)
+    }
+    f.SetRouting(cfg.Routing)
+    return f, nil
+}
+
+// RegisterProvider makes provider available to models under its name
+func (f *ModelFactory) RegisterProvider(provider LLMProvider) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.providers[provider.Name()] = provider
+}
+
+// Provider returns the provider registered under name
+func (f *ModelFactory) Provider(name string) (LLMProvider, bool) {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    provider, ok := f.providers[name]
+    return provider, ok
+}
+
+// Providers returns the registered provider names in order
+func (f *ModelFactory) Providers() []string {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    names := make([]string, 0, len(f.providers))
+    for name := range f.providers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// SetResponseCache sets the cache shared by the factory's chat models
+func (f *ModelFactory) SetResponseCache(responseCache cache.ResponseCache, ttl time.Duration) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.cache = responseCache
+    f.cacheTTL = ttl
+}
+
+// ResponseCache returns the cache shared by the factory's chat models
+func (f *ModelFactory) ResponseCache() cache.ResponseCache {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    return f.cache
+}
+
+// Close closes the response cache, e.g. disconnecting from Redis. Models
+// created afterwards are not cached.
+func (f *ModelFactory) Close() error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if f.cache == nil {
+        return nil
+    }
+    err := f.cache.Close()
+    f.cache = nil
+    return err
 }
 
 func (f *ModelFactory) RegisterModel(name string, config ModelConfig) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
     f.configs[name] = config
+    delete(f.endpoints, name)
 }
 
 func (f *ModelFactory) CreateModel(name string) (ModelExecutor, error) {
+    config, provider, err := f.resolve(name)
+    if err != nil {
+        return nil, err
+    }
+
+    switch config.Type {
+    case ModelTypeCerebras:
+        model := NewCerebrasModel(config)
+        model.provider = provider
+        model.factory = f
+        return model, nil
+    case ModelTypeCustom, ModelTypeOpenAI, ModelTypeOllama:
+        model := NewCustomModel(config)
+        model.provider = provider
+        model.factory = f
+        return model, nil
+    default:
+        return nil, fmt.Errorf("unknown model type: %s", config.Type)
+    }
+}
+
+// ChatModel returns the model registered under name as a ChatModel
+func (f *ModelFactory) ChatModel(name string) (*ChatModel, error) {
+    config, provider, err := f.resolve(name)
+    if err != nil {
+        return nil, err
+    }
+
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+    model := NewChatModel(name, provider, modelSettings(config), f.cache, f.cacheTTL)
+    model.usage = f.usage
+    return model, nil
+}
+
+// ProviderModel returns a ChatModel served by the provider registered under
+// name, for callers such as the processors that set the model of each
+// request. The model is not cached and, as it is kept, its completions go to
+// the recorder the factory has at the time of each completion.
+func (f *ModelFactory) ProviderModel(name string, settings config.ModelConfig) (*ChatModel, error) {
+    provider, ok := f.Provider(name)
+    if !ok {
+        return nil, fmt.Errorf("provider not found: %s", name)
+    }
+
+    settings.Provider = name
+    model := NewChatModel(name, provider, settings, nil, 0)
+    model.usage = f.forwardUsage
+    return model, nil
+}
+
+// resolve looks up a model and the provider serving it
+func (f *ModelFactory) resolve(name string) (ModelConfig, LLMProvider, error) {
+    f.mu.RLock()
     config, exists := f.configs[name]
+    f.mu.RUnlock()
     if !exists {
-        return nil, fmt.Errorf("model not found: %s", name)
+        return config, nil, fmt.Errorf("model not found: %s", name)
+    }
+
+    if config.Provider != "" {
+        provider, ok := f.Provider(config.Provider)
+        if !ok {
+            return config, nil, fmt.Errorf("model %s: provider not found: %s", name, config.Provider)
+        }
+        return config, provider, nil
+    }
+
+    // Endpoint providers are created once so their circuit breakers persist
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if provider, ok := f.endpoints[name]; ok {
+        return config, provider, nil
     }
 
+    var provider LLMProvider
     switch config.Type {
     case ModelTypeCerebras:
-        return NewCerebrasModel(config), nil
-    case ModelTypeCustom:
-        return NewCustomModel(config), nil
+        client := newCerebrasClient(nil)
+        if config.Endpoint != "" {
+            client.apiURL = config.Endpoint
+        }
+        provider = client
+    case ModelTypeCustom, ModelTypeOpenAI:
+        if config.Endpoint == "" {
+            return config, nil, fmt.Errorf("model %s: endpoint is required", name)
+        }
+        provider = NewOpenAIProvider(name, config.Endpoint, "")
+    case ModelTypeOllama:
+        provider = NewOllamaProvider(name, config.Endpoint)
     default:
-        return nil, fmt.Errorf("unknown model type: %s", config.Type)
+        return config, nil, fmt.Errorf("unknown model type: %s", config.Type)
+    }
+    f.endpoints[name] = provider
+    return config, provider, nil
+}
+
+// modelSettings converts a registered model to the settings of a ChatModel
+func modelSettings(m ModelConfig) config.ModelConfig {
+    return config.ModelConfig{
+        Provider:    m.Provider,
+        Model:       m.Name,
+        Temperature: floatParameter(m.Parameters, "temperature"),
+        MaxTokens:   int(floatParameter(m.Parameters, "max_tokens")),
     }
 }
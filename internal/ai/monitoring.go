@@ -79,6 +79,10 @@ func (m *MonitoredModel) Execute(ctx context.Context, input interface{}) (*Model
     return response, err
 }
 
+func (m *MonitoredModel) Configure(config ModelConfig) error {
+    return m.executor.Configure(config)
+}
+
 // Health check for AI system
 type HealthCheck struct {
     Status    string
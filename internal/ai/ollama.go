@@ -0,0 +1,304 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	defaultOllamaURL = "http://localhost:11434"
+	// Local models on modest hardware can take a while to answer
+	defaultOllamaTimeout = 2 * time.Minute
+)
+
+// OllamaProvider talks to a local Ollama server through its native /api/chat
+// endpoint. Requests and responses are translated from and to the OpenAI
+// chat format; images in message parts are sent as Ollama images.
+type OllamaProvider struct {
+	name       string
+	url        string
+	httpClient *retryablehttp.Client
+	breaker    *CircuitBreaker
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at baseURL,
+// http://localhost:11434 when empty
+func NewOllamaProvider(name string, baseURL string) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = defaultOllamaURL
+	}
+	breakerConfig := DefaultCircuitBreakerConfig()
+	breakerConfig.Service = name
+	return &OllamaProvider{
+		name:       name,
+		url:        strings.TrimRight(baseURL, "/") + "/api/chat",
+		httpClient: newProviderHTTPClient(defaultOllamaTimeout),
+		breaker:    NewCircuitBreaker(breakerConfig),
+	}
+}
+
+// ollamaMessage is a chat message in Ollama's format
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+// ollamaToolCall is a tool call; Ollama passes arguments as a JSON object
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChatRequest is the body of a /api/chat request
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Tools    []Tool                 `json:"tools,omitempty"`
+	Format   interface{}            `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is a /api/chat response, or one line of a stream
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Name returns the provider's configured name
+func (p *OllamaProvider) Name() string {
+	return p.name
+}
+
+// CreateChatCompletion sends a request and returns the whole response
+func (p *OllamaProvider) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	resp, _, err := p.post(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResponse ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if chatResponse.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chatResponse.Error)
+	}
+	return chatResponse.toCompletion(), nil
+}
+
+// StreamChatCompletion streams the response's content to onDelta
+func (p *OllamaProvider) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest, onDelta func(string) error) error {
+	resp, done, err := p.post(ctx, request, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readOllamaStream(resp.Body, onDelta)
+	// A cancelled context surfaces as a read error; report the cause
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	done(err)
+	return err
+}
+
+// CheckCircuitBreaker reports whether a request would be let through
+func (p *OllamaProvider) CheckCircuitBreaker() error {
+	return p.breaker.Ready()
+}
+
+// GetCircuitState returns the state of the provider's circuit breaker
+func (p *OllamaProvider) GetCircuitState() string {
+	return p.breaker.State()
+}
+
+// post sends a request through the circuit breaker. For streaming requests
+// the caller passes the outcome to done once the stream ends.
+func (p *OllamaProvider) post(ctx context.Context, request ChatCompletionRequest, stream bool) (*http.Response, func(error), error) {
+	chatRequest, err := toOllamaRequest(request, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := json.Marshal(chatRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	retryReq, err := retryablehttp.FromRequest(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create retry request: %w", err)
+	}
+
+	done, err := p.breaker.Allow()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := p.httpClient.Do(retryReq)
+	if err != nil || !stream || resp.StatusCode != http.StatusOK {
+		done(statusError(resp, err))
+	}
+	if err != nil {
+		return nil, nil, sendError(ctx, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("Ollama error: status code %d, body: %s", resp.StatusCode, string(body))
+		return nil, nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp, done, nil
+}
+
+// toOllamaRequest translates an OpenAI-style request
+func toOllamaRequest(request ChatCompletionRequest, stream bool) (ollamaChatRequest, error) {
+	chatRequest := ollamaChatRequest{
+		Model:   request.Model,
+		Stream:  stream,
+		Tools:   request.Tools,
+		Options: make(map[string]interface{}),
+	}
+
+	for _, msg := range request.Messages {
+		converted := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, part := range msg.Parts {
+			switch {
+			case part.Type == "text":
+				converted.Content += part.Text
+			case part.ImageURL != nil:
+				// Ollama takes the bare base64 data of a data URL
+				_, data, ok := strings.Cut(part.ImageURL.URL, ";base64,")
+				if !ok {
+					return chatRequest, fmt.Errorf("ollama only accepts base64 data URL images")
+				}
+				converted.Images = append(converted.Images, data)
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			var toolCall ollamaToolCall
+			toolCall.Function.Name = call.FunctionCall.Name
+			toolCall.Function.Arguments = json.RawMessage(call.FunctionCall.Arguments)
+			if !json.Valid(toolCall.Function.Arguments) {
+				toolCall.Function.Arguments = json.RawMessage("{}")
+			}
+			converted.ToolCalls = append(converted.ToolCalls, toolCall)
+		}
+		chatRequest.Messages = append(chatRequest.Messages, converted)
+	}
+
+	if request.Temperature != 0 {
+		chatRequest.Options["temperature"] = request.Temperature
+	}
+	if request.MaxTokens != 0 {
+		chatRequest.Options["num_predict"] = request.MaxTokens
+	}
+	if request.TopP != 0 {
+		chatRequest.Options["top_p"] = request.TopP
+	}
+	if request.Seed != 0 {
+		chatRequest.Options["seed"] = request.Seed
+	}
+	if len(request.Stop) > 0 {
+		chatRequest.Options["stop"] = request.Stop
+	}
+
+	if format := request.ResponseFormat; format != nil {
+		switch {
+		case format.JSONSchema != nil:
+			chatRequest.Format = format.JSONSchema.Schema
+		case format.Type == "json_object":
+			chatRequest.Format = "json"
+		}
+	}
+	return chatRequest, nil
+}
+
+// toCompletion translates a response to the OpenAI format
+func (r ollamaChatResponse) toCompletion() *ChatCompletionResponse {
+	message := ResponseMessage{Role: r.Message.Role, Content: r.Message.Content}
+	for i, call := range r.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, ToolCall{
+			// Ollama does not identify tool calls; tool results are matched by order
+			ID:   fmt.Sprintf("call_%d", i),
+			Type: "function",
+			FunctionCall: FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: string(call.Function.Arguments),
+			},
+		})
+	}
+	finishReason := r.DoneReason
+	if len(message.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	return &ChatCompletionResponse{
+		Object:  "chat.completion",
+		Created: r.CreatedAt.Unix(),
+		Model:   r.Model,
+		Choices: []Choice{{Message: message, FinishReason: finishReason}},
+		Usage: Usage{
+			PromptTokens:     r.PromptEvalCount,
+			CompletionTokens: r.EvalCount,
+			TotalTokens:      r.PromptEvalCount + r.EvalCount,
+		},
+	}
+}
+
+// readOllamaStream parses the newline-delimited JSON of a streamed response
+func readOllamaStream(body io.Reader, onDelta func(string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			if err := onDelta(chunk.Message.Content); err != nil {
+				return err
+			}
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
@@ -5,120 +5,369 @@ package processors
 
 import (
     "context"
+    "crypto/sha256"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
+    "os"
+    "sync/atomic"
     "time"
+
+    "github.com/lyffseba/ana/internal/ai"
+    "github.com/lyffseba/ana/internal/cache"
+    "github.com/lyffseba/ana/internal/eval"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "gopkg.in/yaml.v3"
+)
+
+// ErrNoEvalDataset is returned by Evaluate when no dataset is configured
+var ErrNoEvalDataset = errors.New("no evaluation dataset configured")
+
+// ErrInvalidInput is returned by Process for input it cannot send to the model
+var ErrInvalidInput = errors.New("invalid input")
+
+// cacheTTL is how long cached results are kept, as the cache section of config.yaml
+const cacheTTL = time.Hour
+
+var (
+    processorRequests = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "ana_processor_requests_total",
+            Help: "Requests handled by AI processors, by outcome",
+        },
+        []string{"processor", "status"},
+    )
+
+    processorDuration = promauto.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "ana_processor_duration_seconds",
+            Help:    "Time AI processors take to handle a request",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"processor"},
+    )
 )
 
-// CerebrasProcessor handles Cerebras AI model processing
+// CerebrasProcessor handles Cerebras AI model processing. It is safe for
+// concurrent use.
 type CerebrasProcessor struct {
     config     *Config
-    metrics    *Metrics
+    model      *ai.ChatModel
+    cache      cache.ResponseCache
+    counters   counters
     monitoring *Monitoring
 }
 
 // Config holds Cerebras configuration
 type Config struct {
-    Endpoint    string
-    APIKey      string
-    ModelID     string
-    MaxTokens   int
-    Temperature float64
+    Endpoint    string  `yaml:"endpoint"`
+    APIKey      string  `yaml:"api_key"`
+    ModelID     string  `yaml:"model_id"`
+    MaxTokens   int     `yaml:"max_tokens"`
+    Temperature float64 `yaml:"temperature"`
+    Options     Options `yaml:"options"`
+    // EvalDataset is the JSONL golden dataset Evaluate replays
+    EvalDataset string `yaml:"eval_dataset"`
 }
 
-// Metrics tracks processing metrics
+// Options tune how a processor calls its model
+type Options struct {
+    // CacheResults answers repeated requests from memory
+    CacheResults bool `yaml:"cache_results"`
+    // Timeout bounds each Process call, the provider's retries included;
+    // zero means none
+    Timeout time.Duration `yaml:"timeout"`
+}
+
+// LoadConfig reads the configuration of the named processor from a file
+// such as processors/config.yaml. ${VAR} references are replaced with
+// environment variables.
+func LoadConfig(path, name string) (*Config, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var file struct {
+        Processors map[string]*Config `yaml:"processors"`
+    }
+    if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &file); err != nil {
+        return nil, fmt.Errorf("reading %s: %w", path, err)
+    }
+    cfg, ok := file.Processors[name]
+    if !ok || cfg == nil {
+        return nil, fmt.Errorf("%s: no configuration for processor %s", path, name)
+    }
+    return cfg, nil
+}
+
+// Metrics is a snapshot of a processor's counters
 type Metrics struct {
     RequestCount   int64
-    ErrorCount    int64
+    ErrorCount     int64
+    CacheHits      int64
     ProcessingTime time.Duration
 }
 
+// counters are updated by concurrent Process calls
+type counters struct {
+    requests  atomic.Int64
+    errors    atomic.Int64
+    cacheHits atomic.Int64
+    nanos     atomic.Int64
+}
+
 // Monitoring handles processor monitoring
 type Monitoring struct {
     // Add monitoring fields
 }
 
-// NewCerebrasProcessor creates a new Cerebras processor
-func NewCerebrasProcessor(config *Config) *CerebrasProcessor {
-    return &CerebrasProcessor{
-        config:  config,
-        metrics: &Metrics{},
+// NewCerebrasProcessor creates a processor that sends requests through
+// model, normally the model factory's ProviderModel for the Cerebras
+// provider, so it shares the provider's circuit breaker and completions are
+// recorded against the caller's usage
+func NewCerebrasProcessor(cfg *Config, model *ai.ChatModel) *CerebrasProcessor {
+    p := &CerebrasProcessor{
+        config:     cfg,
+        model:      model,
         monitoring: &Monitoring{},
     }
+    if cfg.Options.CacheResults {
+        p.cache = cache.NewLRU(cache.Config{Service: "processor_cerebras", MaxEntries: 1000})
+    }
+    return p
+}
+
+// Request is the input of Process
+type Request struct {
+    Text string `json:"text"`
+    // Options may set "system", "temperature" and "max_tokens"
+    Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// Response is the output of Process
+type Response struct {
+    Text         string   `json:"text"`
+    Model        string   `json:"model"`
+    FinishReason string   `json:"finish_reason"`
+    Usage        ai.Usage `json:"usage"`
+    Cached       bool     `json:"cached"`
 }
 
-// Process handles AI processing requests
+// Process answers a JSON Request with a JSON Response
 func (p *CerebrasProcessor) Process(ctx context.Context, input []byte) ([]byte, error) {
     start := time.Now()
+    status := "ok"
     defer func() {
-        p.metrics.ProcessingTime += time.Since(start)
-        p.metrics.RequestCount++
+        duration := time.Since(start)
+        p.counters.requests.Add(1)
+        p.counters.nanos.Add(int64(duration))
+        processorRequests.WithLabelValues("cerebras", status).Inc()
+        processorDuration.WithLabelValues("cerebras").Observe(duration.Seconds())
     }()
 
     // Parse input
-    var request struct {
-        Text string `json:"text"`
-        Options map[string]interface{} `json:"options,omitempty"`
-    }
+    var request Request
     if err := json.Unmarshal(input, &request); err != nil {
-        p.metrics.ErrorCount++
-        return nil, fmt.Errorf("invalid input format: %w", err)
+        status = "invalid"
+        p.counters.errors.Add(1)
+        return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
     }
 
     // Process with Cerebras API
     result, err := p.processCerebras(ctx, request.Text, request.Options)
     if err != nil {
-        p.metrics.ErrorCount++
+        status = "error"
+        if errors.Is(err, ErrInvalidInput) {
+            status = "invalid"
+        }
+        p.counters.errors.Add(1)
         return nil, fmt.Errorf("cerebras processing error: %w", err)
     }
+    if result.Cached {
+        status = "cached"
+        p.counters.cacheHits.Add(1)
+    }
 
     return json.Marshal(result)
 }
 
-// processCerebras handles actual Cerebras API interaction
-func (p *CerebrasProcessor) processCerebras(ctx context.Context, text string, options map[string]interface{}) (interface{}, error) {
-    // TODO: Implement actual Cerebras API call
-    // This is a placeholder implementation
-    result := map[string]interface{}{
-        "text": text,
-        "processed": true,
-        "timestamp": time.Now(),
-        "model": p.config.ModelID,
+// processCerebras sends text to the chat completions API and caches the
+// answer if configured. Rate limited and unavailable requests are retried by
+// the provider.
+func (p *CerebrasProcessor) processCerebras(ctx context.Context, text string, options map[string]interface{}) (*Response, error) {
+    request, err := p.request(text, options)
+    if err != nil {
+        return nil, err
+    }
+
+    key := cacheKey(request)
+    if p.cache != nil {
+        if cached, ok, _ := p.cache.Get(ctx, key); ok {
+            var result Response
+            if err := json.Unmarshal([]byte(cached), &result); err == nil {
+                result.Cached = true
+                return &result, nil
+            }
+        }
+    }
+
+    if p.config.Options.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, p.config.Options.Timeout)
+        defer cancel()
+    }
+
+    resp, err := p.model.CreateChatCompletion(ctx, request)
+    if err != nil {
+        return nil, err
+    }
+    if len(resp.Choices) == 0 {
+        return nil, errors.New("model returned no choices")
+    }
+
+    result := &Response{
+        Text:         ai.RemoveThinkingTags(resp.Choices[0].Message.Content),
+        Model:        resp.Model,
+        FinishReason: resp.Choices[0].FinishReason,
+        Usage:        resp.Usage,
+    }
+    if p.cache != nil {
+        if data, err := json.Marshal(result); err == nil {
+            p.cache.Set(ctx, key, string(data), cacheTTL)
+        }
     }
     return result, nil
 }
 
-// Train trains the model with new data
+// request builds the chat completion request for text
+func (p *CerebrasProcessor) request(text string, options map[string]interface{}) (ai.ChatCompletionRequest, error) {
+    request := ai.ChatCompletionRequest{
+        Model:       p.config.ModelID,
+        Temperature: p.config.Temperature,
+        MaxTokens:   p.config.MaxTokens,
+    }
+    if text == "" {
+        return request, fmt.Errorf("%w: text is required", ErrInvalidInput)
+    }
+    for name, value := range options {
+        switch name {
+        case "system":
+            system, ok := value.(string)
+            if !ok {
+                return request, fmt.Errorf("%w: system must be a string", ErrInvalidInput)
+            }
+            request.Messages = append(request.Messages, ai.Message{Role: "system", Content: system})
+        case "temperature":
+            temperature, ok := value.(float64)
+            if !ok || temperature < 0 || temperature > 2 {
+                return request, fmt.Errorf("%w: temperature must be a number between 0 and 2", ErrInvalidInput)
+            }
+            request.Temperature = temperature
+        case "max_tokens":
+            maxTokens, ok := value.(float64)
+            if !ok || maxTokens < 1 {
+                return request, fmt.Errorf("%w: max_tokens must be a positive integer", ErrInvalidInput)
+            }
+            request.MaxTokens = int(maxTokens)
+        default:
+            return request, fmt.Errorf("%w: unknown option %q", ErrInvalidInput, name)
+        }
+    }
+    request.Messages = append(request.Messages, ai.Message{Role: "user", Content: text})
+    return request, nil
+}
+
+// cacheKey identifies a request in the result cache
+func cacheKey(request ai.ChatCompletionRequest) string {
+    data, _ := json.Marshal(request)
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+// Train is not supported: hosted models are not fine-tuned. Improve the
+// prompts instead and measure the change with Evaluate.
 func (p *CerebrasProcessor) Train(ctx context.Context, data []byte) error {
-    // TODO: Implement model training
-    return fmt.Errorf("training not implemented")
+    return errors.New("training is not supported; evaluate prompt and model changes with cmd/anaeval")
 }
 
-// Evaluate evaluates model performance
+// Evaluate replays the configured golden dataset through the processor and
+// scores the answers. Judge checks fail, as the processor has no judge model;
+// use cmd/anaeval for those.
 func (p *CerebrasProcessor) Evaluate(ctx context.Context) (*EvaluationResult, error) {
-    result := &EvaluationResult{
-        Accuracy:    0.95,
-        Error:       0.05,
-        SampleSize:  1000,
-        TimeStamp:   time.Now(),
+    if p.config.EvalDataset == "" {
+        return nil, ErrNoEvalDataset
     }
-    return result, nil
+    dataset, err := eval.LoadDataset(p.config.EvalDataset)
+    if err != nil {
+        return nil, err
+    }
+    runner := &eval.Runner{Model: processorAnswerer{p}, ModelName: "processor:" + p.config.ModelID}
+    run, err := runner.Run(ctx, dataset)
+    if err != nil {
+        return nil, err
+    }
+    return &EvaluationResult{
+        Accuracy:   run.Summary.PassRate,
+        Error:      1 - run.Summary.PassRate,
+        Score:      run.Summary.Score,
+        SampleSize: run.Summary.Cases,
+        RunID:      run.ID,
+        TimeStamp:  run.StartedAt,
+    }, nil
+}
+
+// processorAnswerer answers eval cases with the processor
+type processorAnswerer struct {
+    p *CerebrasProcessor
+}
+
+// Answer sends the system prompt and the last user message through the processor
+func (a processorAnswerer) Answer(ctx context.Context, messages []ai.Message) (string, error) {
+    var text string
+    options := map[string]interface{}{}
+    for _, m := range messages {
+        switch m.Role {
+        case "system":
+            options["system"] = m.Content
+        case "user":
+            text = m.Content
+        }
+    }
+    result, err := a.p.processCerebras(ctx, text, options)
+    if err != nil {
+        return "", err
+    }
+    return result.Text, nil
 }
 
 // EvaluationResult holds model evaluation results
 type EvaluationResult struct {
+    // Accuracy is the share of cases whose checks all passed
     Accuracy    float64   `json:"accuracy"`
     Error       float64   `json:"error"`
+    // Score is the mean case score
+    Score       float64   `json:"score"`
     SampleSize  int       `json:"sample_size"`
+    RunID       string    `json:"run_id"`
     TimeStamp   time.Time `json:"timestamp"`
 }
 
 // GetMetrics returns current metrics
 func (p *CerebrasProcessor) GetMetrics() *Metrics {
-    return p.metrics
+    return &Metrics{
+        RequestCount:   p.counters.requests.Load(),
+        ErrorCount:     p.counters.errors.Load(),
+        CacheHits:      p.counters.cacheHits.Load(),
+        ProcessingTime: time.Duration(p.counters.nanos.Load()),
+    }
 }
 
 // Reset resets metrics
 func (p *CerebrasProcessor) Reset() {
-    p.metrics = &Metrics{}
+    p.counters.requests.Store(0)
+    p.counters.errors.Store(0)
+    p.counters.cacheHits.Store(0)
+    p.counters.nanos.Store(0)
 }
@@ -0,0 +1,167 @@
+package processors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// fakeProvider answers with the user's text after failing the first failures requests
+type fakeProvider struct {
+	calls    atomic.Int64
+	failures int64
+	err      error
+	delay    time.Duration
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) CreateChatCompletion(ctx context.Context, request ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	if f.calls.Add(1) <= f.failures {
+		return nil, f.err
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	last := request.Messages[len(request.Messages)-1].Content
+	return &ai.ChatCompletionResponse{
+		Model:   request.Model,
+		Choices: []ai.Choice{{Message: ai.ResponseMessage{Content: "<think>…</think>" + last}, FinishReason: "stop"}},
+		Usage:   ai.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+	}, nil
+}
+
+func (f *fakeProvider) StreamChatCompletion(ctx context.Context, request ai.ChatCompletionRequest, onDelta func(string) error) error {
+	return errors.New("not supported")
+}
+
+func (f *fakeProvider) CheckCircuitBreaker() error { return nil }
+
+func (f *fakeProvider) GetCircuitState() string { return "closed" }
+
+// chatModel serves the processor's requests through provider
+func chatModel(provider ai.LLMProvider) *ai.ChatModel {
+	return ai.NewChatModel("cerebras", provider, config.ModelConfig{}, nil, 0)
+}
+
+// TestCerebrasProcessor tests answering concurrent requests through the provider
+func TestCerebrasProcessor(t *testing.T) {
+	provider := &fakeProvider{}
+	p := NewCerebrasProcessor(&Config{ModelID: "qwen-3-32b", MaxTokens: 100}, chatModel(provider))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := p.Process(context.Background(), []byte(`{"text": "Hola", "options": {"temperature": 0.2}}`))
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			var resp Response
+			if err := json.Unmarshal(output, &resp); err != nil || resp.Text != "Hola" || resp.Model != "qwen-3-32b" || resp.Usage.TotalTokens != 4 {
+				t.Errorf("Unexpected response %s: %v", output, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, input := range []string{`nope`, `{"text": ""}`, `{"text": "Hola", "options": {"top_k": 1}}`} {
+		if _, err := p.Process(context.Background(), []byte(input)); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %s, got %v", input, err)
+		}
+	}
+
+	metrics := p.GetMetrics()
+	if metrics.RequestCount != 53 || metrics.ErrorCount != 3 || metrics.ProcessingTime <= 0 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+	p.Reset()
+	if metrics := p.GetMetrics(); *metrics != (Metrics{}) {
+		t.Errorf("Expected reset metrics, got %+v", metrics)
+	}
+}
+
+// TestCerebrasProcessorOptions tests the cache_results and timeout options
+func TestCerebrasProcessorOptions(t *testing.T) {
+	provider := &fakeProvider{}
+	p := NewCerebrasProcessor(&Config{Options: Options{CacheResults: true}}, chatModel(provider))
+	for i := 0; i < 2; i++ {
+		if _, err := p.Process(context.Background(), []byte(`{"text": "Hola"}`)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("Expected one call and one cached answer, got %d calls", calls)
+	}
+	if hits := p.GetMetrics().CacheHits; hits != 1 {
+		t.Errorf("Expected 1 cache hit, got %d", hits)
+	}
+
+	// Retrying is left to the provider, so a failure is returned as is
+	provider = &fakeProvider{failures: 1, err: ai.ErrRateLimited}
+	p = NewCerebrasProcessor(&Config{}, chatModel(provider))
+	if _, err := p.Process(context.Background(), []byte(`{"text": "Hola"}`)); !errors.Is(err, ai.ErrRateLimited) || provider.calls.Load() != 1 {
+		t.Errorf("Expected ErrRateLimited without retries, got %v after %d calls", err, provider.calls.Load())
+	}
+
+	p = NewCerebrasProcessor(&Config{Options: Options{Timeout: 10 * time.Millisecond}}, chatModel(&fakeProvider{delay: time.Second}))
+	if _, err := p.Process(context.Background(), []byte(`{"text": "Hola"}`)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+// TestCerebrasProcessorUsage tests recording completions with the model factory's recorder
+func TestCerebrasProcessorUsage(t *testing.T) {
+	factory := ai.NewModelFactory()
+	factory.RegisterProvider(&fakeProvider{})
+	model, err := factory.ProviderModel("fake", config.ModelConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	p := NewCerebrasProcessor(&Config{ModelID: "qwen-3-32b"}, model)
+
+	// The recorder is set after the processor is created, as by the server
+	var recorded []ai.TokenUsage
+	factory.SetUsageRecorder(func(ctx context.Context, usage ai.TokenUsage) {
+		recorded = append(recorded, usage)
+	})
+	if _, err := p.Process(context.Background(), []byte(`{"text": "Hola"}`)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := ai.TokenUsage{Provider: "fake", Model: "qwen-3-32b", PromptTokens: 3, CompletionTokens: 1}
+	if len(recorded) != 1 || recorded[0] != want {
+		t.Errorf("Expected %+v to be recorded, got %+v", want, recorded)
+	}
+
+	if _, err := factory.ProviderModel("missing", config.ModelConfig{}); err == nil {
+		t.Error("Expected an error for a missing provider")
+	}
+}
+
+// TestLoadConfig tests reading processors/config.yaml
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("CEREBRAS_API_KEY", "test-key")
+	cfg, err := LoadConfig(filepath.Join(".", "config.yaml"), "cerebras")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := Options{CacheResults: true, Timeout: 30 * time.Second}
+	if cfg.APIKey != "test-key" || cfg.MaxTokens != 1000 || cfg.Options != want {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+	if _, err := LoadConfig("config.yaml", "missing"); err == nil {
+		t.Error("Expected an error for a missing processor")
+	}
+}
@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// LLMProvider is a backend that serves chat completions.
+// Requests and responses use the OpenAI chat format; providers with another
+// wire format translate them. Failed calls return *APIError for non-200
+// answers, ErrMissingAPIKey when the provider is not configured and
+// ErrCircuitOpen while the provider's circuit breaker rejects requests.
+type LLMProvider interface {
+	// Name identifies the provider in logs, metrics and cache keys
+	Name() string
+	// CreateChatCompletion sends a request and returns the whole response
+	CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error)
+	// StreamChatCompletion sends a request and passes each piece of content
+	// to onDelta as it arrives. Streaming stops when ctx is done or onDelta
+	// returns an error.
+	StreamChatCompletion(ctx context.Context, request ChatCompletionRequest, onDelta func(string) error) error
+	// CheckCircuitBreaker reports whether a request would be let through
+	CheckCircuitBreaker() error
+	// GetCircuitState returns the provider's circuit breaker state
+	GetCircuitState() string
+}
+
+// NewProvider creates the provider described by cfg.
+// name labels the provider's circuit breaker and metrics.
+func NewProvider(name string, cfg config.ProviderConfig) (LLMProvider, error) {
+	switch cfg.Type {
+	case config.ProviderCerebras:
+		// Answers are cached by the ChatModel, not by the client
+		client := newCerebrasClient(nil)
+		if cfg.Endpoint != "" {
+			client.apiURL = cfg.Endpoint
+		}
+		if cfg.APIKey != "" {
+			client.apiKey = cfg.APIKey
+		}
+		return client, nil
+	case config.ProviderOpenAI:
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("provider %s: endpoint is required", name)
+		}
+		return NewOpenAIProvider(name, cfg.Endpoint, cfg.APIKey), nil
+	case config.ProviderOllama:
+		return NewOllamaProvider(name, cfg.Endpoint), nil
+	default:
+		return nil, fmt.Errorf("provider %s: unsupported type %q", name, cfg.Type)
+	}
+}
+
+// newProviderHTTPClient returns the retrying HTTP client shared by the providers
+func newProviderHTTPClient(timeout time.Duration) *retryablehttp.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = defaultMaxRetries
+	retryClient.RetryWaitMin = 1 * time.Second
+	retryClient.RetryWaitMax = 5 * time.Second
+	retryClient.Logger = nil
+	// Caps a single attempt; the caller's context bounds the whole call
+	retryClient.HTTPClient.Timeout = timeout
+	// Return the final response of an exhausted retry instead of a bare error,
+	// so the status code reaches the caller and the circuit breaker
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	return retryClient
+}
+
+// chatEndpoint sends requests to an OpenAI-compatible chat completions URL
+type chatEndpoint struct {
+	url        string
+	apiKey     string
+	httpClient *retryablehttp.Client
+	breaker    *CircuitBreaker
+	// maxTokensField sends the token limit as max_tokens, which older
+	// OpenAI-compatible servers expect, instead of max_completion_tokens
+	maxTokensField bool
+}
+
+// encode marshals a request for the endpoint
+func (e chatEndpoint) encode(request ChatCompletionRequest) ([]byte, error) {
+	if !e.maxTokensField {
+		return json.Marshal(request)
+	}
+	maxTokens := request.MaxTokens
+	request.MaxTokens = 0
+	return json.Marshal(struct {
+		ChatCompletionRequest
+		MaxTokens int `json:"max_tokens,omitempty"`
+	}{request, maxTokens})
+}
+
+// post sends a request through the circuit breaker. For streaming requests
+// the caller passes the outcome to done once the stream ends.
+func (e chatEndpoint) post(ctx context.Context, request ChatCompletionRequest) (*http.Response, func(error), error) {
+	body, err := e.encode(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	retryReq, err := retryablehttp.FromRequest(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create retry request: %w", err)
+	}
+
+	done, err := e.breaker.Allow()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := e.httpClient.Do(retryReq)
+	if err != nil || !request.Stream || resp.StatusCode != http.StatusOK {
+		done(statusError(resp, err))
+	}
+	if err != nil {
+		return nil, nil, sendError(ctx, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("API error from %s: status code %d, body: %s", e.url, resp.StatusCode, string(body))
+		return nil, nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp, done, nil
+}
+
+// complete sends a request and decodes the whole response
+func (e chatEndpoint) complete(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	request.Stream = false
+	resp, _, err := e.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	var completionResponse ChatCompletionResponse
+	if err := json.Unmarshal(body, &completionResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(completionResponse.Choices) == 0 {
+		return nil, fmt.Errorf("no completions returned")
+	}
+	return &completionResponse, nil
+}
+
+// stream sends a streaming request and passes each content delta to onDelta
+func (e chatEndpoint) stream(ctx context.Context, request ChatCompletionRequest, onDelta func(string) error) error {
+	request.Stream = true
+	resp, done, err := e.post(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readStreamChunks(resp.Body, func(chunk chatCompletionChunk) error {
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// A cancelled context surfaces as a read error; report the cause
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	done(err)
+	return err
+}
+
+// OpenAIProvider talks to any OpenAI-compatible chat completions endpoint,
+// such as OpenAI, vLLM or a llama.cpp server
+type OpenAIProvider struct {
+	name     string
+	endpoint chatEndpoint
+}
+
+// NewOpenAIProvider creates a provider for baseURL, either the API root
+// (e.g. https://api.openai.com/v1) or the full chat completions URL.
+// apiKey may be empty for local servers.
+func NewOpenAIProvider(name string, baseURL string, apiKey string) *OpenAIProvider {
+	url := strings.TrimRight(baseURL, "/")
+	if !strings.HasSuffix(url, "/chat/completions") {
+		url += "/chat/completions"
+	}
+	breakerConfig := DefaultCircuitBreakerConfig()
+	breakerConfig.Service = name
+	return &OpenAIProvider{
+		name: name,
+		endpoint: chatEndpoint{
+			url:            url,
+			apiKey:         apiKey,
+			httpClient:     newProviderHTTPClient(defaultTimeout),
+			breaker:        NewCircuitBreaker(breakerConfig),
+			maxTokensField: true,
+		},
+	}
+}
+
+// Name returns the provider's configured name
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// CreateChatCompletion sends a request and returns the whole response
+func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	return p.endpoint.complete(ctx, request)
+}
+
+// StreamChatCompletion streams the response's content to onDelta
+func (p *OpenAIProvider) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest, onDelta func(string) error) error {
+	return p.endpoint.stream(ctx, request, onDelta)
+}
+
+// CheckCircuitBreaker reports whether a request would be let through
+func (p *OpenAIProvider) CheckCircuitBreaker() error {
+	return p.endpoint.breaker.Ready()
+}
+
+// GetCircuitState returns the state of the provider's circuit breaker
+func (p *OpenAIProvider) GetCircuitState() string {
+	return p.endpoint.breaker.State()
+}
@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/cache"
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// TestOpenAIProvider tests requests to an OpenAI-compatible endpoint
+func TestOpenAIProvider(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"model": "gpt-test", "choices": [{"message": {"role": "assistant", "content": "Hola"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-openai", server.URL+"/v1/", "test-key")
+	resp, err := provider.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:     "gpt-test",
+		Messages:  []Message{{Role: "user", Content: "Hola"}},
+		MaxTokens: 50,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Hola" || resp.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if got["max_tokens"] != float64(50) {
+		t.Errorf("Expected max_tokens 50, got %v", got["max_tokens"])
+	}
+	if _, ok := got["max_completion_tokens"]; ok {
+		t.Error("Expected max_completion_tokens to be omitted")
+	}
+}
+
+// TestOllamaProvider tests translation to and from Ollama's native API
+func TestOllamaProvider(t *testing.T) {
+	var got ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected /api/chat, got %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"model": "llama3.1:8b", "created_at": "2025-05-28T09:00:00Z", "message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "list_tasks", "arguments": {"status": "To-Do"}}}]}, "done": true, "done_reason": "stop", "prompt_eval_count": 12, "eval_count": 5}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider("test-ollama", server.URL)
+	resp, err := provider.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model: "llama3.1:8b",
+		Messages: []Message{
+			{Role: "system", Content: "Eres un asistente"},
+			ImageMessage("¿Qué muestra?", "image/png", "aW1hZ2Vu"),
+		},
+		Temperature:    0.2,
+		MaxTokens:      100,
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got.Stream || got.Format != "json" {
+		t.Errorf("Unexpected stream or format: %+v", got)
+	}
+	if got.Options["temperature"] != 0.2 || got.Options["num_predict"] != float64(100) {
+		t.Errorf("Unexpected options: %v", got.Options)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].Content != "¿Qué muestra?" || len(got.Messages[1].Images) != 1 || got.Messages[1].Images[0] != "aW1hZ2Vu" {
+		t.Errorf("Unexpected messages: %+v", got.Messages)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %+v", choice)
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.ID != "call_0" || call.FunctionCall.Name != "list_tasks" || call.FunctionCall.Arguments != `{"status": "To-Do"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 5 || resp.Usage.TotalTokens != 17 {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+}
+
+// TestOllamaProviderStream tests reading a newline-delimited JSON stream
+func TestOllamaProviderStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, piece := range []string{"<think>hmm</think>", "Hola ", "mundo"} {
+			fmt.Fprintf(w, `{"message": {"role": "assistant", "content": %q}, "done": false}`+"\n", piece)
+		}
+		fmt.Fprint(w, `{"message": {"role": "assistant", "content": ""}, "done": true, "eval_count": 3}`+"\n")
+	}))
+	defer server.Close()
+
+	model := NewChatModel("assistant", NewOllamaProvider("test-ollama-stream", server.URL), config.ModelConfig{Model: "llama3.1:8b"}, cache.NewLRU(cache.Config{}), time.Minute)
+	var tokens []string
+	err := model.StreamAnswer(context.Background(), []Message{{Role: "user", Content: "Saluda"}}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if answer := strings.Join(tokens, ""); answer != "Hola mundo" {
+		t.Errorf("Expected think section removed, got %q", answer)
+	}
+	if cached, ok := model.CachedAnswer(context.Background(), []Message{{Role: "user", Content: "Saluda"}}); !ok || cached != "Hola mundo" {
+		t.Errorf("Expected streamed answer to be cached, got %q, %v", cached, ok)
+	}
+}
+
+// TestModelFactoryFromConfig tests resolving logical models to providers
+func TestModelFactoryFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Model != "local-model" || got.Temperature != 0.3 {
+			t.Errorf("Unexpected request: %+v", got)
+		}
+		fmt.Fprint(w, `{"model": "local-model", "choices": [{"message": {"role": "assistant", "content": "<think>x</think>Listo"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 2, "completion_tokens": 1}}`)
+	}))
+	defer server.Close()
+
+	llmConfig := config.DefaultLLMConfig().Merge(config.LLMConfig{
+		Providers: map[string]config.ProviderConfig{
+			"local": {Type: config.ProviderOpenAI, Endpoint: server.URL},
+		},
+		Models: map[string]config.ModelConfig{
+			config.ModelAssistant: {Provider: "local", Model: "local-model", Temperature: 0.3},
+		},
+	})
+	factory, err := NewModelFactoryFromConfig(llmConfig)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	factory.SetResponseCache(cache.NewLRU(cache.Config{}), time.Minute)
+	var usage []TokenUsage
+	factory.SetUsageRecorder(func(ctx context.Context, u TokenUsage) { usage = append(usage, u) })
+
+	if _, ok := factory.Provider(config.ProviderCerebras); !ok {
+		t.Error("Expected the default cerebras provider to be registered")
+	}
+	if _, err := factory.ChatModel("unknown"); err == nil {
+		t.Error("Expected an error for an unknown model")
+	}
+
+	model, err := factory.ChatModel(config.ModelAssistant)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	messages := []Message{{Role: "user", Content: "¿Terminaste?"}}
+	answer, err := model.Answer(context.Background(), messages)
+	if err != nil || answer != "Listo" {
+		t.Fatalf("Expected Listo, got %q, %v", answer, err)
+	}
+	if cached, ok := model.CachedAnswer(context.Background(), messages); !ok || cached != "Listo" {
+		t.Errorf("Expected the answer to be cached, got %q, %v", cached, ok)
+	}
+	if len(usage) != 1 || usage[0] != (TokenUsage{Provider: "local", Model: "local-model", PromptTokens: 2, CompletionTokens: 1}) {
+		t.Errorf("Expected the completion's usage to be recorded, got %+v", usage)
+	}
+
+	executor, err := factory.CreateModel(config.ModelAssistant)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	resp, err := executor.Execute(context.Background(), "¿Terminaste?")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var output string
+	if err := json.Unmarshal(resp.Output, &output); err != nil || output != "Listo" {
+		t.Errorf("Expected output Listo, got %s", resp.Output)
+	}
+	if resp.Metadata["provider"] != "local" || resp.Metadata["prompt_tokens"] != 2 {
+		t.Errorf("Unexpected metadata: %v", resp.Metadata)
+	}
+}
+
+// TestModelFactoryRejectsInvalidConfig tests configuration errors
+func TestModelFactoryRejectsInvalidConfig(t *testing.T) {
+	_, err := NewModelFactoryFromConfig(config.LLMConfig{
+		Models: map[string]config.ModelConfig{
+			config.ModelAssistant: {Provider: "missing", Model: "x"},
+		},
+	})
+	if err == nil {
+		t.Error("Expected an error for a model with an unknown provider")
+	}
+}
@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// RouteHints describes a request so the factory can pick a model for it
+type RouteHints struct {
+	// Image is set for questions about an image
+	Image bool
+	// NoThink is set for /no_think requests, which want short direct answers
+	NoThink bool
+	// PromptTokens is the estimated size of the prompt (see EstimateTokens)
+	PromptTokens int
+}
+
+// fallbackReason names the failure that lets a chain try its next model,
+// or returns "" if another model would not help
+func fallbackReason(err error) string {
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrMissingAPIKey):
+		return "not_configured"
+	default:
+		return ""
+	}
+}
+
+// ModelChain answers with the first of an ordered list of models that is
+// available. The next model is tried when one is rate limited, unavailable,
+// behind an open circuit or not configured; other errors, such as a rejected
+// request or a cancelled context, are returned as they are.
+type ModelChain struct {
+	models []*ChatModel
+}
+
+// NewModelChain creates a chain that tries models in order
+func NewModelChain(models ...*ChatModel) *ModelChain {
+	return &ModelChain{models: models}
+}
+
+// Primary returns the model tried first
+func (c *ModelChain) Primary() *ChatModel {
+	return c.models[0]
+}
+
+// Models returns the models in the order they are tried
+func (c *ModelChain) Models() []*ChatModel {
+	return c.models
+}
+
+// CheckCircuitBreaker reports whether any model would accept a request. If
+// none would, the primary model's error is returned.
+func (c *ModelChain) CheckCircuitBreaker() error {
+	var first error
+	for _, m := range c.models {
+		err := m.CheckCircuitBreaker()
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// CachedAnswer returns a cached answer from any model of the chain
+func (c *ModelChain) CachedAnswer(ctx context.Context, messages []Message) (string, bool) {
+	for _, m := range c.models {
+		if answer, ok := m.CachedAnswer(ctx, messages); ok {
+			return answer, true
+		}
+	}
+	return "", false
+}
+
+// Answer returns the first available model's answer to messages
+func (c *ModelChain) Answer(ctx context.Context, messages []Message) (string, error) {
+	var answer string
+	err := c.each(ctx, func(m *ChatModel) error {
+		var err error
+		answer, err = m.Answer(ctx, messages)
+		return err
+	})
+	return answer, err
+}
+
+// CreateChatCompletion sends request to the first available model. The
+// request's model is ignored: every model of the chain uses its own.
+func (c *ModelChain) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	request.Model = ""
+	var resp *ChatCompletionResponse
+	err := c.each(ctx, func(m *ChatModel) error {
+		var err error
+		resp, err = m.CreateChatCompletion(ctx, request)
+		return err
+	})
+	return resp, err
+}
+
+// StreamAnswer streams the first available model's answer. Once a model has
+// sent text the chain is committed to it, so a failure mid-answer is returned.
+func (c *ModelChain) StreamAnswer(ctx context.Context, messages []Message, onToken func(string) error) error {
+	started := false
+	return c.each(ctx, func(m *ChatModel) error {
+		err := m.StreamAnswer(ctx, messages, func(token string) error {
+			started = true
+			return onToken(token)
+		})
+		if err != nil && started {
+			return &committedError{err}
+		}
+		return err
+	})
+}
+
+// committedError stops a chain from falling back after output was sent
+type committedError struct{ error }
+
+func (e *committedError) Unwrap() error { return e.error }
+
+// each calls call with every model in turn until one succeeds or fails in a
+// way the next model cannot help with
+func (c *ModelChain) each(ctx context.Context, call func(*ChatModel) error) error {
+	var err error
+	for i, m := range c.models {
+		err = call(m)
+		var committed *committedError
+		if errors.As(err, &committed) {
+			return committed.error
+		}
+		reason := fallbackReason(err)
+		if err == nil || reason == "" || ctx.Err() != nil || i == len(c.models)-1 {
+			return err
+		}
+		next := c.models[i+1]
+		log.Printf("Model %s failed (%v), falling back to %s", m.Label(), err, next.Label())
+		monitoring.RecordModelFallback(m.Label(), next.Label(), reason)
+	}
+	return err
+}
+
+// SetRouting sets the rules Route uses to pick a model by request type
+func (f *ModelFactory) SetRouting(routing config.RoutingConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routing = routing
+}
+
+// SetFallbacks sets the models tried, in order, when the model registered
+// under name is rate limited or unavailable
+func (f *ModelFactory) SetFallbacks(name string, fallbacks ...config.ModelConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallbacks[name] = fallbacks
+}
+
+// Chain returns the model registered under name followed by its fallbacks
+func (f *ModelFactory) Chain(name string) (*ModelChain, error) {
+	primary, err := f.ChatModel(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	fallbacks := f.fallbacks[name]
+	f.mu.RUnlock()
+
+	models := []*ChatModel{primary}
+	for _, m := range fallbacks {
+		provider, ok := f.Provider(m.Provider)
+		if !ok {
+			return nil, fmt.Errorf("model %s: fallback provider not found: %s", name, m.Provider)
+		}
+		f.mu.RLock()
+		model := NewChatModel(name, provider, m, f.cache, f.cacheTTL)
+		model.usage = f.usage
+		f.mu.RUnlock()
+		models = append(models, model)
+	}
+	return NewModelChain(models...), nil
+}
+
+// Route returns the chain that should answer a request for the model
+// registered under name. Questions about images go to the vision route,
+// /no_think requests to the no_think route and prompts longer than the long
+// context limit to the long_context route, when those routes are set.
+func (f *ModelFactory) Route(name string, hints RouteHints) (*ModelChain, error) {
+	f.mu.RLock()
+	routing := f.routing
+	f.mu.RUnlock()
+
+	switch {
+	case hints.Image && routing.Vision != "":
+		name = routing.Vision
+	case hints.NoThink && routing.NoThink != "":
+		name = routing.NoThink
+	case routing.LongContext != "" && routing.LongContextTokens > 0 && hints.PromptTokens > routing.LongContextTokens:
+		name = routing.LongContext
+	}
+	return f.Chain(name)
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/config"
+)
+
+// statusServer answers every chat completion with status, or with answer
+// when status is 200, and counts the requests it receives
+func statusServer(t *testing.T, status int, answer string, calls *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if status != http.StatusOK {
+			http.Error(w, `{"error": "no"}`, status)
+			return
+		}
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": %q}, "finish_reason": "stop"}]}`, answer)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testProvider returns an OpenAI-compatible provider for server that does
+// not retry
+func testProvider(name string, server *httptest.Server) *OpenAIProvider {
+	provider := NewOpenAIProvider(name, server.URL, "")
+	provider.endpoint.httpClient.RetryMax = 0 // Disable retries for testing
+	return provider
+}
+
+// TestAPIErrorKinds tests that provider failures match the typed errors
+func TestAPIErrorKinds(t *testing.T) {
+	tests := []struct {
+		status      int
+		rateLimited bool
+		unavailable bool
+	}{
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusServiceUnavailable, false, true},
+		{http.StatusBadGateway, false, true},
+		{http.StatusBadRequest, false, false},
+		{http.StatusUnauthorized, false, false},
+	}
+	for _, tt := range tests {
+		err := error(&APIError{StatusCode: tt.status})
+		if errors.Is(err, ErrRateLimited) != tt.rateLimited || errors.Is(err, ErrUnavailable) != tt.unavailable {
+			t.Errorf("Status %d: rate limited %v, unavailable %v", tt.status, errors.Is(err, ErrRateLimited), errors.Is(err, ErrUnavailable))
+		}
+	}
+}
+
+// TestModelChainFallback tests falling back to the next model
+func TestModelChainFallback(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := NewChatModel("assistant", testProvider("test-primary", statusServer(t, http.StatusTooManyRequests, "", &primaryCalls)), config.ModelConfig{Model: "a"}, nil, 0)
+	fallback := NewChatModel("assistant", testProvider("test-fallback", statusServer(t, http.StatusOK, "Respuesta", &fallbackCalls)), config.ModelConfig{Model: "b"}, nil, 0)
+
+	messages := []Message{{Role: "user", Content: "Hola"}}
+	answer, err := NewModelChain(primary, fallback).Answer(context.Background(), messages)
+	if err != nil || answer != "Respuesta" {
+		t.Fatalf("Expected the fallback's answer, got %q, %v", answer, err)
+	}
+	if fallbackCalls != 1 {
+		t.Errorf("Expected one fallback request, got %d", fallbackCalls)
+	}
+
+	// The last model's error is returned when every model fails
+	_, err = NewModelChain(primary).Answer(context.Background(), messages)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	// A rejected request is not retried on another model
+	var rejectedCalls int
+	rejected := NewChatModel("assistant", testProvider("test-rejected", statusServer(t, http.StatusBadRequest, "", &rejectedCalls)), config.ModelConfig{Model: "c"}, nil, 0)
+	fallbackCalls = 0
+	_, err = NewModelChain(rejected, fallback).Answer(context.Background(), messages)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected the 400 error, got %v", err)
+	}
+	if fallbackCalls != 0 {
+		t.Errorf("Expected no fallback request, got %d", fallbackCalls)
+	}
+}
+
+// TestModelChainStreamCommits tests that a stream does not fall back once
+// text was sent
+func TestModelChainStreamCommits(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"message": {"role": "assistant", "content": "Hola "}, "done": false}`+"\n")
+		fmt.Fprint(w, `{"error": "model crashed"}`+"\n")
+	}))
+	defer broken.Close()
+	var fallbackCalls int
+	fallback := NewChatModel("assistant", testProvider("test-stream-fallback", statusServer(t, http.StatusOK, "Otra", &fallbackCalls)), config.ModelConfig{Model: "b"}, nil, 0)
+	primary := NewChatModel("assistant", NewOllamaProvider("test-stream-broken", broken.URL), config.ModelConfig{Model: "a"}, nil, 0)
+
+	var streamed string
+	err := NewModelChain(primary, fallback).StreamAnswer(context.Background(), []Message{{Role: "user", Content: "Hola"}}, func(token string) error {
+		streamed += token
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected the stream error")
+	}
+	if streamed != "Hola " || fallbackCalls != 0 {
+		t.Errorf("Expected no fallback after output, got %q and %d fallback requests", streamed, fallbackCalls)
+	}
+}
+
+// TestModelFactoryRoute tests picking a model by request type
+func TestModelFactoryRoute(t *testing.T) {
+	llmConfig := config.DefaultLLMConfig().Merge(config.LLMConfig{
+		Routing: config.RoutingConfig{
+			NoThink:           config.ModelSummary,
+			LongContext:       config.ModelAgent,
+			LongContextTokens: 100,
+		},
+		Fallbacks: map[string][]string{
+			config.ModelAssistant: {config.ModelSummary},
+		},
+	})
+	factory, err := NewModelFactoryFromConfig(llmConfig)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		hints RouteHints
+		want  string
+	}{
+		{RouteHints{}, config.ModelAssistant},
+		{RouteHints{Image: true, NoThink: true}, config.ModelVision},
+		{RouteHints{NoThink: true}, config.ModelSummary},
+		{RouteHints{PromptTokens: 50}, config.ModelAssistant},
+		{RouteHints{PromptTokens: 500}, config.ModelAgent},
+	}
+	for _, tt := range tests {
+		chain, err := factory.Route(config.ModelAssistant, tt.hints)
+		if err != nil {
+			t.Fatalf("Route(%+v): %v", tt.hints, err)
+		}
+		if chain.Primary().Name != tt.want {
+			t.Errorf("Route(%+v) = %s, expected %s", tt.hints, chain.Primary().Name, tt.want)
+		}
+	}
+
+	chain, err := factory.Chain(config.ModelAssistant)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(chain.Models()) != 2 {
+		t.Errorf("Expected the assistant and its fallback, got %d models", len(chain.Models()))
+	}
+}
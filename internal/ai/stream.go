@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// chatCompletionChunk is one streamed chunk of a chat completion
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// StreamTextResponse generates a response to a text-only query and passes
+// each piece of visible text to onToken as it arrives. <think> sections are
+// removed incrementally. Streaming stops when ctx is done or onToken returns
+// an error, and the upstream request is cancelled. Failures are returned as
+// errors, as for GenerateTextResponse.
+func (c *CerebrasClient) StreamTextResponse(ctx context.Context, userQuery string, model string, conversationContext []Message, onToken func(string) error) error {
+	request := textRequest(model, withUserQuery(conversationContext, userQuery), true)
+
+	// The visible text is collected so a complete answer can be cached
+	var filter ThinkFilter
+	var answer strings.Builder
+	err := c.StreamChatCompletion(ctx, request, func(delta string) error {
+		if visible := filter.Write(delta); visible != "" {
+			answer.WriteString(visible)
+			return onToken(visible)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if rest := filter.Flush(); rest != "" {
+		answer.WriteString(rest)
+		if err := onToken(rest); err != nil {
+			return err
+		}
+	}
+
+	// Cached under the same key as GenerateTextResponse, which does not stream
+	request.Stream = false
+	c.setCachedResponse(ctx, request, answer.String())
+	return nil
+}
+
+// readStreamChunks parses the "data:" lines of an upstream event stream
+// until the [DONE] marker or the end of the body
+func readStreamChunks(body io.Reader, handle func(chatCompletionChunk) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			// Blank separators, comments and other fields carry no content
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if err := handle(chunk); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// ThinkFilter removes <think>...</think> sections from text that arrives in
+// pieces. Tags may be split across pieces, so a possible partial tag at the
+// end of a piece is held back until the next one arrives.
+type ThinkFilter struct {
+	inThink bool
+	pending string
+}
+
+// Write consumes the next piece of text and returns the part that is visible
+func (f *ThinkFilter) Write(text string) string {
+	buf := f.pending + text
+	f.pending = ""
+	var out strings.Builder
+	for buf != "" {
+		tag := thinkOpenTag
+		if f.inThink {
+			tag = thinkCloseTag
+		}
+		if i := strings.Index(buf, tag); i >= 0 {
+			if !f.inThink {
+				out.WriteString(buf[:i])
+			}
+			buf = buf[i+len(tag):]
+			f.inThink = !f.inThink
+			continue
+		}
+		// Hold back a suffix that could be the start of the tag
+		keep := partialTagSuffix(buf, tag)
+		if !f.inThink {
+			out.WriteString(buf[:len(buf)-keep])
+		}
+		f.pending = buf[len(buf)-keep:]
+		break
+	}
+	return out.String()
+}
+
+// Flush returns any held-back text once the stream has ended.
+// An unterminated <think> section is dropped.
+func (f *ThinkFilter) Flush() string {
+	rest := f.pending
+	f.pending = ""
+	if f.inThink {
+		return ""
+	}
+	return rest
+}
+
+// partialTagSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag
+func partialTagSuffix(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
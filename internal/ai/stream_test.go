@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lyffseba/ana/internal/cache"
+)
+
+// newStreamTestClient returns a client whose transport is handled by roundTrip
+func newStreamTestClient(roundTrip func(req *http.Request) (*http.Response, error)) *CerebrasClient {
+	retryClient := retryablehttp.NewClient()
+	retryClient.HTTPClient = &http.Client{Transport: &MockRoundTripper{RoundTripFunc: roundTrip}}
+	retryClient.RetryMax = 0
+	retryClient.Logger = nil
+	return &CerebrasClient{
+		apiKey:     "test-api-key",
+		apiURL:     "https://test-url.com",
+		httpClient: retryClient,
+		cache:      cache.NewLRU(cache.Config{}),
+		cacheTTL:   15 * time.Minute,
+	}
+}
+
+// TestThinkFilter tests removal of think sections whose tags are split across pieces
+func TestThinkFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		pieces []string
+		want   string
+	}{
+		{"no tags", []string{"Hola ", "mundo"}, "Hola mundo"},
+		{"whole section", []string{"<think>razonando</think>Respuesta"}, "Respuesta"},
+		{"split tags", []string{"<thi", "nk>razo", "nando</th", "ink>Resp", "uesta"}, "Respuesta"},
+		{"text around", []string{"A<think>x</think>B<think>y</think>C"}, "ABC"},
+		{"lookalike kept", []string{"a <th", "ead> b"}, "a <thead> b"},
+		{"trailing partial tag", []string{"fin <thi"}, "fin <thi"},
+		{"unterminated section", []string{"ok<think>sin cierre"}, "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f ThinkFilter
+			var got strings.Builder
+			for _, piece := range tt.pieces {
+				got.WriteString(f.Write(piece))
+			}
+			got.WriteString(f.Flush())
+			if got.String() != tt.want {
+				t.Errorf("got %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestStreamTextResponse tests parsing of upstream data chunks
+func TestStreamTextResponse(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}`,
+		``,
+		`: keep-alive`,
+		`data: {"choices":[{"delta":{"content":"<think>Plan"}}]}`,
+		`data: {"choices":[{"delta":{"content":"ear</think>La NSR-10 "}}]}`,
+		``,
+		`data: {"choices":[{"delta":{"content":"aplica."},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+		`data: {"choices":[{"delta":{"content":"ignored"}}]}`,
+		``,
+	}, "\n")
+
+	var requestBody string
+	client := newStreamTestClient(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		requestBody = string(body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       io.NopCloser(strings.NewReader(stream)),
+		}, nil
+	})
+
+	var tokens []string
+	err := client.StreamTextResponse(context.Background(), "¿Qué norma aplica?", "test-model", nil, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(requestBody, `"stream":true`) {
+		t.Errorf("Request did not ask for a stream: %s", requestBody)
+	}
+	if got := strings.Join(tokens, ""); got != "La NSR-10 aplica." {
+		t.Errorf("Unexpected streamed text %q (tokens %q)", got, tokens)
+	}
+}
+
+// TestStreamTextResponseAPIError tests that upstream errors are reported with their status
+func TestStreamTextResponseAPIError(t *testing.T) {
+	client := newStreamTestClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"Invalid API key"}}`)),
+		}, nil
+	})
+
+	err := client.StreamTextResponse(context.Background(), "Test query", "test-model", nil, func(string) error { return nil })
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected APIError with status 401, got %v", err)
+	}
+}
+
+// TestStreamTextResponseCancel tests that cancelling the context stops the stream
+func TestStreamTextResponseCancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	client := newStreamTestClient(func(req *http.Request) (*http.Response, error) {
+		go func() {
+			io.WriteString(pw, "data: {\"choices\":[{\"delta\":{\"content\":\"Hola\"}}]}\n\n")
+			<-req.Context().Done()
+			pw.CloseWithError(req.Context().Err())
+		}()
+		return &http.Response{StatusCode: http.StatusOK, Body: pr}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.StreamTextResponse(ctx, "Test query", "test-model", nil, func(token string) error {
+		cancel() // The client disconnects after the first token
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
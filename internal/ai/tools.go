@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ToolHandler executes a tool call. args holds the JSON arguments produced by
+// the model; the returned value is encoded as JSON and sent back to it.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (interface{}, error)
+
+// RegisteredTool is a tool the model may call
+type RegisteredTool struct {
+	Function Function
+	// Mutating tools change data and only run after the user confirms them
+	Mutating bool
+	Handler  ToolHandler
+	// Describe summarises a call for the confirmation prompt (optional)
+	Describe func(ctx context.Context, args json.RawMessage) string
+}
+
+// ToolRegistry holds the tools offered to the model
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]RegisteredTool
+	order []string
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]RegisteredTool)}
+}
+
+// Register adds a tool. Names must be unique.
+func (r *ToolRegistry) Register(tool RegisteredTool) error {
+	if tool.Function.Name == "" || tool.Handler == nil {
+		return fmt.Errorf("tool needs a name and a handler")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[tool.Function.Name]; exists {
+		return fmt.Errorf("tool already registered: %s", tool.Function.Name)
+	}
+	r.tools[tool.Function.Name] = tool
+	r.order = append(r.order, tool.Function.Name)
+	return nil
+}
+
+// Get returns a registered tool by name
+func (r *ToolRegistry) Get(name string) (RegisteredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Definitions returns the tool definitions sent with a chat completion request
+func (r *ToolRegistry) Definitions() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		defs = append(defs, Tool{Type: "function", Function: r.tools[name].Function})
+	}
+	return defs
+}
+
+// describe summarises a tool call for the user
+func (t RegisteredTool) describe(ctx context.Context, args json.RawMessage) string {
+	if t.Describe != nil {
+		return t.Describe(ctx, args)
+	}
+	return fmt.Sprintf("%s(%s)", t.Function.Name, string(args))
+}
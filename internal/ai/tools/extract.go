@@ -0,0 +1,379 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+)
+
+// Bogota is the time zone relative due dates are resolved in. Colombia does
+// not observe daylight saving time, so a fixed offset avoids depending on the
+// system's time zone database.
+var Bogota = time.FixedZone("America/Bogota", -5*60*60)
+
+// ErrInvalidExtraction is returned when the model's output does not follow
+// the task proposal schema
+var ErrInvalidExtraction = errors.New("model returned invalid task proposals")
+
+// maxProposals bounds the proposals kept from one text
+const maxProposals = 50
+
+// TaskProposal is a task found in a text, for the user to review before it
+// is created. Task is ready to be created as is; the other fields explain
+// where it came from.
+type TaskProposal struct {
+	Task models.Task `json:"task"`
+	// DueText is the phrase the due date was resolved from, e.g. "el viernes"
+	DueText string `json:"due_text,omitempty"`
+	// ProjectName is the project as mentioned in the text
+	ProjectName string `json:"project_name,omitempty"`
+	// Source is the passage of the text the task was taken from
+	Source string `json:"source,omitempty"`
+	// Warnings list what could not be resolved and needs the user's attention
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// TaskExtractor proposes tasks found in free text such as meeting minutes
+// or client emails. The model's output is constrained to a JSON schema;
+// due dates and projects are then resolved here rather than trusted.
+type TaskExtractor struct {
+	Client   ai.ChatCompleter
+	Projects repositories.ProjectRepository
+	// Now returns the current time; it defaults to time.Now in Bogotá
+	Now func() time.Time
+}
+
+// extractedTask is one item of the model's output
+type extractedTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DueText     string `json:"due_text"`
+	DueDate     string `json:"due_date"`
+	Priority    string `json:"priority"`
+	Project     string `json:"project"`
+	Source      string `json:"source"`
+}
+
+// taskProposalSchema is the strict schema of the model's output. Strict
+// mode needs every property to be required, so absent values are "".
+var taskProposalSchema = map[string]interface{}{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"tasks"},
+	"properties": map[string]interface{}{
+		"tasks": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"title", "description", "due_text", "due_date", "priority", "project", "source"},
+				"properties": map[string]interface{}{
+					"title":       map[string]interface{}{"type": "string", "description": "Título breve en infinitivo, p. ej. «Enviar planos al cliente»"},
+					"description": map[string]interface{}{"type": "string"},
+					"due_text":    map[string]interface{}{"type": "string", "description": "La expresión de fecha tal como aparece en el texto, o vacío"},
+					"due_date":    map[string]interface{}{"type": "string", "description": "La fecha de vencimiento en formato YYYY-MM-DD, o vacío"},
+					"priority":    map[string]interface{}{"type": "string", "enum": []string{"Low", "Medium", "High"}},
+					"project":     map[string]interface{}{"type": "string", "description": "El nombre del proyecto mencionado, o vacío"},
+					"source":      map[string]interface{}{"type": "string", "description": "La frase del texto de la que sale la tarea"},
+				},
+			},
+		},
+	},
+}
+
+// Extract proposes the tasks found in text. Proposals that name no known
+// project are assigned defaultProject, which may be zero.
+func (e *TaskExtractor) Extract(ctx context.Context, text string, defaultProject models.Project) ([]TaskProposal, error) {
+	var projects []models.Project
+	if e.Projects != nil {
+		var err error
+		if projects, err = e.Projects.FindAll(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	now := e.now()
+	resp, err := e.Client.CreateChatCompletion(ctx, ai.ChatCompletionRequest{
+		Messages: []ai.Message{
+			{Role: "system", Content: extractionPrompt(now, projects, defaultProject)},
+			{Role: "user", Content: text},
+		},
+		ResponseFormat: &ai.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &ai.JSONSchema{Name: "task_proposals", Strict: true, Schema: taskProposalSchema},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, ErrInvalidExtraction
+	}
+
+	var output struct {
+		Tasks []extractedTask `json:"tasks"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject(resp.Choices[0].Message.Content)), &output); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtraction, err)
+	}
+
+	proposals := []TaskProposal{}
+	for _, item := range output.Tasks {
+		if strings.TrimSpace(item.Title) == "" {
+			continue
+		}
+		proposals = append(proposals, e.propose(item, now, projects, defaultProject))
+		if len(proposals) == maxProposals {
+			break
+		}
+	}
+	return proposals, nil
+}
+
+// propose turns an extracted task into a proposal, resolving its due date
+// and project
+func (e *TaskExtractor) propose(item extractedTask, now time.Time, projects []models.Project, defaultProject models.Project) TaskProposal {
+	proposal := TaskProposal{
+		Task: models.Task{
+			Title:       strings.TrimSpace(item.Title),
+			Description: strings.TrimSpace(item.Description),
+			Priority:    item.Priority,
+			Status:      "To-Do",
+		},
+		DueText:     strings.TrimSpace(item.DueText),
+		ProjectName: strings.TrimSpace(item.Project),
+		Source:      strings.TrimSpace(item.Source),
+	}
+	if proposal.Task.Priority != "Low" && proposal.Task.Priority != "Medium" && proposal.Task.Priority != "High" {
+		proposal.Task.Priority = "Medium"
+	}
+
+	// The phrase is resolved here; the model's date is only used when the
+	// phrase is not understood, since models are unreliable at date arithmetic
+	if due, ok := ResolveDueDate(proposal.DueText, now); ok {
+		proposal.Task.DueDate = due
+	} else if due, err := time.ParseInLocation(dateLayout, item.DueDate, now.Location()); err == nil {
+		proposal.Task.DueDate = due
+		if proposal.DueText != "" {
+			proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("Revisa la fecha: «%s» se interpretó como %s.", proposal.DueText, item.DueDate))
+		}
+	} else if proposal.DueText != "" {
+		proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("No se pudo interpretar la fecha «%s».", proposal.DueText))
+	}
+	if !proposal.Task.DueDate.IsZero() && proposal.Task.DueDate.Before(startOfDay(now)) {
+		proposal.Warnings = append(proposal.Warnings, "La fecha de vencimiento ya pasó.")
+	}
+
+	if project, ok := matchProject(proposal.ProjectName, projects); ok {
+		proposal.Task.ProjectID = project.ID
+	} else {
+		proposal.Task.ProjectID = defaultProject.ID
+		if proposal.ProjectName != "" {
+			proposal.Warnings = append(proposal.Warnings, fmt.Sprintf("No se encontró el proyecto «%s».", proposal.ProjectName))
+		}
+	}
+	return proposal
+}
+
+// now returns the current time in Bogotá
+func (e *TaskExtractor) now() time.Time {
+	if e.Now != nil {
+		return e.Now().In(Bogota)
+	}
+	return time.Now().In(Bogota)
+}
+
+// extractionPrompt tells the model what to extract, what day it is and
+// which projects exist
+func extractionPrompt(now time.Time, projects []models.Project, defaultProject models.Project) string {
+	lines := []string{
+		"Eres el asistente de ana.world. Extrae del texto del usuario (actas de reunión, correos de clientes o notas) las tareas concretas que alguien debe realizar.",
+		fmt.Sprintf("Hoy es %s %s en Bogotá.", spanishWeekdays[now.Weekday()], now.Format(dateLayout)),
+		"Para cada tarea copia en due_text la expresión de fecha tal como aparece en el texto y escribe en due_date la fecha que corresponde en formato YYYY-MM-DD; deja ambos vacíos si no hay fecha.",
+		"Usa prioridad High para lo urgente o bloqueante, Low para lo opcional y Medium en los demás casos.",
+		"No inventes tareas: si el texto no contiene ninguna, devuelve una lista vacía.",
+	}
+	if len(projects) > 0 {
+		names := make([]string, len(projects))
+		for i, project := range projects {
+			names[i] = project.Name
+		}
+		lines = append(lines, "Proyectos existentes: "+strings.Join(names, "; ")+". En project escribe el nombre del proyecto al que se refiere la tarea, o vacío.")
+	}
+	if defaultProject.Name != "" {
+		lines = append(lines, fmt.Sprintf("El texto trata del proyecto «%s» salvo que se indique otro.", defaultProject.Name))
+	}
+	return strings.Join(lines, " ")
+}
+
+// spanishWeekdays names the days of the week, starting on Sunday
+var spanishWeekdays = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+
+// spanishMonths names the months, starting on January
+var spanishMonths = [...]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+
+var (
+	inDaysPattern      = regexp.MustCompile(`\ben (\d+|un|una|dos|tres|cuatro|cinco) (dia|dias|semana|semanas|mes|meses)\b`)
+	dayMonthPattern    = regexp.MustCompile(`\b(\d{1,2}) de ([a-z]+)(?: de (\d{4}))?\b`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})(?:/(\d{4}))?\b`)
+	isoDatePattern     = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// smallNumbers are the counts written out in relative dates
+var smallNumbers = map[string]int{"un": 1, "una": 1, "dos": 2, "tres": 3, "cuatro": 4, "cinco": 5}
+
+// ResolveDueDate resolves a Spanish date expression such as "mañana", "el
+// viernes", "en dos semanas", "15 de junio" or "15/06" to midnight of that
+// day in now's location. Dates without a year that have already passed
+// fall in the next year. It returns false if the expression is not
+// understood.
+func ResolveDueDate(text string, now time.Time) (time.Time, bool) {
+	s := foldAccents(strings.ToLower(strings.TrimSpace(text)))
+	if s == "" {
+		return time.Time{}, false
+	}
+	today := startOfDay(now)
+
+	if match := isoDatePattern.FindString(s); match != "" {
+		if d, err := time.ParseInLocation(dateLayout, match, now.Location()); err == nil {
+			return d, true
+		}
+	}
+	if m := dayMonthPattern.FindStringSubmatch(s); m != nil {
+		for i, month := range spanishMonths {
+			if m[2] == month || (len(m[2]) >= 3 && strings.HasPrefix(month, m[2])) {
+				day, _ := strconv.Atoi(m[1])
+				return calendarDate(today, m[3], time.Month(i+1), day)
+			}
+		}
+	}
+	if m := numericDatePattern.FindStringSubmatch(s); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		return calendarDate(today, m[3], time.Month(month), day)
+	}
+	if m := inDaysPattern.FindStringSubmatch(s); m != nil {
+		n, ok := smallNumbers[m[1]]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch {
+		case strings.HasPrefix(m[2], "dia"):
+			return today.AddDate(0, 0, n), true
+		case strings.HasPrefix(m[2], "semana"):
+			return today.AddDate(0, 0, 7*n), true
+		default:
+			return today.AddDate(0, n, 0), true
+		}
+	}
+
+	// Next week starts on the coming Monday
+	nextWeek := (8 - int(today.Weekday())) % 7
+	if nextWeek == 0 {
+		nextWeek = 7
+	}
+	inNextWeek := strings.Contains(s, "proxima semana") || strings.Contains(s, "semana que viene") || strings.Contains(s, "siguiente semana")
+	for i, name := range spanishWeekdays {
+		if !strings.Contains(s, foldAccents(name)) {
+			continue
+		}
+		if inNextWeek {
+			return today.AddDate(0, 0, nextWeek+(i+6)%7), true
+		}
+		// "el viernes" said on a Friday means next week's
+		days := (i - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), true
+	}
+
+	switch {
+	case inNextWeek:
+		return today.AddDate(0, 0, nextWeek), true
+	case strings.Contains(s, "pasado manana"):
+		return today.AddDate(0, 0, 2), true
+	case strings.Contains(s, "manana"):
+		return today.AddDate(0, 0, 1), true
+	case strings.Contains(s, "hoy"):
+		return today, true
+	case strings.Contains(s, "fin de mes"):
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// calendarDate returns the given day, in the year written or else the
+// first year in which it has not passed
+func calendarDate(today time.Time, year string, month time.Month, day int) (time.Time, bool) {
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	y := today.Year()
+	if year != "" {
+		y, _ = strconv.Atoi(year)
+	}
+	d := time.Date(y, month, day, 0, 0, 0, 0, today.Location())
+	if d.Day() != day {
+		// e.g. 31 de junio
+		return time.Time{}, false
+	}
+	if year == "" && d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d, true
+}
+
+// matchProject finds the project a name refers to, ignoring case and
+// accents. An exact match wins; otherwise the name must be contained in, or
+// contain, exactly one project's name.
+func matchProject(name string, projects []models.Project) (models.Project, bool) {
+	key := foldAccents(strings.ToLower(strings.TrimSpace(name)))
+	if key == "" {
+		return models.Project{}, false
+	}
+	var partial []models.Project
+	for _, project := range projects {
+		candidate := foldAccents(strings.ToLower(project.Name))
+		if candidate == key {
+			return project, true
+		}
+		if strings.Contains(candidate, key) || strings.Contains(key, candidate) {
+			partial = append(partial, project)
+		}
+	}
+	if len(partial) == 1 {
+		return partial[0], true
+	}
+	return models.Project{}, false
+}
+
+// accentFolder removes the accents of Spanish text
+var accentFolder = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// foldAccents removes the accents of lower case Spanish text
+func foldAccents(s string) string {
+	return accentFolder.Replace(s)
+}
+
+// jsonObject returns the JSON object in content, dropping any <think>
+// section or code fence the model put around it
+func jsonObject(content string) string {
+	if i := strings.LastIndex(content, "</think>"); i >= 0 {
+		content = content[i+len("</think>"):]
+	}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
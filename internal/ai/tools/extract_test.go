@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cannedCompleter answers every request with the same content and keeps the last request
+type cannedCompleter struct {
+	content string
+	request ai.ChatCompletionRequest
+}
+
+func (c *cannedCompleter) CreateChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	c.request = req
+	if c.content == "" {
+		return nil, errors.New("unexpected request")
+	}
+	return &ai.ChatCompletionResponse{Choices: []ai.Choice{{Message: ai.ResponseMessage{Role: "assistant", Content: c.content}}}}, nil
+}
+
+// TestResolveDueDate tests resolving Spanish date expressions in Bogotá
+func TestResolveDueDate(t *testing.T) {
+	// Wednesday 11 June 2025, 8 p.m. in Bogotá: already Thursday in UTC
+	now := time.Date(2025, 6, 12, 1, 0, 0, 0, time.UTC).In(Bogota)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, Bogota) }
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"hoy", day(2025, 6, 11)},
+		{"mañana a primera hora", day(2025, 6, 12)},
+		{"pasado mañana", day(2025, 6, 13)},
+		{"el viernes", day(2025, 6, 13)},
+		{"el próximo miércoles", day(2025, 6, 18)},
+		{"el lunes por la mañana", day(2025, 6, 16)},
+		{"el martes de la próxima semana", day(2025, 6, 17)},
+		{"la semana que viene", day(2025, 6, 16)},
+		{"en dos semanas", day(2025, 6, 25)},
+		{"en 3 días", day(2025, 6, 14)},
+		{"antes del 20 de junio", day(2025, 6, 20)},
+		{"5 de febrero", day(2026, 2, 5)},
+		{"30 de julio de 2025", day(2025, 7, 30)},
+		{"15/07", day(2025, 7, 15)},
+		{"2025-08-01", day(2025, 8, 1)},
+		{"a fin de mes", day(2025, 6, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := ResolveDueDate(tt.text, now)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, text := range []string{"", "cuando puedas", "31 de junio", "45/13"} {
+		_, ok := ResolveDueDate(text, now)
+		assert.False(t, ok, text)
+	}
+}
+
+// TestTaskExtractor tests turning the model's output into reviewed proposals
+func TestTaskExtractor(t *testing.T) {
+	ctx := context.Background()
+	repos := repositories.NewMemoryRepositories()
+	usaquen := models.Project{Name: "Casa Usaquén"}
+	require.NoError(t, repos.Projects.Create(ctx, &usaquen))
+	chapinero := models.Project{Name: "Oficinas Chapinero"}
+	require.NoError(t, repos.Projects.Create(ctx, &chapinero))
+
+	client := &cannedCompleter{content: "<think>Hay tres tareas.</think>\n" + `{"tasks": [
+		{"title": "Enviar planos de fachada", "description": "", "due_text": "el viernes", "due_date": "2025-06-20", "priority": "High", "project": "casa usaquen", "source": "Enviar los planos de fachada el viernes"},
+		{"title": "Cotizar guadua", "description": "Tres proveedores", "due_text": "cuando acabe la obra gris", "due_date": "2025-07-01", "priority": "Urgent", "project": "Edificio Cedritos", "source": ""},
+		{"title": "Agendar visita", "description": "", "due_text": "", "due_date": "", "priority": "Low", "project": "", "source": ""},
+		{"title": "  ", "description": "", "due_text": "", "due_date": "", "priority": "Low", "project": "", "source": ""}
+	]}`}
+	now := time.Date(2025, 6, 11, 10, 0, 0, 0, Bogota) // Wednesday
+	extractor := &TaskExtractor{Client: client, Projects: repos.Projects, Now: func() time.Time { return now }}
+
+	proposals, err := extractor.Extract(ctx, "Acta de reunión...", chapinero)
+	require.NoError(t, err)
+	require.Len(t, proposals, 3)
+
+	// The output is constrained to the proposal schema
+	require.NotNil(t, client.request.ResponseFormat)
+	assert.Equal(t, "json_schema", client.request.ResponseFormat.Type)
+	assert.True(t, client.request.ResponseFormat.JSONSchema.Strict)
+	assert.Contains(t, client.request.Messages[0].Content, "2025-06-11")
+	assert.Contains(t, client.request.Messages[0].Content, "Casa Usaquén")
+
+	// The phrase wins over the model's date, and the project is matched without accents
+	first := proposals[0]
+	assert.Equal(t, time.Date(2025, 6, 13, 0, 0, 0, 0, Bogota), first.Task.DueDate)
+	assert.Equal(t, usaquen.ID, first.Task.ProjectID)
+	assert.Equal(t, "High", first.Task.Priority)
+	assert.Equal(t, "To-Do", first.Task.Status)
+	assert.Empty(t, first.Warnings)
+
+	// Unknown phrases and projects fall back, with warnings for the user
+	second := proposals[1]
+	assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, Bogota), second.Task.DueDate)
+	assert.Equal(t, chapinero.ID, second.Task.ProjectID)
+	assert.Equal(t, "Medium", second.Task.Priority)
+	assert.Len(t, second.Warnings, 2)
+
+	assert.True(t, proposals[2].Task.DueDate.IsZero())
+	assert.Equal(t, chapinero.ID, proposals[2].Task.ProjectID)
+
+	client.content = "No encontré tareas."
+	_, err = extractor.Extract(ctx, "Hola", models.Project{})
+	assert.ErrorIs(t, err, ErrInvalidExtraction)
+}
+
+// TestMatchProject tests matching project names as written in a text
+func TestMatchProject(t *testing.T) {
+	projects := []models.Project{{Name: "Casa Usaquén"}, {Name: "Casa Chía"}, {Name: "Oficinas Chapinero"}}
+
+	project, ok := matchProject("CASA USAQUEN", projects)
+	require.True(t, ok)
+	assert.Equal(t, "Casa Usaquén", project.Name)
+	project, ok = matchProject("Chapinero", projects)
+	require.True(t, ok)
+	assert.Equal(t, "Oficinas Chapinero", project.Name)
+
+	_, ok = matchProject("Casa", projects)
+	assert.False(t, ok, "ambiguous names are not matched")
+	_, ok = matchProject("", projects)
+	assert.False(t, ok)
+}
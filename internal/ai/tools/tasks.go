@@ -0,0 +1,357 @@
+// Package tools provides the tools the AI assistant can call to work with
+// the user's tasks, and extracts proposed tasks from free text
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tool names
+const (
+	ListTasks        = "list_tasks"
+	GetAgenda        = "get_agenda"
+	CreateTask       = "create_task"
+	UpdateTaskStatus = "update_task_status"
+)
+
+const dateLayout = "2006-01-02"
+
+// earliestDueDate bounds the overdue query from below so tasks without a
+// due date, which are never overdue, are left out by the repository
+var earliestDueDate = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TaskTools exposes the task repository to the model
+type TaskTools struct {
+	Tasks    repositories.TaskRepository
+	Projects repositories.ProjectRepository
+	// Now returns the current time; it defaults to time.Now. Dates are
+	// resolved in Bogotá whatever its location.
+	Now func() time.Time
+}
+
+// taskView is the compact form of a task returned to the model
+type taskView struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	DueDate   string `json:"due_date,omitempty"`
+	Priority  string `json:"priority"`
+	Status    string `json:"status"`
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// Register adds the task tools to the registry
+func (t *TaskTools) Register(registry *ai.ToolRegistry) error {
+	for _, tool := range []ai.RegisteredTool{
+		{
+			Function: ai.Function{
+				Name:        ListTasks,
+				Description: "Lista las tareas del usuario. Usa overdue=true para las tareas vencidas y sin terminar.",
+				Parameters: ai.FunctionParameters{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"status":   map[string]interface{}{"type": "string", "enum": []string{"To-Do", "In-Progress", "Done"}},
+						"priority": map[string]interface{}{"type": "string", "enum": []string{"Low", "Medium", "High"}},
+						"overdue":  map[string]interface{}{"type": "boolean", "description": "Solo tareas con fecha de vencimiento pasada que no están terminadas"},
+						"query":    map[string]interface{}{"type": "string", "description": "Texto a buscar en el título"},
+						"limit":    map[string]interface{}{"type": "integer", "description": "Número máximo de tareas (por defecto 20)"},
+					},
+				},
+			},
+			Handler: t.listTasks,
+		},
+		{
+			Function: ai.Function{
+				Name:        GetAgenda,
+				Description: "Devuelve las tareas que vencen en un día (por defecto hoy).",
+				Parameters: ai.FunctionParameters{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"date": map[string]interface{}{"type": "string", "description": "Fecha en formato YYYY-MM-DD"},
+					},
+				},
+			},
+			Handler: t.getAgenda,
+		},
+		{
+			Function: ai.Function{
+				Name:        CreateTask,
+				Description: "Crea una tarea nueva. Requiere confirmación del usuario.",
+				Parameters: ai.FunctionParameters{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"title":       map[string]interface{}{"type": "string"},
+						"description": map[string]interface{}{"type": "string"},
+						"due_date":    map[string]interface{}{"type": "string", "description": "Fecha de vencimiento en formato YYYY-MM-DD"},
+						"priority":    map[string]interface{}{"type": "string", "enum": []string{"Low", "Medium", "High"}},
+						"project_id":  map[string]interface{}{"type": "string"},
+					},
+					Required: []string{"title"},
+				},
+			},
+			Mutating: true,
+			Handler:  t.createTask,
+			Describe: t.describeCreate,
+		},
+		{
+			Function: ai.Function{
+				Name:        UpdateTaskStatus,
+				Description: "Cambia el estado de una tarea existente. Requiere confirmación del usuario.",
+				Parameters: ai.FunctionParameters{
+					Type: "object",
+					Properties: map[string]interface{}{
+						"id":     map[string]interface{}{"type": "string"},
+						"status": map[string]interface{}{"type": "string", "enum": []string{"To-Do", "In-Progress", "Done"}},
+					},
+					Required: []string{"id", "status"},
+				},
+			},
+			Mutating: true,
+			Handler:  t.updateTaskStatus,
+			Describe: t.describeStatusUpdate,
+		},
+	} {
+		if err := registry.Register(tool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type listTasksArgs struct {
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+	Overdue  bool   `json:"overdue"`
+	Query    string `json:"query"`
+	Limit    int    `json:"limit"`
+}
+
+func (t *TaskTools) listTasks(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args listTasksArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Limit <= 0 || args.Limit > 50 {
+		args.Limit = 20
+	}
+
+	query := repositories.TaskQuery{Search: args.Query, Limit: args.Limit}
+	if args.Status != "" {
+		query.Status = []string{args.Status}
+	}
+	if args.Priority != "" {
+		query.Priority = []string{args.Priority}
+	}
+	if args.Overdue {
+		query.DueFrom = earliestDueDate
+		query.DueBefore = startOfDay(t.now())
+		if args.Status == "" {
+			query.Status = []string{"To-Do", "In-Progress"}
+		}
+	}
+
+	page, err := t.Tasks.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]taskView, 0, len(page.Tasks))
+	for _, task := range page.Tasks {
+		views = append(views, viewOf(task))
+	}
+	return views, nil
+}
+
+func (t *TaskTools) getAgenda(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Date string `json:"date"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	day := startOfDay(t.now())
+	if args.Date != "" {
+		parsed, err := t.parseDate(args.Date)
+		if err != nil {
+			return nil, err
+		}
+		day = parsed
+	}
+
+	tasks, err := repositories.FindAllMatching(ctx, t.Tasks, repositories.TaskQuery{DueFrom: day, DueBefore: day.AddDate(0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	views := make([]taskView, 0, len(tasks))
+	for _, task := range tasks {
+		views = append(views, viewOf(task))
+	}
+	return map[string]interface{}{"date": day.Format(dateLayout), "tasks": views}, nil
+}
+
+type createTaskArgs struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DueDate     string `json:"due_date"`
+	Priority    string `json:"priority"`
+	ProjectID   string `json:"project_id"`
+}
+
+func (t *TaskTools) createTask(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	task, err := t.taskFromArgs(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Tasks.Create(ctx, &task); err != nil {
+		return nil, err
+	}
+	return viewOf(task), nil
+}
+
+func (t *TaskTools) describeCreate(ctx context.Context, raw json.RawMessage) string {
+	var args createTaskArgs
+	_ = json.Unmarshal(raw, &args)
+	summary := fmt.Sprintf("Crear la tarea «%s»", args.Title)
+	if args.DueDate != "" {
+		summary += " con vencimiento " + args.DueDate
+	}
+	if args.Priority != "" {
+		summary += " (prioridad " + args.Priority + ")"
+	}
+	return summary
+}
+
+// taskFromArgs validates create_task arguments and builds the task
+func (t *TaskTools) taskFromArgs(ctx context.Context, raw json.RawMessage) (models.Task, error) {
+	var args createTaskArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return models.Task{}, fmt.Errorf("invalid arguments: %w", err)
+	}
+	task := models.Task{
+		Title:       strings.TrimSpace(args.Title),
+		Description: args.Description,
+		Priority:    args.Priority,
+		Status:      "To-Do",
+	}
+	if task.Title == "" {
+		return task, errors.New("title is required")
+	}
+	if task.Priority == "" {
+		task.Priority = "Medium"
+	}
+	if task.Priority != "Low" && task.Priority != "Medium" && task.Priority != "High" {
+		return task, fmt.Errorf("invalid priority: %s", task.Priority)
+	}
+	if args.DueDate != "" {
+		due, err := t.parseDate(args.DueDate)
+		if err != nil {
+			return task, err
+		}
+		task.DueDate = due
+	}
+	if args.ProjectID != "" {
+		id, err := primitive.ObjectIDFromHex(args.ProjectID)
+		if err != nil {
+			return task, fmt.Errorf("invalid project_id: %s", args.ProjectID)
+		}
+		if t.Projects != nil {
+			exists, err := t.Projects.Exists(ctx, id)
+			if err != nil {
+				return task, err
+			}
+			if !exists {
+				return task, fmt.Errorf("project does not exist: %s", args.ProjectID)
+			}
+		}
+		task.ProjectID = id
+	}
+	return task, nil
+}
+
+type updateStatusArgs struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func (t *TaskTools) updateTaskStatus(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args updateStatusArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Status != "To-Do" && args.Status != "In-Progress" && args.Status != "Done" {
+		return nil, fmt.Errorf("invalid status: %s", args.Status)
+	}
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task id: %s", args.ID)
+	}
+	task, err := t.Tasks.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	task.Status = args.Status
+	if err := t.Tasks.Update(ctx, &task); err != nil {
+		return nil, err
+	}
+	return viewOf(task), nil
+}
+
+func (t *TaskTools) describeStatusUpdate(ctx context.Context, raw json.RawMessage) string {
+	var args updateStatusArgs
+	_ = json.Unmarshal(raw, &args)
+	name := args.ID
+	if id, err := primitive.ObjectIDFromHex(args.ID); err == nil {
+		if task, err := t.Tasks.FindByID(ctx, id); err == nil {
+			name = "«" + task.Title + "»"
+		}
+	}
+	return fmt.Sprintf("Cambiar el estado de la tarea %s a %s", name, args.Status)
+}
+
+// now returns the current time in Bogotá, where the user's "today" and
+// "el viernes" are, as for the agent's prompt and the extractor
+func (t *TaskTools) now() time.Time {
+	if t.Now != nil {
+		return t.Now().In(Bogota)
+	}
+	return time.Now().In(Bogota)
+}
+
+// parseDate parses a YYYY-MM-DD date in the location of the current time
+func (t *TaskTools) parseDate(value string) (time.Time, error) {
+	d, err := time.ParseInLocation(dateLayout, value, t.now().Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return d, nil
+}
+
+// startOfDay returns midnight of the day containing t
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// viewOf converts a task to the form returned to the model
+func viewOf(task models.Task) taskView {
+	view := taskView{
+		ID:       task.ID.Hex(),
+		Title:    task.Title,
+		Priority: task.Priority,
+		Status:   task.Status,
+	}
+	if !task.DueDate.IsZero() {
+		view.DueDate = task.DueDate.In(Bogota).Format(dateLayout)
+	}
+	if !task.ProjectID.IsZero() {
+		view.ProjectID = task.ProjectID.Hex()
+	}
+	return view
+}
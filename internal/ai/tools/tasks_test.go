@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTools registers the task tools on memory repositories with a fixed clock
+func newTestTools(t *testing.T) (*ai.ToolRegistry, *repositories.Repositories) {
+	repos := repositories.NewMemoryRepositories()
+	now := time.Date(2025, 6, 11, 10, 0, 0, 0, time.UTC) // Wednesday
+	taskTools := &TaskTools{Tasks: repos.Tasks, Projects: repos.Projects, Now: func() time.Time { return now }}
+	registry := ai.NewToolRegistry()
+	require.NoError(t, taskTools.Register(registry))
+	return registry, repos
+}
+
+// call runs a registered tool and decodes its result into out
+func call(t *testing.T, registry *ai.ToolRegistry, name, args string, out interface{}) error {
+	tool, ok := registry.Get(name)
+	require.True(t, ok, "tool %s not registered", name)
+	result, err := tool.Handler(context.Background(), json.RawMessage(args))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	return json.Unmarshal(data, out)
+}
+
+// TestTaskToolsRegistration tests which tools need confirmation
+func TestTaskToolsRegistration(t *testing.T) {
+	registry, _ := newTestTools(t)
+	for name, mutating := range map[string]bool{ListTasks: false, GetAgenda: false, CreateTask: true, UpdateTaskStatus: true} {
+		tool, ok := registry.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, mutating, tool.Mutating, name)
+	}
+	assert.Len(t, registry.Definitions(), 4)
+}
+
+// TestCreateAndUpdateTask tests the mutating tools against the repository
+func TestCreateAndUpdateTask(t *testing.T) {
+	registry, repos := newTestTools(t)
+
+	var created taskView
+	require.NoError(t, call(t, registry, CreateTask, `{"title":"Revisar planos","due_date":"2025-06-13","priority":"High"}`, &created))
+	assert.Equal(t, "Revisar planos", created.Title)
+	assert.Equal(t, "2025-06-13", created.DueDate)
+	assert.Equal(t, "To-Do", created.Status)
+
+	var updated taskView
+	require.NoError(t, call(t, registry, UpdateTaskStatus, `{"id":"`+created.ID+`","status":"Done"}`, &updated))
+	assert.Equal(t, "Done", updated.Status)
+
+	tasks, err := repos.Tasks.FindAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Done", tasks[0].Status)
+
+	assert.Error(t, call(t, registry, CreateTask, `{"title":""}`, &created))
+	assert.Error(t, call(t, registry, CreateTask, `{"title":"x","due_date":"viernes"}`, &created))
+	assert.Error(t, call(t, registry, CreateTask, `{"title":"x","project_id":"64b7f0c2a1b2c3d4e5f60718"}`, &created))
+	assert.Error(t, call(t, registry, UpdateTaskStatus, `{"id":"`+created.ID+`","status":"Archived"}`, &updated))
+
+	tool, _ := registry.Get(UpdateTaskStatus)
+	summary := tool.Describe(context.Background(), json.RawMessage(`{"id":"`+created.ID+`","status":"In-Progress"}`))
+	assert.Equal(t, "Cambiar el estado de la tarea «Revisar planos» a In-Progress", summary)
+}
+
+// TestListOverdueAndAgenda tests the read-only tools
+func TestListOverdueAndAgenda(t *testing.T) {
+	registry, repos := newTestTools(t)
+	ctx := context.Background()
+	for _, task := range []models.Task{
+		{Title: "Vencida", DueDate: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), Priority: "High", Status: "To-Do"},
+		{Title: "Vencida terminada", DueDate: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), Priority: "Low", Status: "Done"},
+		{Title: "Hoy", DueDate: time.Date(2025, 6, 11, 15, 0, 0, 0, time.UTC), Priority: "Medium", Status: "To-Do"},
+		{Title: "Sin fecha", Priority: "Low", Status: "To-Do"},
+	} {
+		task := task
+		require.NoError(t, repos.Tasks.Create(ctx, &task))
+	}
+
+	var overdue []taskView
+	require.NoError(t, call(t, registry, ListTasks, `{"overdue":true}`, &overdue))
+	require.Len(t, overdue, 1)
+	assert.Equal(t, "Vencida", overdue[0].Title)
+
+	var agenda struct {
+		Date  string     `json:"date"`
+		Tasks []taskView `json:"tasks"`
+	}
+	require.NoError(t, call(t, registry, GetAgenda, `{}`, &agenda))
+	assert.Equal(t, "2025-06-11", agenda.Date)
+	require.Len(t, agenda.Tasks, 1)
+	assert.Equal(t, "Hoy", agenda.Tasks[0].Title)
+}
+
+// TestTaskToolsBogotaTime tests that dates are resolved in Bogotá on a
+// server whose clock is in UTC
+func TestTaskToolsBogotaTime(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	now := time.Date(2025, 6, 13, 2, 0, 0, 0, time.UTC) // Thursday 21:00 in Bogotá
+	taskTools := &TaskTools{Tasks: repos.Tasks, Projects: repos.Projects, Now: func() time.Time { return now }}
+	registry := ai.NewToolRegistry()
+	require.NoError(t, taskTools.Register(registry))
+
+	// "el viernes" is due at midnight in Bogotá, not the evening before
+	var created taskView
+	require.NoError(t, call(t, registry, CreateTask, `{"title":"Entrega de planos","due_date":"2025-06-13"}`, &created))
+	assert.Equal(t, "2025-06-13", created.DueDate)
+	tasks, err := repos.Tasks.FindAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.True(t, tasks[0].DueDate.Equal(time.Date(2025, 6, 13, 5, 0, 0, 0, time.UTC)), "got %v", tasks[0].DueDate)
+
+	// It is still Thursday in Bogotá, so Friday's task is not due today
+	var agenda struct {
+		Date  string     `json:"date"`
+		Tasks []taskView `json:"tasks"`
+	}
+	require.NoError(t, call(t, registry, GetAgenda, `{}`, &agenda))
+	assert.Equal(t, "2025-06-12", agenda.Date)
+	assert.Empty(t, agenda.Tasks)
+}
+
+// TestListOverdueManyUndated tests that undated tasks do not use up the
+// overdue list's limit
+func TestListOverdueManyUndated(t *testing.T) {
+	registry, repos := newTestTools(t)
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, repos.Tasks.Create(ctx, &models.Task{Title: fmt.Sprintf("Sin fecha %d", i), Priority: "Low", Status: "To-Do"}))
+	}
+	for _, day := range []int{2, 9} {
+		require.NoError(t, repos.Tasks.Create(ctx, &models.Task{Title: fmt.Sprintf("Vencida %d", day), DueDate: time.Date(2025, 6, day, 12, 0, 0, 0, time.UTC), Priority: "High", Status: "To-Do"}))
+	}
+
+	var overdue []taskView
+	require.NoError(t, call(t, registry, ListTasks, `{"overdue":true,"limit":20}`, &overdue))
+	require.Len(t, overdue, 2)
+	assert.Equal(t, "Vencida 2", overdue[0].Title)
+	assert.Equal(t, "Vencida 9", overdue[1].Title)
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// TokenUsage is the token count of one completion
+type TokenUsage struct {
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	// Estimated is set when the provider did not report usage and the
+	// counts were estimated from the text (see EstimateTokens)
+	Estimated bool
+}
+
+// UsageRecorder is called with the token usage of every completion a
+// ChatModel makes. ctx is the context of the request, so recorders can tell
+// who the completion was for.
+type UsageRecorder func(ctx context.Context, usage TokenUsage)
+
+// SetUsageRecorder sets the recorder given the usage of every completion
+// made by the factory's chat models
+func (f *ModelFactory) SetUsageRecorder(recorder UsageRecorder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage = recorder
+}
+
+// recordUsage counts a completion's tokens in the metrics and passes them
+// to the model's recorder
+func (m *ChatModel) recordUsage(ctx context.Context, usage TokenUsage) {
+	countUsage(ctx, m.usage, usage)
+}
+
+// recordUsage counts the tokens of a completion made by a model from
+// CreateModel, passing them to the recorder the factory has at the time
+func (f *ModelFactory) recordUsage(ctx context.Context, usage TokenUsage) {
+	f.mu.RLock()
+	recorder := f.usage
+	f.mu.RUnlock()
+	countUsage(ctx, recorder, usage)
+}
+
+// forwardUsage passes usage to the recorder the factory has at the time
+func (f *ModelFactory) forwardUsage(ctx context.Context, usage TokenUsage) {
+	f.mu.RLock()
+	recorder := f.usage
+	f.mu.RUnlock()
+	if recorder != nil {
+		recorder(ctx, usage)
+	}
+}
+
+// countUsage counts a completion's tokens in the metrics and passes them to
+// recorder, if any
+func countUsage(ctx context.Context, recorder UsageRecorder, usage TokenUsage) {
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		return
+	}
+	monitoring.RecordTokenUsage(usage.Provider, usage.Model, "prompt", usage.PromptTokens)
+	monitoring.RecordTokenUsage(usage.Provider, usage.Model, "completion", usage.CompletionTokens)
+	if recorder != nil {
+		recorder(ctx, usage)
+	}
+}
+
+// responseUsage returns the usage a provider reported for request
+func (m *ChatModel) responseUsage(request ChatCompletionRequest, resp *ChatCompletionResponse) TokenUsage {
+	return TokenUsage{
+		Provider:         m.Provider.Name(),
+		Model:            request.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+}
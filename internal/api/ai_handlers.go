@@ -4,7 +4,9 @@
 package api
 
 import (
+    "context"
     "encoding/json"
+    "errors"
     "net/http"
     "time"
 
@@ -42,7 +44,7 @@ func (h *AIHandler) ProcessRequest(w http.ResponseWriter, r *http.Request) {
     ctx := r.Context()
     result, err := h.aiService.ProcessTask(ctx, &task)
     if err != nil {
-        h.handleError(w, "Failed to process task: "+err.Error(), http.StatusInternalServerError)
+        h.handleError(w, "Failed to process task: "+err.Error(), processStatus(err))
         return
     }
 
@@ -52,9 +54,28 @@ func (h *AIHandler) ProcessRequest(w http.ResponseWriter, r *http.Request) {
 
 // GetModels returns available AI models
 func (h *AIHandler) GetModels(w http.ResponseWriter, r *http.Request) {
-    // Return a static list of models for now
-    models := []string{"gpt-3.5-turbo", "gpt-4"}
-    h.respondJSON(w, models)
+    h.respondJSON(w, h.aiService.Models())
+}
+
+// processStatus maps a ProcessTask error to an HTTP status
+func processStatus(err error) int {
+    var apiErr *ai.APIError
+    switch {
+    case errors.Is(err, ai.ErrInvalidTask):
+        return http.StatusBadRequest
+    case errors.Is(err, ai.ErrModelNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, ai.ErrRateLimited):
+        return http.StatusTooManyRequests
+    case errors.Is(err, ai.ErrUnavailable), errors.Is(err, ai.ErrCircuitOpen):
+        return http.StatusServiceUnavailable
+    case errors.Is(err, context.DeadlineExceeded):
+        return http.StatusGatewayTimeout
+    case errors.Is(err, ai.ErrInvalidOutput), errors.As(err, &apiErr):
+        return http.StatusBadGateway
+    default:
+        return http.StatusInternalServerError
+    }
 }
 
 // HealthCheck returns AI system health status
@@ -65,11 +86,16 @@ func (h *AIHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 
 func (h *AIHandler) handleError(w http.ResponseWriter, message string, status int) {
     h.metrics.RecordError("ai_api_error")
-    http.Error(w, message, status)
+    h.writeJSON(w, status, map[string]string{"error": message})
 }
 
 func (h *AIHandler) respondJSON(w http.ResponseWriter, data interface{}) {
+    h.writeJSON(w, http.StatusOK, data)
+}
+
+func (h *AIHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
     w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
     json.NewEncoder(w).Encode(data)
 }
 
@@ -77,10 +103,3 @@ func (h *AIHandler) recordMetrics(operation string, start time.Time) {
     duration := time.Since(start).Seconds()
     h.metrics.RecordAIProcessing(operation, duration)
 }
-
-// RegisterRoutes registers AI endpoints
-func (h *AIHandler) RegisterRoutes(router *http.ServeMux) {
-    router.HandleFunc("/api/v1/ai/process", h.ProcessRequest)
-    router.HandleFunc("/api/v1/ai/models", h.GetModels)
-    router.HandleFunc("/api/v1/ai/health", h.HealthCheck)
-}
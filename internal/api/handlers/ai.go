@@ -6,6 +6,7 @@ package handlers
 import (
     "context"
     "encoding/json"
+    "errors"
     "net/http"
     "time"
 
@@ -54,7 +55,11 @@ func (h *AIHandler) ProcessRequest(w http.ResponseWriter, r *http.Request) {
     ctx := r.Context()
     result, err := h.processAI(ctx, request.Processor, request.Input, request.Options)
     if err != nil {
-        h.handleError(w, "Processing error: "+err.Error(), http.StatusInternalServerError)
+        status := http.StatusInternalServerError
+        if errors.Is(err, processors.ErrInvalidInput) {
+            status = http.StatusBadRequest
+        }
+        h.handleError(w, "Processing error: "+err.Error(), status)
         return
     }
 
@@ -103,11 +108,16 @@ func (h *AIHandler) ResetMetrics(w http.ResponseWriter, r *http.Request) {
 
 func (h *AIHandler) handleError(w http.ResponseWriter, message string, status int) {
     h.metrics.RecordError("ai_api_error")
-    http.Error(w, message, status)
+    h.writeJSON(w, status, map[string]string{"error": message})
 }
 
 func (h *AIHandler) respondJSON(w http.ResponseWriter, data interface{}) {
+    h.writeJSON(w, http.StatusOK, data)
+}
+
+func (h *AIHandler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
     w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
     json.NewEncoder(w).Encode(data)
 }
 
@@ -8,6 +8,9 @@ import (
     "encoding/json"
     "log"
     "net/http"
+    "net/url"
+    "strings"
+    "sync"
     "time"
 
     "github.com/gorilla/websocket"
@@ -20,6 +23,13 @@ type WebSocketHandler struct {
     manager  *processors.ProcessorManager
     metrics  *metrics.Metrics
     upgrader websocket.Upgrader
+
+    // clients are the open connections and the cancellation of their
+    // messages; closing refuses new connections during shutdown
+    mu          sync.Mutex
+    clients     map[*client]context.CancelFunc
+    closing     bool
+    connections sync.WaitGroup
 }
 
 // Message represents a WebSocket message
@@ -30,22 +40,75 @@ type Message struct {
     Options   map[string]interface{} `json:"options,omitempty"`
 }
 
-// NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(manager *processors.ProcessorManager, metrics *metrics.Metrics) *WebSocketHandler {
+// client is a WebSocket connection whose messages are processed
+// concurrently; gorilla/websocket allows one writer at a time
+type client struct {
+    conn     *websocket.Conn
+    mu       sync.Mutex
+    inFlight sync.WaitGroup
+}
+
+// write sends a text message to the client
+func (c *client) write(data []byte) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// NewWebSocketHandler creates a new WebSocket handler. Connections are
+// accepted from pages of the server itself and of allowedOrigins, such as
+// "https://ana.world".
+func NewWebSocketHandler(manager *processors.ProcessorManager, metrics *metrics.Metrics, allowedOrigins ...string) *WebSocketHandler {
     return &WebSocketHandler{
         manager: manager,
         metrics: metrics,
+        clients: make(map[*client]context.CancelFunc),
         upgrader: websocket.Upgrader{
-            CheckOrigin: func(r *http.Request) bool {
-                // TODO: Implement proper origin check
-                return true
-            },
+            CheckOrigin: checkOrigin(allowedOrigins),
         },
     }
 }
 
+// checkOrigin accepts same-origin requests and those from allowed origins,
+// so other sites cannot open a WebSocket with a user's session cookie.
+// Requests without an Origin do not come from a browser page and are accepted.
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+    return func(r *http.Request) bool {
+        origin := r.Header.Get("Origin")
+        if origin == "" {
+            return true
+        }
+        u, err := url.Parse(origin)
+        if err != nil || u.Host == "" {
+            return false
+        }
+        if strings.EqualFold(u.Host, r.Host) {
+            return true
+        }
+        for _, a := range allowed {
+            if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+                return true
+            }
+        }
+        log.Printf("WebSocket connection from origin %s refused", origin)
+        return false
+    }
+}
+
 // HandleConnection handles WebSocket connections
 func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
+    h.mu.Lock()
+    if h.closing {
+        h.mu.Unlock()
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusServiceUnavailable)
+        json.NewEncoder(w).Encode(map[string]string{"error": "Server is shutting down"})
+        return
+    }
+    h.connections.Add(1)
+    h.mu.Unlock()
+    defer h.connections.Done()
+
     // Upgrade connection
     conn, err := h.upgrader.Upgrade(w, r, nil)
     if err != nil {
@@ -53,35 +116,105 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
         return
     }
     defer conn.Close()
+    h.metrics.IncrementWSConnections()
+    defer h.metrics.DecrementWSConnections()
+
+    // Handle connection; messages still being processed are cancelled when
+    // the client disconnects
+    ctx, cancel := context.WithCancel(r.Context())
+    defer cancel()
+    c := &client{conn: conn}
+    h.mu.Lock()
+    h.clients[c] = cancel
+    if h.closing {
+        conn.SetReadDeadline(time.Now())
+    }
+    h.mu.Unlock()
+    defer func() {
+        h.mu.Lock()
+        delete(h.clients, c)
+        h.mu.Unlock()
+    }()
+    h.handleClient(ctx, c)
+}
+
+// Shutdown stops reading from the open connections and refuses new ones.
+// Messages being processed are still answered before each connection is
+// closed; those still running when ctx is done are cancelled.
+func (h *WebSocketHandler) Shutdown(ctx context.Context) error {
+    h.mu.Lock()
+    h.closing = true
+    for c := range h.clients {
+        // Unblocks the read loop; SetReadDeadline is safe to call concurrently
+        c.conn.SetReadDeadline(time.Now())
+    }
+    h.mu.Unlock()
+
+    done := make(chan struct{})
+    go func() {
+        h.connections.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        h.mu.Lock()
+        for _, cancel := range h.clients {
+            cancel()
+        }
+        h.mu.Unlock()
+        <-done
+        return ctx.Err()
+    }
+}
 
-    // Handle connection
-    h.handleClient(r.Context(), conn)
+// shuttingDown reports whether Shutdown was called
+func (h *WebSocketHandler) shuttingDown() bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return h.closing
 }
 
 // handleClient handles a WebSocket client
-func (h *WebSocketHandler) handleClient(ctx context.Context, conn *websocket.Conn) {
+func (h *WebSocketHandler) handleClient(ctx context.Context, c *client) {
+    defer c.inFlight.Wait()
     for {
         // Read message
-        _, data, err := conn.ReadMessage()
+        _, data, err := c.conn.ReadMessage()
         if err != nil {
-            log.Printf("WebSocket read error: %v", err)
+            if h.shuttingDown() {
+                // Answer the messages being processed, then say goodbye
+                c.inFlight.Wait()
+                c.mu.Lock()
+                c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+                c.mu.Unlock()
+                return
+            }
+            if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+                log.Printf("WebSocket read error: %v", err)
+            }
             return
         }
 
         // Parse message
         var msg Message
         if err := json.Unmarshal(data, &msg); err != nil {
-            h.sendError(conn, "Invalid message format")
+            h.sendError(c, "Invalid message format")
             continue
         }
 
         // Process message
-        go h.processMessage(ctx, conn, msg)
+        c.inFlight.Add(1)
+        go func() {
+            defer c.inFlight.Done()
+            h.processMessage(ctx, c, msg)
+        }()
     }
 }
 
 // processMessage processes a WebSocket message
-func (h *WebSocketHandler) processMessage(ctx context.Context, conn *websocket.Conn, msg Message) {
+func (h *WebSocketHandler) processMessage(ctx context.Context, c *client, msg Message) {
     start := time.Now()
     defer h.recordMetrics("process_message", start)
 
@@ -92,27 +225,28 @@ func (h *WebSocketHandler) processMessage(ctx context.Context, conn *websocket.C
     }
     inputBytes, err := json.Marshal(inputData)
     if err != nil {
-        h.sendError(conn, "Invalid input format")
+        h.sendError(c, "Invalid input format")
         return
     }
 
     result, err := h.manager.Process(ctx, msg.Processor, inputBytes)
     if err != nil {
-        h.sendError(conn, "Processing error: "+err.Error())
+        h.sendError(c, "Processing error: "+err.Error())
         return
     }
 
     // Send result
-    if err := conn.WriteMessage(websocket.TextMessage, result); err != nil {
+    if err := c.write(result); err != nil {
         log.Printf("WebSocket write error: %v", err)
         return
     }
 }
 
-func (h *WebSocketHandler) sendError(conn *websocket.Conn, message string) {
+func (h *WebSocketHandler) sendError(c *client, message string) {
+    h.metrics.RecordError("websocket_error")
     response := map[string]string{"error": message}
     data, _ := json.Marshal(response)
-    conn.WriteMessage(websocket.TextMessage, data)
+    c.write(data)
 }
 
 func (h *WebSocketHandler) recordMetrics(operation string, start time.Time) {
@@ -0,0 +1,88 @@
+// Package cache provides the response caches used by the AI clients
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// Backends selectable through Config.Backend
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+	BackendRedis  = "redis"
+)
+
+// Eviction reasons reported in the ana_cache_evictions_total metric
+const (
+	EvictCapacity = "capacity"
+	EvictExpired  = "expired"
+)
+
+// ResponseCache stores generated responses by key until their TTL passes
+type ResponseCache interface {
+	// Get returns the value stored under key and whether it was found
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores value under key for ttl; a zero ttl never expires
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	// Len returns the number of stored entries
+	Len(ctx context.Context) (int, error)
+	// Close releases the backend's resources
+	Close() error
+}
+
+// Config selects and sizes a cache backend
+type Config struct {
+	// Backend is memory, disk or redis
+	Backend string
+	// Service labels the exported metrics, e.g. "cerebras"
+	Service string
+	// MaxEntries and MaxBytes bound the memory and disk caches; zero means no limit
+	MaxEntries int
+	MaxBytes   int64
+	// Dir holds the disk cache files
+	Dir string
+	// RedisURL is a redis:// or rediss:// URL for the redis backend
+	RedisURL string
+	// KeyPrefix namespaces keys in a shared Redis database
+	KeyPrefix string
+}
+
+// New creates the cache selected by cfg.Backend
+func New(cfg Config) (ResponseCache, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return NewLRU(cfg), nil
+	case BackendDisk:
+		return NewDisk(cfg)
+	case BackendRedis:
+		return NewRedis(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported cache backend: %s", cfg.Backend)
+	}
+}
+
+// recordLookup exports a hit or miss for the cache
+func recordLookup(service, backend string, hit bool) {
+	if hit {
+		monitoring.RecordCacheHit(service, backend)
+	} else {
+		monitoring.RecordCacheMiss(service, backend)
+	}
+}
+
+// expiry returns the expiry time for a ttl; zero means never
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// expired reports whether an expiry time has passed
+func expired(expires time.Time) bool {
+	return !expires.IsZero() && time.Now().After(expires)
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyffseba/ana/internal/monitoring"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend is a cache under test and a way to move its clock past a TTL
+type testBackend struct {
+	cache   ResponseCache
+	advance func(d time.Duration)
+}
+
+// testBackends opens every backend with the same limits
+func testBackends(t *testing.T) map[string]func(t *testing.T) testBackend {
+	sleep := func(d time.Duration) { time.Sleep(d) }
+	return map[string]func(t *testing.T) testBackend{
+		BackendMemory: func(t *testing.T) testBackend {
+			return testBackend{NewLRU(Config{Service: "test"}), sleep}
+		},
+		BackendDisk: func(t *testing.T) testBackend {
+			c, err := NewDisk(Config{Service: "test", Dir: t.TempDir()})
+			require.NoError(t, err)
+			return testBackend{c, sleep}
+		},
+		BackendRedis: func(t *testing.T) testBackend {
+			server := miniredis.RunT(t)
+			c, err := NewRedis(Config{Service: "test", RedisURL: "redis://" + server.Addr()})
+			require.NoError(t, err)
+			t.Cleanup(func() { c.Close() })
+			return testBackend{c, server.FastForward}
+		},
+	}
+}
+
+// TestResponseCacheContract checks that every backend behaves the same way
+func TestResponseCacheContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := open(t)
+			c := backend.cache
+
+			_, ok, err := c.Get(ctx, "missing")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NoError(t, c.Set(ctx, "nsr10", "Norma Sismo Resistente", time.Minute))
+			require.NoError(t, c.Set(ctx, "nsr10", "NSR-10", time.Minute))
+			value, ok, err := c.Get(ctx, "nsr10")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "NSR-10", value)
+
+			require.NoError(t, c.Set(ctx, "short", "dura poco", 20*time.Millisecond))
+			require.NoError(t, c.Set(ctx, "forever", "sin vencimiento", 0))
+			n, err := c.Len(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+
+			backend.advance(50 * time.Millisecond)
+			_, ok, err = c.Get(ctx, "short")
+			require.NoError(t, err)
+			assert.False(t, ok, "expired entries are not returned")
+			_, ok, err = c.Get(ctx, "forever")
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+// TestLRUEviction tests the entry and byte limits of the memory cache
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	evictions := monitoring.CacheEvictions.WithLabelValues("lru-test", BackendMemory, EvictCapacity)
+	before := testutil.ToFloat64(evictions)
+
+	c := NewLRU(Config{Service: "lru-test", MaxEntries: 2, MaxBytes: 100})
+	require.NoError(t, c.Set(ctx, "a", "1", 0))
+	require.NoError(t, c.Set(ctx, "b", "2", 0))
+	_, _, _ = c.Get(ctx, "a") // a is now the most recently used
+	require.NoError(t, c.Set(ctx, "c", "3", 0))
+
+	_, ok, _ := c.Get(ctx, "b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok, _ = c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, before+1, testutil.ToFloat64(evictions))
+
+	// A large value pushes out older entries to respect MaxBytes
+	require.NoError(t, c.Set(ctx, "d", string(make([]byte, 98)), 0))
+	n, _ := c.Len(ctx)
+	assert.Equal(t, 1, n)
+	assert.LessOrEqual(t, c.bytes, int64(100))
+
+	// Values that can never fit are not stored
+	require.NoError(t, c.Set(ctx, "huge", string(make([]byte, 200)), 0))
+	_, ok, _ = c.Get(ctx, "huge")
+	assert.False(t, ok)
+}
+
+// TestDiskCacheSurvivesRestart tests that entries and their recency are reloaded
+func TestDiskCacheSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	c, err := NewDisk(Config{Dir: dir})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Set(ctx, fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%d", i), time.Hour))
+		time.Sleep(10 * time.Millisecond) // distinct modification times
+	}
+	require.NoError(t, c.Set(ctx, "expiring", "x", 10*time.Millisecond))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "damaged.json"), []byte("{"), 0o600))
+	time.Sleep(20 * time.Millisecond)
+	_, _, _ = c.Get(ctx, "key-0") // key-0 becomes the most recently used
+	require.NoError(t, c.Close())
+
+	// Reopening with a smaller limit keeps the most recently used entries
+	reopened, err := NewDisk(Config{Dir: dir, MaxEntries: 2})
+	require.NoError(t, err)
+	n, _ := reopened.Len(ctx)
+	assert.Equal(t, 2, n)
+	value, ok, err := reopened.Get(ctx, "key-0")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value-0", value)
+	_, ok, _ = reopened.Get(ctx, "key-1")
+	assert.False(t, ok)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "expired, damaged and evicted files are removed")
+}
+
+// TestNew tests backend selection
+func TestNew(t *testing.T) {
+	c, err := New(Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &LRU{}, c)
+
+	_, err = New(Config{Backend: "memcached"})
+	assert.Error(t, err)
+	_, err = New(Config{Backend: BackendDisk})
+	assert.Error(t, err, "disk cache needs a directory")
+}
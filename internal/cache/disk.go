@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// DiskCache stores each entry in its own file so cached responses survive
+// restarts. An in-memory index tracks recency and size; file modification
+// times carry the recency across restarts.
+type DiskCache struct {
+	mu         sync.Mutex
+	service    string
+	dir        string
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // front is the most recently used
+	items      map[string]*list.Element
+}
+
+// diskEntry is the index record of one cache file
+type diskEntry struct {
+	key     string
+	size    int64
+	expires time.Time
+}
+
+// diskRecord is the content of a cache file
+type diskRecord struct {
+	Key     string    `json:"key"`
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// NewDisk opens the cache stored in cfg.Dir, creating the directory if
+// needed. Expired and unreadable files are removed while the index is built.
+func NewDisk(cfg Config) (*DiskCache, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("disk cache requires a directory")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	c := &DiskCache{
+		service:    cfg.Service,
+		dir:        cfg.Dir,
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load rebuilds the index from the files in the cache directory
+func (c *DiskCache) load() error {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("reading cache directory: %w", err)
+	}
+
+	type loaded struct {
+		entry    *diskEntry
+		accessed time.Time
+	}
+	var entries []loaded
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(c.dir, file.Name())
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		record, err := readDiskRecord(path)
+		if err != nil || expired(record.Expires) || c.path(record.Key) != path {
+			os.Remove(path)
+			continue
+		}
+		entries = append(entries, loaded{
+			entry:    &diskEntry{key: record.Key, size: info.Size(), expires: record.Expires},
+			accessed: info.ModTime(),
+		})
+	}
+
+	// Oldest first, so the most recently used file ends up at the front
+	sort.Slice(entries, func(i, j int) bool { return entries[i].accessed.Before(entries[j].accessed) })
+	for _, e := range entries {
+		c.items[e.entry.key] = c.order.PushFront(e.entry)
+		c.bytes += e.entry.size
+	}
+	c.evict()
+	return nil
+}
+
+// Get returns the value stored under key
+func (c *DiskCache) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		recordLookup(c.service, BackendDisk, false)
+		return "", false, nil
+	}
+	record, err := readDiskRecord(c.path(key))
+	if err != nil || record.Key != key {
+		// Removed or damaged outside the cache; forget it
+		c.remove(elem, "")
+		recordLookup(c.service, BackendDisk, false)
+		return "", false, nil
+	}
+	if expired(record.Expires) {
+		c.remove(elem, EvictExpired)
+		recordLookup(c.service, BackendDisk, false)
+		return "", false, nil
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	c.order.MoveToFront(elem)
+	recordLookup(c.service, BackendDisk, true)
+	return record.Value, true, nil
+}
+
+// Set writes value to the entry's file, evicting old entries to stay within
+// the limits. Values larger than MaxBytes are not stored.
+func (c *DiskCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	data, err := json.Marshal(diskRecord{Key: key, Value: value, Expires: expiry(ttl)})
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	entry := &diskEntry{key: key, size: size, expires: expiry(ttl)}
+	if elem, ok := c.items[key]; ok {
+		c.bytes += size - elem.Value.(*diskEntry).size
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(entry)
+		c.bytes += size
+	}
+	c.evict()
+	return nil
+}
+
+// Len returns the number of stored entries
+func (c *DiskCache) Len(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), nil
+}
+
+// Close does nothing; entries stay on disk for the next start
+func (c *DiskCache) Close() error {
+	return nil
+}
+
+// path returns the file that stores key
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// evict removes the least recently used entries until both limits hold
+func (c *DiskCache) evict() {
+	for (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		back := c.order.Back()
+		reason := EvictCapacity
+		if expired(back.Value.(*diskEntry).expires) {
+			reason = EvictExpired
+		}
+		c.remove(back, reason)
+	}
+}
+
+// remove deletes an entry and its file. An empty reason is not reported as an eviction.
+func (c *DiskCache) remove(elem *list.Element, reason string) {
+	entry := c.order.Remove(elem).(*diskEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+	os.Remove(c.path(entry.key))
+	if reason != "" {
+		monitoring.RecordCacheEviction(c.service, BackendDisk, reason)
+	}
+}
+
+// readDiskRecord reads and decodes one cache file
+func readDiskRecord(path string) (diskRecord, error) {
+	var record diskRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, err
+}
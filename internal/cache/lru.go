@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/monitoring"
+)
+
+// LRU is an in-memory cache bounded by entry count and total size.
+// The least recently used entries are evicted first; expired entries are
+// dropped when they are next looked up or reach the back of the list.
+type LRU struct {
+	mu         sync.Mutex
+	service    string
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // front is the most recently used
+	items      map[string]*list.Element
+}
+
+// lruEntry is one cached value
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// size is the number of bytes an entry counts against MaxBytes
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// NewLRU creates an in-memory cache limited by cfg.MaxEntries and cfg.MaxBytes
+func NewLRU(cfg Config) *LRU {
+	return &LRU{
+		service:    cfg.Service,
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key
+func (c *LRU) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if ok && expired(elem.Value.(*lruEntry).expires) {
+		c.remove(elem, EvictExpired)
+		ok = false
+	}
+	recordLookup(c.service, BackendMemory, ok)
+	if !ok {
+		return "", false, nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true, nil
+}
+
+// Set stores value under key, evicting old entries to stay within the limits.
+// Values larger than MaxBytes are not stored.
+func (c *LRU) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: value, expires: expiry(ttl)}
+	if c.maxBytes > 0 && entry.size() > c.maxBytes {
+		return nil
+	}
+	if elem, ok := c.items[key]; ok {
+		c.bytes += entry.size() - elem.Value.(*lruEntry).size()
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(entry)
+		c.bytes += entry.size()
+	}
+
+	for c.overLimit() {
+		back := c.order.Back()
+		reason := EvictCapacity
+		if expired(back.Value.(*lruEntry).expires) {
+			reason = EvictExpired
+		}
+		c.remove(back, reason)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet dropped
+func (c *LRU) Len(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), nil
+}
+
+// Close does nothing; the entries are released with the cache
+func (c *LRU) Close() error {
+	return nil
+}
+
+// overLimit reports whether the cache exceeds either limit
+func (c *LRU) overLimit() bool {
+	return (c.maxEntries > 0 && c.order.Len() > c.maxEntries) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+// remove deletes an entry and records why
+func (c *LRU) remove(elem *list.Element, reason string) {
+	entry := c.order.Remove(elem).(*lruEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size()
+	monitoring.RecordCacheEviction(c.service, BackendMemory, reason)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache stores entries in Redis or a compatible server such as Valkey
+// or KeyDB. Expiry uses Redis TTLs and the server's maxmemory policy bounds
+// its size, so MaxEntries and MaxBytes do not apply.
+type RedisCache struct {
+	client  *redis.Client
+	service string
+	prefix  string
+}
+
+// NewRedis connects to cfg.RedisURL and checks the connection
+func NewRedis(cfg Config) (*RedisCache, error) {
+	if cfg.RedisURL == "" {
+		return nil, fmt.Errorf("redis cache requires a URL")
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = "ana:cache:"
+	}
+	return &RedisCache{client: client, service: cfg.Service, prefix: prefix}, nil
+}
+
+// Get returns the value stored under key
+func (c *RedisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		recordLookup(c.service, BackendRedis, false)
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	recordLookup(c.service, BackendRedis, true)
+	return value, true, nil
+}
+
+// Set stores value under key with a Redis TTL
+func (c *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+// Len counts the keys under the cache's prefix
+func (c *RedisCache) Len(ctx context.Context) (int, error) {
+	count := 0
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	return count, iter.Err()
+}
+
+// Close closes the connection pool
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
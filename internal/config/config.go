@@ -4,13 +4,17 @@
 package config
 
 import (
+    "encoding/base64"
     "fmt"
-    "os"
+    "net/url"
+    "sort"
+    "strings"
     "time"
-
-    "gopkg.in/yaml.v3"
 )
 
+// DefaultCerebrasEndpoint is the Cerebras chat completions URL
+const DefaultCerebrasEndpoint = "https://api.cerebras.ai/v1/chat/completions"
+
 // Config represents the application configuration
 type Config struct {
     Server   ServerConfig   `yaml:"server"`
@@ -18,6 +22,9 @@ type Config struct {
     Database DatabaseConfig `yaml:"database"`
     Logging  LoggingConfig `yaml:"logging"`
     Metrics  MetricsConfig `yaml:"metrics"`
+    Timeouts TimeoutConfig `yaml:"timeouts"`
+    Auth     AuthConfig    `yaml:"auth"`
+    Prompts  PromptsConfig `yaml:"prompts"`
 }
 
 // ServerConfig holds server configuration
@@ -27,36 +34,107 @@ type ServerConfig struct {
     ReadTimeout  time.Duration `yaml:"read_timeout"`
     WriteTimeout time.Duration `yaml:"write_timeout"`
     IdleTimeout  time.Duration `yaml:"idle_timeout"`
+    // ShutdownDelay is how long requests are still served after readiness
+    // is withdrawn on shutdown, so load balancers stop sending traffic first
+    ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+    // ShutdownTimeout bounds the drain of in-flight requests on shutdown
+    ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+    // AllowedOrigins are the origins, e.g. "https://ana.world", whose pages
+    // may open WebSockets besides the server's own
+    AllowedOrigins []string `yaml:"allowed_origins"`
 }
 
-// AIConfig holds AI configuration
+// AIConfig holds AI configuration.
+// The providers and models keys of the ai section fill LLM.
 type AIConfig struct {
     Cerebras CerebrasConfig `yaml:"cerebras"`
     Cache    CacheConfig    `yaml:"cache"`
+    LLM      LLMConfig      `yaml:",inline"`
+    // HistoryTokenBudget bounds the conversation history sent with each
+    // question; zero keeps the handlers' default
+    HistoryTokenBudget int `yaml:"history_token_budget"`
+    // ProcessorsConfig is the file the AI processors are configured in
+    ProcessorsConfig string `yaml:"processors_config"`
+    // RateLimit bounds the questions each client IP may ask the assistant
+    RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// RateLimitConfig holds a per-client request rate
+type RateLimitConfig struct {
+    RequestsPerMinute int `yaml:"requests_per_minute"`
+    Burst             int `yaml:"burst"`
 }
 
 // CerebrasConfig holds Cerebras-specific configuration
 type CerebrasConfig struct {
+    // Endpoint is the chat completions URL
     Endpoint    string  `yaml:"endpoint"`
     APIKey      string  `yaml:"api_key"`
     MaxTokens   int     `yaml:"max_tokens"`
     Temperature float64 `yaml:"temperature"`
+    VisionModel string  `yaml:"vision_model"`
+    // MaxConcurrent limits the requests each client sends at once
+    MaxConcurrent  int                  `yaml:"max_concurrent"`
+    CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+    // Metrics exports the client's request metrics
+    Metrics bool `yaml:"metrics"`
 }
 
-// CacheConfig holds cache configuration
+// CircuitBreakerConfig tunes when calls to a failing provider are stopped
+type CircuitBreakerConfig struct {
+    FailureThreshold int           `yaml:"failure_threshold"`
+    Window           time.Duration `yaml:"window"`
+    OpenTimeout      time.Duration `yaml:"open_timeout"`
+    HalfOpenRequests int           `yaml:"half_open_requests"`
+}
+
+// CacheConfig holds the configuration of the AI response cache
 type CacheConfig struct {
     Enabled  bool          `yaml:"enabled"`
+    // Duration is how long answers are kept
     Duration time.Duration `yaml:"duration"`
+    // Backend is memory, disk or redis
+    Backend    string `yaml:"backend"`
+    MaxEntries int    `yaml:"max_entries"`
+    MaxBytes   int64  `yaml:"max_bytes"`
+    Dir        string `yaml:"dir"`
+    RedisURL   string `yaml:"redis_url"`
 }
 
-// DatabaseConfig holds database configuration
+// Database drivers supported by the repositories package
+const (
+    DatabaseDriverMemory   = "memory"
+    DatabaseDriverMongo    = "mongo"
+    DatabaseDriverPostgres = "postgres"
+)
+
+// DatabaseConfig holds database configuration.
+// Driver selects the storage backend. URI is required for MongoDB; for
+// PostgreSQL it overrides the individual connection fields when set.
 type DatabaseConfig struct {
-    Host     string `yaml:"host"`
-    Port     int    `yaml:"port"`
-    User     string `yaml:"user"`
-    Password string `yaml:"password"`
-    Database string `yaml:"database"`
-    SSLMode  string `yaml:"ssl_mode"`
+    Driver         string `yaml:"driver"`
+    URI            string `yaml:"uri"`
+    Host           string `yaml:"host"`
+    Port           int    `yaml:"port"`
+    User           string `yaml:"user"`
+    Password       string `yaml:"password"`
+    Database       string `yaml:"database"`
+    SSLMode        string `yaml:"ssl_mode"`
+    MigrationsPath string `yaml:"migrations_path"`
+}
+
+// PostgresDSN returns the PostgreSQL connection string for the configuration
+func (d DatabaseConfig) PostgresDSN() string {
+    u := url.URL{
+        Scheme: "postgres",
+        User:   url.UserPassword(d.User, d.Password),
+        Host:   fmt.Sprintf("%s:%d", d.Host, d.Port),
+        Path:   "/" + d.Database,
+    }
+    q := u.Query()
+    q.Set("sslmode", d.SSLMode)
+    u.RawQuery = q.Encode()
+    return u.String()
 }
 
 // LoggingConfig holds logging configuration
@@ -66,6 +144,56 @@ type LoggingConfig struct {
     Output string `yaml:"output"`
 }
 
+// AuthConfig holds the credentials of the admin endpoints and sign-in
+type AuthConfig struct {
+    // AdminToken is the bearer token of the admin endpoints; they are
+    // refused without one
+    AdminToken string       `yaml:"admin_token"`
+    Google     GoogleConfig `yaml:"google"`
+    // SessionSecret signs the session cookies of signed-in users. Without
+    // one a random key is used and sessions end when the server restarts.
+    SessionSecret string `yaml:"session_secret"`
+    // SessionMaxAge is how long users stay signed in
+    SessionMaxAge time.Duration `yaml:"session_max_age"`
+    // TokenKey encrypts the stored Google tokens: 32 bytes, base64-encoded.
+    // Without one a random key is used and users must sign in again after
+    // the server restarts to reconnect their Google account.
+    TokenKey string `yaml:"token_key"`
+}
+
+// DecodeTokenKey returns the key that encrypts the stored Google tokens, or
+// nil if none is set
+func (a AuthConfig) DecodeTokenKey() ([]byte, error) {
+    if a.TokenKey == "" {
+        return nil, nil
+    }
+    key, err := base64.StdEncoding.DecodeString(a.TokenKey)
+    if err != nil {
+        return nil, fmt.Errorf("must be base64: %w", err)
+    }
+    if len(key) != 32 {
+        return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+    }
+    return key, nil
+}
+
+// GoogleConfig holds the Google OAuth 2.0 client configuration
+type GoogleConfig struct {
+    // CredentialsPath is the client credentials file from the Google Cloud Console
+    CredentialsPath string `yaml:"credentials_path"`
+    // RedirectURL must match an authorized redirect URI of the client
+    RedirectURL string   `yaml:"redirect_url"`
+    Scopes      []string `yaml:"scopes"`
+}
+
+// PromptsConfig holds where system prompt templates are loaded from
+type PromptsConfig struct {
+    // Dir holds templates overriding the built-in ones
+    Dir string `yaml:"dir"`
+    // Reload is how often the templates are reloaded
+    Reload time.Duration `yaml:"reload"`
+}
+
 // MetricsConfig holds metrics configuration
 type MetricsConfig struct {
     Enabled bool   `yaml:"enabled"`
@@ -73,41 +201,158 @@ type MetricsConfig struct {
     Path    string `yaml:"path"`
 }
 
-// LoadConfig loads configuration from file
+// LoadConfig loads configuration from file, applied over the defaults and
+// overridden by the environment
 func LoadConfig(path string) (*Config, error) {
-    data, err := os.ReadFile(path)
-    if err != nil {
-        return nil, fmt.Errorf("reading config file: %w", err)
-    }
+    return Load(LoadOptions{Path: path})
+}
 
-    var config Config
-    if err := yaml.Unmarshal(data, &config); err != nil {
-        return nil, fmt.Errorf("parsing config file: %w", err)
+// ResolvedLLM returns the LLM configuration over the defaults, with the
+// cerebras endpoint and API key of the section applied to the cerebras
+// provider unless it sets its own
+func (a AIConfig) ResolvedLLM() LLMConfig {
+    llm := DefaultLLMConfig().Merge(a.LLM)
+    if cerebras, ok := llm.Providers[ProviderCerebras]; ok {
+        if cerebras.Endpoint == "" {
+            cerebras.Endpoint = a.Cerebras.Endpoint
+        }
+        if cerebras.APIKey == "" {
+            cerebras.APIKey = a.Cerebras.APIKey
+        }
+        llm.Providers[ProviderCerebras] = cerebras
     }
+    return llm
+}
 
-    if err := config.validate(); err != nil {
-        return nil, fmt.Errorf("validating config: %w", err)
-    }
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+    Problems []string
+}
 
-    config.setDefaults()
-    return &config, nil
+func (e *ValidationError) Error() string {
+    return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
 }
 
-// validate validates the configuration
+// validate reports every problem of the configuration at once. Zero values
+// that setDefaults fills in are accepted.
 func (c *Config) validate() error {
-    if c.Server.Port <= 0 {
-        return fmt.Errorf("invalid server port: %d", c.Server.Port)
+    if problems := c.problems(); len(problems) > 0 {
+        return &ValidationError{Problems: problems}
     }
+    return nil
+}
+
+// problems lists what is wrong with the configuration, by key
+func (c *Config) problems() []string {
+    var problems []string
+    add := func(format string, args ...interface{}) {
+        problems = append(problems, fmt.Sprintf(format, args...))
+    }
+    negative := func(key string, value int64) {
+        if value < 0 {
+            add("%s: must not be negative", key)
+        }
+    }
+
+    if c.Server.Port <= 0 || c.Server.Port > 65535 {
+        add("server.port: invalid port %d", c.Server.Port)
+    }
+    negative("server.read_timeout", int64(c.Server.ReadTimeout))
+    negative("server.write_timeout", int64(c.Server.WriteTimeout))
+    negative("server.idle_timeout", int64(c.Server.IdleTimeout))
+    negative("server.shutdown_delay", int64(c.Server.ShutdownDelay))
+    negative("server.shutdown_timeout", int64(c.Server.ShutdownTimeout))
 
     if c.AI.Cerebras.Endpoint == "" {
-        return fmt.Errorf("cerebras endpoint is required")
+        add("ai.cerebras.endpoint: required")
     }
+    llm := c.AI.ResolvedLLM()
+    if err := llm.Validate(); err != nil {
+        add("ai: %v", err)
+    }
+    for _, name := range sortedKeys(llm.Models) {
+        provider := llm.Providers[llm.Models[name].Provider]
+        if provider.Type == ProviderCerebras && provider.APIKey == "" {
+            add("ai.cerebras.api_key: required by model %s; set CEREBRAS_API_KEY", name)
+            break
+        }
+    }
+    if t := c.AI.Cerebras.Temperature; t < 0 || t > 2 {
+        add("ai.cerebras.temperature: must be between 0 and 2, got %g", t)
+    }
+    negative("ai.cerebras.max_tokens", int64(c.AI.Cerebras.MaxTokens))
+    negative("ai.cerebras.max_concurrent", int64(c.AI.Cerebras.MaxConcurrent))
+    negative("ai.cerebras.circuit_breaker.failure_threshold", int64(c.AI.Cerebras.CircuitBreaker.FailureThreshold))
+    negative("ai.cerebras.circuit_breaker.window", int64(c.AI.Cerebras.CircuitBreaker.Window))
+    negative("ai.cerebras.circuit_breaker.open_timeout", int64(c.AI.Cerebras.CircuitBreaker.OpenTimeout))
+    negative("ai.cerebras.circuit_breaker.half_open_requests", int64(c.AI.Cerebras.CircuitBreaker.HalfOpenRequests))
+    negative("ai.history_token_budget", int64(c.AI.HistoryTokenBudget))
+    negative("ai.rate_limit.requests_per_minute", int64(c.AI.RateLimit.RequestsPerMinute))
+    negative("ai.rate_limit.burst", int64(c.AI.RateLimit.Burst))
 
-    if c.AI.Cerebras.APIKey == "" {
-        return fmt.Errorf("cerebras API key is required")
+    switch c.AI.Cache.Backend {
+    case "", "memory", "disk":
+    case "redis":
+        if c.AI.Cache.RedisURL == "" {
+            add("ai.cache.redis_url: required by the redis backend")
+        }
+    default:
+        add("ai.cache.backend: unsupported backend %q", c.AI.Cache.Backend)
     }
+    negative("ai.cache.duration", int64(c.AI.Cache.Duration))
+    negative("ai.cache.max_entries", int64(c.AI.Cache.MaxEntries))
+    negative("ai.cache.max_bytes", c.AI.Cache.MaxBytes)
 
-    return nil
+    switch c.Database.Driver {
+    case "", DatabaseDriverMemory, DatabaseDriverMongo, DatabaseDriverPostgres:
+    default:
+        add("database.driver: unsupported database driver: %s", c.Database.Driver)
+    }
+    if c.Database.Port < 0 || c.Database.Port > 65535 {
+        add("database.port: invalid port %d", c.Database.Port)
+    }
+
+    switch c.Logging.Level {
+    case "", "debug", "info", "warn", "error":
+    default:
+        add("logging.level: unknown level %q", c.Logging.Level)
+    }
+    switch c.Logging.Format {
+    case "", "json", "console":
+    default:
+        add("logging.format: unknown format %q", c.Logging.Format)
+    }
+
+    negative("timeouts.default", int64(c.Timeouts.Default))
+    for _, op := range sortedKeys(c.Timeouts.Operations) {
+        negative("timeouts.operations."+op, int64(c.Timeouts.Operations[op]))
+    }
+    negative("prompts.reload", int64(c.Prompts.Reload))
+
+    if secret := c.Auth.SessionSecret; secret != "" && len(secret) < 32 {
+        add("auth.session_secret: must be at least 32 characters, got %d", len(secret))
+    }
+    negative("auth.session_max_age", int64(c.Auth.SessionMaxAge))
+    if _, err := c.Auth.DecodeTokenKey(); err != nil {
+        add("auth.token_key: %v", err)
+    }
+    if redirect := c.Auth.Google.RedirectURL; redirect != "" {
+        if u, err := url.Parse(redirect); err != nil || !u.IsAbs() {
+            add("auth.google.redirect_url: must be an absolute URL, got %q", redirect)
+        }
+    }
+    return problems
+}
+
+// sortedKeys returns the keys of m in order, so problems are reported in a
+// stable order
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
 }
 
 // setDefaults sets default values
@@ -124,6 +369,14 @@ func (c *Config) setDefaults() {
         c.Server.IdleTimeout = 60 * time.Second
     }
 
+    if c.Server.ShutdownTimeout == 0 {
+        c.Server.ShutdownTimeout = 30 * time.Second
+    }
+
+    if c.AI.Cerebras.Endpoint == "" {
+        c.AI.Cerebras.Endpoint = DefaultCerebrasEndpoint
+    }
+
     if c.AI.Cerebras.MaxTokens == 0 {
         c.AI.Cerebras.MaxTokens = 1000
     }
@@ -132,8 +385,60 @@ func (c *Config) setDefaults() {
         c.AI.Cerebras.Temperature = 0.7
     }
 
+    if c.AI.Cerebras.VisionModel == "" {
+        c.AI.Cerebras.VisionModel = "llama-4-scout-17b-16e-instruct"
+    }
+
+    if c.AI.Cerebras.MaxConcurrent == 0 {
+        c.AI.Cerebras.MaxConcurrent = 10
+    }
+
+    if c.AI.Cerebras.CircuitBreaker.FailureThreshold == 0 {
+        c.AI.Cerebras.CircuitBreaker.FailureThreshold = 5
+    }
+
+    if c.AI.Cerebras.CircuitBreaker.Window == 0 {
+        c.AI.Cerebras.CircuitBreaker.Window = 60 * time.Second
+    }
+
+    if c.AI.Cerebras.CircuitBreaker.OpenTimeout == 0 {
+        c.AI.Cerebras.CircuitBreaker.OpenTimeout = 60 * time.Second
+    }
+
+    if c.AI.Cerebras.CircuitBreaker.HalfOpenRequests == 0 {
+        c.AI.Cerebras.CircuitBreaker.HalfOpenRequests = 3
+    }
+
     if c.AI.Cache.Duration == 0 {
-        c.AI.Cache.Duration = 1 * time.Hour
+        c.AI.Cache.Duration = 15 * time.Minute
+    }
+
+    if c.AI.Cache.Backend == "" {
+        c.AI.Cache.Backend = "memory"
+    }
+
+    if c.AI.Cache.MaxEntries == 0 {
+        c.AI.Cache.MaxEntries = 1000
+    }
+
+    if c.AI.Cache.MaxBytes == 0 {
+        c.AI.Cache.MaxBytes = 32 << 20
+    }
+
+    if c.AI.Cache.Dir == "" {
+        c.AI.Cache.Dir = "data/cache"
+    }
+
+    if c.AI.ProcessorsConfig == "" {
+        c.AI.ProcessorsConfig = "internal/ai/processors/config.yaml"
+    }
+
+    if c.AI.RateLimit.RequestsPerMinute == 0 {
+        c.AI.RateLimit.RequestsPerMinute = 5
+    }
+
+    if c.AI.RateLimit.Burst == 0 {
+        c.AI.RateLimit.Burst = 10
     }
 
     if c.Logging.Level == "" {
@@ -144,7 +449,69 @@ func (c *Config) setDefaults() {
         c.Logging.Format = "json"
     }
 
+    if c.Logging.Output == "" {
+        c.Logging.Output = "stdout"
+    }
+
+    if c.Database.Driver == "" {
+        c.Database.Driver = DatabaseDriverMongo
+    }
+
+    if c.Database.Driver == DatabaseDriverMongo && c.Database.URI == "" {
+        c.Database.URI = "mongodb://localhost:27017/ana_world"
+    }
+
+    if c.Database.Host == "" {
+        c.Database.Host = "localhost"
+    }
+
+    if c.Database.User == "" {
+        c.Database.User = "postgres"
+    }
+
+    if c.Database.Database == "" {
+        c.Database.Database = "ana_world"
+    }
+
+    if c.Database.Port == 0 {
+        c.Database.Port = 5432
+    }
+
+    if c.Database.SSLMode == "" {
+        c.Database.SSLMode = "disable"
+    }
+
+    if c.Database.MigrationsPath == "" {
+        c.Database.MigrationsPath = "db/migrations"
+    }
+
     if c.Metrics.Path == "" {
         c.Metrics.Path = "/metrics"
     }
+
+    if c.Prompts.Reload == 0 {
+        c.Prompts.Reload = 30 * time.Second
+    }
+
+    if c.Auth.Google.CredentialsPath == "" {
+        c.Auth.Google.CredentialsPath = "config/credentials.json"
+    }
+
+    if c.Auth.Google.RedirectURL == "" {
+        c.Auth.Google.RedirectURL = fmt.Sprintf("http://localhost:%d/api/auth/google/callback", c.Server.Port)
+    }
+
+    if c.Auth.SessionMaxAge == 0 {
+        c.Auth.SessionMaxAge = 7 * 24 * time.Hour
+    }
+
+    if len(c.Auth.Google.Scopes) == 0 {
+        c.Auth.Google.Scopes = []string{
+            "https://www.googleapis.com/auth/calendar",
+            "https://www.googleapis.com/auth/gmail.readonly",
+        }
+    }
+
+    c.Timeouts = DefaultTimeouts().Merge(c.Timeouts)
+    c.AI.LLM = DefaultLLMConfig().Merge(c.AI.LLM)
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+    "context"
     "os"
     "testing"
     "time"
@@ -102,6 +103,20 @@ func TestConfigValidation(t *testing.T) {
             },
             shouldError: true,
         },
+        {
+            name: "short token key",
+            config: Config{
+                Server: ServerConfig{Port: 8080},
+                AI: AIConfig{
+                    Cerebras: CerebrasConfig{
+                        Endpoint: "http://cerebras.api",
+                        APIKey:   "test-key",
+                    },
+                },
+                Auth: AuthConfig{TokenKey: "c2hvcnQ="},
+            },
+            shouldError: true,
+        },
     }
 
     for _, tt := range tests {
@@ -115,3 +130,277 @@ func TestConfigValidation(t *testing.T) {
         })
     }
 }
+
+func TestTimeoutConfig(t *testing.T) {
+    timeouts := DefaultTimeouts().Merge(TimeoutConfig{
+        Operations: map[string]time.Duration{OpTaskRead: 2 * time.Second},
+    })
+
+    assert.Equal(t, 2*time.Second, timeouts.For(OpTaskRead))
+    assert.Equal(t, 30*time.Second, timeouts.For(OpAIText))
+    assert.Equal(t, 5*time.Second, timeouts.For("unknown.operation"))
+
+    ctx, cancel := timeouts.WithDeadline(context.Background(), OpTaskRead)
+    defer cancel()
+    deadline, ok := ctx.Deadline()
+    require.True(t, ok)
+    assert.WithinDuration(t, time.Now().Add(2*time.Second), deadline, 100*time.Millisecond)
+
+    // A zero duration disables the deadline
+    ctx, cancel = TimeoutConfig{}.WithDeadline(context.Background(), OpTaskRead)
+    defer cancel()
+    _, ok = ctx.Deadline()
+    assert.False(t, ok)
+}
+
+func TestParseTimeouts(t *testing.T) {
+    timeouts, err := ParseTimeouts("default=3s, ai.text=45s,tasks.write=0s")
+    require.NoError(t, err)
+    assert.Equal(t, 3*time.Second, timeouts.Default)
+    assert.Equal(t, 45*time.Second, timeouts.Operations[OpAIText])
+    assert.Equal(t, time.Duration(0), timeouts.For(OpTaskWrite))
+
+    _, err = ParseTimeouts("ai.text")
+    assert.Error(t, err)
+    _, err = ParseTimeouts("ai.text=soon")
+    assert.Error(t, err)
+}
+
+func TestLLMConfig(t *testing.T) {
+    llm := DefaultLLMConfig().Merge(LLMConfig{
+        Providers: map[string]ProviderConfig{
+            "local": {Type: ProviderOpenAI, Endpoint: "http://localhost:8081/v1"},
+        },
+        Models: map[string]ModelConfig{
+            ModelAssistant: {Provider: "local", Model: "qwen2.5-7b-instruct"},
+        },
+    })
+    require.NoError(t, llm.Validate())
+    assert.Equal(t, "local", llm.Models[ModelAssistant].Provider)
+    assert.Equal(t, ProviderCerebras, llm.Models[ModelVision].Provider)
+
+    llm.Models["broken"] = ModelConfig{Provider: "missing", Model: "x"}
+    assert.Error(t, llm.Validate())
+
+    noEndpoint := LLMConfig{Providers: map[string]ProviderConfig{"remote": {Type: ProviderOpenAI}}}
+    assert.Error(t, noEndpoint.Validate())
+    unknownType := LLMConfig{Providers: map[string]ProviderConfig{"remote": {Type: "bard"}}}
+    assert.Error(t, unknownType.Validate())
+
+    // Embeddings need a provider with an embeddings API
+    embeddings := DefaultLLMConfig().Merge(LLMConfig{Embeddings: ModelConfig{Provider: ProviderOllama, Model: "nomic-embed-text"}})
+    require.NoError(t, embeddings.Validate())
+    assert.Equal(t, "nomic-embed-text", embeddings.Merge(LLMConfig{}).Embeddings.Model)
+    embeddings.Embeddings.Provider = ProviderCerebras
+    assert.Error(t, embeddings.Validate())
+}
+
+func TestParseModels(t *testing.T) {
+    models, err := ParseModels("assistant=ollama:llama3.1:8b, vision=cerebras:llama-4-scout-17b-16e-instruct")
+    require.NoError(t, err)
+    assert.Equal(t, ModelConfig{Provider: ProviderOllama, Model: "llama3.1:8b"}, models[ModelAssistant])
+    assert.Equal(t, "llama-4-scout-17b-16e-instruct", models[ModelVision].Model)
+
+    _, err = ParseModels("assistant")
+    assert.Error(t, err)
+    _, err = ParseModels("assistant=qwen-3-32b")
+    assert.Error(t, err)
+}
+
+func TestLoadConfigModels(t *testing.T) {
+    configContent := `
+server:
+  port: 8080
+ai:
+  cerebras:
+    endpoint: "http://cerebras.api"
+    api_key: "test-key"
+  providers:
+    local:
+      type: openai
+      endpoint: "http://localhost:8081/v1"
+  models:
+    assistant:
+      provider: local
+      model: qwen2.5-7b-instruct
+`
+    tmpfile, err := os.CreateTemp("", "config-*.yaml")
+    require.NoError(t, err)
+    defer os.Remove(tmpfile.Name())
+    _, err = tmpfile.WriteString(configContent)
+    require.NoError(t, err)
+    tmpfile.Close()
+
+    config, err := LoadConfig(tmpfile.Name())
+    require.NoError(t, err)
+    assert.Equal(t, "qwen2.5-7b-instruct", config.AI.LLM.Models[ModelAssistant].Model)
+    assert.Equal(t, ProviderCerebras, config.AI.LLM.Models[ModelVision].Provider, "defaults fill unset models")
+}
+
+func TestLLMFallbacksAndRouting(t *testing.T) {
+    llm := DefaultLLMConfig().Merge(LLMConfig{
+        Models: map[string]ModelConfig{
+            "fast": {Provider: ProviderOllama, Model: "llama3.2:3b"},
+        },
+        Fallbacks: map[string][]string{
+            ModelAssistant: {"fast", "ollama:qwen3:32b", ProviderCerebras},
+        },
+        Routing: RoutingConfig{NoThink: "fast"},
+    })
+    require.NoError(t, llm.Validate())
+    assert.Equal(t, ModelVision, llm.Routing.Vision, "defaults keep unset routes")
+    assert.Equal(t, "fast", llm.Routing.NoThink)
+
+    m, err := llm.ResolveFallback(ModelAssistant, "ollama:qwen3:32b")
+    require.NoError(t, err)
+    assert.Equal(t, ModelConfig{Provider: ProviderOllama, Model: "qwen3:32b", Temperature: 0.7, MaxTokens: 500}, m)
+    m, err = llm.ResolveFallback(ModelAssistant, ProviderOllama)
+    require.NoError(t, err)
+    assert.Equal(t, "qwen-3-32b", m.Model, "a provider alone keeps the model")
+
+    llm.Fallbacks[ModelAssistant] = []string{"nowhere:x"}
+    assert.Error(t, llm.Validate())
+    llm.Fallbacks = nil
+    llm.Routing.LongContext = "missing"
+    assert.Error(t, llm.Validate())
+}
+
+func TestParseFallbacksAndRouting(t *testing.T) {
+    fallbacks, err := ParseFallbacks("assistant=ollama:llama3.1:8b | openai:gpt-4o-mini, vision=cerebras")
+    require.NoError(t, err)
+    assert.Equal(t, []string{"ollama:llama3.1:8b", "openai:gpt-4o-mini"}, fallbacks[ModelAssistant])
+    assert.Equal(t, []string{ProviderCerebras}, fallbacks[ModelVision])
+    _, err = ParseFallbacks("assistant=")
+    assert.Error(t, err)
+
+    routing, err := ParseRouting("long_context=assistant,long_context_tokens=6000,no_think=fast")
+    require.NoError(t, err)
+    assert.Equal(t, RoutingConfig{LongContext: ModelAssistant, LongContextTokens: 6000, NoThink: "fast"}, routing)
+    _, err = ParseRouting("long_context_tokens=many")
+    assert.Error(t, err)
+    _, err = ParseRouting("audio=whisper")
+    assert.Error(t, err)
+}
+
+func TestLoadLayers(t *testing.T) {
+    path := t.TempDir() + "/config.yaml"
+    require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: ${ANA_TEST_PORT:-8081}
+  read_timeout: 10s
+ai:
+  cerebras:
+    api_key: ${ANA_TEST_KEY}
+  cache:
+    backend: disk
+database:
+  driver: postgres
+  password: "$${literal}"
+timeouts:
+  operations:
+    ai.text: 40s
+`), 0o600))
+    env := map[string]string{
+        "ANA_TEST_KEY":   "sk-file",
+        "DB_HOST":        "db.internal",
+        "ANA_LOG_LEVEL":  "debug",
+        "ANA_TIMEOUTS":   "ai.vision=90s",
+        "MONGODB_URI":    "mongodb://ignored",
+        "ANA_TEST_EMPTY": "",
+    }
+    lookup := func(key string) (string, bool) {
+        value, ok := env[key]
+        return value, ok
+    }
+
+    config, err := Load(LoadOptions{Path: path, Lookup: lookup, Overrides: []string{
+        "server.read_timeout=20s",
+        "timeouts.operations.ai.text=45s",
+        "ai.cache.backend=memory",
+    }})
+    require.NoError(t, err)
+
+    // Defaults fill what no layer sets
+    assert.Equal(t, "0.0.0.0", config.Server.Host)
+    assert.Equal(t, 60*time.Second, config.Server.IdleTimeout)
+    assert.Equal(t, 5*time.Second, config.Timeouts.Default)
+    // The file, with ${VAR:-default} expanded
+    assert.Equal(t, 8081, config.Server.Port)
+    assert.Equal(t, "sk-file", config.AI.Cerebras.APIKey)
+    assert.Equal(t, "${literal}", config.Database.Password)
+    // The environment, where it applies to the driver
+    assert.Equal(t, "db.internal", config.Database.Host)
+    assert.Equal(t, "", config.Database.URI)
+    assert.Equal(t, "debug", config.Logging.Level)
+    assert.Equal(t, 90*time.Second, config.Timeouts.For(OpAIVision))
+    // The overrides
+    assert.Equal(t, 20*time.Second, config.Server.ReadTimeout)
+    assert.Equal(t, 45*time.Second, config.Timeouts.For(OpAIText))
+    assert.Equal(t, "memory", config.AI.Cache.Backend)
+    // Derived defaults follow the port
+    assert.Equal(t, "http://localhost:8081/api/auth/google/callback", config.Auth.Google.RedirectURL)
+
+    env["PORT"] = "9090"
+    env["ANA_ALLOWED_ORIGINS"] = "https://ana.world, https://app.ana.world"
+    config, err = Load(LoadOptions{Path: path, Lookup: lookup})
+    require.NoError(t, err)
+    assert.Equal(t, 9090, config.Server.Port, "the environment overrides the file")
+    assert.Equal(t, []string{"https://ana.world", "https://app.ana.world"}, config.Server.AllowedOrigins)
+}
+
+func TestExpand(t *testing.T) {
+    lookup := func(key string) (string, bool) {
+        value, ok := map[string]string{"HOST": "db", "EMPTY": ""}[key]
+        return value, ok
+    }
+    assert.Equal(t, "db:5432", Expand("${HOST}:${PORT:-5432}", lookup))
+    assert.Equal(t, "fallback", Expand("${EMPTY:-fallback}", lookup))
+    assert.Equal(t, "", Expand("${MISSING}", lookup))
+    assert.Equal(t, "${HOST}", Expand("$${HOST}", lookup))
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+    path := t.TempDir() + "/config.yaml"
+    require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: 70000
+  unknown_key: true
+logging:
+  format: xml
+`), 0o600))
+    lookup := func(key string) (string, bool) {
+        value, ok := map[string]string{"CEREBRAS_API_KEY": "sk", "DB_PORT": "many"}[key]
+        return value, ok
+    }
+
+    config, err := Load(LoadOptions{Path: path, Lookup: lookup, Overrides: []string{"ai.cache.backend=tape", "nothing"}})
+    require.NotNil(t, config, "the configuration can still be inspected")
+    var invalid *ValidationError
+    require.ErrorAs(t, err, &invalid)
+    assert.Len(t, invalid.Problems, 6, invalid.Error())
+    for _, want := range []string{"unknown_key", "DB_PORT", "--set nothing", "server.port", "logging.format", "ai.cache.backend"} {
+        assert.Contains(t, err.Error(), want)
+    }
+}
+
+func TestRedacted(t *testing.T) {
+    config := Default()
+    config.AI.Cerebras.APIKey = "sk-secret"
+    config.AI.LLM.Providers = map[string]ProviderConfig{"openai": {Type: ProviderOpenAI, Endpoint: "https://api.openai.com/v1", APIKey: "sk-openai"}}
+    config.Database.URI = "postgres://ana:hunter2@db/ana"
+    config.Database.Password = "hunter2"
+    config.Auth.AdminToken = "admin"
+    config.Auth.SessionSecret = "0123456789abcdef0123456789abcdef"
+    config.Auth.TokenKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+    redacted := config.Redacted()
+    assert.Equal(t, "REDACTED", redacted.AI.Cerebras.APIKey)
+    assert.Equal(t, "REDACTED", redacted.AI.LLM.Providers["openai"].APIKey)
+    assert.Equal(t, "REDACTED", redacted.Database.Password)
+    assert.Equal(t, "REDACTED", redacted.Auth.AdminToken)
+    assert.Equal(t, "REDACTED", redacted.Auth.SessionSecret)
+    assert.Equal(t, "REDACTED", redacted.Auth.TokenKey)
+    assert.NotContains(t, redacted.Database.URI, "hunter2")
+    assert.Equal(t, "sk-secret", config.AI.Cerebras.APIKey, "the original is unchanged")
+    assert.Equal(t, "sk-openai", config.AI.LLM.Providers["openai"].APIKey, "the original is unchanged")
+}
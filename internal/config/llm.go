@@ -0,0 +1,383 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Provider types understood by the ai package
+const (
+	// ProviderCerebras is the Cerebras inference API
+	ProviderCerebras = "cerebras"
+	// ProviderOpenAI is any OpenAI-compatible chat completions endpoint,
+	// such as OpenAI itself, vLLM or a llama.cpp server
+	ProviderOpenAI = "openai"
+	// ProviderOllama is a local Ollama server, using its native API
+	ProviderOllama = "ollama"
+)
+
+// Logical model names the handlers ask for
+const (
+	ModelAssistant = "assistant"
+	ModelVision    = "vision"
+	ModelAgent     = "agent"
+	ModelSummary   = "summary"
+)
+
+// ProviderConfig describes one LLM backend.
+// Empty Endpoint and APIKey use the provider's defaults; for Cerebras those
+// come from CEREBRAS_API_URL and CEREBRAS_API_KEY.
+type ProviderConfig struct {
+	Type     string `yaml:"type"`
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key"`
+}
+
+// ModelConfig binds a logical model name to a model served by a provider.
+// Zero Temperature and MaxTokens leave the choice to the caller.
+type ModelConfig struct {
+	Provider    string  `yaml:"provider"`
+	Model       string  `yaml:"model"`
+	Temperature float64 `yaml:"temperature"`
+	MaxTokens   int     `yaml:"max_tokens"`
+}
+
+// RoutingConfig picks the logical model for a request by its type.
+// Empty fields leave the requested model in place.
+type RoutingConfig struct {
+	// Vision answers questions with an image
+	Vision string `yaml:"vision"`
+	// LongContext answers requests whose prompt exceeds LongContextTokens
+	LongContext       string `yaml:"long_context"`
+	LongContextTokens int    `yaml:"long_context_tokens"`
+	// NoThink answers /no_think requests, which want short direct answers
+	NoThink string `yaml:"no_think"`
+}
+
+// LLMConfig holds the LLM providers and the logical models they serve
+type LLMConfig struct {
+	Providers map[string]ProviderConfig `yaml:"providers"`
+	Models    map[string]ModelConfig    `yaml:"models"`
+	// Fallbacks lists, per logical model, what to try in order when it is
+	// rate limited or unavailable: another logical model, "provider:model",
+	// or a provider name to ask that provider for the same model
+	Fallbacks map[string][]string `yaml:"fallbacks"`
+	Routing   RoutingConfig       `yaml:"routing"`
+	// Embeddings is the model that embeds documents and questions for
+	// retrieval. Without a provider the built-in hashing embedder is used.
+	Embeddings ModelConfig `yaml:"embeddings"`
+}
+
+// DefaultLLMConfig returns the providers and models used when none are configured
+func DefaultLLMConfig() LLMConfig {
+	return LLMConfig{
+		Providers: map[string]ProviderConfig{
+			ProviderCerebras: {Type: ProviderCerebras},
+			ProviderOllama:   {Type: ProviderOllama, Endpoint: "http://localhost:11434"},
+		},
+		Models: map[string]ModelConfig{
+			ModelAssistant: {Provider: ProviderCerebras, Model: "qwen-3-32b", Temperature: 0.7, MaxTokens: 500},
+			ModelVision:    {Provider: ProviderCerebras, Model: "llama-4-scout-17b-16e-instruct", Temperature: 0.7, MaxTokens: 800},
+			ModelAgent:     {Provider: ProviderCerebras, Model: "qwen-3-32b"},
+			ModelSummary:   {Provider: ProviderCerebras, Model: "qwen-3-32b"},
+		},
+		Routing: RoutingConfig{Vision: ModelVision},
+	}
+}
+
+// Merge returns a copy of l with the providers and models set in other applied on top
+func (l LLMConfig) Merge(other LLMConfig) LLMConfig {
+	merged := LLMConfig{
+		Providers:  make(map[string]ProviderConfig),
+		Models:     make(map[string]ModelConfig),
+		Fallbacks:  make(map[string][]string),
+		Routing:    l.Routing.merge(other.Routing),
+		Embeddings: l.Embeddings,
+	}
+	if other.Embeddings.Provider != "" {
+		merged.Embeddings = other.Embeddings
+	}
+	for name, p := range l.Providers {
+		merged.Providers[name] = p
+	}
+	for name, p := range other.Providers {
+		merged.Providers[name] = p
+	}
+	for name, m := range l.Models {
+		merged.Models[name] = m
+	}
+	for name, m := range other.Models {
+		merged.Models[name] = m
+	}
+	for name, chain := range l.Fallbacks {
+		merged.Fallbacks[name] = chain
+	}
+	for name, chain := range other.Fallbacks {
+		merged.Fallbacks[name] = chain
+	}
+	return merged
+}
+
+// merge returns r with the fields set in other applied on top
+func (r RoutingConfig) merge(other RoutingConfig) RoutingConfig {
+	if other.Vision != "" {
+		r.Vision = other.Vision
+	}
+	if other.LongContext != "" {
+		r.LongContext = other.LongContext
+	}
+	if other.LongContextTokens != 0 {
+		r.LongContextTokens = other.LongContextTokens
+	}
+	if other.NoThink != "" {
+		r.NoThink = other.NoThink
+	}
+	return r
+}
+
+// Validate checks that every provider has a known type and every model
+// refers to a configured provider
+func (l LLMConfig) Validate() error {
+	for name, p := range l.Providers {
+		switch p.Type {
+		case ProviderCerebras, ProviderOpenAI, ProviderOllama:
+		default:
+			return fmt.Errorf("provider %s: unsupported type %q", name, p.Type)
+		}
+		if p.Type == ProviderOpenAI && p.Endpoint == "" {
+			return fmt.Errorf("provider %s: endpoint is required", name)
+		}
+	}
+	for name, m := range l.Models {
+		if _, ok := l.Providers[m.Provider]; !ok {
+			return fmt.Errorf("model %s: unknown provider %q", name, m.Provider)
+		}
+		if m.Model == "" {
+			return fmt.Errorf("model %s: model is required", name)
+		}
+	}
+	for name, chain := range l.Fallbacks {
+		if _, ok := l.Models[name]; !ok {
+			return fmt.Errorf("fallbacks for unknown model %s", name)
+		}
+		for _, entry := range chain {
+			if _, err := l.ResolveFallback(name, entry); err != nil {
+				return err
+			}
+		}
+	}
+	for route, target := range map[string]string{
+		"vision":       l.Routing.Vision,
+		"long_context": l.Routing.LongContext,
+		"no_think":     l.Routing.NoThink,
+	} {
+		if _, ok := l.Models[target]; target != "" && !ok {
+			return fmt.Errorf("routing %s: unknown model %q", route, target)
+		}
+	}
+	if e := l.Embeddings; e.Provider != "" {
+		p, ok := l.Providers[e.Provider]
+		switch {
+		case !ok:
+			return fmt.Errorf("embeddings: unknown provider %q", e.Provider)
+		case p.Type == ProviderCerebras:
+			return fmt.Errorf("embeddings: provider %s does not serve embedding models", e.Provider)
+		case e.Model == "":
+			return fmt.Errorf("embeddings: model is required")
+		}
+	}
+	if l.Routing.LongContext != "" && l.Routing.LongContextTokens <= 0 {
+		return fmt.Errorf("routing long_context: long_context_tokens must be positive")
+	}
+	return nil
+}
+
+// ResolveFallback returns the model a fallback entry of model name refers to.
+// Bindings made with a provider name or "provider:model" keep the generation
+// parameters of name.
+func (l LLMConfig) ResolveFallback(name string, entry string) (ModelConfig, error) {
+	if m, ok := l.Models[entry]; ok {
+		return m, nil
+	}
+	m := l.Models[name]
+	provider, model, hasModel := strings.Cut(entry, ":")
+	if _, ok := l.Providers[provider]; !ok {
+		return m, fmt.Errorf("fallback %q for %s: unknown model or provider", entry, name)
+	}
+	m.Provider = provider
+	if hasModel {
+		if model == "" {
+			return m, fmt.Errorf("fallback %q for %s: model is required", entry, name)
+		}
+		m.Model = model
+	}
+	return m, nil
+}
+
+// ParseModels parses model bindings of the form
+// "assistant=ollama:llama3.1:8b,vision=cerebras:llama-4-scout-17b-16e-instruct".
+// The provider ends at the first colon; the rest is the provider's model name.
+func ParseModels(spec string) (map[string]ModelConfig, error) {
+	models := make(map[string]ModelConfig)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, binding, ok := strings.Cut(entry, "=")
+		if !ok {
+			return models, fmt.Errorf("invalid model %q: expected name=provider:model", entry)
+		}
+		provider, model, ok := strings.Cut(strings.TrimSpace(binding), ":")
+		if !ok || provider == "" || model == "" {
+			return models, fmt.Errorf("invalid binding for %s: %q", name, binding)
+		}
+		models[strings.TrimSpace(name)] = ModelConfig{Provider: provider, Model: model}
+	}
+	return models, nil
+}
+
+// ParseFallbacks parses fallback chains of the form
+// "assistant=ollama:llama3.1:8b|openai:gpt-4o-mini,vision=cerebras".
+// Each chain lists the entries to try in order, separated by "|".
+func ParseFallbacks(spec string) (map[string][]string, error) {
+	fallbacks := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, chain, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fallbacks, fmt.Errorf("invalid fallback %q: expected name=entry|entry", entry)
+		}
+		var entries []string
+		for _, e := range strings.Split(chain, "|") {
+			if e = strings.TrimSpace(e); e != "" {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == 0 {
+			return fallbacks, fmt.Errorf("invalid fallback for %s: empty chain", name)
+		}
+		fallbacks[strings.TrimSpace(name)] = entries
+	}
+	return fallbacks, nil
+}
+
+// ParseRouting parses routing rules of the form
+// "vision=vision,long_context=assistant_long,long_context_tokens=6000,no_think=fast"
+func ParseRouting(spec string) (RoutingConfig, error) {
+	var routing RoutingConfig
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return routing, fmt.Errorf("invalid route %q: expected key=value", entry)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "vision":
+			routing.Vision = value
+		case "long_context":
+			routing.LongContext = value
+		case "long_context_tokens":
+			tokens, err := strconv.Atoi(value)
+			if err != nil || tokens <= 0 {
+				return routing, fmt.Errorf("invalid long_context_tokens %q", value)
+			}
+			routing.LongContextTokens = tokens
+		case "no_think":
+			routing.NoThink = value
+		default:
+			return routing, fmt.Errorf("unknown route %q", key)
+		}
+	}
+	return routing, nil
+}
+
+// LLMConfigFromEnv builds the LLM configuration from the defaults and
+// environment variables
+func LLMConfigFromEnv() (LLMConfig, error) {
+	defaults := DefaultLLMConfig()
+	overrides, err := llmEnvOverrides(defaults, os.Getenv)
+	if err != nil {
+		return defaults, err
+	}
+	llmConfig := defaults.Merge(overrides)
+	return llmConfig, llmConfig.Validate()
+}
+
+// llmEnvOverrides returns the providers, models, fallbacks and routes set by
+// environment variables, to be merged over base
+func llmEnvOverrides(base LLMConfig, getenv func(string) string) (LLMConfig, error) {
+	overrides := LLMConfig{
+		Providers: make(map[string]ProviderConfig),
+		Models:    make(map[string]ModelConfig),
+	}
+	if url := getenv("OPENAI_BASE_URL"); url != "" {
+		overrides.Providers[ProviderOpenAI] = ProviderConfig{
+			Type:     ProviderOpenAI,
+			Endpoint: url,
+			APIKey:   getenv("OPENAI_API_KEY"),
+		}
+	}
+	if url := getenv("OLLAMA_BASE_URL"); url != "" {
+		overrides.Providers[ProviderOllama] = ProviderConfig{Type: ProviderOllama, Endpoint: url}
+	}
+
+	if model := getenv("CEREBRAS_VISION_MODEL"); model != "" {
+		vision := base.Models[ModelVision]
+		vision.Model = model
+		overrides.Models[ModelVision] = vision
+	}
+	if model := getenv("CEREBRAS_AGENT_MODEL"); model != "" {
+		agent := base.Models[ModelAgent]
+		agent.Model = model
+		overrides.Models[ModelAgent] = agent
+	}
+
+	if spec := getenv("ANA_MODELS"); spec != "" {
+		bindings, err := ParseModels(spec)
+		if err != nil {
+			return overrides, fmt.Errorf("ANA_MODELS: %w", err)
+		}
+		for name, model := range bindings {
+			// Keep the generation parameters of the model being rebound
+			if current, ok := base.Models[name]; ok {
+				model.Temperature = current.Temperature
+				model.MaxTokens = current.MaxTokens
+			}
+			overrides.Models[name] = model
+		}
+	}
+
+	// e.g. ANA_EMBEDDINGS="ollama:nomic-embed-text"; unset uses the built-in embedder
+	if spec := getenv("ANA_EMBEDDINGS"); spec != "" {
+		provider, model, _ := strings.Cut(spec, ":")
+		overrides.Embeddings = ModelConfig{Provider: provider, Model: model}
+	}
+
+	// e.g. ANA_MODEL_FALLBACKS="assistant=openai|ollama:llama3.1:8b"
+	if spec := getenv("ANA_MODEL_FALLBACKS"); spec != "" {
+		fallbacks, err := ParseFallbacks(spec)
+		if err != nil {
+			return overrides, fmt.Errorf("ANA_MODEL_FALLBACKS: %w", err)
+		}
+		overrides.Fallbacks = fallbacks
+	}
+	// e.g. ANA_MODEL_ROUTING="long_context=agent,long_context_tokens=6000"
+	if spec := getenv("ANA_MODEL_ROUTING"); spec != "" {
+		routing, err := ParseRouting(spec)
+		if err != nil {
+			return overrides, fmt.Errorf("ANA_MODEL_ROUTING: %w", err)
+		}
+		overrides.Routing = routing
+	}
+	return overrides, nil
+}
@@ -0,0 +1,428 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOptions selects the layers of the configuration. Each layer overrides
+// the ones before it: the defaults, the YAML file, the environment and the
+// overrides.
+type LoadOptions struct {
+	// Path is the YAML file; no file is read when empty
+	Path string
+	// Lookup reads environment variables, os.LookupEnv if nil
+	Lookup func(key string) (string, bool)
+	// Overrides are key=value settings, e.g. "server.port=9090" or
+	// "ai.cache.backend=disk", as given with --set
+	Overrides []string
+}
+
+// Default returns the configuration used where neither the file, the
+// environment nor an override sets a value
+func Default() Config {
+	c := base()
+	c.setDefaults()
+	return c
+}
+
+// base returns the defaults that a zero value cannot express. The others are
+// filled in by setDefaults once every layer is applied, so that defaults
+// derived from other settings, like the OAuth redirect URL from the port,
+// follow them.
+func base() Config {
+	return Config{
+		Server:  ServerConfig{Host: "0.0.0.0", Port: 8080},
+		AI:      AIConfig{Cache: CacheConfig{Enabled: true}},
+		Metrics: MetricsConfig{Enabled: true},
+	}
+}
+
+// FromEnv returns the defaults overridden by the environment. Invalid
+// variables are reported and leave the default in place.
+func FromEnv() (Config, error) {
+	c := base()
+	problems := c.applyEnv(os.LookupEnv)
+	c.setDefaults()
+	if len(problems) > 0 {
+		return c, &ValidationError{Problems: problems}
+	}
+	return c, nil
+}
+
+// Load builds the configuration from the layers of opts and validates it.
+// Every problem found is reported at once in a *ValidationError, returned
+// along with the configuration so it can still be inspected.
+func Load(opts LoadOptions) (*Config, error) {
+	lookup := opts.Lookup
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	c := base()
+	var problems []string
+	if opts.Path != "" {
+		data, err := os.ReadFile(opts.Path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		fileProblems, err := c.decodeYAML(data, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", opts.Path, err)
+		}
+		for _, problem := range fileProblems {
+			problems = append(problems, opts.Path+": "+problem)
+		}
+	}
+	problems = append(problems, c.applyEnv(lookup)...)
+	for _, override := range opts.Overrides {
+		if err := c.set(override); err != nil {
+			problems = append(problems, fmt.Sprintf("--set %s: %v", override, err))
+		}
+	}
+	c.setDefaults()
+
+	problems = append(problems, c.problems()...)
+	if len(problems) > 0 {
+		return &c, &ValidationError{Problems: problems}
+	}
+	return &c, nil
+}
+
+// decodeYAML applies a YAML document over c, replacing ${VAR} and
+// ${VAR:-default} in its values with environment variables first. Unknown
+// keys and values of the wrong type are returned as problems.
+func (c *Config) decodeYAML(data []byte, lookup func(string) (string, bool)) ([]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	interpolate(&root, lookup)
+	expanded, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(expanded))
+	decoder.KnownFields(true)
+	var typeErr *yaml.TypeError
+	switch err := decoder.Decode(c); {
+	case errors.As(err, &typeErr):
+		return typeErr.Errors, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, nil
+}
+
+// variablePattern matches ${VAR} and ${VAR:-default}; $${VAR} is kept as
+// the literal ${VAR}
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// Expand replaces ${VAR} in s with the value of the environment variable
+// VAR, and ${VAR:-default} with default when VAR is unset or empty
+func Expand(s string, lookup func(string) (string, bool)) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		groups := variablePattern.FindStringSubmatch(match)
+		if value, ok := lookup(groups[1]); ok && value != "" {
+			return value
+		}
+		return groups[2]
+	})
+}
+
+// interpolate expands the variables of every scalar value under node.
+// Plain scalars are retyped from their expanded value, so "${DB_PORT}" can
+// fill an integer.
+func interpolate(node *yaml.Node, lookup func(string) (string, bool)) {
+	if node.Kind == yaml.MappingNode {
+		for i := 1; i < len(node.Content); i += 2 {
+			interpolate(node.Content[i], lookup)
+		}
+		return
+	}
+	if node.Kind == yaml.ScalarNode {
+		expanded := Expand(node.Value, lookup)
+		if expanded == node.Value {
+			return
+		}
+		node.Value = expanded
+		if node.Style == 0 {
+			node.Tag = ""
+			if expanded == "" {
+				node.Tag = "!!null"
+			}
+		}
+		return
+	}
+	for _, child := range node.Content {
+		interpolate(child, lookup)
+	}
+}
+
+// envVar binds an environment variable to a configuration field
+type envVar struct {
+	name  string
+	field func(c *Config) interface{}
+	// driver restricts the variable to one database driver
+	driver string
+}
+
+// envVars are the environment variables the configuration reads, applied
+// in order
+var envVars = []envVar{
+	{name: "ANA_HOST", field: func(c *Config) interface{} { return &c.Server.Host }},
+	{name: "PORT", field: func(c *Config) interface{} { return &c.Server.Port }},
+	{name: "ANA_SHUTDOWN_DELAY", field: func(c *Config) interface{} { return &c.Server.ShutdownDelay }},
+	{name: "ANA_SHUTDOWN_TIMEOUT", field: func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{name: "ANA_ALLOWED_ORIGINS", field: func(c *Config) interface{} { return &c.Server.AllowedOrigins }},
+
+	{name: "DATABASE_DRIVER", field: func(c *Config) interface{} { return &c.Database.Driver }},
+	{name: "MONGODB_URI", field: func(c *Config) interface{} { return &c.Database.URI }, driver: DatabaseDriverMongo},
+	{name: "MONGODB_DATABASE", field: func(c *Config) interface{} { return &c.Database.Database }, driver: DatabaseDriverMongo},
+	{name: "DATABASE_URL", field: func(c *Config) interface{} { return &c.Database.URI }, driver: DatabaseDriverPostgres},
+	{name: "DB_NAME", field: func(c *Config) interface{} { return &c.Database.Database }, driver: DatabaseDriverPostgres},
+	{name: "DB_HOST", field: func(c *Config) interface{} { return &c.Database.Host }},
+	{name: "DB_PORT", field: func(c *Config) interface{} { return &c.Database.Port }},
+	{name: "DB_USER", field: func(c *Config) interface{} { return &c.Database.User }},
+	{name: "DB_PASSWORD", field: func(c *Config) interface{} { return &c.Database.Password }},
+	{name: "DB_SSLMODE", field: func(c *Config) interface{} { return &c.Database.SSLMode }},
+	{name: "DB_MIGRATIONS_PATH", field: func(c *Config) interface{} { return &c.Database.MigrationsPath }},
+
+	{name: "CEREBRAS_API_URL", field: func(c *Config) interface{} { return &c.AI.Cerebras.Endpoint }},
+	{name: "CEREBRAS_API_KEY", field: func(c *Config) interface{} { return &c.AI.Cerebras.APIKey }},
+	{name: "CEREBRAS_VISION_MODEL", field: func(c *Config) interface{} { return &c.AI.Cerebras.VisionModel }},
+	{name: "CEREBRAS_MAX_CONCURRENT", field: func(c *Config) interface{} { return &c.AI.Cerebras.MaxConcurrent }},
+	{name: "CEREBRAS_CIRCUIT_FAILURE_THRESHOLD", field: func(c *Config) interface{} { return &c.AI.Cerebras.CircuitBreaker.FailureThreshold }},
+	{name: "CEREBRAS_CIRCUIT_WINDOW", field: func(c *Config) interface{} { return &c.AI.Cerebras.CircuitBreaker.Window }},
+	{name: "CEREBRAS_CIRCUIT_OPEN_TIMEOUT", field: func(c *Config) interface{} { return &c.AI.Cerebras.CircuitBreaker.OpenTimeout }},
+	{name: "CEREBRAS_CIRCUIT_HALF_OPEN_REQUESTS", field: func(c *Config) interface{} { return &c.AI.Cerebras.CircuitBreaker.HalfOpenRequests }},
+	{name: "ENABLE_CEREBRAS_METRICS", field: func(c *Config) interface{} { return &c.AI.Cerebras.Metrics }},
+	{name: "CEREBRAS_CACHE_TTL", field: func(c *Config) interface{} { return &c.AI.Cache.Duration }},
+	{name: "CEREBRAS_CACHE_BACKEND", field: func(c *Config) interface{} { return &c.AI.Cache.Backend }},
+	{name: "CEREBRAS_CACHE_MAX_ENTRIES", field: func(c *Config) interface{} { return &c.AI.Cache.MaxEntries }},
+	{name: "CEREBRAS_CACHE_MAX_BYTES", field: func(c *Config) interface{} { return &c.AI.Cache.MaxBytes }},
+	{name: "CEREBRAS_CACHE_DIR", field: func(c *Config) interface{} { return &c.AI.Cache.Dir }},
+	{name: "REDIS_URL", field: func(c *Config) interface{} { return &c.AI.Cache.RedisURL }},
+	{name: "CEREBRAS_CACHE_REDIS_URL", field: func(c *Config) interface{} { return &c.AI.Cache.RedisURL }},
+	{name: "ANA_HISTORY_TOKEN_BUDGET", field: func(c *Config) interface{} { return &c.AI.HistoryTokenBudget }},
+	{name: "ANA_PROCESSORS_CONFIG", field: func(c *Config) interface{} { return &c.AI.ProcessorsConfig }},
+	{name: "ANA_RATE_LIMIT_PER_MINUTE", field: func(c *Config) interface{} { return &c.AI.RateLimit.RequestsPerMinute }},
+	{name: "ANA_RATE_LIMIT_BURST", field: func(c *Config) interface{} { return &c.AI.RateLimit.Burst }},
+
+	{name: "ANA_LOG_LEVEL", field: func(c *Config) interface{} { return &c.Logging.Level }},
+	{name: "ANA_LOG_FORMAT", field: func(c *Config) interface{} { return &c.Logging.Format }},
+	{name: "ANA_ADMIN_TOKEN", field: func(c *Config) interface{} { return &c.Auth.AdminToken }},
+	{name: "ANA_SESSION_SECRET", field: func(c *Config) interface{} { return &c.Auth.SessionSecret }},
+	{name: "ANA_SESSION_MAX_AGE", field: func(c *Config) interface{} { return &c.Auth.SessionMaxAge }},
+	{name: "ANA_TOKEN_KEY", field: func(c *Config) interface{} { return &c.Auth.TokenKey }},
+	{name: "GOOGLE_APPLICATION_CREDENTIALS_PATH", field: func(c *Config) interface{} { return &c.Auth.Google.CredentialsPath }},
+	{name: "GOOGLE_REDIRECT_URL", field: func(c *Config) interface{} { return &c.Auth.Google.RedirectURL }},
+	{name: "ANA_PROMPTS_DIR", field: func(c *Config) interface{} { return &c.Prompts.Dir }},
+	{name: "ANA_PROMPTS_RELOAD", field: func(c *Config) interface{} { return &c.Prompts.Reload }},
+}
+
+// applyEnv overrides c with the environment variables that are set and not
+// empty, including ANA_TIMEOUTS and the model variables of LLMConfigFromEnv,
+// and returns the ones that could not be parsed
+func (c *Config) applyEnv(lookup func(string) (string, bool)) []string {
+	env := func(key string) string {
+		value, _ := lookup(key)
+		return value
+	}
+
+	var problems []string
+	for _, v := range envVars {
+		value := env(v.name)
+		if value == "" || (v.driver != "" && v.driver != c.Database.Driver) {
+			continue
+		}
+		if err := setField(v.field(c), value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", v.name, err))
+		}
+	}
+
+	// e.g. ANA_TIMEOUTS="default=5s,ai.text=45s"
+	if spec := env("ANA_TIMEOUTS"); spec != "" {
+		overrides, err := ParseTimeouts(spec)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ANA_TIMEOUTS: %v", err))
+		} else {
+			c.Timeouts = c.Timeouts.Merge(overrides)
+		}
+	}
+
+	llm, err := llmEnvOverrides(c.AI.LLM, env)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	c.AI.LLM = c.AI.LLM.Merge(llm)
+	return problems
+}
+
+// setField parses value into the field field points to
+func setField(field interface{}, value string) error {
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field = parsed
+	case *int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: expected a duration such as 30s", value)
+		}
+		*field = parsed
+	case *[]string:
+		// A comma-separated list
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported field type %T", field)
+	}
+	return nil
+}
+
+// set applies a key=value override, where key is the dotted YAML path of a
+// setting and value is parsed as YAML. Existing keys that contain dots, such
+// as the operations of timeouts, are matched whole.
+func (c *Config) set(override string) error {
+	key, value, ok := strings.Cut(override, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value")
+	}
+
+	var root yaml.Node
+	if err := root.Encode(c); err != nil {
+		return err
+	}
+	node := &root
+	for parts := strings.Split(key, "."); len(parts) > 0; {
+		var n int
+		node, n = child(node, parts)
+		parts = parts[n:]
+	}
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return err
+	}
+	if len(parsed.Content) == 0 {
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	} else {
+		*node = *parsed.Content[0]
+	}
+
+	data, err := yaml.Marshal(&root)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var updated Config
+	if err := decoder.Decode(&updated); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return errors.New(strings.Join(typeErr.Errors, "; "))
+		}
+		return err
+	}
+	*c = updated
+	return nil
+}
+
+// child returns the value in the mapping node of the longest key made of
+// the first path parts, and how many parts it used. Without one, the first
+// part is added as a new key.
+func child(node *yaml.Node, parts []string) (*yaml.Node, int) {
+	if node.Kind != yaml.MappingNode {
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for n := len(parts); n > 0; n-- {
+		key := strings.Join(parts[:n], ".")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1], n
+			}
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: parts[0]}, value)
+	return value, 1
+}
+
+// redacted replaces secrets when a configuration is printed
+const redacted = "REDACTED"
+
+// Redacted returns a copy of c with its API keys, passwords and tokens
+// replaced, for printing
+func (c Config) Redacted() Config {
+	secret := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return redacted
+	}
+
+	c.AI.Cerebras.APIKey = secret(c.AI.Cerebras.APIKey)
+	c.AI.Cache.RedisURL = redactURL(c.AI.Cache.RedisURL)
+	providers := make(map[string]ProviderConfig, len(c.AI.LLM.Providers))
+	for name, p := range c.AI.LLM.Providers {
+		p.APIKey = secret(p.APIKey)
+		providers[name] = p
+	}
+	c.AI.LLM.Providers = providers
+	c.Database.Password = secret(c.Database.Password)
+	c.Database.URI = redactURL(c.Database.URI)
+	c.Auth.AdminToken = secret(c.Auth.AdminToken)
+	c.Auth.SessionSecret = secret(c.Auth.SessionSecret)
+	c.Auth.TokenKey = secret(c.Auth.TokenKey)
+	return c
+}
+
+// redactURL replaces the password of a connection URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); !ok {
+		return raw
+	}
+	u.User = url.UserPassword(u.User.Username(), redacted)
+	return u.String()
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Operation names used to look up deadlines in TimeoutConfig
+const (
+	OpTaskRead          = "tasks.read"
+	OpTaskWrite         = "tasks.write"
+	OpProjectRead       = "projects.read"
+	OpProjectWrite      = "projects.write"
+	OpConversationRead  = "conversations.read"
+	OpConversationWrite = "conversations.write"
+	OpAIText            = "ai.text"
+	OpAIVision          = "ai.vision"
+	OpAIStream          = "ai.stream"
+	OpAIBatch           = "ai.batch"
+	OpUsageRead         = "usage.read"
+	OpUsageWrite        = "usage.write"
+	OpDocumentRead      = "documents.read"
+	OpDocumentWrite     = "documents.write"
+	OpPromptRead        = "prompts.read"
+	OpPromptWrite       = "prompts.write"
+	OpUserRead          = "users.read"
+	OpUserWrite         = "users.write"
+)
+
+// TimeoutConfig holds the deadline applied to each operation.
+// Operations without an entry use Default; a zero duration means no deadline.
+type TimeoutConfig struct {
+	Default    time.Duration            `yaml:"default"`
+	Operations map[string]time.Duration `yaml:"operations"`
+}
+
+// DefaultTimeouts returns the deadlines used when none are configured
+func DefaultTimeouts() TimeoutConfig {
+	return TimeoutConfig{
+		Default: 5 * time.Second,
+		Operations: map[string]time.Duration{
+			OpAIText:   30 * time.Second,
+			OpAIVision: 60 * time.Second,
+			OpAIStream: 2 * time.Minute,
+			// A batch waits for every query, a few at a time
+			OpAIBatch: 3 * time.Minute,
+			// Ingesting embeds every passage of a document
+			OpDocumentWrite: 5 * time.Minute,
+		},
+	}
+}
+
+// For returns the deadline for an operation
+func (t TimeoutConfig) For(op string) time.Duration {
+	if d, ok := t.Operations[op]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// Longest returns the longest deadline of any operation, so the server's
+// write timeout can be raised to let it complete
+func (t TimeoutConfig) Longest() time.Duration {
+	longest := t.Default
+	for _, d := range t.Operations {
+		longest = max(longest, d)
+	}
+	return longest
+}
+
+// WithDeadline derives a context that expires after the operation's deadline.
+// The parent's own cancellation and deadline still apply.
+func (t TimeoutConfig) WithDeadline(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	d := t.For(op)
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// Merge returns a copy of t with the default and operations set in other applied on top
+func (t TimeoutConfig) Merge(other TimeoutConfig) TimeoutConfig {
+	merged := TimeoutConfig{Default: t.Default, Operations: make(map[string]time.Duration)}
+	for op, d := range t.Operations {
+		merged.Operations[op] = d
+	}
+	if other.Default != 0 {
+		merged.Default = other.Default
+	}
+	for op, d := range other.Operations {
+		merged.Operations[op] = d
+	}
+	return merged
+}
+
+// ParseTimeouts parses overrides of the form "default=5s,ai.text=45s"
+func ParseTimeouts(spec string) (TimeoutConfig, error) {
+	t := TimeoutConfig{Operations: make(map[string]time.Duration)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		op, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return t, fmt.Errorf("invalid timeout %q: expected operation=duration", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			return t, fmt.Errorf("invalid duration for %s: %q", op, value)
+		}
+		op = strings.TrimSpace(op)
+		if op == "default" {
+			t.Default = d
+		} else {
+			t.Operations[op] = d
+		}
+	}
+	return t, nil
+}
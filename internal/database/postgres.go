@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Register the "postgres" driver with database/sql
+	_ "github.com/lib/pq"
+)
+
+// OpenPostgres opens a PostgreSQL connection pool and verifies it with a ping
+func OpenPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening PostgreSQL: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pinging PostgreSQL: %w", err)
+	}
+	return db, nil
+}
+
+// Migrate applies the *.sql files in dir that have not been applied yet.
+// Files run in lexical order, each in its own transaction, and are recorded
+// in the schema_migrations table by file name.
+func Migrate(ctx context.Context, db *sql.DB, dir string) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("listing migrations: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".sql")
+		var applied bool
+		err := db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version,
+		).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("checking migration %s: %w", version, err)
+		}
+		if applied {
+			continue
+		}
+		if err := applyMigration(ctx, db, version, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a single migration file and records it
+func applyMigration(ctx context.Context, db *sql.DB, version, file string) error {
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading migration %s: %w", version, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("applying migration %s: %w", version, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return fmt.Errorf("recording migration %s: %w", version, err)
+	}
+	return tx.Commit()
+}
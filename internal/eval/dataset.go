@@ -0,0 +1,167 @@
+// Package eval replays golden datasets of questions against the configured
+// models and scores the answers, so prompt and model changes can be compared
+// run over run. Datasets are JSONL files of cases; each case lists the checks
+// its answer must pass. Runs are saved as JSON files by a FileStore.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDataset is returned for dataset files that cannot be replayed
+var ErrInvalidDataset = errors.New("invalid eval dataset")
+
+// Check types
+const (
+	// CheckExact passes when the answer equals Value, ignoring surrounding space
+	CheckExact = "exact"
+	// CheckContains passes when the answer contains Value
+	CheckContains = "contains"
+	// CheckRegex passes when the answer matches the regular expression Value
+	CheckRegex = "regex"
+	// CheckJSONSchema passes when the answer is JSON valid against Schema
+	CheckJSONSchema = "json_schema"
+	// CheckJudge asks a judge model to grade the answer against Criteria
+	CheckJudge = "judge"
+)
+
+// Check is one expectation about an answer
+type Check struct {
+	Type string `json:"type"`
+	// Value is the expected text or pattern of exact, contains and regex checks
+	Value string `json:"value,omitempty"`
+	// IgnoreCase compares exact and contains checks case-insensitively
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+	// Schema is the JSON schema of json_schema checks
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// Criteria tells the judge what a good answer contains
+	Criteria string `json:"criteria,omitempty"`
+	// Threshold is the judge score needed to pass, 0.7 if zero
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+// Case is a question and what its answer must contain
+type Case struct {
+	ID       string `json:"id"`
+	Question string `json:"question"`
+	// Prompt is the system prompt template to use, the assistant's by default
+	Prompt string `json:"prompt,omitempty"`
+	// Project, Language and Date fill in the template; Date is YYYY-MM-DD
+	// so answers about relative dates are reproducible
+	Project  string   `json:"project,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Date     string   `json:"date,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Checks   []Check  `json:"checks"`
+}
+
+// Dataset is a named list of cases
+type Dataset struct {
+	Name  string
+	Cases []Case
+}
+
+// LoadDataset reads a JSONL dataset. Blank lines and lines starting with
+// "#" are skipped. The dataset is named after the file.
+func LoadDataset(path string) (Dataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Dataset{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ParseDataset(name, data)
+}
+
+// ParseDataset parses JSONL cases and checks that they can be run
+func ParseDataset(name string, data []byte) (Dataset, error) {
+	dataset := Dataset{Name: name}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&c); err != nil {
+			return dataset, fmt.Errorf("%w: %s line %d: %v", ErrInvalidDataset, name, line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("%s-%d", name, line)
+		}
+		if seen[c.ID] {
+			return dataset, fmt.Errorf("%w: %s line %d: duplicate id %q", ErrInvalidDataset, name, line, c.ID)
+		}
+		seen[c.ID] = true
+		if err := c.validate(); err != nil {
+			return dataset, fmt.Errorf("%w: %s line %d: %v", ErrInvalidDataset, name, line, err)
+		}
+		dataset.Cases = append(dataset.Cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return dataset, err
+	}
+	if len(dataset.Cases) == 0 {
+		return dataset, fmt.Errorf("%w: %s has no cases", ErrInvalidDataset, name)
+	}
+	return dataset, nil
+}
+
+// validate checks that a case has a question and valid checks
+func (c Case) validate() error {
+	if strings.TrimSpace(c.Question) == "" {
+		return errors.New("question is required")
+	}
+	if len(c.Checks) == 0 {
+		return errors.New("at least one check is required")
+	}
+	if c.Date != "" {
+		if _, err := time.Parse("2006-01-02", c.Date); err != nil {
+			return fmt.Errorf("date %q: expected YYYY-MM-DD", c.Date)
+		}
+	}
+	for i, check := range c.Checks {
+		if _, err := newScorer(check); err != nil {
+			return fmt.Errorf("check %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Filter returns the cases having any of the tags, or all cases if none are given
+func (d Dataset) Filter(tags ...string) Dataset {
+	if len(tags) == 0 {
+		return d
+	}
+	filtered := Dataset{Name: d.Name}
+	for _, c := range d.Cases {
+		for _, tag := range tags {
+			if hasTag(c, tag) {
+				filtered.Cases = append(filtered.Cases, c)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// hasTag reports whether a case is tagged with tag
+func hasTag(c Case, tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
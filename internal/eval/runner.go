@@ -0,0 +1,273 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/prompts"
+)
+
+// Run is the outcome of replaying a dataset against a model
+type Run struct {
+	ID      string `json:"id"`
+	Dataset string `json:"dataset"`
+	// Model and Judge describe the models used, e.g. "cerebras/qwen-3-32b"
+	Model      string            `json:"model"`
+	Judge      string            `json:"judge,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMS int64             `json:"duration_ms"`
+	Summary    Summary           `json:"summary"`
+	Results    []CaseResult      `json:"results"`
+	Options    map[string]string `json:"options,omitempty"`
+}
+
+// Summary aggregates the results of a run
+type Summary struct {
+	Cases  int `json:"cases"`
+	Passed int `json:"passed"`
+	// Errors counts the cases the model failed to answer
+	Errors int `json:"errors"`
+	// PassRate is the share of cases whose checks all passed
+	PassRate float64 `json:"pass_rate"`
+	// Score is the mean case score
+	Score float64 `json:"score"`
+	// Prompts counts the cases per system prompt template version
+	Prompts map[string]int `json:"prompts,omitempty"`
+}
+
+// CaseResult is the answer to a case and how it scored
+type CaseResult struct {
+	ID       string   `json:"id"`
+	Question string   `json:"question"`
+	Tags     []string `json:"tags,omitempty"`
+	// Prompt is the system prompt template version, e.g. "assistant@2"
+	Prompt string `json:"prompt,omitempty"`
+	Answer string `json:"answer"`
+	// Error is set when the model did not answer; the case then scores 0
+	Error     string        `json:"error,omitempty"`
+	Passed    bool          `json:"passed"`
+	Score     float64       `json:"score"`
+	LatencyMS int64         `json:"latency_ms"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+// Runner replays datasets against a model
+type Runner struct {
+	Model Answerer
+	// ModelName and JudgeName are recorded with the run
+	ModelName string
+	// Judge grades judge checks; without one they fail
+	Judge     Answerer
+	JudgeName string
+	// Prompts renders the system prompts; the built-in templates by default
+	Prompts *prompts.Registry
+	// Parallel is how many cases are answered at once, 1 if zero
+	Parallel int
+	// Options are recorded with the run to tell runs apart, e.g. a prompt override
+	Options map[string]string
+}
+
+// Run answers and scores every case of dataset. Cases the model fails to
+// answer are recorded with their error; only a cancelled ctx stops the run.
+func (r *Runner) Run(ctx context.Context, dataset Dataset) (*Run, error) {
+	if r.Model == nil {
+		return nil, fmt.Errorf("eval: no model to evaluate")
+	}
+	registry := r.Prompts
+	if registry == nil {
+		registry = prompts.NewRegistry()
+	}
+	parallel := r.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	started := time.Now()
+	results := make([]CaseResult, len(dataset.Cases))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, c := range dataset.Cases {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int, c Case) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = r.runCase(ctx, registry, c)
+		}(i, c)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		Dataset:    dataset.Name,
+		Model:      r.ModelName,
+		Judge:      r.JudgeName,
+		StartedAt:  started.UTC(),
+		DurationMS: time.Since(started).Milliseconds(),
+		Summary:    summarize(results),
+		Results:    results,
+		Options:    r.Options,
+	}
+	run.ID = runID(run)
+	return run, nil
+}
+
+// runCase answers one case and scores the answer
+func (r *Runner) runCase(ctx context.Context, registry *prompts.Registry, c Case) CaseResult {
+	result := CaseResult{ID: c.ID, Question: c.Question, Tags: c.Tags, Checks: []CheckResult{}}
+
+	name := c.Prompt
+	if name == "" {
+		name = prompts.Assistant
+	}
+	vars := prompts.Vars{Project: c.Project, Language: c.Language, Date: time.Now().In(tools.Bogota)}
+	if c.Date != "" {
+		// Checked when the dataset was loaded
+		vars.Date, _ = time.ParseInLocation("2006-01-02", c.Date, tools.Bogota)
+	}
+	system, err := registry.Render(name, vars)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Prompt = system.Ref
+
+	started := time.Now()
+	answer, err := r.Model.Answer(ctx, []ai.Message{
+		{Role: "system", Content: system.Text},
+		{Role: "user", Content: c.Question},
+	})
+	result.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Answer = answer
+
+	result.Passed = true
+	var total float64
+	for _, check := range c.Checks {
+		scorer, err := NewScorer(check, r.Judge)
+		if err != nil {
+			// Checked when the dataset was loaded
+			result.Checks = append(result.Checks, passed(check.Type, false, err.Error()))
+			result.Passed = false
+			continue
+		}
+		checked := scorer.Score(ctx, c.Question, answer)
+		result.Checks = append(result.Checks, checked)
+		result.Passed = result.Passed && checked.Passed
+		total += checked.Score
+	}
+	result.Score = total / float64(len(c.Checks))
+	return result
+}
+
+// summarize aggregates case results
+func summarize(results []CaseResult) Summary {
+	summary := Summary{Cases: len(results), Prompts: make(map[string]int)}
+	var total float64
+	for _, result := range results {
+		if result.Passed {
+			summary.Passed++
+		}
+		if result.Error != "" {
+			summary.Errors++
+		}
+		if result.Prompt != "" {
+			summary.Prompts[result.Prompt]++
+		}
+		total += result.Score
+	}
+	if summary.Cases > 0 {
+		summary.PassRate = float64(summary.Passed) / float64(summary.Cases)
+		summary.Score = total / float64(summary.Cases)
+	}
+	return summary
+}
+
+// unsafeIDChars are replaced in run IDs, which are used as file names
+var unsafeIDChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// runID names a run after when it started, its dataset and its model
+func runID(run *Run) string {
+	parts := []string{run.StartedAt.Format("20060102-150405"), run.Dataset}
+	if run.Model != "" {
+		parts = append(parts, run.Model)
+	}
+	return strings.Trim(unsafeIDChars.ReplaceAllString(strings.Join(parts, "-"), "_"), "_")
+}
+
+// CaseDiff is how a case's result changed between two runs
+type CaseDiff struct {
+	ID         string  `json:"id"`
+	BaseScore  float64 `json:"base_score"`
+	HeadScore  float64 `json:"head_score"`
+	BasePassed bool    `json:"base_passed"`
+	HeadPassed bool    `json:"head_passed"`
+}
+
+// Comparison is how a run compares to an earlier one of the same dataset
+type Comparison struct {
+	Base       string  `json:"base"`
+	Head       string  `json:"head"`
+	ScoreDelta float64 `json:"score_delta"`
+	PassDelta  float64 `json:"pass_rate_delta"`
+	// Regressions are cases that passed in base and fail in head, or scored lower
+	Regressions []CaseDiff `json:"regressions"`
+	// Improvements are cases that failed in base and pass in head, or scored higher
+	Improvements []CaseDiff `json:"improvements"`
+	// Added and Removed list the cases only one of the runs has
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Compare compares head to base case by case
+func Compare(base, head *Run) Comparison {
+	comparison := Comparison{
+		Base:         base.ID,
+		Head:         head.ID,
+		ScoreDelta:   head.Summary.Score - base.Summary.Score,
+		PassDelta:    head.Summary.PassRate - base.Summary.PassRate,
+		Regressions:  []CaseDiff{},
+		Improvements: []CaseDiff{},
+	}
+	baseResults := make(map[string]CaseResult, len(base.Results))
+	for _, result := range base.Results {
+		baseResults[result.ID] = result
+	}
+	for _, h := range head.Results {
+		b, ok := baseResults[h.ID]
+		if !ok {
+			comparison.Added = append(comparison.Added, h.ID)
+			continue
+		}
+		delete(baseResults, h.ID)
+		diff := CaseDiff{ID: h.ID, BaseScore: b.Score, HeadScore: h.Score, BasePassed: b.Passed, HeadPassed: h.Passed}
+		switch {
+		case b.Passed && !h.Passed, b.Passed == h.Passed && h.Score < b.Score:
+			comparison.Regressions = append(comparison.Regressions, diff)
+		case !b.Passed && h.Passed, b.Passed == h.Passed && h.Score > b.Score:
+			comparison.Improvements = append(comparison.Improvements, diff)
+		}
+	}
+	for id := range baseResults {
+		comparison.Removed = append(comparison.Removed, id)
+	}
+	sort.Strings(comparison.Removed)
+	return comparison
+}
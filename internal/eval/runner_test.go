@@ -0,0 +1,128 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedAnswerer answers each question with a scripted reply and records
+// the system prompts it was given
+type scriptedAnswerer struct {
+	mu      sync.Mutex
+	replies map[string]string
+	systems []string
+}
+
+func (a *scriptedAnswerer) Answer(ctx context.Context, messages []ai.Message) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.systems = append(a.systems, messages[0].Content)
+	reply, ok := a.replies[messages[len(messages)-1].Content]
+	if !ok {
+		return "", errors.New("provider unavailable")
+	}
+	return reply, nil
+}
+
+// goldenDataset has a case that passes, one that fails a check and one the
+// model does not answer
+var goldenDataset = Dataset{Name: "golden", Cases: []Case{
+	{ID: "recubrimiento", Question: "¿Recubrimiento en columnas?", Project: "Casa Usaquén",
+		Checks: []Check{{Type: CheckContains, Value: "40 mm"}, {Type: CheckJudge, Criteria: "Indica 40 mm"}}},
+	{ID: "lunes", Question: "¿Próximo lunes?", Date: "2025-06-13",
+		Checks: []Check{{Type: CheckContains, Value: "2025-06-16"}, {Type: CheckRegex, Value: `\d{4}`}}},
+	{ID: "caido", Question: "¿Sin respuesta?", Prompt: prompts.AssistantNoThink, Checks: []Check{{Type: CheckContains, Value: "x"}}},
+}}
+
+// TestRunner tests replaying a dataset and summarizing the scores
+func TestRunner(t *testing.T) {
+	model := &scriptedAnswerer{replies: map[string]string{
+		"¿Recubrimiento en columnas?": "Son 40 mm.",
+		"¿Próximo lunes?":             "El 16 de junio de 2025.",
+	}}
+	runner := &Runner{
+		Model:     model,
+		ModelName: "ollama/llama3.1:8b",
+		Judge:     fixedAnswerer{reply: `{"score": 0.8, "reason": "Correcta"}`},
+		JudgeName: "cerebras/qwen-3-32b",
+		Parallel:  2,
+	}
+	run, err := runner.Run(context.Background(), goldenDataset)
+	require.NoError(t, err)
+
+	assert.Equal(t, "golden", run.Dataset)
+	assert.True(t, strings.HasSuffix(run.ID, "-golden-ollama_llama3.1_8b"), run.ID)
+	assert.InDelta(t, (0.9+0.5+0)/3, run.Summary.Score, 1e-9)
+	run.Summary.Score = 0
+	assert.Equal(t, Summary{
+		Cases: 3, Passed: 1, Errors: 1, PassRate: 1.0 / 3,
+		Prompts: map[string]int{"assistant@1": 2, "assistant_no_think@1": 1},
+	}, run.Summary)
+
+	require.Len(t, run.Results, 3)
+	assert.Equal(t, "recubrimiento", run.Results[0].ID)
+	assert.True(t, run.Results[0].Passed)
+	assert.InDelta(t, 0.9, run.Results[0].Score, 1e-9)
+	assert.False(t, run.Results[1].Passed)
+	assert.Equal(t, "assistant_no_think@1", run.Results[2].Prompt)
+	assert.Equal(t, "provider unavailable", run.Results[2].Error)
+
+	// The system prompts are filled in with each case's variables
+	joined := strings.Join(model.systems, "\n")
+	assert.Contains(t, joined, "Casa Usaquén")
+	assert.Contains(t, joined, "2025-06-13")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = runner.Run(ctx, goldenDataset)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestFileStoreAndCompare tests saving runs and comparing them case by case
+func TestFileStoreAndCompare(t *testing.T) {
+	store := FileStore{Dir: t.TempDir()}
+	_, err := store.Latest("golden")
+	assert.ErrorIs(t, err, ErrRunNotFound)
+
+	base, err := (&Runner{Model: &scriptedAnswerer{replies: map[string]string{
+		"¿Recubrimiento en columnas?": "Son 40 mm.",
+		"¿Sin respuesta?":             "x",
+	}}}).Run(context.Background(), goldenDataset)
+	require.NoError(t, err)
+	base.ID = "base"
+	require.NoError(t, store.Save(base))
+
+	head, err := (&Runner{Model: &scriptedAnswerer{replies: map[string]string{
+		"¿Próximo lunes?": "2025-06-16",
+	}}}).Run(context.Background(), Dataset{Name: "golden", Cases: goldenDataset.Cases[1:]})
+	require.NoError(t, err)
+	head.ID = "head"
+	head.StartedAt = base.StartedAt.Add(1)
+	require.NoError(t, store.Save(head))
+
+	runs, err := store.List("golden")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "base", runs[0].ID)
+	assert.Nil(t, runs[0].Results)
+	latest, err := store.Latest("golden")
+	require.NoError(t, err)
+	assert.Equal(t, head, latest)
+	_, err = store.Load("missing")
+	assert.ErrorIs(t, err, ErrRunNotFound)
+
+	comparison := Compare(base, head)
+	assert.Equal(t, "base", comparison.Base)
+	assert.Equal(t, []CaseDiff{{ID: "caido", BaseScore: 1, HeadScore: 0, BasePassed: true}}, comparison.Regressions)
+	assert.Equal(t, []CaseDiff{{ID: "lunes", BaseScore: 0, HeadScore: 1, HeadPassed: true}}, comparison.Improvements)
+	assert.Equal(t, []string{"recubrimiento"}, comparison.Removed)
+	assert.Empty(t, comparison.Added)
+}
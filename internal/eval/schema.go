@@ -0,0 +1,161 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is a parsed JSON schema. Only the keywords used by the
+// structured outputs of the models are checked: type, enum, properties,
+// required, additionalProperties, items, minItems, maxItems, minLength,
+// maxLength, minimum and maximum.
+type jsonSchema map[string]interface{}
+
+// parseSchema parses a JSON schema document
+func parseSchema(raw json.RawMessage) (jsonSchema, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("schema is required")
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("schema must be a JSON object: %v", err)
+	}
+	return schema, nil
+}
+
+// validate checks value, decoded from JSON, against the schema. The error
+// names the path of the first mismatch, e.g. "$.tasks[0].title".
+func (s jsonSchema) validate(value interface{}, path string) error {
+	if types, ok := s["type"]; ok && !matchesType(value, types) {
+		return fmt.Errorf("%s: expected %v, got %s", path, types, jsonType(value))
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[fmt.Sprint(name)]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		for name, item := range v {
+			sub, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, set := s["additionalProperties"].(bool); set && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := jsonSchema(sub).validate(item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if min, ok := number(s["minItems"]); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, min, len(v))
+		}
+		if max, ok := number(s["maxItems"]); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, max, len(v))
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := jsonSchema(items).validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := number(s["minLength"]); ok && length < min {
+			return fmt.Errorf("%s: expected at least %v characters", path, min)
+		}
+		if max, ok := number(s["maxLength"]); ok && length > max {
+			return fmt.Errorf("%s: expected at most %v characters", path, max)
+		}
+	case float64:
+		if min, ok := number(s["minimum"]); ok && v < min {
+			return fmt.Errorf("%s: %v is less than %v", path, v, min)
+		}
+		if max, ok := number(s["maximum"]); ok && v > max {
+			return fmt.Errorf("%s: %v is greater than %v", path, v, max)
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether value has the schema type, or one of the
+// schema types if a list is given
+func matchesType(value interface{}, types interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		actual := jsonType(value)
+		return actual == t || (t == "number" && actual == "integer")
+	case []interface{}:
+		for _, one := range t {
+			if matchesType(value, one) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// number returns a numeric schema keyword
+func number(value interface{}) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
+
+// extractJSON returns the JSON document in an answer, without the Markdown
+// code fence models often wrap it in
+func extractJSON(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if rest, ok := strings.CutPrefix(answer, "```"); ok {
+		// Drop the fence's language tag, e.g. ```json
+		if newline := strings.IndexByte(rest, '\n'); newline >= 0 {
+			rest = rest[newline+1:]
+		}
+		answer = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "```"))
+	}
+	return answer
+}
@@ -0,0 +1,189 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lyffseba/ana/internal/ai"
+)
+
+// DefaultJudgeThreshold is the judge score a judge check needs to pass
+const DefaultJudgeThreshold = 0.7
+
+// Answerer answers a conversation. ai.ChatModel and ai.ModelChain are Answerers.
+type Answerer interface {
+	Answer(ctx context.Context, messages []ai.Message) (string, error)
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Type   string `json:"type"`
+	Passed bool   `json:"passed"`
+	// Score is between 0 and 1; checks other than the judge's score 0 or 1
+	Score float64 `json:"score"`
+	// Detail explains a failure, or holds the judge's reasoning
+	Detail string `json:"detail,omitempty"`
+}
+
+// Scorer grades an answer against one check
+type Scorer interface {
+	Score(ctx context.Context, question, answer string) CheckResult
+}
+
+// NewScorer returns the scorer of a check. Judge checks ask judge, which
+// may be nil to only validate the check.
+func NewScorer(check Check, judge Answerer) (Scorer, error) {
+	switch check.Type {
+	case CheckExact, CheckContains:
+		if check.Value == "" {
+			return nil, fmt.Errorf("%s check: value is required", check.Type)
+		}
+		return textScorer{check: check}, nil
+	case CheckRegex:
+		pattern := check.Value
+		if check.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex check: %v", err)
+		}
+		return regexScorer{re: re}, nil
+	case CheckJSONSchema:
+		schema, err := parseSchema(check.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("json_schema check: %v", err)
+		}
+		return schemaScorer{schema: schema}, nil
+	case CheckJudge:
+		if strings.TrimSpace(check.Criteria) == "" {
+			return nil, fmt.Errorf("judge check: criteria is required")
+		}
+		threshold := check.Threshold
+		if threshold == 0 {
+			threshold = DefaultJudgeThreshold
+		}
+		if threshold < 0 || threshold > 1 {
+			return nil, fmt.Errorf("judge check: threshold must be between 0 and 1")
+		}
+		return judgeScorer{judge: judge, criteria: check.Criteria, threshold: threshold}, nil
+	default:
+		return nil, fmt.Errorf("unknown check type %q", check.Type)
+	}
+}
+
+// newScorer validates a check without a judge model
+func newScorer(check Check) (Scorer, error) {
+	return NewScorer(check, nil)
+}
+
+// passed returns the result of a check that passes or fails outright
+func passed(checkType string, ok bool, detail string) CheckResult {
+	if ok {
+		return CheckResult{Type: checkType, Passed: true, Score: 1}
+	}
+	return CheckResult{Type: checkType, Detail: detail}
+}
+
+// textScorer runs exact and contains checks
+type textScorer struct {
+	check Check
+}
+
+func (s textScorer) Score(ctx context.Context, question, answer string) CheckResult {
+	expected, actual := s.check.Value, strings.TrimSpace(answer)
+	if s.check.IgnoreCase {
+		expected, actual = strings.ToLower(expected), strings.ToLower(actual)
+	}
+	if s.check.Type == CheckExact {
+		return passed(CheckExact, actual == strings.TrimSpace(expected), fmt.Sprintf("expected %q", s.check.Value))
+	}
+	return passed(CheckContains, strings.Contains(actual, expected), fmt.Sprintf("%q not found", s.check.Value))
+}
+
+// regexScorer runs regex checks
+type regexScorer struct {
+	re *regexp.Regexp
+}
+
+func (s regexScorer) Score(ctx context.Context, question, answer string) CheckResult {
+	return passed(CheckRegex, s.re.MatchString(answer), fmt.Sprintf("no match for %s", s.re))
+}
+
+// schemaScorer runs json_schema checks
+type schemaScorer struct {
+	schema jsonSchema
+}
+
+func (s schemaScorer) Score(ctx context.Context, question, answer string) CheckResult {
+	var value interface{}
+	if err := json.Unmarshal([]byte(extractJSON(answer)), &value); err != nil {
+		return passed(CheckJSONSchema, false, "answer is not JSON: "+err.Error())
+	}
+	if err := s.schema.validate(value, "$"); err != nil {
+		return passed(CheckJSONSchema, false, err.Error())
+	}
+	return passed(CheckJSONSchema, true, "")
+}
+
+// judgeScorer asks a model to grade the answer
+type judgeScorer struct {
+	judge     Answerer
+	criteria  string
+	threshold float64
+}
+
+// judgeInstructions tell the judge model how to grade answers
+const judgeInstructions = "Eres un evaluador de respuestas de un asistente de arquitectura para Colombia. " +
+	"Califica la respuesta según los criterios con un número entre 0 y 1, donde 1 significa que los cumple por completo " +
+	"y 0 que no los cumple o que contiene datos incorrectos. " +
+	`Responde solo con JSON de la forma {"score": 0.8, "reason": "explicación breve"}.`
+
+// judgeVerdict is the judge model's answer
+type judgeVerdict struct {
+	Score  *float64 `json:"score"`
+	Reason string   `json:"reason"`
+}
+
+func (s judgeScorer) Score(ctx context.Context, question, answer string) CheckResult {
+	if s.judge == nil {
+		return passed(CheckJudge, false, "no judge model configured")
+	}
+	prompt := []ai.Message{
+		{Role: "system", Content: judgeInstructions},
+		{Role: "user", Content: fmt.Sprintf("Pregunta:\n%s\n\nCriterios:\n%s\n\nRespuesta a evaluar:\n%s", question, s.criteria, answer)},
+	}
+	reply, err := s.judge.Answer(ctx, prompt)
+	if err != nil {
+		return passed(CheckJudge, false, "judge failed: "+err.Error())
+	}
+	verdict, err := parseVerdict(reply)
+	if err != nil {
+		return passed(CheckJudge, false, err.Error())
+	}
+	return CheckResult{
+		Type:   CheckJudge,
+		Passed: *verdict.Score >= s.threshold,
+		Score:  *verdict.Score,
+		Detail: verdict.Reason,
+	}
+}
+
+// parseVerdict reads the judge's JSON verdict, ignoring any text around it
+func parseVerdict(reply string) (judgeVerdict, error) {
+	var verdict judgeVerdict
+	text := extractJSON(reply)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	if err := json.Unmarshal([]byte(text), &verdict); err != nil || verdict.Score == nil {
+		return verdict, fmt.Errorf("judge returned no score: %.200q", reply)
+	}
+	if *verdict.Score < 0 || *verdict.Score > 1 {
+		return verdict, fmt.Errorf("judge score %v is not between 0 and 1", *verdict.Score)
+	}
+	return verdict, nil
+}
@@ -0,0 +1,148 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedAnswerer answers every conversation with the same reply
+type fixedAnswerer struct {
+	reply string
+	err   error
+}
+
+func (a fixedAnswerer) Answer(ctx context.Context, messages []ai.Message) (string, error) {
+	return a.reply, a.err
+}
+
+// score grades answer against check with judge
+func score(t *testing.T, check Check, judge Answerer, answer string) CheckResult {
+	t.Helper()
+	scorer, err := NewScorer(check, judge)
+	require.NoError(t, err)
+	return scorer.Score(context.Background(), "¿Pregunta?", answer)
+}
+
+// TestScorers tests each check type
+func TestScorers(t *testing.T) {
+	const answer = "El recubrimiento mínimo es de 40 mm según la NSR-10, Título C."
+	tests := []struct {
+		name   string
+		check  Check
+		passed bool
+	}{
+		{"exact", Check{Type: CheckExact, Value: "  " + answer + "\n"}, true},
+		{"exact differs", Check{Type: CheckExact, Value: "40 mm"}, false},
+		{"contains", Check{Type: CheckContains, Value: "40 mm"}, true},
+		{"contains case", Check{Type: CheckContains, Value: "título c"}, false},
+		{"contains ignoring case", Check{Type: CheckContains, Value: "título c", IgnoreCase: true}, true},
+		{"regex", Check{Type: CheckRegex, Value: `\d+\s*mm`}, true},
+		{"regex ignoring case", Check{Type: CheckRegex, Value: `nsr-?10`, IgnoreCase: true}, true},
+		{"regex no match", Check{Type: CheckRegex, Value: `\d+\s*cm`}, false},
+	}
+	for _, tt := range tests {
+		result := score(t, tt.check, nil, answer)
+		assert.Equal(t, tt.passed, result.Passed, tt.name)
+		assert.Equal(t, tt.check.Type, result.Type, tt.name)
+		if tt.passed {
+			assert.Equal(t, 1.0, result.Score, tt.name)
+		} else {
+			assert.Zero(t, result.Score, tt.name)
+			assert.NotEmpty(t, result.Detail, tt.name)
+		}
+	}
+}
+
+// TestJSONSchemaScorer tests validating structured answers
+func TestJSONSchemaScorer(t *testing.T) {
+	check := Check{Type: CheckJSONSchema, Schema: json.RawMessage(`{
+		"type": "object",
+		"required": ["material", "costo_m2_cop"],
+		"additionalProperties": false,
+		"properties": {
+			"material": {"type": "string", "minLength": 1},
+			"costo_m2_cop": {"type": "number", "minimum": 1},
+			"capas": {"type": "array", "items": {"type": "string", "enum": ["pañete", "pintura"]}}
+		}
+	}`)}
+
+	assert.True(t, score(t, check, nil, `{"material": "ladrillo tolete", "costo_m2_cop": 85000}`).Passed)
+	assert.True(t, score(t, check, nil, "```json\n{\"material\": \"bloque\", \"costo_m2_cop\": 62000.5, \"capas\": [\"pintura\"]}\n```").Passed)
+
+	for answer, detail := range map[string]string{
+		`El costo es de 85000 pesos`:                                "answer is not JSON",
+		`{"material": "ladrillo"}`:                                  `$: missing required property "costo_m2_cop"`,
+		`{"material": "ladrillo", "costo_m2_cop": "85000"}`:         "$.costo_m2_cop: expected number, got string",
+		`{"material": "", "costo_m2_cop": 85000}`:                   "$.material: expected at least 1 characters",
+		`{"material": "a", "costo_m2_cop": 0}`:                      "$.costo_m2_cop: 0 is less than 1",
+		`{"material": "a", "costo_m2_cop": 1, "capas": ["estuco"]}`: "$.capas[0]: estuco is not one of [pañete pintura]",
+		`{"material": "a", "costo_m2_cop": 1, "moneda": "COP"}`:     `$: unexpected property "moneda"`,
+	} {
+		result := score(t, check, nil, answer)
+		assert.False(t, result.Passed, answer)
+		assert.Contains(t, result.Detail, detail, answer)
+	}
+}
+
+// TestJudgeScorer tests grading answers with a judge model
+func TestJudgeScorer(t *testing.T) {
+	check := Check{Type: CheckJudge, Criteria: "Indica 40 mm"}
+
+	result := score(t, check, fixedAnswerer{reply: "```json\n{\"score\": 0.9, \"reason\": \"Cita el valor correcto\"}\n```"}, "40 mm")
+	assert.Equal(t, CheckResult{Type: CheckJudge, Passed: true, Score: 0.9, Detail: "Cita el valor correcto"}, result)
+
+	result = score(t, check, fixedAnswerer{reply: `Mi evaluación: {"score": 0.5, "reason": "Incompleta"}`}, "40")
+	assert.False(t, result.Passed)
+	assert.Equal(t, 0.5, result.Score)
+
+	check.Threshold = 0.4
+	assert.True(t, score(t, check, fixedAnswerer{reply: `{"score": 0.5}`}, "40").Passed)
+
+	for _, judge := range []Answerer{
+		nil,
+		fixedAnswerer{err: errors.New("rate limited")},
+		fixedAnswerer{reply: "Buena respuesta"},
+		fixedAnswerer{reply: `{"score": 7}`},
+	} {
+		result := score(t, check, judge, "40 mm")
+		assert.False(t, result.Passed)
+		assert.Zero(t, result.Score)
+		assert.NotEmpty(t, result.Detail)
+	}
+}
+
+// TestParseDataset tests reading and validating JSONL cases
+func TestParseDataset(t *testing.T) {
+	dataset, err := ParseDataset("golden", []byte(`# Preguntas de prueba
+{"id": "a", "question": "¿Recubrimiento?", "tags": ["nsr10"], "checks": [{"type": "contains", "value": "40 mm"}]}
+
+{"question": "¿Fecha?", "date": "2025-06-13", "checks": [{"type": "judge", "criteria": "Indica el lunes"}]}
+`))
+	require.NoError(t, err)
+	require.Len(t, dataset.Cases, 2)
+	assert.Equal(t, "golden-4", dataset.Cases[1].ID)
+	assert.Len(t, dataset.Filter("nsr10").Cases, 1)
+	assert.Len(t, dataset.Filter().Cases, 2)
+
+	for _, data := range []string{
+		``,
+		`{"id": "a", "checks": [{"type": "contains", "value": "x"}]}`,
+		`{"id": "a", "question": "q", "checks": []}`,
+		`{"id": "a", "question": "q", "checks": [{"type": "similar", "value": "x"}]}`,
+		`{"id": "a", "question": "q", "checks": [{"type": "regex", "value": "("}]}`,
+		`{"id": "a", "question": "q", "checks": [{"type": "json_schema"}]}`,
+		`{"id": "a", "question": "q", "checks": [{"type": "judge"}]}`,
+		`{"id": "a", "question": "q", "date": "13/06/2025", "checks": [{"type": "contains", "value": "x"}]}`,
+		`{"id": "a", "question": "q", "expected": "x", "checks": [{"type": "contains", "value": "x"}]}`,
+		"{\"id\": \"a\", \"question\": \"q\", \"checks\": [{\"type\": \"contains\", \"value\": \"x\"}]}\n{\"id\": \"a\", \"question\": \"q\", \"checks\": [{\"type\": \"contains\", \"value\": \"x\"}]}",
+	} {
+		_, err := ParseDataset("bad", []byte(data))
+		assert.ErrorIs(t, err, ErrInvalidDataset, data)
+	}
+}
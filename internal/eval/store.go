@@ -0,0 +1,89 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrRunNotFound is returned for run IDs without a saved run
+var ErrRunNotFound = errors.New("eval run not found")
+
+// FileStore saves runs as JSON files in a directory, one file per run, so
+// they can be kept alongside the datasets and compared later
+type FileStore struct {
+	Dir string
+}
+
+// Save writes a run
+func (s FileStore) Save(run *Run) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(run.ID), append(data, '\n'), 0o644)
+}
+
+// Load reads a run by ID
+func (s FileStore) Load(id string) (*Run, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("reading run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// List returns the saved runs of a dataset, or of every dataset if it is
+// empty, oldest first. Case results are left out.
+func (s FileStore) List(dataset string) ([]Run, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(files))
+	for _, file := range files {
+		run, err := s.Load(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if dataset != "" && run.Dataset != dataset {
+			continue
+		}
+		run.Results = nil
+		runs = append(runs, *run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// Latest returns the most recent run of a dataset
+func (s FileStore) Latest(dataset string) (*Run, error) {
+	runs, err := s.List(dataset)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w: no runs of %s", ErrRunNotFound, dataset)
+	}
+	return s.Load(runs[len(runs)-1].ID)
+}
+
+// path returns the file of a run
+func (s FileStore) path(id string) string {
+	return filepath.Join(s.Dir, filepath.Base(id)+".json")
+}
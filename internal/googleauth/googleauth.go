@@ -3,14 +3,21 @@ package googleauth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/session"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"go.uber.org/zap"
@@ -21,18 +28,34 @@ import (
 type OAuthService struct {
 	Config *oauth2.Config
 	Logger *zap.Logger
+	// Users stores the account of each person who signs in
+	Users repositories.UserRepository
+	// Sessions issues the session cookie of a user who signed in
+	Sessions *session.Manager
+	// UserInfoURL is the OpenID Connect endpoint the user's profile is read from
+	UserInfoURL string
+	// Tokens keeps the Google token of each user for the Calendar and Gmail features
+	Tokens repositories.TokenStore
+	// RevokeURL is the endpoint tokens are revoked at when a user disconnects
+	RevokeURL string
 	// stateStore is a simple in-memory store for OAuth state tokens.
 	// For production, consider a more robust distributed store (e.g., Redis) if running multiple instances.
+	stateMu    sync.Mutex
 	stateStore map[string]time.Time
 }
 
 const (
 	stateTokenExpiry   = 10 * time.Minute // OAuth state token expires in 10 minutes
 	stateCookieName    = "oauthstate"
-	redirectURLDev     = "http://localhost:8080/api/auth/google/callback" // Default, adjust if your port differs
-	// Add production redirect URL when ready: const redirectURLProd = "https://ana.world/api/auth/google/callback"
+	// googleUserInfoURL returns the profile of the user a token belongs to
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+	// signedInRedirect is where the browser goes once signed in
+	signedInRedirect = "/"
 )
 
+// signInScopes are requested in addition to the configured ones to identify the user
+var signInScopes = []string{"openid", "email", "profile"}
+
 // NewOAuthService creates a new OAuthService.
 // credPath should be the path to your credentials.json file.
 // redirectURL should be the callback URL registered in Google Cloud Console.
@@ -49,6 +72,11 @@ func NewOAuthService(credPath string, redirectURL string, logger *zap.Logger, sc
 		return nil, fmt.Errorf("unable to read client secret file at %s: %w", absCredPath, err)
 	}
 
+	for _, scope := range signInScopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
 	oauthConfig, err := google.ConfigFromJSON(b, scopes...)
 	if err != nil {
 		logger.Error("Unable to parse client secret file to config", zap.Error(err))
@@ -57,9 +85,13 @@ func NewOAuthService(credPath string, redirectURL string, logger *zap.Logger, sc
 	oauthConfig.RedirectURL = redirectURL
 
 	return &OAuthService{
-		Config:     oauthConfig,
-		Logger:     logger.Named("OAuthService"),
-		stateStore: make(map[string]time.Time),
+		Config:      oauthConfig,
+		Logger:      logger.Named("OAuthService"),
+		Users:       repositories.NewMemoryUserRepository(),
+		UserInfoURL: googleUserInfoURL,
+		Tokens:      repositories.NewMemoryTokenStore(),
+		RevokeURL:   googleRevokeURL,
+		stateStore:  make(map[string]time.Time),
 	}, nil
 }
 
@@ -74,42 +106,47 @@ func (s *OAuthService) generateStateToken(c *gin.Context) (string, error) {
 	state := base64.URLEncoding.EncodeToString(stateBytes)
 
 	// Store the state token with an expiry in the service's stateStore
+	s.stateMu.Lock()
 	s.stateStore[state] = time.Now().Add(stateTokenExpiry)
-	s.Logger.Info("Generated and stored state token", zap.String("state", state))
+	s.stateMu.Unlock()
+	s.Logger.Debug("Generated and stored state token")
 
 	// Clean up expired state tokens (simple cleanup, can be improved for efficiency)
 	go s.cleanupExpiredStates()
 
-	// Also set as a cookie for potential stateless verification, though primary check is server-side store
-	c.SetCookie(stateCookieName, state, int(stateTokenExpiry.Seconds()), "/api/auth/google", "localhost", false, true)
+	// Also set as a cookie, which the callback must carry, binding the state to this browser
+	c.SetCookie(stateCookieName, state, int(stateTokenExpiry.Seconds()), "/", "", c.Request.TLS != nil, true)
 
 	return state, nil
 }
 
 // validateStateToken checks if the provided state is valid and removes it from the store.
 func (s *OAuthService) validateStateToken(state string) bool {
+	s.stateMu.Lock()
 	expiry, ok := s.stateStore[state]
+	delete(s.stateStore, state) // Consume the state token
+	s.stateMu.Unlock()
 	if !ok {
-		s.Logger.Warn("State token not found in store", zap.String("state", state))
+		s.Logger.Warn("State token not found in store")
 		return false
 	}
-	delete(s.stateStore, state) // Consume the state token
 
 	if time.Now().After(expiry) {
-		s.Logger.Warn("State token expired", zap.String("state", state))
+		s.Logger.Warn("State token expired")
 		return false
 	}
-	s.Logger.Info("State token validated successfully", zap.String("state", state))
+	s.Logger.Debug("State token validated successfully")
 	return true
 }
 
 // cleanupExpiredStates iterates through the state store and removes expired tokens.
 func (s *OAuthService) cleanupExpiredStates() {
 	s.Logger.Debug("Running state token cleanup")
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
 	for st, expiry := range s.stateStore {
 		if time.Now().After(expiry) {
 			delete(s.stateStore, st)
-			s.Logger.Debug("Deleted expired state token", zap.String("state", st))
 		}
 	}
 }
@@ -127,28 +164,30 @@ func (s *OAuthService) HandleLogin(c *gin.Context) {
 	// Remove oauth2.ApprovalForce for a smoother UX in production after initial testing,
 	// especially if AccessTypeOffline is used and you have a refresh token.
 	authURL := s.Config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
-	s.Logger.Info("Redirecting to Google for authentication", zap.String("url", authURL))
+	s.Logger.Info("Redirecting to Google for authentication")
 	c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
 // HandleCallback handles the OAuth 2.0 callback from Google.
-// It exchanges the authorization code for tokens.
+// It exchanges the authorization code for tokens, creates or updates the
+// user from their Google profile, stores the tokens and signs them in with a
+// session cookie. The tokens never leave the server.
 func (s *OAuthService) HandleCallback(c *gin.Context) {
 	stateFromQuery := c.Query("state")
 	code := c.Query("code")
 
-	// Validate state token from query against server-side store
-	if !s.validateStateToken(stateFromQuery) {
-		// As a fallback, check cookie if state wasn't in query (though it should be)
-		stateFromCookie, cookieErr := c.Cookie(stateCookieName)
-		if cookieErr != nil || !s.validateStateToken(stateFromCookie) {
-			s.Logger.Error("Invalid or missing state token during callback", zap.String("queryState", stateFromQuery), zap.String("cookieState", stateFromCookie))
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state token. Authentication failed."})
-			return
-		}
+	// The state must be the one issued to this browser, so nobody can sign
+	// a victim in with their own account, and still be in the server-side store
+	stateFromCookie, cookieErr := c.Cookie(stateCookieName)
+	if cookieErr != nil || stateFromQuery == "" ||
+		subtle.ConstantTimeCompare([]byte(stateFromQuery), []byte(stateFromCookie)) != 1 ||
+		!s.validateStateToken(stateFromQuery) {
+		s.Logger.Error("Invalid or missing state token during callback")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state token. Authentication failed."})
+		return
 	}
 	// Clear the cookie after use
-	c.SetCookie(stateCookieName, "", -1, "/api/auth/google", "localhost", false, true)
+	c.SetCookie(stateCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
 
 	if code == "" {
 		errorDesc := c.Query("error_description")
@@ -160,31 +199,70 @@ func (s *OAuthService) HandleCallback(c *gin.Context) {
 		return
 	}
 
-	s.Logger.Info("Received authorization code", zap.String("code", code))
-
 	// Exchange code for token
-	token, err := s.Config.Exchange(context.Background(), code)
+	ctx := c.Request.Context()
+	token, err := s.Config.Exchange(ctx, code)
 	if err != nil {
 		s.Logger.Error("Failed to exchange authorization code for token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange code for token. " + err.Error()})
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to exchange code for token. Please try again."})
 		return
 	}
-
 	s.Logger.Info("Successfully exchanged code for token",
-		zap.String("accessToken", token.AccessToken),
 		zap.Bool("hasRefreshToken", token.RefreshToken != ""),
 		zap.Time("expiry", token.Expiry),
 	)
 
-	// TODO: Securely store the token (token.AccessToken and token.RefreshToken if present)
-	// For now, just return a success message with the access token (for demonstration)
-	// In a real app, you would associate this token with a user session/ID.
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Authentication successful! Tokens obtained.",
-		"access_token":   token.AccessToken, // DO NOT expose this in production like this directly to client for general use
-		"refresh_token":  token.RefreshToken, // Especially DO NOT expose this. Store server-side only.
-		"token_type":     token.TokenType,
-		"expiry":         token.Expiry,
-		"scope_returned": token.Extra("scope"),
-	})
+	// Create or update the user from their Google profile
+	user, err := s.fetchUser(ctx, token)
+	if err != nil {
+		s.Logger.Error("Failed to read the Google profile", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read your Google profile. Please try again."})
+		return
+	}
+	if err := s.Users.SignIn(ctx, &user); err != nil {
+		s.Logger.Error("Failed to save user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in. Please try again."})
+		return
+	}
+	s.Logger.Info("User signed in", zap.String("userID", user.ID.Hex()))
+
+	// Keep the token for the Calendar and Gmail features
+	if err := s.saveToken(ctx, user.ID, token); err != nil {
+		s.Logger.Error("Failed to save token", zap.String("userID", user.ID.Hex()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in. Please try again."})
+		return
+	}
+
+	if s.Sessions != nil {
+		s.Sessions.Issue(c.Writer, c.Request, user.ID.Hex())
+	}
+	c.Redirect(http.StatusFound, signedInRedirect)
+}
+
+// userInfo is the profile returned by the OpenID Connect userinfo endpoint
+type userInfo struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// fetchUser reads the profile of the Google account token belongs to
+func (s *OAuthService) fetchUser(ctx context.Context, token *oauth2.Token) (models.User, error) {
+	resp, err := s.Config.Client(ctx, token).Get(s.UserInfoURL)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.User{}, fmt.Errorf("userinfo returned status %d", resp.StatusCode)
+	}
+	var info userInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return models.User{}, fmt.Errorf("decoding userinfo: %w", err)
+	}
+	if info.Subject == "" {
+		return models.User{}, fmt.Errorf("userinfo has no subject")
+	}
+	return models.User{GoogleID: info.Subject, Email: info.Email, Name: info.Name, Picture: info.Picture}, nil
 }
@@ -0,0 +1,338 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// standIn is a local stand-in for Google's OAuth endpoints
+type standIn struct {
+	mu        sync.Mutex
+	refreshes int
+	revoked   []string
+}
+
+// newTestService creates a service whose Google endpoints are served by a
+// local stand-in
+func newTestService(t *testing.T) (*OAuthService, *standIn) {
+	t.Helper()
+	stub := &standIn{}
+	google := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, r.ParseForm())
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		switch r.URL.Path {
+		case "/token":
+			switch {
+			case r.Form.Get("grant_type") == "refresh_token" && r.Form.Get("refresh_token") == "refresh-1":
+				// Google may rotate the refresh token
+				stub.refreshes++
+				fmt.Fprint(w, `{"access_token": "access-2", "refresh_token": "refresh-2", "token_type": "Bearer", "expires_in": 3600}`)
+			case r.Form.Get("code") == "valid-code":
+				fmt.Fprint(w, `{"access_token": "access-1", "refresh_token": "refresh-1", "token_type": "Bearer", "expires_in": 3600}`)
+			case r.Form.Get("code") == "returning-code":
+				// Access granted before comes without a refresh token
+				fmt.Fprint(w, `{"access_token": "access-1", "token_type": "Bearer", "expires_in": 3600}`)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			}
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer access-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"sub": "1234567890", "email": "ana@example.com", "name": "Ana", "picture": "https://example.com/ana.png"}`)
+		case "/revoke":
+			switch token := r.Form.Get("token"); token {
+			case "unavailable":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case "revoked":
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": "invalid_token", "error_description": "Token expired or revoked"}`)
+			default:
+				stub.revoked = append(stub.revoked, token)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(google.Close)
+
+	credentials, err := json.Marshal(map[string]interface{}{"web": map[string]interface{}{
+		"client_id":     "client",
+		"client_secret": "secret",
+		"auth_uri":      google.URL + "/auth",
+		"token_uri":     google.URL + "/token",
+		"redirect_uris": []string{"http://localhost:8080/api/auth/google/callback"},
+	}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, credentials, 0o600))
+
+	service, err := NewOAuthService(path, "http://localhost:8080/api/auth/google/callback", zap.NewNop(), []string{"https://www.googleapis.com/auth/calendar"})
+	require.NoError(t, err)
+	service.UserInfoURL = google.URL + "/userinfo"
+	service.RevokeURL = google.URL + "/revoke"
+	service.Sessions = session.NewManager([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	return service, stub
+}
+
+// login starts a sign-in and returns the state Google is sent and the
+// cookie that binds it to the browser
+func login(t *testing.T, router *gin.Engine) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Contains(t, location.Query().Get("scope"), "openid", "the user's profile is requested")
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == stateCookieName {
+			return location.Query().Get("state"), cookie
+		}
+	}
+	t.Fatal("Expected a state cookie")
+	return "", nil
+}
+
+// callback sends Google's redirect with query from a browser holding
+// cookie, which may be nil
+func callback(router *gin.Engine, query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/callback?"+query, nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandleCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _ := newTestService(t)
+	users := repositories.NewMemoryUserRepository()
+	service.Users = users
+	router := gin.New()
+	router.GET("/login", service.HandleLogin)
+	router.GET("/callback", service.HandleCallback)
+
+	state, stateCookie := login(t, router)
+	w := callback(router, "state="+url.QueryEscape(state)+"&code=valid-code", stateCookie)
+
+	// The user is signed in and sent to the app; no token reaches the browser
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	assert.Equal(t, "/", w.Header().Get("Location"))
+	assert.NotContains(t, w.Body.String(), "access-1")
+	assert.NotContains(t, w.Body.String(), "refresh-1")
+	var sessionCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		assert.NotContains(t, cookie.Value, "access-1")
+		if cookie.Name == session.CookieName {
+			sessionCookie = cookie
+		}
+	}
+	require.NotNil(t, sessionCookie)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(sessionCookie)
+	userID, err := service.Sessions.UserID(r)
+	require.NoError(t, err)
+	id, err := primitive.ObjectIDFromHex(userID)
+	require.NoError(t, err)
+	user, err := users.FindByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890", user.GoogleID)
+	assert.Equal(t, "ana@example.com", user.Email)
+	assert.Equal(t, "Ana", user.Name)
+
+	// The tokens are kept for the user
+	token, err := service.Tokens.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+
+	// Signing in again keeps the same user and their refresh token
+	state, stateCookie = login(t, router)
+	w = callback(router, "state="+url.QueryEscape(state)+"&code=returning-code", stateCookie)
+	require.Equal(t, http.StatusFound, w.Code)
+	r = httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range w.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	again, err := service.Sessions.UserID(r)
+	require.NoError(t, err)
+	assert.Equal(t, userID, again)
+	token, err = service.Tokens.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+}
+
+func TestHandleCallbackErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.GET("/login", service.HandleLogin)
+	router.GET("/callback", service.HandleCallback)
+
+	state, stateCookie := login(t, router)
+	otherState, otherCookie := login(t, router)
+	attackerState, _ := login(t, router)
+	tests := []struct {
+		name   string
+		query  string
+		cookie *http.Cookie
+		status int
+	}{
+		{"unknown state", "state=forged&code=valid-code", &http.Cookie{Name: stateCookieName, Value: "forged"}, http.StatusBadRequest},
+		{"state without its cookie", "state=" + url.QueryEscape(otherState) + "&code=valid-code", nil, http.StatusBadRequest},
+		{"state of another browser", "state=" + url.QueryEscape(attackerState) + "&code=valid-code", otherCookie, http.StatusBadRequest},
+		{"cookie without a state", "code=valid-code", otherCookie, http.StatusBadRequest},
+		{"rejected code", "state=" + url.QueryEscape(state) + "&code=stolen", stateCookie, http.StatusBadGateway},
+		{"reused state", "state=" + url.QueryEscape(state) + "&code=stolen", stateCookie, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := callback(router, tt.query, tt.cookie)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+		assert.False(t, strings.Contains(w.Header().Get("Set-Cookie"), session.CookieName), tt.name)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	service, google := newTestService(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	require.NoError(t, service.Tokens.Save(ctx, userID, &oauth2.Token{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(-time.Minute),
+	}))
+
+	source, err := service.TokenSource(ctx, userID)
+	require.NoError(t, err)
+	token, err := source.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token.AccessToken, "the expired token is refreshed")
+
+	// The refreshed token and rotated refresh token are saved
+	stored, err := service.Tokens.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", stored.AccessToken)
+	assert.Equal(t, "refresh-2", stored.RefreshToken)
+
+	// A valid token is reused
+	_, err = source.Token()
+	require.NoError(t, err)
+	again, err := service.TokenSource(ctx, userID)
+	require.NoError(t, err)
+	token, err = again.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token.AccessToken)
+	assert.Equal(t, 1, google.refreshes)
+
+	_, err = service.TokenSource(ctx, primitive.NewObjectID())
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestTokenSourceUnreadable(t *testing.T) {
+	service, google := newTestService(t)
+	ctx := context.Background()
+	tokens := repositories.NewMemoryTokenStore()
+	service.Tokens = unreadableStore{tokens}
+	userID := primitive.NewObjectID()
+	require.NoError(t, tokens.Save(ctx, userID, &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"}))
+
+	// Tokens sealed with a previous key count as not connected
+	_, err := service.TokenSource(ctx, userID)
+	assert.ErrorIs(t, err, ErrNotConnected)
+
+	// They cannot be revoked, only deleted
+	require.NoError(t, service.Revoke(ctx, userID))
+	assert.Empty(t, google.revoked)
+	_, err = tokens.Get(ctx, userID)
+	assert.ErrorIs(t, err, repositories.ErrTokenNotFound)
+}
+
+// unreadableStore fails to decrypt the tokens it stores
+type unreadableStore struct {
+	repositories.TokenStore
+}
+
+func (s unreadableStore) Get(ctx context.Context, userID primitive.ObjectID) (*oauth2.Token, error) {
+	if _, err := s.TokenStore.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: message authentication failed", repositories.ErrTokenUnreadable)
+}
+
+func TestHandleDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, google := newTestService(t)
+	router := gin.New()
+	router.POST("/disconnect", service.HandleDisconnect)
+	ctx := context.Background()
+
+	disconnect := func(userID string) int {
+		r := httptest.NewRequest("POST", "/disconnect", nil)
+		if userID != "" {
+			w := httptest.NewRecorder()
+			service.Sessions.Issue(w, r, userID)
+			r.AddCookie(w.Result().Cookies()[0])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+	connect := func(refreshToken string) primitive.ObjectID {
+		userID := primitive.NewObjectID()
+		require.NoError(t, service.Tokens.Save(ctx, userID, &oauth2.Token{AccessToken: "access-1", RefreshToken: refreshToken}))
+		return userID
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, disconnect(""))
+
+	// The refresh token is revoked at Google and deleted
+	userID := connect("refresh-1")
+	assert.Equal(t, http.StatusNoContent, disconnect(userID.Hex()))
+	assert.Equal(t, []string{"refresh-1"}, google.revoked)
+	_, err := service.Tokens.Get(ctx, userID)
+	assert.ErrorIs(t, err, repositories.ErrTokenNotFound)
+
+	// Disconnecting again does nothing
+	assert.Equal(t, http.StatusNoContent, disconnect(userID.Hex()))
+	assert.Len(t, google.revoked, 1)
+
+	// A token the user already revoked at Google is deleted
+	userID = connect("revoked")
+	assert.Equal(t, http.StatusNoContent, disconnect(userID.Hex()))
+	_, err = service.Tokens.Get(ctx, userID)
+	assert.ErrorIs(t, err, repositories.ErrTokenNotFound)
+
+	// The token is kept when Google cannot revoke it
+	userID = connect("unavailable")
+	assert.Equal(t, http.StatusBadGateway, disconnect(userID.Hex()))
+	_, err = service.Tokens.Get(ctx, userID)
+	assert.NoError(t, err)
+}
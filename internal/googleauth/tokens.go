@@ -0,0 +1,183 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// googleRevokeURL revokes a token and every token granted with it
+	googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+	// tokenSaveTimeout bounds saving a refreshed token, which happens even
+	// when the request that needed it has ended
+	tokenSaveTimeout = 10 * time.Second
+)
+
+// ErrNotConnected is returned for users whose Google account is not connected
+var ErrNotConnected = errors.New("google account not connected")
+
+// saveToken stores the token of a user who signed in. Google only returns a
+// refresh token the first time access is granted, so a token without one
+// keeps the stored refresh token.
+func (s *OAuthService) saveToken(ctx context.Context, userID primitive.ObjectID, token *oauth2.Token) error {
+	if token.RefreshToken == "" {
+		stored, err := s.Tokens.Get(ctx, userID)
+		if err == nil {
+			token.RefreshToken = stored.RefreshToken
+		} else if !errors.Is(err, repositories.ErrTokenNotFound) {
+			s.Logger.Warn("Failed to read the stored token; keeping the new one only",
+				zap.String("userID", userID.Hex()), zap.Error(err))
+		}
+	}
+	return s.Tokens.Save(ctx, userID, token)
+}
+
+// TokenSource returns the Google token of a user for calling Google APIs,
+// e.g. with oauth2.NewClient. The token is refreshed when it expires and the
+// refreshed token is saved, including a rotated refresh token. ctx carries
+// the refresh requests. ErrNotConnected is returned if the user has no token.
+func (s *OAuthService) TokenSource(ctx context.Context, userID primitive.ObjectID) (oauth2.TokenSource, error) {
+	token, err := s.Tokens.Get(ctx, userID)
+	if errors.Is(err, repositories.ErrTokenNotFound) || errors.Is(err, repositories.ErrTokenUnreadable) {
+		// A token sealed with a previous key is replaced when the user signs in again
+		return nil, ErrNotConnected
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading token: %w", err)
+	}
+	saving := &savingTokenSource{
+		ctx:    ctx,
+		base:   s.Config.TokenSource(ctx, token),
+		tokens: s.Tokens,
+		userID: userID,
+		logger: s.Logger,
+		last:   token,
+	}
+	return oauth2.ReuseTokenSource(token, saving), nil
+}
+
+// savingTokenSource saves every new token its base source returns
+type savingTokenSource struct {
+	ctx    context.Context
+	base   oauth2.TokenSource
+	tokens repositories.TokenStore
+	userID primitive.ObjectID
+	logger *zap.Logger
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+// Token returns the base source's token, saving it if it changed
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken == s.last.AccessToken && token.RefreshToken == s.last.RefreshToken {
+		return token, nil
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), tokenSaveTimeout)
+	defer cancel()
+	if err := s.tokens.Save(ctx, s.userID, token); err != nil {
+		// The token is valid; it is saved again on the next refresh
+		s.logger.Error("Failed to save refreshed token", zap.String("userID", s.userID.Hex()), zap.Error(err))
+		return token, nil
+	}
+	s.logger.Debug("Saved refreshed token", zap.String("userID", s.userID.Hex()),
+		zap.Bool("rotated", token.RefreshToken != s.last.RefreshToken))
+	s.last = token
+	return token, nil
+}
+
+// Revoke revokes the Google access granted by a user and deletes their
+// token. The token is kept if Google cannot be reached, so it can be
+// revoked later; users without a token are already disconnected.
+func (s *OAuthService) Revoke(ctx context.Context, userID primitive.ObjectID) error {
+	token, err := s.Tokens.Get(ctx, userID)
+	if errors.Is(err, repositories.ErrTokenNotFound) {
+		return nil
+	}
+	if errors.Is(err, repositories.ErrTokenUnreadable) {
+		// It cannot be revoked without the key it was sealed with
+		return s.Tokens.Delete(ctx, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("reading token: %w", err)
+	}
+
+	// Revoking the refresh token also revokes the access tokens granted with it
+	revoke := token.RefreshToken
+	if revoke == "" {
+		revoke = token.AccessToken
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.RevokeURL, strings.NewReader(url.Values{"token": {revoke}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && !alreadyRevoked(resp) {
+		return fmt.Errorf("revoking token: status %d", resp.StatusCode)
+	}
+	return s.Tokens.Delete(ctx, userID)
+}
+
+// alreadyRevoked reports whether Google refused to revoke a token because it
+// is no longer valid, e.g. the user removed the access from their account
+func alreadyRevoked(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return json.Unmarshal(data, &body) == nil && body.Error == "invalid_token"
+}
+
+// HandleDisconnect revokes the Google access of the signed-in user and
+// deletes their stored token. They stay signed in.
+func (s *OAuthService) HandleDisconnect(c *gin.Context) {
+	if s.Sessions == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	id, err := s.Sessions.UserID(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	if err := s.Revoke(c.Request.Context(), userID); err != nil {
+		s.Logger.Error("Failed to disconnect Google account", zap.String("userID", id), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to disconnect your Google account. Please try again."})
+		return
+	}
+	s.Logger.Info("Google account disconnected", zap.String("userID", id))
+	c.Status(http.StatusNoContent)
+}
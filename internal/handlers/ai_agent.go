@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/usage"
+)
+
+// AgentRequest is a message for the task assistant.
+// History holds earlier user and assistant messages of the conversation.
+type AgentRequest struct {
+	Message string       `json:"message" binding:"required"`
+	History []ai.Message `json:"history"`
+	// ProjectID is the project the message is about; its token usage
+	// counts toward the project's budget
+	ProjectID string `json:"project_id"`
+}
+
+// AgentResponse is the agent's answer and the system prompt template it was given
+type AgentResponse struct {
+	ai.AgentResult
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// AgentConfirmRequest approves or rejects a pending action
+type AgentConfirmRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// taskAgent returns the task assistant, creating it on first use
+func (h *Handlers) taskAgent() (*ai.Agent, error) {
+	h.agentMu.Lock()
+	defer h.agentMu.Unlock()
+	if h.agent == nil {
+		registry := ai.NewToolRegistry()
+		taskTools := &tools.TaskTools{Tasks: h.tasks, Projects: h.projects}
+		if err := taskTools.Register(registry); err != nil {
+			log.Printf("Error registering task tools: %v", err)
+		}
+		model, err := h.modelChain(config.ModelAgent, config.ModelAssistant, ai.RouteHints{})
+		if err != nil {
+			return nil, err
+		}
+		h.agent = ai.NewAgent(model, registry, model.Primary().Config.Model)
+	}
+	return h.agent, nil
+}
+
+// RunTaskAgent answers a message using tools that read and modify tasks.
+// Changes are not applied directly: the response carries a pending_action
+// that must be confirmed through ConfirmAgentAction.
+func (h *Handlers) RunTaskAgent(c *gin.Context) {
+	var request AgentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados. Verifica que has incluido un mensaje."})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpAIText)
+	defer cancel()
+	ctx, ok := h.accountUsage(ctx, c, request.ProjectID)
+	if !ok {
+		return
+	}
+
+	// The template tells the model what day it is, so relative dates such
+	// as "el viernes" can be resolved
+	messages, promptVersion := h.systemPrompt(prompts.Agent, h.promptVars(ctx, c, request.ProjectID))
+	ctx = usage.WithPrompt(ctx, promptVersion)
+	for _, m := range request.History {
+		// Only plain conversation turns are accepted from the client
+		if m.Role == "user" || m.Role == "assistant" {
+			messages = append(messages, ai.Message{Role: m.Role, Content: m.Content})
+		}
+	}
+	messages = append(messages, ai.Message{Role: "user", Content: request.Message})
+
+	agent, err := h.taskAgent()
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	result, err := agent.Run(ctx, sessionOwner(c), messages)
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, AgentResponse{AgentResult: result, PromptVersion: promptVersion})
+}
+
+// ConfirmAgentAction approves or rejects a pending action and continues the conversation
+func (h *Handlers) ConfirmAgentAction(c *gin.Context) {
+	var request AgentConfirmRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados."})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpAIText)
+	defer cancel()
+	ctx, ok := h.accountUsage(ctx, c, "")
+	if !ok {
+		return
+	}
+
+	agent, err := h.taskAgent()
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	result, err := agent.Confirm(ctx, sessionOwner(c), c.Param("id"), request.Approve)
+	if err != nil {
+		respondAgentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// respondAgentError maps agent errors to HTTP responses
+func respondAgentError(c *gin.Context, err error) {
+	var apiErr *ai.APIError
+	switch {
+	case errors.Is(err, ai.ErrPendingActionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "La acción ya no está disponible. Por favor, vuelve a pedirla."})
+	case errors.Is(err, ai.ErrPendingActionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Solo quien pidió esta acción puede confirmarla."})
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "El asistente tardó demasiado en responder. Por favor, intenta de nuevo."})
+	case errors.Is(err, context.Canceled):
+		log.Printf("Agent request cancelled by client")
+	case errors.Is(err, ai.ErrToolLoopLimit):
+		log.Printf("Agent stopped: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No pude completar la solicitud. Intenta formularla de otra manera."})
+	case fallbackable(err), errors.As(err, &apiErr):
+		log.Printf("Agent model error: %v", err)
+		status, message := modelErrorResponse(err, false)
+		c.JSON(status, gin.H{"error": message})
+	default:
+		log.Printf("Error running task agent: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en el procesamiento de la consulta. Intenta reformularla."})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/usage"
+)
+
+const (
+	// maxBatchItems bounds the queries of one batch request
+	maxBatchItems = 50
+	// maxBatchQuery bounds the characters of each query of a batch
+	maxBatchQuery = 8000
+)
+
+// BatchRequest asks the assistant many independent questions at once, such
+// as summarizing every task of a project or translating descriptions
+type BatchRequest struct {
+	// Instruction is applied to every item, e.g. "Traduce al inglés"
+	Instruction string      `json:"instruction"`
+	Items       []BatchItem `json:"items" binding:"required,min=1,dive"`
+	// ModelType is the logical model to use, summary by default
+	ModelType string `json:"model_type"`
+	// ProjectID is the project the items belong to; its budget is charged
+	ProjectID string `json:"project_id"`
+}
+
+// BatchItem is one query of a batch
+type BatchItem struct {
+	// ID is echoed in the item's result, e.g. a task ID
+	ID    string `json:"id"`
+	Query string `json:"query" binding:"required"`
+}
+
+// BatchItemResult is the answer to one item, or why it failed
+type BatchItemResult struct {
+	ID       string `json:"id,omitempty"`
+	Response string `json:"response,omitempty"`
+	Cached   bool   `json:"cached,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchResponse holds the results of a batch in the order of its items
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	// Failed counts the items that have an error instead of a response
+	Failed        int    `json:"failed"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	UsageWarning  string `json:"usage_warning,omitempty"`
+}
+
+// GenerateBatch answers the items of a batch concurrently. Items that fail
+// carry their own error; the request fails only when every item does.
+func (h *Handlers) GenerateBatch(c *gin.Context) {
+	var request BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados. Incluye al menos una consulta en items."})
+		return
+	}
+	if len(request.Items) > maxBatchItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Demasiadas consultas: se pueden enviar hasta 50 a la vez."})
+		return
+	}
+	for _, item := range request.Items {
+		if strings.TrimSpace(item.Query) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados. Ninguna consulta puede estar vacía."})
+			return
+		}
+		if utf8.RuneCountInString(item.Query) > maxBatchQuery {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Una de las consultas es demasiado larga. Divídela en partes más pequeñas."})
+			return
+		}
+	}
+
+	ctx, cancel := operationContext(c, config.OpAIBatch)
+	defer cancel()
+	ctx, ok := h.accountUsage(ctx, c, request.ProjectID)
+	if !ok {
+		return
+	}
+
+	system, promptVersion := h.systemPrompt(prompts.AssistantNoThink, h.promptVars(ctx, c, request.ProjectID))
+	ctx = usage.WithPrompt(ctx, promptVersion)
+	if instruction := strings.TrimSpace(request.Instruction); instruction != "" {
+		system = append(system, ai.Message{Role: "system", Content: instruction})
+	}
+
+	model, err := h.modelChain(request.ModelType, config.ModelSummary, ai.RouteHints{NoThink: true})
+	if err != nil {
+		log.Printf("Error getting model for batch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en el procesamiento de la consulta. Intenta reformularla."})
+		return
+	}
+
+	requests := make([]ai.BatchRequest, len(request.Items))
+	for i, item := range request.Items {
+		requests[i] = ai.BatchRequest{ID: item.ID, Query: item.Query, Context: system}
+	}
+	response := BatchResponse{Results: make([]BatchItemResult, len(requests)), PromptVersion: promptVersion}
+	status := http.StatusOK
+	for i, result := range model.GenerateBatch(ctx, requests) {
+		response.Results[i] = BatchItemResult{ID: result.ID, Response: result.Response, Cached: result.Cached}
+		if result.Err != nil {
+			log.Printf("Batch item %d of %d failed: %v", i+1, len(requests), result.Err)
+			response.Failed++
+			status, response.Results[i].Error = batchErrorResponse(result.Err)
+		}
+	}
+	if response.Failed < len(requests) {
+		status = http.StatusOK
+	}
+	response.UsageWarning = usageWarning(c)
+	c.JSON(status, response)
+}
+
+// batchErrorResponse returns the status and user-facing message for the
+// failure of a batch item
+func batchErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "El asistente tardó demasiado en responder. Por favor, intenta de nuevo."
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "La consulta fue cancelada."
+	default:
+		return modelErrorResponse(err, false)
+	}
+}
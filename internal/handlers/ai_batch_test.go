@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveBatch runs a request through the batch route
+func serveBatch(h *Handlers, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ai/batch", h.GenerateBatch)
+
+	req := httptest.NewRequest("POST", "/api/ai/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestGenerateBatch tests answering a batch with per-item failures
+func TestGenerateBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		query := request.Messages[len(request.Messages)-1].Content
+		if query == "falla" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "Traduce al inglés", request.Messages[len(request.Messages)-2].Content)
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": "%s"}, "finish_reason": "stop"}]}`, strings.ToUpper(query))
+	}))
+	defer server.Close()
+
+	factory, err := ai.NewModelFactoryFromConfig(config.DefaultLLMConfig().Merge(config.LLMConfig{
+		Providers: map[string]config.ProviderConfig{"local": {Type: config.ProviderOpenAI, Endpoint: server.URL}},
+		Models:    map[string]config.ModelConfig{config.ModelSummary: {Provider: "local", Model: "local-model"}},
+	}))
+	require.NoError(t, err)
+	factory.SetResponseCache(nil, 0)
+	h := New(Dependencies{Models: factory})
+
+	assert.Equal(t, http.StatusBadRequest, serveBatch(h, `{"items": []}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveBatch(h, `{"items": [{"query": "  "}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveBatch(h, `{"items": [`+strings.Repeat(`{"query": "a"},`, maxBatchItems)+`{"query": "a"}]}`).Code)
+
+	w := serveBatch(h, `{"instruction": "Traduce al inglés", "items": [{"id": "t1", "query": "muro"}, {"id": "t2", "query": "falla"}, {"id": "t3", "query": "losa"}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response BatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Failed)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, BatchItemResult{ID: "t1", Response: "MURO"}, response.Results[0])
+	assert.Equal(t, "t2", response.Results[1].ID)
+	assert.NotEmpty(t, response.Results[1].Error)
+	assert.Equal(t, BatchItemResult{ID: "t3", Response: "LOSA"}, response.Results[2])
+	assert.Equal(t, "assistant_no_think@1", response.PromptVersion)
+
+	// The request fails when every item does
+	w = serveBatch(h, `{"items": [{"query": "falla"}]}`)
+	assert.Equal(t, http.StatusBadGateway, w.Code, w.Body.String())
+}
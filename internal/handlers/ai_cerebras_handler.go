@@ -5,7 +5,8 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -19,21 +20,36 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/usage"
 )
 
 // CerebrasAIRequest represents an incoming request to the Cerebras AI assistant
 type CerebrasAIRequest struct {
 	Query     string                `form:"query" binding:"required"`
-	ModelType string                `form:"model_type" binding:"required"`
+	ModelType string                `form:"model_type"`
 	Image     *multipart.FileHeader `form:"image"`
+	// ConversationID continues an existing conversation; empty starts a new one
+	ConversationID string `form:"conversation_id"`
+	// ProjectID is the project the question is about; its token usage
+	// counts toward the project's budget
+	ProjectID string `form:"project_id"`
 }
 
 // CerebrasAIResponse represents the response from the Cerebras AI assistant
 type CerebrasAIResponse struct {
-	Response     string  `json:"response"`
-	HasImage     bool    `json:"has_image,omitempty"`
-	FromCache    bool    `json:"from_cache,omitempty"`
-	ResponseTime float64 `json:"response_time_ms,omitempty"`
+	Response       string  `json:"response"`
+	ConversationID string  `json:"conversation_id,omitempty"`
+	HasImage       bool    `json:"has_image,omitempty"`
+	FromCache      bool    `json:"from_cache,omitempty"`
+	ResponseTime   float64 `json:"response_time_ms,omitempty"`
+	// UsageWarning is set when a usage budget is nearly used up
+	UsageWarning string `json:"usage_warning,omitempty"`
+	// Sources are the passages and tasks the answer cites as [n]
+	Sources []AnswerSource `json:"sources,omitempty"`
+	// PromptVersion is the system prompt template used, e.g. "assistant@2"
+	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
 // CerebrasHealthResponse represents the health check response
@@ -57,16 +73,27 @@ type CerebrasStatsResponse struct {
 // UserRateLimiter manages rate limiting per IP
 type UserRateLimiter struct {
 	limiters map[string]*rate.Limiter
+	limit    rate.Limit
+	burst    int
 	mu       sync.RWMutex
 }
 
-// NewUserRateLimiter creates a new rate limiter manager
-func NewUserRateLimiter() *UserRateLimiter {
+// NewUserRateLimiter creates a new rate limiter manager that allows each IP
+// perMinute requests per minute with bursts of burst
+func NewUserRateLimiter(perMinute, burst int) *UserRateLimiter {
 	return &UserRateLimiter{
 		limiters: make(map[string]*rate.Limiter),
+		limit:    rate.Limit(float64(perMinute) / 60),
+		burst:    burst,
 	}
 }
 
+// SetRateLimit sets the requests per minute and burst each IP may send to
+// the assistant, replacing the limiters of every IP
+func SetRateLimit(perMinute, burst int) {
+	rateLimiter = NewUserRateLimiter(perMinute, burst)
+}
+
 // GetLimiter gets or creates a rate limiter for a given IP
 func (u *UserRateLimiter) GetLimiter(ip string) *rate.Limiter {
 	u.mu.RLock()
@@ -74,21 +101,24 @@ func (u *UserRateLimiter) GetLimiter(ip string) *rate.Limiter {
 	u.mu.RUnlock()
 
 	if !exists {
-		// Create a new limiter that allows 5 requests per minute with a burst of 10
-		limiter = rate.NewLimiter(rate.Limit(5/60.0), 10)
 		u.mu.Lock()
-		u.limiters[ip] = limiter
+		if limiter, exists = u.limiters[ip]; !exists {
+			limiter = rate.NewLimiter(u.limit, u.burst)
+			u.limiters[ip] = limiter
+		}
 		u.mu.Unlock()
 	}
 
 	return limiter
 }
 
+// circuitOpenMessage is shown while the circuit breaker rejects requests
+const circuitOpenMessage = "El servicio de IA está experimentando problemas temporales. Por favor, intenta de nuevo en unos minutos."
+
 // Global instances
 var (
-	cerebrasClient *ai.CerebrasClient
-	clientOnce     sync.Once
-	rateLimiter    = NewUserRateLimiter()
+	// By default each IP may ask 5 questions per minute with a burst of 10
+	rateLimiter = NewUserRateLimiter(5, 10)
 
 	// Statistics for monitoring
 	requestCount    int64
@@ -99,32 +129,27 @@ var (
 	statsMutex      sync.RWMutex
 )
 
-// getCerebrasClient returns the Cerebras client, initializing it if needed
-func getCerebrasClient() *ai.CerebrasClient {
-	clientOnce.Do(func() {
-		cerebrasClient = ai.NewCerebrasClient()
-	})
-	return cerebrasClient
-}
-
-// RegisterCerebrasRoutes registers all Cerebras AI-related routes
-func RegisterCerebrasRoutes(router *gin.Engine) {
+// RegisterCerebrasRoutes registers all Cerebras AI-related routes under
+// /cerebras of the API group router
+func (h *Handlers) RegisterCerebrasRoutes(router gin.IRouter) {
 	// AI assistant endpoint
-	router.POST("/api/cerebras/assistant", GetCerebrasAIAssistance)
+	router.POST("/cerebras/assistant", h.GetCerebrasAIAssistance)
+	router.GET("/cerebras/assistant/stream", h.StreamCerebrasAIAssistance)
+	router.POST("/cerebras/assistant/stream", h.StreamCerebrasAIAssistance)
 
 	// Monitoring endpoints
-	router.GET("/api/cerebras/health", GetCerebrasHealth)
-	router.GET("/api/cerebras/metrics", gin.WrapH(promhttp.Handler()))
-	router.GET("/api/cerebras/stats", GetCerebrasStats)
+	router.GET("/cerebras/health", h.GetCerebrasHealth)
+	router.GET("/cerebras/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/cerebras/stats", h.GetCerebrasStats)
 }
 
 // GetCerebrasHealth provides health check information
-func GetCerebrasHealth(c *gin.Context) {
+func (h *Handlers) GetCerebrasHealth(c *gin.Context) {
 	// Simple health check that confirms API client is initialized
 	apiStatus := "healthy"
 
-	// Check if API key is configured
-	if cerebrasClient.GetAPIStatus() != "ok" {
+	// Check that the assistant's provider is configured
+	if chain, err := h.modelChain(config.ModelAssistant, config.ModelAssistant, ai.RouteHints{}); err != nil || providerStatus(chain.Primary().Provider) != "ok" {
 		apiStatus = "degraded"
 	}
 
@@ -137,7 +162,7 @@ func GetCerebrasHealth(c *gin.Context) {
 }
 
 // GetCerebrasStats provides cache and performance statistics
-func GetCerebrasStats(c *gin.Context) {
+func (h *Handlers) GetCerebrasStats(c *gin.Context) {
 	statsMutex.RLock()
 	defer statsMutex.RUnlock()
 
@@ -152,14 +177,23 @@ func GetCerebrasStats(c *gin.Context) {
 		avgResponseTime = totalResponseMs / float64(requestCount)
 	}
 
-	client := getCerebrasClient()
+	cacheSize := 0
+	if responseCache := h.models.ResponseCache(); responseCache != nil {
+		if size, err := responseCache.Len(c.Request.Context()); err == nil {
+			cacheSize = size
+		}
+	}
+	circuitState := ai.CircuitClosed
+	if chain, err := h.modelChain(config.ModelAssistant, config.ModelAssistant, ai.RouteHints{}); err == nil {
+		circuitState = chain.Primary().Provider.GetCircuitState()
+	}
 	c.JSON(http.StatusOK, CerebrasStatsResponse{
-		CacheSize:       client.GetCacheSize(),
+		CacheSize:       cacheSize,
 		CacheHitRate:    hitRate,
 		AvgResponseTime: avgResponseTime,
 		RequestCount:    requestCount,
 		ErrorCount:      errorCount,
-		CircuitState:    client.GetCircuitState(),
+		CircuitState:    circuitState,
 	})
 }
 
@@ -192,9 +226,11 @@ func getEnvironment() string {
 	return env
 }
 
-// GetCerebrasAIAssistance handles requests to the Cerebras AI assistant
-// Supports both text models (QWen-3B-32B) and vision models (QWen-2.5-Vision)
-func GetCerebrasAIAssistance(c *gin.Context) {
+// GetCerebrasAIAssistance handles requests to the Cerebras AI assistant.
+// model_type names a configured model and defaults to "assistant". Requests
+// with an image are validated, downscaled if needed and answered by the
+// "vision" model.
+func (h *Handlers) GetCerebrasAIAssistance(c *gin.Context) {
 	startTime := time.Now()
 	fromCache := false
 	var responseTimeMs float64
@@ -226,56 +262,80 @@ func GetCerebrasAIAssistance(c *gin.Context) {
 	modelType := request.ModelType
 
 	if modelType == "" {
-		modelType = "qwen-3-32b" // Default to text model
+		modelType = config.ModelAssistant
 	}
 
 	// Log request info
 	log.Printf("Processing AI request: model=%s, query_length=%d", modelType, len(query))
 
 	// Check for /no_think command
-	isNoThink := false
-	if strings.HasPrefix(strings.ToLower(query), "/no_think") {
-		isNoThink = true
-		// Remove the command from the query
-		query = strings.TrimSpace(query[9:])
-		log.Printf("No-think mode detected, processing query: %s", query)
+	query, isNoThink := parseNoThinkCommand(query)
+
+	// Questions with an image are routed to the vision model
+	var image *ai.PreparedImage
+	op := config.OpAIText
+	if request.Image != nil {
+		var ok bool
+		if image, ok = readUploadedImage(c, request.Image); !ok {
+			updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+			return
+		}
+		op = config.OpAIVision
 	}
 
-	// System message for context with enhanced architectural domain knowledge
-	noThinkInstructions := ""
-	responseStyleInstructions := "Responde siempre en español con terminología técnica precisa."
-	technicalInfoInstructions := "Cuando proporciones información técnica, incluye referencias a códigos específicos, ejemplos prácticos y consideraciones para el contexto colombiano."
+	// Abandon storage and model calls if the client disconnects
+	ctx, cancel := operationContext(c, op)
+	defer cancel()
 
-	// Adjust instructions for no_think mode
-	if isNoThink {
-		noThinkInstructions = "Estás en modo respuesta directa. Responde con la información precisa sin explicaciones adicionales, usando el mínimo de palabras posible."
-		responseStyleInstructions = "Responde solo con datos concretos sin introducción ni explicación."
-		technicalInfoInstructions = ""
+	// Count the tokens against the caller's budgets
+	ctx, ok := h.accountUsage(ctx, c, request.ProjectID)
+	if !ok {
+		updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+		return
+	}
+
+	// Create system context and record its template version with the usage
+	systemContext, promptVersion := h.buildSystemContext(image != nil, isNoThink, h.promptVars(ctx, c, request.ProjectID))
+	ctx = usage.WithPrompt(ctx, promptVersion)
+
+	// Continue the conversation, summarising old turns that exceed the history budget
+	conv, ok := h.openConversation(ctx, c, request.ConversationID, query)
+	if !ok {
+		return
+	}
+	h.compactHistory(ctx, &conv)
+
+	// Ground text questions in the documents and tasks they relate to
+	var sources []AnswerSource
+	if image == nil {
+		var grounding []ai.Message
+		grounding, sources = h.groundingContext(ctx, query, request.ProjectID)
+		systemContext = append(systemContext, grounding...)
+	}
+	prompt := conversationPrompt(systemContext, conv)
+	if image != nil {
+		prompt = append(prompt, ai.ImageMessage(query, image.MIMEType, image.Base64()))
+	} else {
+		prompt = append(prompt, ai.Message{Role: "user", Content: query})
 	}
 
-	// Construct the system prompt
-	systemPrompt := fmt.Sprintf(
-		"Eres un asistente especializado en arquitectura para la plataforma ana.world de gestión de proyectos arquitectónicos. %s%sTu conocimiento incluye: 1) Normativas colombianas: NSR-10 (Norma Sismo Resistente), POT de Bogotá, Decreto 1077 de 2015, normas urbanísticas locales; 2) Diseño arquitectónico: metodología BIM, diseño paramétrico, estilos arquitectónicos latinoamericanos, soluciones para clima tropical; 3) Gestión de proyectos: metodologías PMI/PRINCE2 adaptadas a construcción, control de cronogramas, gestión de contratistas, licencias de construcción; 4) Materiales sostenibles: guadua, tierra compactada, sistemas pasivos de climatización, certificación LEED/EDGE para Colombia; 5) Presupuestos: estimación de costos por m², control de presupuestos, análisis de precios unitarios (APU). %s Si te preguntan en inglés, comprende la consulta pero responde en español.",
-		noThinkInstructions,
-		responseStyleInstructions,
-		technicalInfoInstructions,
-	)
-
-	// Create system context
-	systemContext := []ai.Message{
-		{
-			Role:    "system",
-			Content: systemPrompt,
-		},
+	// Pick the model by request type; its fallbacks answer if it is unavailable
+	model, err := h.modelChain(modelType, config.ModelAssistant, ai.RouteHints{
+		Image:        image != nil,
+		NoThink:      isNoThink,
+		PromptTokens: ai.EstimateTokens(prompt),
+	})
+	if err != nil {
+		log.Printf("Error resolving model %s: %v", modelType, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": modelErrorMessage(ai.ErrMissingAPIKey, image != nil)})
+		updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+		return
 	}
 
 	// Check circuit breaker state before making request
-	client := getCerebrasClient()
-	if err := client.CheckCircuitBreaker(); err != nil {
+	if err := model.CheckCircuitBreaker(); err != nil {
 		log.Printf("Circuit breaker is open: %v", err)
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "El servicio de IA está experimentando problemas temporales. Por favor, intenta de nuevo en unos minutos.",
-		})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": circuitOpenMessage})
 
 		// Update stats
 		responseTimeMs = float64(time.Since(startTime).Milliseconds())
@@ -283,11 +343,8 @@ func GetCerebrasAIAssistance(c *gin.Context) {
 		return
 	}
 
-	// Use text model only
-	modelName := "qwen-3-32b"
-
-	// Check cache first
-	cachedResponse, isCached := client.GetCachedResponse(modelName, systemContext)
+	// Check cache first; answers about images are not cached
+	cachedResponse, isCached := model.CachedAnswer(ctx, prompt)
 	if isCached {
 		response = cachedResponse
 		fromCache = true
@@ -317,27 +374,81 @@ func GetCerebrasAIAssistance(c *gin.Context) {
 			response = shortestRelevantLine
 		}
 	} else {
-		// Generate new response
-		var err error
-		response, err = client.GenerateTextResponse(query, modelName, systemContext)
+		// Generate new response, abandoning it if the client disconnects
+		if image != nil {
+			log.Printf("Sending %s image (%dx%d) to the vision model", image.MIMEType, image.Width, image.Height)
+		}
+		response, err = model.Answer(ctx, prompt)
 		if err != nil {
-			log.Printf("Error getting text response: %v", err)
-			errorMsg := "Error en el procesamiento de la consulta. Intenta reformularla."
-
-			// Record failure in circuit breaker
-			client.RecordFailure()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": errorMsg})
+			if errors.Is(err, context.Canceled) {
+				log.Printf("AI request cancelled by client")
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("AI request timed out: %v", err)
+				responseTimeMs = float64(time.Since(startTime).Milliseconds())
+				updateStats(false, responseTimeMs, true)
+				c.JSON(http.StatusGatewayTimeout, gin.H{"error": "El asistente tardó demasiado en responder. Por favor, intenta de nuevo."})
+				return
+			}
+			log.Printf("Error getting AI response from %s: %v", model.Primary().Label(), err)
+			responseTimeMs = float64(time.Since(startTime).Milliseconds())
+			updateStats(false, responseTimeMs, true)
+			status, message := modelErrorResponse(err, image != nil)
+			c.JSON(status, gin.H{"error": message})
 			return
 		}
 	}
 
+	// Store the exchange; the answer is still returned if that fails
+	conversationID := conv.ID.Hex()
+	question := query
+	if image != nil {
+		question += "\n\n[Imagen adjunta: " + request.Image.Filename + "]"
+	}
+	if err := h.saveExchange(ctx, &conv, question, response); err != nil {
+		log.Printf("Error saving conversation %s: %v", conversationID, err)
+		conversationID = ""
+	}
+
 	// Return the response
 	responseTimeMs = float64(time.Since(startTime).Milliseconds())
 	updateStats(fromCache, responseTimeMs, false)
 
 	c.JSON(http.StatusOK, CerebrasAIResponse{
-		Response:     response,
-		FromCache:    fromCache,
-		ResponseTime: responseTimeMs,
+		Response:       response,
+		ConversationID: conversationID,
+		HasImage:       image != nil,
+		FromCache:      fromCache,
+		ResponseTime:   responseTimeMs,
+		UsageWarning:   usageWarning(c),
+		Sources:        sources,
+		PromptVersion:  promptVersion,
 	})
 }
+
+// parseNoThinkCommand strips a leading /no_think command from the query and
+// reports whether it was present
+func parseNoThinkCommand(query string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(query), "/no_think") {
+		return query, false
+	}
+	query = strings.TrimSpace(query[9:])
+	log.Printf("No-think mode detected, processing query: %s", query)
+	return query, true
+}
+
+// buildSystemContext returns the system message with the architectural domain
+// knowledge used by the assistant, rendered from the vision, no_think or
+// assistant template, and the template version. Specific regulations are
+// cited from the sources added by groundingContext.
+func (h *Handlers) buildSystemContext(hasImage, isNoThink bool, vars prompts.Vars) ([]ai.Message, string) {
+	name := prompts.Assistant
+	switch {
+	case hasImage:
+		name = prompts.Vision
+	case isNoThink:
+		name = prompts.AssistantNoThink
+	}
+	return h.systemPrompt(name, vars)
+}
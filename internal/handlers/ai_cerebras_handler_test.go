@@ -17,6 +17,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
 )
 
 // MockCerebrasClient is a mock implementation of the Cerebras client for testing
@@ -703,3 +704,44 @@ func TestUnsupportedModelType(t *testing.T) {
 	}
 }
 
+
+// TestAssistantDefaultModel tests that questions without a model_type are
+// answered by the assistant model
+func TestAssistantDefaultModel(t *testing.T) {
+	var model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		model = request.Model
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "Hola"}, "finish_reason": "stop"}]}`)
+	}))
+	defer server.Close()
+
+	factory, err := ai.NewModelFactoryFromConfig(config.DefaultLLMConfig().Merge(config.LLMConfig{
+		Providers: map[string]config.ProviderConfig{"local": {Type: config.ProviderOpenAI, Endpoint: server.URL}},
+		Models:    map[string]config.ModelConfig{config.ModelAssistant: {Provider: "local", Model: "assistant-model"}},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create model factory: %v", err)
+	}
+	factory.SetResponseCache(nil, 0)
+	h := New(Dependencies{Models: factory})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/cerebras/assistant", h.GetCerebrasAIAssistance)
+	req := httptest.NewRequest("POST", "/api/cerebras/assistant", strings.NewReader("query=Hola"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.20:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 without a model_type, got %d: %s", w.Code, w.Body.String())
+	}
+	if model != "assistant-model" {
+		t.Errorf("Expected the assistant model to answer, got %q", model)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/usage"
+)
+
+// StreamCerebrasAIAssistance relays the assistant's answer as Server-Sent Events.
+// GET reads the query from the URL so it works with EventSource; POST accepts
+// the same form fields as /api/cerebras/assistant, including conversation_id
+// and project_id.
+//
+// Events: "token" carries {"content": "..."} for each piece of text, "done"
+// carries {"response_time_ms": n, "conversation_id": "...", "usage_warning":
+// "...", "sources": [...]} and "error" carries {"error": "..."}.
+// The upstream request is cancelled when the client disconnects.
+func (h *Handlers) StreamCerebrasAIAssistance(c *gin.Context) {
+	startTime := time.Now()
+
+	clientIP := c.ClientIP()
+	if !rateLimiter.GetLimiter(clientIP).Allow() {
+		log.Printf("Rate limit exceeded for IP: %s", clientIP)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Has excedido el límite de solicitudes. Por favor, intenta de nuevo en un momento."})
+		updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("query"))
+	conversationID := c.Query("conversation_id")
+	projectID := c.Query("project_id")
+	if c.Request.Method == http.MethodPost {
+		query = strings.TrimSpace(c.PostForm("query"))
+		conversationID = c.PostForm("conversation_id")
+		projectID = c.PostForm("project_id")
+	}
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados. Verifica que has incluido una consulta válida."})
+		return
+	}
+
+	query, isNoThink := parseNoThinkCommand(query)
+
+	// The request context is cancelled when the browser disconnects
+	ctx, cancel := operationContext(c, config.OpAIStream)
+	defer cancel()
+
+	ctx, ok := h.accountUsage(ctx, c, projectID)
+	if !ok {
+		updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+		return
+	}
+	systemContext, promptVersion := h.buildSystemContext(false, isNoThink, h.promptVars(ctx, c, projectID))
+	ctx = usage.WithPrompt(ctx, promptVersion)
+
+	conv, ok := h.openConversation(ctx, c, conversationID, query)
+	if !ok {
+		return
+	}
+	h.compactHistory(ctx, &conv)
+	grounding, sources := h.groundingContext(ctx, query, projectID)
+	prompt := append(conversationPrompt(append(systemContext, grounding...), conv), ai.Message{Role: "user", Content: query})
+
+	model, err := h.modelChain(config.ModelAssistant, config.ModelAssistant, ai.RouteHints{
+		NoThink:      isNoThink,
+		PromptTokens: ai.EstimateTokens(prompt),
+	})
+	if err != nil {
+		log.Printf("Error resolving model %s: %v", config.ModelAssistant, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": modelErrorMessage(ai.ErrMissingAPIKey, false)})
+		return
+	}
+	if err := model.CheckCircuitBreaker(); err != nil {
+		log.Printf("Circuit breaker is open: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": circuitOpenMessage})
+		updateStats(false, float64(time.Since(startTime).Milliseconds()), true)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	log.Printf("Streaming AI request: query_length=%d", len(query))
+	var response strings.Builder
+	err = model.StreamAnswer(ctx, prompt, func(token string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		response.WriteString(token)
+		c.SSEvent("token", gin.H{"content": token})
+		c.Writer.Flush()
+		return nil
+	})
+
+	responseTimeMs := float64(time.Since(startTime).Milliseconds())
+	switch {
+	case err == nil:
+		updateStats(false, responseTimeMs, false)
+		done := gin.H{"response_time_ms": responseTimeMs}
+		if promptVersion != "" {
+			done["prompt_version"] = promptVersion
+		}
+		if err := h.saveExchange(ctx, &conv, query, response.String()); err != nil {
+			log.Printf("Error saving conversation %s: %v", conv.ID.Hex(), err)
+		} else {
+			done["conversation_id"] = conv.ID.Hex()
+		}
+		if warning := usageWarning(c); warning != "" {
+			done["usage_warning"] = warning
+		}
+		if len(sources) > 0 {
+			done["sources"] = sources
+		}
+		c.SSEvent("done", done)
+	case errors.Is(err, context.Canceled):
+		// Client went away; nobody is listening for an error event
+		log.Printf("AI stream cancelled by client after %.0fms", responseTimeMs)
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("AI stream timed out: %v", err)
+		updateStats(false, responseTimeMs, true)
+		c.SSEvent("error", gin.H{"error": "El asistente tardó demasiado en responder. Por favor, intenta de nuevo."})
+	default:
+		log.Printf("Error streaming AI response: %v", err)
+		updateStats(false, responseTimeMs, true)
+		c.SSEvent("error", gin.H{"error": modelErrorMessage(err, false)})
+	}
+	c.Writer.Flush()
+}
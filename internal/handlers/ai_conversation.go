@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// sessionCookieName identifies the browser session that owns conversations
+	sessionCookieName   = "ana_session"
+	sessionCookieMaxAge = 365 * 24 * 60 * 60
+
+	// conversationTitleLength is how much of the first question becomes the title
+	conversationTitleLength = 60
+	// maxConversationTitleLength caps titles set through RenameConversation
+	maxConversationTitleLength = 120
+)
+
+var (
+	// historyTokenBudget limits the conversation history sent with each question
+	historyTokenBudget = ai.DefaultHistoryTokenBudget
+)
+
+// SetHistoryTokenBudget sets how many tokens of history are sent to the model.
+// Older turns are summarised once the budget is exceeded.
+func SetHistoryTokenBudget(tokens int) {
+	if tokens > 0 {
+		historyTokenBudget = tokens
+	}
+}
+
+// RenameConversationRequest is the body of PATCH /api/ai/conversations/:id
+type RenameConversationRequest struct {
+	Title string `json:"title" binding:"required"`
+}
+
+// ListConversations returns the caller's conversations, most recent first
+func (h *Handlers) ListConversations(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpConversationRead)
+	defer cancel()
+
+	conversations, err := h.conversations.ListByOwner(ctx, sessionOwner(c))
+	if err != nil {
+		log.Printf("Error fetching conversations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// GetConversation returns a conversation with its messages so it can be resumed
+func (h *Handlers) GetConversation(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpConversationRead)
+	defer cancel()
+
+	conv, ok := h.findOwnedConversation(ctx, c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, conv)
+}
+
+// RenameConversation changes the title of a conversation
+func (h *Handlers) RenameConversation(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpConversationWrite)
+	defer cancel()
+
+	var request RenameConversationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" || len([]rune(title)) > maxConversationTitleLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must be between 1 and 120 characters"})
+		return
+	}
+
+	conv, ok := h.findOwnedConversation(ctx, c)
+	if !ok {
+		return
+	}
+	conv.Title = title
+	if err := h.conversations.Update(ctx, &conv); err != nil {
+		log.Printf("Error renaming conversation with ID %s: %v", conv.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename conversation"})
+		return
+	}
+
+	conv.Messages = nil
+	c.JSON(http.StatusOK, conv)
+}
+
+// DeleteConversation removes a conversation
+func (h *Handlers) DeleteConversation(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpConversationWrite)
+	defer cancel()
+
+	conv, ok := h.findOwnedConversation(ctx, c)
+	if !ok {
+		return
+	}
+	if err := h.conversations.Delete(ctx, conv.ID); err != nil {
+		log.Printf("Error deleting conversation with ID %s: %v", conv.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete conversation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation deleted successfully"})
+}
+
+// findOwnedConversation loads the conversation named by the :id parameter.
+// Conversations of other sessions are reported as not found.
+func (h *Handlers) findOwnedConversation(ctx context.Context, c *gin.Context) (models.Conversation, bool) {
+	objectID, ok := parseObjectIDParam(c)
+	if !ok {
+		return models.Conversation{}, false
+	}
+	conv, err := h.conversations.FindByID(ctx, objectID)
+	if err == nil && conv.OwnerID != sessionOwner(c) {
+		err = repositories.ErrConversationNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		} else {
+			log.Printf("Error fetching conversation with ID %s: %v", objectID.Hex(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversation"})
+		}
+		return models.Conversation{}, false
+	}
+	return conv, true
+}
+
+// sessionOwner returns the ID that owns the caller's conversations: the
+// signed-in user, or else an anonymous browser session whose cookie is
+// issued on the first visit
+func sessionOwner(c *gin.Context) string {
+	if id := c.GetString(userIDKey); id != "" {
+		return id
+	}
+	if id, err := c.Cookie(sessionCookieName); err == nil && validSessionID(id) {
+		return id
+	}
+	if id := c.GetString(sessionCookieName); id != "" {
+		return id // issued earlier in this request
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("Error generating session ID: %v", err)
+	}
+	id := hex.EncodeToString(b)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   sessionCookieMaxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Set(sessionCookieName, id)
+	return id
+}
+
+// validSessionID reports whether a cookie value looks like an issued session ID
+func validSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// openConversation loads the caller's conversation with the given ID, or
+// starts a new one titled after the question when id is empty. An error
+// response is written when the conversation cannot be used.
+func (h *Handlers) openConversation(ctx context.Context, c *gin.Context, id string, query string) (models.Conversation, bool) {
+	owner := sessionOwner(c)
+	if id == "" {
+		return models.Conversation{
+			ID:      primitive.NewObjectID(),
+			OwnerID: owner,
+			Title:   conversationTitle(query),
+		}, true
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Identificador de conversación inválido."})
+		return models.Conversation{}, false
+	}
+	conv, err := h.conversations.FindByID(ctx, objectID)
+	if err == nil && conv.OwnerID != owner {
+		err = repositories.ErrConversationNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "La conversación no existe o fue eliminada."})
+		} else {
+			log.Printf("Error loading conversation %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo cargar la conversación. Por favor, intenta de nuevo."})
+		}
+		return models.Conversation{}, false
+	}
+	return conv, true
+}
+
+// conversationTitle derives a title from the first question of a conversation
+func conversationTitle(query string) string {
+	title := strings.Join(strings.Fields(query), " ")
+	if runes := []rune(title); len(runes) > conversationTitleLength {
+		title = strings.TrimSpace(string(runes[:conversationTitleLength])) + "…"
+	}
+	if title == "" {
+		title = "Nueva conversación"
+	}
+	return title
+}
+
+// compactHistory compacts the conversation with the summary model
+func (h *Handlers) compactHistory(ctx context.Context, conv *models.Conversation) {
+	model, err := h.modelChain(config.ModelSummary, config.ModelAssistant, ai.RouteHints{})
+	if err != nil {
+		log.Printf("Error resolving summary model: %v", err)
+		return
+	}
+	compactConversation(ctx, model, model.Primary().Config.Model, conv)
+}
+
+// compactConversation folds the oldest turns into the conversation summary
+// when the history no longer fits in historyTokenBudget. If the summary cannot
+// be produced the turns are still dropped from the prompt, so requests stay
+// within budget either way.
+func compactConversation(ctx context.Context, client ai.ChatCompleter, model string, conv *models.Conversation) {
+	recent := conversationMessages(conv.Messages[conv.SummarizedCount:])
+	if ai.EstimateTokens(recent) <= historyTokenBudget {
+		return
+	}
+	cut := ai.HistoryWindow(recent, historyTokenBudget)
+	summary, err := ai.SummarizeHistory(ctx, client, model, conv.Summary, recent[:cut])
+	if err != nil {
+		log.Printf("Error summarizing conversation %s, truncating history instead: %v", conv.ID.Hex(), err)
+	} else {
+		conv.Summary = summary
+	}
+	conv.SummarizedCount += cut
+}
+
+// conversationPrompt returns the system context followed by the conversation
+// summary and the turns that have not been summarised
+func conversationPrompt(systemContext []ai.Message, conv models.Conversation) []ai.Message {
+	prompt := append([]ai.Message{}, systemContext...)
+	if conv.Summary != "" {
+		prompt = append(prompt, ai.Message{
+			Role:    "system",
+			Content: "Resumen de la conversación hasta ahora: " + conv.Summary,
+		})
+	}
+	return append(prompt, conversationMessages(conv.Messages[conv.SummarizedCount:])...)
+}
+
+// conversationMessages converts stored turns into model messages
+func conversationMessages(turns []models.ConversationMessage) []ai.Message {
+	messages := make([]ai.Message, 0, len(turns))
+	for _, turn := range turns {
+		messages = append(messages, ai.Message{Role: turn.Role, Content: turn.Content})
+	}
+	return messages
+}
+
+// saveExchange appends a question and its answer to the conversation and stores it
+func (h *Handlers) saveExchange(ctx context.Context, conv *models.Conversation, query, response string) error {
+	now := time.Now()
+	conv.Messages = append(conv.Messages,
+		models.ConversationMessage{Role: "user", Content: query, CreatedAt: now},
+		models.ConversationMessage{Role: "assistant", Content: response, CreatedAt: now},
+	)
+	if conv.CreatedAt.IsZero() {
+		return h.conversations.Create(ctx, conv)
+	}
+	return h.conversations.Update(ctx, conv)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// summaryCompleter answers every chat completion with a fixed summary or error
+type summaryCompleter struct {
+	summary string
+	err     error
+	calls   int
+}
+
+func (s *summaryCompleter) CreateChatCompletion(ctx context.Context, req ai.ChatCompletionRequest) (*ai.ChatCompletionResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ai.ChatCompletionResponse{Choices: []ai.Choice{{Message: ai.ResponseMessage{Role: "assistant", Content: s.summary}}}}, nil
+}
+
+// serveConversation runs a request through the conversation routes with an optional session cookie
+func serveConversation(h *Handlers, method, path, body, session string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/ai/conversations", h.ListConversations)
+	r.GET("/api/ai/conversations/:id", h.GetConversation)
+	r.PATCH("/api/ai/conversations/:id", h.RenameConversation)
+	r.DELETE("/api/ai/conversations/:id", h.DeleteConversation)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestConversationEndpoints tests listing, resuming, renaming and deleting conversations
+func TestConversationEndpoints(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	repo := repos.Conversations
+	h := New(Dependencies{Repositories: repos})
+
+	// The first request issues a session cookie
+	w := serveConversation(h, "GET", "/api/ai/conversations", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	session := cookies[0].Value
+	assert.True(t, cookies[0].HttpOnly)
+
+	conv := models.Conversation{OwnerID: session, Title: "Normativa", Messages: []models.ConversationMessage{
+		{Role: "user", Content: "¿Qué es la NSR-10?"},
+		{Role: "assistant", Content: "La norma sismo resistente."},
+	}}
+	require.NoError(t, repo.Create(context.Background(), &conv))
+	path := "/api/ai/conversations/" + conv.ID.Hex()
+
+	w = serveConversation(h, "GET", "/api/ai/conversations", "", session)
+	var list []models.Conversation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Empty(t, w.Result().Cookies(), "existing session is reused")
+
+	w = serveConversation(h, "GET", path, "", session)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resumed models.Conversation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resumed))
+	assert.Len(t, resumed.Messages, 2)
+
+	// Other sessions cannot see or change it
+	other := strings.Repeat("ab", 16)
+	assert.Equal(t, http.StatusNotFound, serveConversation(h, "GET", path, "", other).Code)
+	assert.Equal(t, http.StatusNotFound, serveConversation(h, "DELETE", path, "", other).Code)
+
+	assert.Equal(t, http.StatusBadRequest, serveConversation(h, "PATCH", path, `{"title":"   "}`, session).Code)
+	w = serveConversation(h, "PATCH", path, `{"title":"Consulta NSR-10"}`, session)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err := repo.FindByID(context.Background(), conv.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Consulta NSR-10", stored.Title)
+	assert.Len(t, stored.Messages, 2, "renaming keeps the messages")
+
+	assert.Equal(t, http.StatusOK, serveConversation(h, "DELETE", path, "", session).Code)
+	assert.Equal(t, http.StatusNotFound, serveConversation(h, "GET", path, "", session).Code)
+}
+
+// TestCompactConversation tests that old turns are summarised once the history exceeds the budget
+func TestCompactConversation(t *testing.T) {
+	defer SetHistoryTokenBudget(historyTokenBudget)
+	SetHistoryTokenBudget(250)
+
+	long := strings.Repeat("palabra ", 50) // ~100 tokens
+	conv := models.Conversation{}
+	for i := 0; i < 3; i++ {
+		conv.Messages = append(conv.Messages,
+			models.ConversationMessage{Role: "user", Content: long},
+			models.ConversationMessage{Role: "assistant", Content: long},
+		)
+	}
+
+	completer := &summaryCompleter{summary: "Resumen de lo hablado"}
+	compactConversation(context.Background(), completer, "test-model", &conv)
+	assert.Equal(t, 1, completer.calls)
+	assert.Equal(t, "Resumen de lo hablado", conv.Summary)
+	assert.Equal(t, 4, conv.SummarizedCount)
+
+	system, _ := New(Dependencies{}).buildSystemContext(false, false, prompts.Vars{})
+	prompt := conversationPrompt(system, conv)
+	require.Len(t, prompt, 4)
+	assert.Contains(t, prompt[1].Content, "Resumen de lo hablado")
+	assert.Equal(t, "user", prompt[2].Role)
+
+	// Within budget nothing happens
+	compactConversation(context.Background(), completer, "test-model", &conv)
+	assert.Equal(t, 1, completer.calls)
+
+	// Without a summary the history is still truncated
+	conv.Messages = append(conv.Messages,
+		models.ConversationMessage{Role: "user", Content: long},
+		models.ConversationMessage{Role: "assistant", Content: long},
+	)
+	compactConversation(context.Background(), &summaryCompleter{err: errors.New("unavailable")}, "test-model", &conv)
+	assert.Equal(t, "Resumen de lo hablado", conv.Summary)
+	assert.Equal(t, 6, conv.SummarizedCount)
+}
+
+// TestConversationTitle tests titles derived from the first question
+func TestConversationTitle(t *testing.T) {
+	assert.Equal(t, "¿Qué exige la NSR-10?", conversationTitle("  ¿Qué exige   la NSR-10? "))
+	assert.Equal(t, "Nueva conversación", conversationTitle(""))
+	title := conversationTitle(strings.Repeat("á", 80))
+	assert.Equal(t, 61, len([]rune(title)))
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/rag"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxDocumentBytes bounds the size of an uploaded document
+	maxDocumentBytes = 20 << 20
+	// groundingPassages is the number of passages retrieved per question
+	groundingPassages = 4
+	// groundingMinScore drops passages that barely relate to the question
+	groundingMinScore = 0.1
+	// groundingTasks bounds the project tasks included in the prompt
+	groundingTasks = 30
+	// excerptLength is the number of characters of a source returned to clients
+	excerptLength = 240
+)
+
+// Source kinds of an AnswerSource
+const (
+	SourceDocument = "document"
+	SourceTasks    = "tasks"
+)
+
+// AnswerSource is a document passage or task list an answer was grounded in
+type AnswerSource struct {
+	// Ref is the number the answer cites the source by, as in [1]
+	Ref        int    `json:"ref"`
+	Kind       string `json:"kind"`
+	Title      string `json:"title"`
+	Reference  string `json:"reference"`
+	DocumentID string `json:"document_id,omitempty"`
+	ProjectID  string `json:"project_id,omitempty"`
+	Excerpt    string `json:"excerpt"`
+}
+
+// groundingContext retrieves the passages related to a question and, for a
+// project, its tasks. It returns them as a system message the model must
+// cite from, and as the sources returned with the answer. Retrieval errors
+// are logged and the question is answered without sources.
+func (h *Handlers) groundingContext(ctx context.Context, query, projectID string) ([]ai.Message, []AnswerSource) {
+	var project primitive.ObjectID
+	if projectID != "" {
+		project, _ = primitive.ObjectIDFromHex(projectID)
+	}
+
+	sources := []AnswerSource{}
+	var content strings.Builder
+	passages, err := h.documents.Search(ctx, query, rag.SearchOptions{
+		K:         groundingPassages,
+		ProjectID: project,
+		MinScore:  groundingMinScore,
+	})
+	if err != nil {
+		log.Printf("Error retrieving passages for the assistant: %v", err)
+	}
+	for _, passage := range passages {
+		source := AnswerSource{
+			Ref:        len(sources) + 1,
+			Kind:       SourceDocument,
+			Title:      passage.Title,
+			Reference:  passage.Reference(),
+			DocumentID: passage.DocumentID.Hex(),
+			Excerpt:    excerpt(passage.Text),
+		}
+		sources = append(sources, source)
+		fmt.Fprintf(&content, "[%d] %s\n%s\n\n", source.Ref, source.Reference, passage.Text)
+	}
+
+	if !project.IsZero() {
+		if source, text, ok := h.projectTasksSource(ctx, project, len(sources)+1); ok {
+			sources = append(sources, source)
+			fmt.Fprintf(&content, "[%d] %s\n%s\n\n", source.Ref, source.Reference, text)
+		}
+	}
+
+	if len(sources) == 0 {
+		return []ai.Message{{
+			Role:    "system",
+			Content: "No hay documentos de referencia relacionados con esta pregunta. Si la respuesta depende de un artículo, valor o requisito normativo concreto, indica que no está en los documentos disponibles en lugar de citarlo de memoria.",
+		}}, sources
+	}
+	return []ai.Message{{
+		Role: "system",
+		Content: "Fuentes disponibles para esta pregunta. Basa la respuesta en ellas y cita cada dato que tomes de una fuente con su número entre corchetes, por ejemplo [1]. " +
+			"No cites normas, artículos ni valores que no aparezcan en las fuentes; si la información necesaria no está, dilo.\n\n" +
+			strings.TrimSpace(content.String()),
+	}}, sources
+}
+
+// projectTasksSource lists the tasks of a project as a source numbered ref
+func (h *Handlers) projectTasksSource(ctx context.Context, projectID primitive.ObjectID, ref int) (AnswerSource, string, bool) {
+	project, err := h.projects.FindByID(ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrProjectNotFound) {
+			log.Printf("Error fetching project %s for the assistant: %v", projectID.Hex(), err)
+		}
+		return AnswerSource{}, "", false
+	}
+	tasks, err := h.tasks.FindByProject(ctx, projectID)
+	if err != nil {
+		log.Printf("Error fetching tasks of project %s for the assistant: %v", projectID.Hex(), err)
+		return AnswerSource{}, "", false
+	}
+
+	var lines []string
+	for i, task := range tasks {
+		if i == groundingTasks {
+			lines = append(lines, fmt.Sprintf("- … y %d tareas más", len(tasks)-groundingTasks))
+			break
+		}
+		lines = append(lines, taskLine(task))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "- El proyecto no tiene tareas registradas.")
+	}
+	text := strings.Join(lines, "\n")
+	return AnswerSource{
+		Ref:       ref,
+		Kind:      SourceTasks,
+		Title:     project.Name,
+		Reference: "Tareas del proyecto " + project.Name,
+		ProjectID: projectID.Hex(),
+		Excerpt:   excerpt(text),
+	}, text, true
+}
+
+// taskLine describes a task in one line for the model
+func taskLine(task models.Task) string {
+	line := fmt.Sprintf("- %s (%s, prioridad %s", task.Title, task.Status, task.Priority)
+	if !task.DueDate.IsZero() {
+		line += ", vence " + task.DueDate.Format("2006-01-02")
+	}
+	line += ")"
+	if task.Description != "" {
+		line += ": " + task.Description
+	}
+	return line
+}
+
+// excerpt shortens a source's text for display
+func excerpt(text string) string {
+	if utf8.RuneCountInString(text) <= excerptLength {
+		return text
+	}
+	return string([]rune(text)[:excerptLength]) + "…"
+}
+
+// DocumentSearchResponse is the body of GET /api/ai/documents/search
+type DocumentSearchResponse struct {
+	Passages []DocumentPassage `json:"passages"`
+}
+
+// DocumentPassage is a search result with its citation
+type DocumentPassage struct {
+	rag.Passage
+	Reference string `json:"reference"`
+}
+
+// ListDocuments lists the ingested documents
+func (h *Handlers) ListDocuments(c *gin.Context) {
+	c.JSON(http.StatusOK, h.documents.Documents())
+}
+
+// UploadDocument ingests a PDF, Markdown or text file sent as the multipart
+// field "file". The optional fields "title" and "project_id" name the
+// document and restrict it to one project's questions.
+func (h *Handlers) UploadDocument(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	if rag.DocumentKind(header.Filename) == "" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only PDF, Markdown and text files can be ingested"})
+		return
+	}
+	if header.Size > maxDocumentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Documents are limited to %d MB", maxDocumentBytes>>20)})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpDocumentWrite)
+	defer cancel()
+
+	var projectID primitive.ObjectID
+	if id := c.PostForm("project_id"); id != "" {
+		if projectID, err = primitive.ObjectIDFromHex(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID format"})
+			return
+		}
+		if _, err := h.projects.FindByID(ctx, projectID); err != nil {
+			if errors.Is(err, repositories.ErrProjectNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found"})
+				return
+			}
+			log.Printf("Error fetching project %s for document upload: %v", projectID.Hex(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project"})
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded document: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file"})
+		return
+	}
+	defer file.Close()
+	// The declared size comes from the client, so the read is capped as well
+	data, err := io.ReadAll(io.LimitReader(file, maxDocumentBytes+1))
+	if err != nil {
+		log.Printf("Error reading uploaded document: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file"})
+		return
+	}
+	if len(data) > maxDocumentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Documents are limited to %d MB", maxDocumentBytes>>20)})
+		return
+	}
+
+	document, err := h.documents.Ingest(ctx, rag.IngestRequest{
+		Title:     c.PostForm("title"),
+		Filename:  header.Filename,
+		ProjectID: projectID,
+		Data:      data,
+	})
+	switch {
+	case err == nil:
+		log.Printf("Ingested document %q: %d passages", document.Title, document.Chunks)
+		c.JSON(http.StatusCreated, document)
+	case errors.Is(err, rag.ErrUnreadableDocument):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The file could not be parsed"})
+	case errors.Is(err, rag.ErrEmptyDocument):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The file contains no text; scanned PDFs need OCR first"})
+	default:
+		log.Printf("Error ingesting document %q: %v", header.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest document"})
+	}
+}
+
+// DeleteDocument removes a document and its passages
+func (h *Handlers) DeleteDocument(c *gin.Context) {
+	id, ok := parseObjectIDParam(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := operationContext(c, config.OpDocumentWrite)
+	defer cancel()
+	if err := h.documents.Delete(ctx, id); err != nil {
+		if errors.Is(err, repositories.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		log.Printf("Error deleting document %s: %v", id.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
+}
+
+// SearchDocuments returns the passages most related to the query parameter
+// q, as the assistant would retrieve them. project_id adds that project's
+// documents and k sets the number of passages.
+func (h *Handlers) SearchDocuments(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+	opts := rag.SearchOptions{K: groundingPassages}
+	if id := c.Query("project_id"); id != "" {
+		var err error
+		if opts.ProjectID, err = primitive.ObjectIDFromHex(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID format"})
+			return
+		}
+	}
+	if k := c.Query("k"); k != "" {
+		n, err := strconv.Atoi(k)
+		if err != nil || n < 1 || n > 20 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "k must be between 1 and 20"})
+			return
+		}
+		opts.K = n
+	}
+
+	ctx, cancel := operationContext(c, config.OpDocumentRead)
+	defer cancel()
+	passages, err := h.documents.Search(ctx, query, opts)
+	if err != nil {
+		log.Printf("Error searching documents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search documents"})
+		return
+	}
+	response := DocumentSearchResponse{Passages: []DocumentPassage{}}
+	for _, passage := range passages {
+		response.Passages = append(response.Passages, DocumentPassage{Passage: passage, Reference: passage.Reference()})
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/rag"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveDocuments runs a request through the document routes with an
+// optional admin token
+func serveDocuments(h *Handlers, req *http.Request, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	documents := r.Group("/api/ai/documents")
+	documents.GET("", h.ListDocuments)
+	documents.GET("/search", h.SearchDocuments)
+	documents.POST("", RequireAdmin(), h.UploadDocument)
+	documents.DELETE("/:id", RequireAdmin(), h.DeleteDocument)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// documentUpload builds a multipart upload of a document
+func documentUpload(t *testing.T, filename, title, content string) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, form.WriteField("title", title))
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest("POST", "/api/ai/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// TestDocumentGrounding tests ingesting a regulation and grounding questions in it
+func TestDocumentGrounding(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	h := New(Dependencies{Repositories: repos, Documents: rag.NewStore(repos.Documents, ai.NewHashEmbedder(512))})
+	SetAdminToken("secret")
+	t.Cleanup(func() { SetAdminToken("") })
+	ctx := context.Background()
+
+	const nsr10 = "# C.7 Recubrimiento del refuerzo\nEl recubrimiento mínimo de concreto para columnas es 40 mm.\n"
+	assert.Equal(t, http.StatusForbidden, serveDocuments(h, documentUpload(t, "nsr10.md", "NSR-10", nsr10), "").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, serveDocuments(h, documentUpload(t, "plano.dwg", "Plano", "x"), "secret").Code)
+
+	w := serveDocuments(h, documentUpload(t, "nsr10.md", "NSR-10", nsr10), "secret")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var document models.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, "NSR-10", document.Title)
+	assert.Equal(t, 1, document.Chunks)
+
+	w = serveDocuments(h, httptest.NewRequest("GET", "/api/ai/documents/search?q=recubrimiento+columnas", nil), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var search DocumentSearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &search))
+	require.Len(t, search.Passages, 1)
+	assert.Equal(t, "NSR-10, § C.7 Recubrimiento del refuerzo", search.Passages[0].Reference)
+
+	// Questions about a project are grounded in the passages and its tasks
+	project := models.Project{Name: "Casa Chapinero"}
+	require.NoError(t, repos.Projects.Create(ctx, &project))
+	require.NoError(t, repos.Tasks.Create(ctx, &models.Task{Title: "Revisar recubrimiento de columnas", Status: "To-Do", Priority: "High", ProjectID: project.ID}))
+
+	messages, sources := h.groundingContext(ctx, "¿Qué recubrimiento necesitan las columnas?", project.ID.Hex())
+	require.Len(t, messages, 1)
+	require.Len(t, sources, 2)
+	assert.Equal(t, SourceDocument, sources[0].Kind)
+	assert.Equal(t, 1, sources[0].Ref)
+	assert.Equal(t, SourceTasks, sources[1].Kind)
+	assert.Equal(t, "Tareas del proyecto Casa Chapinero", sources[1].Reference)
+	assert.Contains(t, messages[0].Content, "[1] NSR-10, § C.7 Recubrimiento del refuerzo\nEl recubrimiento mínimo")
+	assert.Contains(t, messages[0].Content, "[2] Tareas del proyecto Casa Chapinero\n- Revisar recubrimiento de columnas (To-Do, prioridad High)")
+
+	// Without related sources the model is told not to cite from memory
+	messages, sources = h.groundingContext(ctx, "horario de la oficina", "")
+	assert.Empty(t, sources)
+	assert.Contains(t, messages[0].Content, "No hay documentos")
+
+	assert.Equal(t, http.StatusForbidden, serveDocuments(h, httptest.NewRequest("DELETE", "/api/ai/documents/"+document.ID.Hex(), nil), "").Code)
+	assert.Equal(t, http.StatusOK, serveDocuments(h, httptest.NewRequest("DELETE", "/api/ai/documents/"+document.ID.Hex(), nil), "secret").Code)
+	assert.Equal(t, http.StatusNotFound, serveDocuments(h, httptest.NewRequest("DELETE", "/api/ai/documents/"+document.ID.Hex(), nil), "secret").Code)
+
+	w = serveDocuments(h, httptest.NewRequest("GET", "/api/ai/documents", nil), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxExtractionText bounds the characters of text tasks are extracted from
+	maxExtractionText = 20000
+	// maxBulkTasks bounds the tasks created by one bulk request
+	maxBulkTasks = 50
+)
+
+// ExtractTasksRequest is text to propose tasks from, such as meeting
+// minutes or a client email
+type ExtractTasksRequest struct {
+	Text string `json:"text" binding:"required"`
+	// ProjectID is the project the text is about; proposals that name no
+	// other project are assigned to it, and its budget is charged
+	ProjectID string `json:"project_id"`
+}
+
+// ExtractTasksResponse holds the proposed tasks for the user to review and
+// then create through POST /api/tasks/bulk
+type ExtractTasksResponse struct {
+	Proposals    []tools.TaskProposal `json:"proposals"`
+	UsageWarning string               `json:"usage_warning,omitempty"`
+}
+
+// BulkCreateTasksRequest is the body of POST /api/tasks/bulk
+type BulkCreateTasksRequest struct {
+	Tasks []models.Task `json:"tasks" binding:"required,min=1,dive"`
+}
+
+// ExtractTasks proposes tasks found in free text. Nothing is created: the
+// proposals are returned for review.
+func (h *Handlers) ExtractTasks(c *gin.Context) {
+	var request ExtractTasksRequest
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error en los datos enviados. Pega el texto del que quieres extraer las tareas."})
+		return
+	}
+	if utf8.RuneCountInString(request.Text) > maxExtractionText {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "El texto es demasiado largo. Divídelo en partes más pequeñas."})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpAIText)
+	defer cancel()
+	ctx, ok := h.accountUsage(ctx, c, request.ProjectID)
+	if !ok {
+		return
+	}
+
+	var project models.Project
+	if request.ProjectID != "" {
+		id, _ := primitive.ObjectIDFromHex(request.ProjectID)
+		var err error
+		if project, err = h.projects.FindByID(ctx, id); err != nil {
+			if errors.Is(err, repositories.ErrProjectNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "El proyecto indicado no existe."})
+				return
+			}
+			log.Printf("Error fetching project %s for task extraction: %v", id.Hex(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al consultar el proyecto. Intenta de nuevo."})
+			return
+		}
+	}
+
+	model, err := h.modelChain(config.ModelAgent, config.ModelAssistant, ai.RouteHints{
+		PromptTokens: ai.EstimateTokens([]ai.Message{{Role: "user", Content: request.Text}}),
+	})
+	if err != nil {
+		log.Printf("Error getting model for task extraction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en el procesamiento de la consulta. Intenta reformularla."})
+		return
+	}
+	extractor := &tools.TaskExtractor{Client: model, Projects: h.projects}
+	proposals, err := extractor.Extract(ctx, request.Text, project)
+	if err != nil {
+		respondExtractionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ExtractTasksResponse{Proposals: proposals, UsageWarning: usageWarning(c)})
+}
+
+// respondExtractionError maps task extraction errors to HTTP responses
+func respondExtractionError(c *gin.Context, err error) {
+	var apiErr *ai.APIError
+	switch {
+	case errors.Is(err, tools.ErrInvalidExtraction):
+		log.Printf("Task extraction failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No pude identificar las tareas del texto. Intenta de nuevo o revisa el formato."})
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "El asistente tardó demasiado en responder. Por favor, intenta de nuevo."})
+	case errors.Is(err, context.Canceled):
+		log.Printf("Task extraction cancelled by client")
+	case fallbackable(err), errors.As(err, &apiErr):
+		log.Printf("Task extraction model error: %v", err)
+		status, message := modelErrorResponse(err, false)
+		c.JSON(status, gin.H{"error": message})
+	default:
+		log.Printf("Error extracting tasks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error en el procesamiento de la consulta. Intenta reformularla."})
+	}
+}
+
+// BulkCreateTasks creates the reviewed tasks of an extraction. Every task
+// is validated, and its project checked, before any is created.
+func (h *Handlers) BulkCreateTasks(c *gin.Context) {
+	var request BulkCreateTasksRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Tasks) > maxBulkTasks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many tasks, at most 50 can be created at once"})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpTaskWrite)
+	defer cancel()
+
+	checked := map[primitive.ObjectID]bool{}
+	for _, task := range request.Tasks {
+		if checked[task.ProjectID] {
+			continue
+		}
+		if !h.validateTaskProject(ctx, c, task) {
+			return
+		}
+		checked[task.ProjectID] = true
+	}
+
+	created := make([]models.Task, 0, len(request.Tasks))
+	for _, task := range request.Tasks {
+		task.ID = primitive.NilObjectID
+		if err := h.tasks.Create(ctx, &task); err != nil {
+			log.Printf("Error creating task %d of %d in bulk: %v", len(created)+1, len(request.Tasks), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tasks", "created": created})
+			return
+		}
+		created = append(created, task)
+	}
+	c.JSON(http.StatusCreated, created)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serveExtraction runs a request through the task extraction routes
+func serveExtraction(h *Handlers, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ai/extract-tasks", h.ExtractTasks)
+	r.POST("/api/tasks/bulk", h.BulkCreateTasks)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestExtractTasksValidation tests rejecting requests before the model is called
+func TestExtractTasksValidation(t *testing.T) {
+	h := New(Dependencies{})
+
+	assert.Equal(t, http.StatusBadRequest, serveExtraction(h, "POST", "/api/ai/extract-tasks", `{"text": "   "}`).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge,
+		serveExtraction(h, "POST", "/api/ai/extract-tasks", `{"text": "`+strings.Repeat("a", maxExtractionText+1)+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		serveExtraction(h, "POST", "/api/ai/extract-tasks", `{"text": "Acta", "project_id": "`+primitive.NewObjectID().Hex()+`"}`).Code)
+}
+
+// TestBulkCreateTasks tests that reviewed proposals are created together or not at all
+func TestBulkCreateTasks(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	h := New(Dependencies{Repositories: repos})
+	ctx := context.Background()
+	project := models.Project{Name: "Casa Usaquén"}
+	require.NoError(t, repos.Projects.Create(ctx, &project))
+
+	// One task with an unknown project rejects the whole request
+	body := `{"tasks": [
+		{"title": "Enviar planos", "priority": "High", "status": "To-Do", "project_id": "` + project.ID.Hex() + `", "due_date": "2025-06-13T00:00:00-05:00"},
+		{"title": "Cotizar guadua", "priority": "Medium", "status": "To-Do", "project_id": "` + primitive.NewObjectID().Hex() + `"}
+	]}`
+	assert.Equal(t, http.StatusBadRequest, serveExtraction(h, "POST", "/api/tasks/bulk", body).Code)
+	tasks, err := repos.Tasks.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	assert.Equal(t, http.StatusBadRequest, serveExtraction(h, "POST", "/api/tasks/bulk", `{"tasks": []}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveExtraction(h, "POST", "/api/tasks/bulk", `{"tasks": [{"title": "Sin estado", "priority": "Low"}]}`).Code)
+
+	body = `{"tasks": [
+		{"title": "Enviar planos", "priority": "High", "status": "To-Do", "project_id": "` + project.ID.Hex() + `", "due_date": "2025-06-13T00:00:00-05:00"},
+		{"title": "Agendar visita", "priority": "Low", "status": "To-Do"}
+	]}`
+	w := serveExtraction(h, "POST", "/api/tasks/bulk", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created []models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.Len(t, created, 2)
+	assert.False(t, created[0].ID.IsZero())
+	assert.Equal(t, project.ID, created[0].ProjectID)
+
+	tasks, err = repos.Tasks.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/lyffseba/ana/internal/ai"
+)
+
+// modelChain returns the models that answer a request for the logical model
+// name, routed by hints and followed by their fallbacks. If name is empty or
+// not configured the fallback model is used instead: older clients send
+// provider model names such as "qwen-3-32b".
+func (h *Handlers) modelChain(name string, fallback string, hints ai.RouteHints) (*ai.ModelChain, error) {
+	factory := h.models
+	if name != "" && name != fallback {
+		chain, err := factory.Route(name, hints)
+		if err == nil {
+			return chain, nil
+		}
+		log.Printf("Model %q not available, using %s: %v", name, fallback, err)
+	}
+	return factory.Route(fallback, hints)
+}
+
+// providerStatus reports "ok", or "missing_key" for a provider that needs an
+// API key it does not have
+func providerStatus(provider ai.LLMProvider) string {
+	if p, ok := provider.(interface{ GetAPIStatus() string }); ok {
+		return p.GetAPIStatus()
+	}
+	return "ok"
+}
+
+// modelErrorResponse returns the status and user-facing message for a
+// model error. vision selects the wording for questions about images.
+func modelErrorResponse(err error, vision bool) (int, string) {
+	var apiErr *ai.APIError
+	switch {
+	case errors.Is(err, ai.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, circuitOpenMessage
+	case errors.Is(err, ai.ErrMissingAPIKey):
+		if vision {
+			return http.StatusServiceUnavailable, "Lo sentimos, el asistente de visión arquitectónica no está disponible en este momento. Por favor contacta al administrador para activar esta funcionalidad."
+		}
+		return http.StatusServiceUnavailable, "Lo sentimos, el asistente de arquitectura no está disponible en este momento. Por favor contacta al administrador para activar esta funcionalidad."
+	case errors.Is(err, ai.ErrRateLimited):
+		if vision {
+			return http.StatusTooManyRequests, "El servicio de análisis de imágenes está experimentando mucho tráfico. Por favor intenta de nuevo en unos momentos."
+		}
+		return http.StatusTooManyRequests, "El asistente arquitectónico está experimentando mucho tráfico. Por favor intenta de nuevo en unos momentos."
+	case errors.Is(err, ai.ErrUnavailable):
+		if vision {
+			return http.StatusServiceUnavailable, "El servicio de análisis de imágenes no está disponible temporalmente. Por favor intenta más tarde."
+		}
+		return http.StatusServiceUnavailable, "El servicio de asistencia arquitectónica no está disponible temporalmente. Por favor intenta más tarde."
+	case !errors.As(err, &apiErr):
+		return http.StatusInternalServerError, "Error en el procesamiento de la consulta. Intenta reformularla."
+	}
+
+	// The provider rejected the request
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized && vision:
+		return http.StatusBadGateway, "No se pudo autenticar con el servicio de visión AI. Por favor verifica la configuración del asistente."
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return http.StatusBadGateway, "No se pudo autenticar con el servicio de IA. Por favor verifica la configuración del asistente arquitectónico."
+	case apiErr.StatusCode == http.StatusForbidden && vision:
+		return http.StatusBadGateway, "No tienes permisos para utilizar el análisis de imágenes. Por favor contacta al administrador."
+	case apiErr.StatusCode == http.StatusForbidden:
+		return http.StatusBadGateway, "No tienes permisos para utilizar el asistente arquitectónico. Por favor contacta al administrador."
+	case apiErr.StatusCode == http.StatusRequestEntityTooLarge && vision:
+		return http.StatusRequestEntityTooLarge, "La imagen es demasiado grande. Por favor utiliza una imagen más pequeña (máximo 5MB)."
+	case vision:
+		return http.StatusBadGateway, "Hubo un problema al procesar tu imagen arquitectónica. Por favor intenta con otra imagen o contacta soporte técnico."
+	default:
+		return http.StatusBadGateway, "Hubo un problema al procesar tu consulta arquitectónica. Por favor intenta reformularla o contacta soporte técnico."
+	}
+}
+
+// fallbackable reports whether err means the model could not be reached:
+// not configured, behind an open circuit, rate limited or unavailable
+func fallbackable(err error) bool {
+	return errors.Is(err, ai.ErrMissingAPIKey) || errors.Is(err, ai.ErrCircuitOpen) ||
+		errors.Is(err, ai.ErrRateLimited) || errors.Is(err, ai.ErrUnavailable)
+}
+
+// modelErrorMessage returns the user-facing message for a model error
+func modelErrorMessage(err error, vision bool) string {
+	_, message := modelErrorResponse(err, vision)
+	return message
+}
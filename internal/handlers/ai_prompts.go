@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// promptNamePattern restricts template names to what is safe in a file name
+var promptNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// promptVars returns the template variables of a request: the project's
+// name, the caller and the date in Bogotá
+func (h *Handlers) promptVars(ctx context.Context, c *gin.Context, projectID string) prompts.Vars {
+	vars := prompts.Vars{
+		User:     sessionOwner(c),
+		Language: prompts.DefaultLanguage,
+		Date:     time.Now().In(tools.Bogota),
+	}
+	if id, err := primitive.ObjectIDFromHex(projectID); err == nil {
+		project, err := h.projects.FindByID(ctx, id)
+		switch {
+		case err == nil:
+			vars.Project = project.Name
+		case !errors.Is(err, repositories.ErrProjectNotFound):
+			log.Printf("Error fetching project %s for the system prompt: %v", id.Hex(), err)
+		}
+	}
+	return vars
+}
+
+// systemPrompt renders a system prompt template. It returns the message
+// and the template version, which is empty if the template failed.
+func (h *Handlers) systemPrompt(name string, vars prompts.Vars) ([]ai.Message, string) {
+	rendered, err := h.prompts.Render(name, vars)
+	if err != nil {
+		log.Printf("Error rendering prompt template %s: %v", name, err)
+		return nil, ""
+	}
+	return []ai.Message{{Role: "system", Content: rendered.Text}}, rendered.Ref
+}
+
+// CreatePromptRequest is the body of POST /api/ai/prompts
+type CreatePromptRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Template    string `json:"template" binding:"required"`
+	// Version defaults to one more than the highest loaded version
+	Version int `json:"version"`
+}
+
+// PreviewPromptRequest is the body of POST /api/ai/prompts/:name/preview.
+// Without a template the loaded version is rendered: the one in use, or
+// Version if set.
+type PreviewPromptRequest struct {
+	Version   int    `json:"version"`
+	Template  string `json:"template"`
+	ProjectID string `json:"project_id"`
+	User      string `json:"user"`
+	Language  string `json:"language"`
+	// Date is YYYY-MM-DD, today if empty
+	Date string `json:"date"`
+}
+
+// PreviewPromptResponse is a rendered template
+type PreviewPromptResponse struct {
+	Ref  string       `json:"ref"`
+	Text string       `json:"text"`
+	Vars prompts.Vars `json:"vars"`
+	// Draft is set when the template was sent with the request
+	Draft bool `json:"draft,omitempty"`
+}
+
+// ListPrompts lists the version in use of every template
+func (h *Handlers) ListPrompts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.prompts.List())
+}
+
+// GetPromptVersions lists every loaded version of a template, oldest first
+func (h *Handlers) GetPromptVersions(c *gin.Context) {
+	versions := h.prompts.Versions(c.Param("name"))
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt template not found"})
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// PreviewPrompt renders a template, or a draft of a new version, with the
+// given variables without calling a model
+func (h *Handlers) PreviewPrompt(c *gin.Context) {
+	var request PreviewPromptRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if request.ProjectID != "" {
+		if _, err := primitive.ObjectIDFromHex(request.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID format"})
+			return
+		}
+	}
+
+	ctx, cancel := operationContext(c, config.OpPromptRead)
+	defer cancel()
+	vars := h.promptVars(ctx, c, request.ProjectID)
+	vars.User = request.User
+	if request.Language != "" {
+		vars.Language = request.Language
+	}
+	if request.Date != "" {
+		date, err := time.ParseInLocation("2006-01-02", request.Date, tools.Bogota)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, expected YYYY-MM-DD"})
+			return
+		}
+		vars.Date = date
+	}
+
+	name := c.Param("name")
+	var (
+		template *prompts.Template
+		ok       bool
+	)
+	switch {
+	case request.Template != "":
+		version := 1
+		if current, exists := h.prompts.Get(name); exists {
+			version = current.Version + 1
+		}
+		draft, err := prompts.Parse(name, version, "", "draft", request.Template)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		template, ok = draft, true
+	case request.Version != 0:
+		template, ok = h.prompts.Version(name, request.Version)
+	default:
+		template, ok = h.prompts.Get(name)
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt template not found"})
+		return
+	}
+
+	text, err := template.Execute(vars)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, PreviewPromptResponse{Ref: template.Ref(), Text: text, Vars: vars, Draft: request.Template != ""})
+}
+
+// CreatePrompt stores a new version of a template in the database and
+// starts using it
+func (h *Handlers) CreatePrompt(c *gin.Context) {
+	var request CreatePromptRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and template are required"})
+		return
+	}
+	if !promptNamePattern.MatchString(request.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Template names may only contain lower case letters, digits and underscores"})
+		return
+	}
+	if request.Version == 0 {
+		request.Version = 1
+		if current, ok := h.prompts.Get(request.Name); ok {
+			request.Version = current.Version + 1
+		}
+	}
+	if _, err := prompts.Parse(request.Name, request.Version, request.Description, prompts.OriginDatabase, request.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpPromptWrite)
+	defer cancel()
+	stored := models.PromptTemplate{
+		Name:        request.Name,
+		Version:     request.Version,
+		Description: request.Description,
+		Text:        request.Template,
+	}
+	if err := h.promptRepo.CreatePromptTemplate(ctx, &stored); err != nil {
+		if errors.Is(err, repositories.ErrPromptVersionExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This version of the template already exists"})
+			return
+		}
+		log.Printf("Error storing prompt template %s@%d: %v", request.Name, request.Version, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store prompt template"})
+		return
+	}
+	// The version is stored; a reload failure elsewhere only delays its use
+	if err := h.prompts.Reload(ctx); err != nil {
+		log.Printf("Error reloading prompt templates after storing %s@%d: %v", stored.Name, stored.Version, err)
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+// ReloadPrompts reads the template files and database again
+func (h *Handlers) ReloadPrompts(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpPromptWrite)
+	defer cancel()
+	if err := h.prompts.Reload(ctx); err != nil {
+		log.Printf("Error reloading prompt templates: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to reload prompt templates, the current ones are kept: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.prompts.List())
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servePrompts runs a request through the prompt routes with an optional admin token
+func servePrompts(h *Handlers, method, path, body, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	group := r.Group("/api/ai/prompts", RequireAdmin())
+	group.GET("", h.ListPrompts)
+	group.POST("", h.CreatePrompt)
+	group.POST("/reload", h.ReloadPrompts)
+	group.GET("/:name", h.GetPromptVersions)
+	group.POST("/:name/preview", h.PreviewPrompt)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestPromptTemplates tests previewing templates and publishing a new version
+func TestPromptTemplates(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	registry := prompts.NewRegistry(prompts.RepositorySource{Repo: repos.Prompts})
+	h := New(Dependencies{Repositories: repos, Prompts: registry})
+	SetAdminToken("secret")
+	t.Cleanup(func() { SetAdminToken("") })
+
+	project := models.Project{Name: "Casa Usaquén"}
+	require.NoError(t, repos.Projects.Create(context.Background(), &project))
+
+	assert.Equal(t, http.StatusForbidden, servePrompts(h, "GET", "/api/ai/prompts", "", "").Code)
+
+	w := servePrompts(h, "GET", "/api/ai/prompts", "", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []prompts.Template
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed, 4)
+
+	// The version in use, filled in with the project's name and the given date
+	w = servePrompts(h, "POST", "/api/ai/prompts/assistant/preview",
+		`{"project_id": "`+project.ID.Hex()+`", "date": "2025-06-13"}`, "secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var preview PreviewPromptResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(t, "assistant@1", preview.Ref)
+	assert.Contains(t, preview.Text, "Casa Usaquén")
+	assert.Contains(t, preview.Text, "viernes 2025-06-13")
+
+	// A draft is rendered as the next version without being stored
+	w = servePrompts(h, "POST", "/api/ai/prompts/assistant/preview", `{"template": "Hola {{.User}}", "user": "laura"}`, "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(t, PreviewPromptResponse{Ref: "assistant@2", Text: "Hola laura", Vars: preview.Vars, Draft: true}, preview)
+	assert.Equal(t, http.StatusBadRequest, servePrompts(h, "POST", "/api/ai/prompts/assistant/preview", `{"template": "{{.Missing}}"}`, "secret").Code)
+
+	// Publishing a version puts it in use for the assistant
+	w = servePrompts(h, "POST", "/api/ai/prompts", `{"name": "assistant", "template": "Eres el asistente de {{.Project}}."}`, "secret")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var stored models.PromptTemplate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stored))
+	assert.Equal(t, 2, stored.Version)
+	system, version := h.buildSystemContext(false, false, prompts.Vars{Project: "Casa Usaquén"})
+	assert.Equal(t, "assistant@2", version)
+	require.Len(t, system, 1)
+	assert.Equal(t, "Eres el asistente de Casa Usaquén.", system[0].Content)
+
+	w = servePrompts(h, "POST", "/api/ai/prompts", `{"name": "assistant", "version": 2, "template": "Otra"}`, "secret")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = servePrompts(h, "POST", "/api/ai/prompts", `{"name": "../etc", "template": "Otra"}`, "secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = servePrompts(h, "GET", "/api/ai/prompts/assistant", "", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var versions []prompts.Template
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+	assert.Len(t, versions, 2)
+	assert.Equal(t, http.StatusNotFound, servePrompts(h, "GET", "/api/ai/prompts/missing", "", "secret").Code)
+}
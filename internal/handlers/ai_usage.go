@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/usage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// defaultUsageReportDays is how far back a usage report goes by default
+	defaultUsageReportDays = 30
+	// budgetWarningHeader tells clients a budget is nearly used up
+	budgetWarningHeader = "X-AI-Budget-Warning"
+	// usageWarningKey holds the budget warning of the current request
+	usageWarningKey = "usage_warning"
+)
+
+// adminToken authorises the budget endpoints; they are disabled while it is empty
+var adminToken string
+
+// SetAdminToken sets the bearer token required by the admin endpoints
+func SetAdminToken(token string) {
+	adminToken = token
+}
+
+// UsageReportResponse is the body of GET /api/ai/usage
+type UsageReportResponse struct {
+	usage.Report
+	// Budgets are the caller's budgets, or those of the selected user and project
+	Budgets []usage.BudgetStatus `json:"budgets"`
+}
+
+// accountUsage attributes the request's completions to the caller and
+// projectID, and checks their budgets. When a budget is used up a 429
+// response is written and false returned; when one is nearly used up the
+// request continues with a warning.
+func (h *Handlers) accountUsage(ctx context.Context, c *gin.Context, projectID string) (context.Context, bool) {
+	if projectID != "" {
+		if _, err := primitive.ObjectIDFromHex(projectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El proyecto indicado no es válido."})
+			return ctx, false
+		}
+	}
+	account := usage.Account{UserID: sessionOwner(c), ProjectID: projectID}
+	ctx = usage.WithAccount(ctx, account)
+
+	status, err := h.ledger.Check(ctx, account)
+	if err != nil {
+		// The ledger being unavailable does not stop the assistant
+		log.Printf("Error checking usage budgets of %s: %v", account.UserID, err)
+		return ctx, true
+	}
+	switch status.Level {
+	case usage.LevelExceeded:
+		log.Printf("Usage budget %s exceeded by %s: %d of %d tokens", status.Budget.ID.Hex(), account.UserID, status.Used, status.Budget.Limit)
+		c.Header("Retry-After", fmt.Sprint(int(time.Until(status.ResetsAt).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf(
+			"Se alcanzó el límite de uso del asistente para este periodo (%s). Intenta de nuevo después del %s o contacta al administrador.",
+			budgetDescription(status.Budget), status.ResetsAt.Format("02/01/2006 15:04 MST"))})
+		return ctx, false
+	case usage.LevelWarning:
+		c.Header(budgetWarningHeader, fmt.Sprintf("%s %s budget: %d of %d tokens used",
+			status.Budget.Scope, status.Budget.Period, status.Used, status.Budget.Limit))
+		c.Set(usageWarningKey, fmt.Sprintf("Has usado el %d%% del límite de uso del asistente (%s).",
+			status.Used*100/status.Budget.Limit, budgetDescription(status.Budget)))
+	}
+	return ctx, true
+}
+
+// AccountUsage runs accountUsage for handlers that do not call it
+// themselves, such as /ai/process: the request's completions are attributed
+// to the caller and the project_id query parameter, and requests over budget
+// are refused.
+func (h *Handlers) AccountUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, ok := h.accountUsage(c.Request.Context(), c, c.Query("project_id"))
+		if !ok {
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// usageWarning returns the budget warning set by accountUsage, if any
+func usageWarning(c *gin.Context) string {
+	return c.GetString(usageWarningKey)
+}
+
+// budgetDescription names a budget in Spanish, e.g. "límite diario del proyecto"
+func budgetDescription(budget models.UsageBudget) string {
+	period := "diario"
+	if budget.Period == models.BudgetPeriodMonthly {
+		period = "mensual"
+	}
+	switch budget.Scope {
+	case models.BudgetScopeUser:
+		return "límite " + period + " del usuario"
+	case models.BudgetScopeProject:
+		return "límite " + period + " del proyecto"
+	default:
+		return "límite " + period + " general"
+	}
+}
+
+// RequireAdmin allows only requests that carry the admin bearer token
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request carries the admin bearer token
+func isAdmin(c *gin.Context) bool {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// GetAIUsage reports token usage per day or month. Query parameters: from
+// and to (YYYY-MM-DD, inclusive; the last 30 days by default), interval (day
+// or month) and, for admins, user_id and project_id. Other callers only see
+// their own usage.
+func (h *Handlers) GetAIUsage(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -defaultUsageReportDays)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The from date must not be after the to date"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", usage.IntervalDay)
+	if interval != usage.IntervalDay && interval != usage.IntervalMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval, expected day or month"})
+		return
+	}
+
+	query := usage.ReportQuery{
+		UserID:    c.Query("user_id"),
+		ProjectID: c.Query("project_id"),
+		From:      from,
+		To:        to,
+		Interval:  interval,
+	}
+	if !isAdmin(c) {
+		query.UserID = sessionOwner(c)
+	}
+
+	ctx, cancel := operationContext(c, config.OpUsageRead)
+	defer cancel()
+
+	report, err := h.ledger.Report(ctx, query)
+	if err != nil {
+		log.Printf("Error building usage report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build usage report"})
+		return
+	}
+	budgets := []usage.BudgetStatus{}
+	if query.UserID != "" || query.ProjectID != "" {
+		budgets, err = h.ledger.Statuses(ctx, usage.Account{UserID: query.UserID, ProjectID: query.ProjectID})
+		if err != nil {
+			log.Printf("Error checking usage budgets: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build usage report"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, UsageReportResponse{Report: report, Budgets: budgets})
+}
+
+// ListUsageBudgets lists every budget
+func (h *Handlers) ListUsageBudgets(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpUsageRead)
+	defer cancel()
+
+	budgets, err := h.ledger.Budgets(ctx)
+	if err != nil {
+		log.Printf("Error listing usage budgets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve budgets"})
+		return
+	}
+	c.JSON(http.StatusOK, budgets)
+}
+
+// SaveUsageBudget creates the budget for a scope, subject and period, or
+// replaces the existing one
+func (h *Handlers) SaveUsageBudget(c *gin.Context) {
+	var budget models.UsageBudget
+	if err := c.ShouldBindJSON(&budget); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpUsageWrite)
+	defer cancel()
+
+	if err := h.ledger.SaveBudget(ctx, &budget); err != nil {
+		if errors.Is(err, usage.ErrMissingSubject) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget: " + err.Error()})
+			return
+		}
+		log.Printf("Error saving usage budget: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save budget"})
+		return
+	}
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteUsageBudget removes a budget
+func (h *Handlers) DeleteUsageBudget(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID format"})
+		return
+	}
+
+	ctx, cancel := operationContext(c, config.OpUsageWrite)
+	defer cancel()
+
+	if err := h.ledger.DeleteBudget(ctx, id); err != nil {
+		if errors.Is(err, repositories.ErrBudgetNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+			return
+		}
+		log.Printf("Error deleting usage budget %s: %v", id.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveUsage runs a request through the usage routes with an optional
+// session cookie and admin token
+func serveUsage(h *Handlers, method, path, body, session, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/ai/agent", h.RunTaskAgent)
+	r.GET("/api/ai/usage", h.GetAIUsage)
+	budgets := r.Group("/api/ai/budgets", RequireAdmin())
+	budgets.GET("", h.ListUsageBudgets)
+	budgets.PUT("", h.SaveUsageBudget)
+	budgets.DELETE("/:id", h.DeleteUsageBudget)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// liveCerebras reports whether the model factory's Cerebras provider has an
+// API key, so tests that must not call the real API can skip
+func liveCerebras(h *Handlers) bool {
+	provider, ok := h.models.Provider(config.ProviderCerebras)
+	return ok && providerStatus(provider) == "ok"
+}
+
+// TestUsageBudgets tests warning and blocking requests as a user's budget is used up
+func TestUsageBudgets(t *testing.T) {
+	ledger := usage.NewLedger(repositories.NewMemoryUsageRepository())
+	h := New(Dependencies{Usage: ledger})
+	if liveCerebras(h) {
+		t.Skip("CEREBRAS_API_KEY is set; this test must not call the real API")
+	}
+	SetAdminToken("secret")
+	t.Cleanup(func() { SetAdminToken("") })
+	session := strings.Repeat("ab", 16)
+	ctx := context.Background()
+
+	// Budgets are managed with the admin token only
+	body := `{"scope": "user", "subject": "` + session + `", "period": "daily", "limit": 100}`
+	assert.Equal(t, http.StatusForbidden, serveUsage(h, "PUT", "/api/ai/budgets", body, session, "").Code)
+	assert.Equal(t, http.StatusForbidden, serveUsage(h, "PUT", "/api/ai/budgets", body, session, "wrong").Code)
+	assert.Equal(t, http.StatusBadRequest, serveUsage(h, "PUT", "/api/ai/budgets", `{"scope": "user", "period": "daily", "limit": 100}`, "", "secret").Code)
+	w := serveUsage(h, "PUT", "/api/ai/budgets", body, "", "secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var budget models.UsageBudget
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &budget))
+	assert.Equal(t, usage.DefaultWarnRatio, budget.WarnRatio)
+
+	// Nearly used up: the request goes ahead with a warning
+	require.NoError(t, ledger.Record(ctx, models.UsageRecord{UserID: session, Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 80, CompletionTokens: 5}))
+	w = serveUsage(h, "POST", "/api/ai/agent", `{"message": "¿Qué tareas tengo?"}`, session, "")
+	assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Header().Get(budgetWarningHeader), "85 of 100")
+
+	// Used up: the request is refused
+	require.NoError(t, ledger.Record(ctx, models.UsageRecord{UserID: session, Provider: "cerebras", Model: "qwen-3-32b", CompletionTokens: 15}))
+	w = serveUsage(h, "POST", "/api/ai/agent", `{"message": "¿Qué tareas tengo?"}`, session, "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "límite")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Other sessions are not affected
+	w = serveUsage(h, "POST", "/api/ai/agent", `{"message": "Hola"}`, strings.Repeat("cd", 16), "")
+	assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+
+	// Callers see their own usage and budgets
+	w = serveUsage(h, "GET", "/api/ai/usage?user_id=someone-else", "", session, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report UsageReportResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, int64(100), report.Total.TotalTokens)
+	require.Len(t, report.Budgets, 1)
+	assert.Equal(t, usage.LevelExceeded, report.Budgets[0].Level)
+
+	assert.Equal(t, http.StatusBadRequest, serveUsage(h, "GET", "/api/ai/usage?interval=week", "", session, "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveUsage(h, "GET", "/api/ai/usage?from=2025-06-01&to=2025-05-01", "", session, "").Code)
+
+	w = serveUsage(h, "GET", "/api/ai/budgets", "", "", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), session)
+	assert.Equal(t, http.StatusOK, serveUsage(h, "DELETE", "/api/ai/budgets/"+budget.ID.Hex(), "", "", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, serveUsage(h, "DELETE", "/api/ai/budgets/"+budget.ID.Hex(), "", "", "secret").Code)
+}
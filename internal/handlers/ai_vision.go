@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+)
+
+// imageLimits bounds the images accepted by the assistant
+var imageLimits = ai.DefaultImageLimits()
+
+// SetImageLimits sets the limits applied to uploaded images
+func SetImageLimits(limits ai.ImageLimits) {
+	imageLimits = limits
+}
+
+// readUploadedImage reads the image attached to an assistant request and
+// prepares it for the vision model. An error response is written when the
+// image cannot be used.
+func readUploadedImage(c *gin.Context, header *multipart.FileHeader) (*ai.PreparedImage, bool) {
+	if header.Size > imageLimits.MaxBytes {
+		respondImageError(c, ai.ErrImageTooLarge)
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded image: %v", err)
+		respondImageError(c, err)
+		return nil, false
+	}
+	defer file.Close()
+
+	// The declared size comes from the client, so the read is capped as well
+	data, err := io.ReadAll(io.LimitReader(file, imageLimits.MaxBytes+1))
+	if err != nil {
+		log.Printf("Error reading uploaded image: %v", err)
+		respondImageError(c, err)
+		return nil, false
+	}
+
+	image, err := ai.PrepareImage(data, imageLimits)
+	if err != nil {
+		log.Printf("Rejected uploaded image %q: %v", header.Filename, err)
+		respondImageError(c, err)
+		return nil, false
+	}
+	if image.Resized {
+		log.Printf("Downscaled uploaded image %q to %dx%d (%d bytes)", header.Filename, image.Width, image.Height, len(image.Data))
+	}
+	return image, true
+}
+
+// respondImageError writes the user-facing response for an unusable image
+func respondImageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ai.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf(
+			"La imagen es demasiado grande. Usa una imagen de máximo %d MB y %d megapíxeles.",
+			imageLimits.MaxBytes>>20, imageLimits.MaxPixels/1_000_000)})
+	case errors.Is(err, ai.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Formato de imagen no soportado. Usa una imagen JPG, PNG, WebP o GIF."})
+	case errors.Is(err, ai.ErrImageTooSmall):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La imagen es demasiado pequeña para analizarla."})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer la imagen. Verifica que el archivo no esté dañado."})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postAssistantImage sends a question with an attached file to the assistant handler
+func postAssistantImage(t *testing.T, h *Handlers, filename string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("query", "¿Qué muestra este plano?"))
+	require.NoError(t, form.WriteField("model_type", "qwen-3-32b"))
+	part, err := form.CreateFormFile("image", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/cerebras/assistant", h.GetCerebrasAIAssistance)
+	req := httptest.NewRequest("POST", "/api/cerebras/assistant", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.RemoteAddr = "192.0.2.10:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestAssistantImageUpload tests that uploaded images take the vision path
+func TestAssistantImageUpload(t *testing.T) {
+	h := New(Dependencies{})
+	if liveCerebras(h) {
+		t.Skip("CEREBRAS_API_KEY is set; this test must not call the real API")
+	}
+
+	// The type is detected from the content, not the file name
+	w := postAssistantImage(t, h, "plano.png", []byte("%PDF-1.7 no es una imagen"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 400, 300))))
+	w = postAssistantImage(t, h, "plano.png", buf.Bytes())
+	// Without an API key the vision model reports itself unavailable
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var response map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response["error"], "visión")
+}
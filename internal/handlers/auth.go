@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userIDKey holds the ID of the signed-in user of the current request
+const userIDKey = "user_id"
+
+// RequireUser rejects requests without a valid session with 401, except
+// those carrying the admin token, and makes the signed-in user the owner of
+// the conversations and usage of the request
+func (h *Handlers) RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.sessions == nil || isAdmin(c) {
+			c.Next()
+			return
+		}
+		userID, err := h.sessions.UserID(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		c.Set(userIDKey, userID)
+		c.Next()
+	}
+}
+
+// GetMe returns the signed-in user
+func (h *Handlers) GetMe(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpUserRead)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(c.GetString(userIDKey))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	user, err := h.users.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			// The account is gone; the session is no longer valid
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		} else {
+			log.Printf("Error fetching user %s: %v", id.Hex(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// Logout ends the caller's session
+func (h *Handlers) Logout(c *gin.Context) {
+	if h.sessions != nil {
+		h.sessions.Clear(c.Writer, c.Request)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/rag"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/session"
+	"github.com/lyffseba/ana/internal/usage"
+)
+
+// Dependencies are the storage and AI services the handlers use. Nil
+// fields are replaced with in-memory storage and the default models.
+type Dependencies struct {
+	Repositories *repositories.Repositories
+	// Sessions signs users in; without it RequireUser lets every request
+	// through and conversations belong to an anonymous browser session
+	Sessions *session.Manager
+	// Models serves the logical models the assistant handlers ask for
+	Models *ai.ModelFactory
+	// Usage records the tokens used by AI requests and enforces budgets
+	Usage *usage.Ledger
+	// Documents holds the documents answers are grounded in
+	Documents *rag.Store
+	// Prompts renders system prompts; new versions are stored in
+	// Repositories.Prompts
+	Prompts *prompts.Registry
+}
+
+// Handlers serves the task, project, user and assistant routes
+type Handlers struct {
+	tasks         repositories.TaskRepository
+	projects      repositories.ProjectRepository
+	conversations repositories.ConversationRepository
+	users         repositories.UserRepository
+	sessions      *session.Manager
+	models        *ai.ModelFactory
+	ledger        *usage.Ledger
+	documents     *rag.Store
+	prompts       *prompts.Registry
+	promptRepo    repositories.PromptRepository
+
+	// agent is the task assistant, created on first use
+	agent   *ai.Agent
+	agentMu sync.Mutex
+}
+
+// New returns handlers using deps. The model factory's completions are
+// recorded in the usage ledger.
+func New(deps Dependencies) *Handlers {
+	repos := deps.Repositories
+	if repos == nil {
+		repos = repositories.NewMemoryRepositories()
+	}
+	h := &Handlers{
+		tasks:         repos.Tasks,
+		projects:      repos.Projects,
+		conversations: repos.Conversations,
+		users:         repos.Users,
+		sessions:      deps.Sessions,
+		models:        deps.Models,
+		ledger:        deps.Usage,
+		documents:     deps.Documents,
+		prompts:       deps.Prompts,
+		promptRepo:    repos.Prompts,
+	}
+	if h.models == nil {
+		factory, err := ai.NewModelFactoryFromConfig(config.DefaultLLMConfig())
+		if err != nil {
+			// The default configuration is always valid
+			log.Panicf("Error creating default model factory: %v", err)
+		}
+		h.models = factory
+	}
+	if h.ledger == nil {
+		h.ledger = usage.NewLedger(repositories.NewMemoryUsageRepository())
+	}
+	if h.documents == nil {
+		h.documents = rag.NewStore(repositories.NewMemoryDocumentRepository(), ai.NewHashEmbedder(512))
+	}
+	if h.prompts == nil {
+		h.prompts = prompts.NewRegistry()
+	}
+	h.models.SetUsageRecorder(h.ledger.Recorder())
+	return h
+}
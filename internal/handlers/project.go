@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetProjects returns all projects
+func (h *Handlers) GetProjects(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectRead)
+	defer cancel()
+
+	projects, err := h.projects.FindAll(ctx)
+	if err != nil {
+		log.Printf("Error fetching projects: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProjectByID returns a specific project by ID
+func (h *Handlers) GetProjectByID(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectRead)
+	defer cancel()
+
+	objectID, ok := parseObjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projects.FindByID(ctx, objectID)
+	if err != nil {
+		respondProjectLookupError(c, objectID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// CreateProject creates a new project
+func (h *Handlers) CreateProject(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectWrite)
+	defer cancel()
+
+	var newProject models.Project
+	if err := c.ShouldBindJSON(&newProject); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if newProject.Status == "" {
+		newProject.Status = models.ProjectStatusActive
+	}
+	if !validProjectDates(newProject) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	if err := h.projects.Create(ctx, &newProject); err != nil {
+		log.Printf("Error creating project: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newProject)
+}
+
+// UpdateProject updates an existing project
+func (h *Handlers) UpdateProject(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectWrite)
+	defer cancel()
+
+	objectID, ok := parseObjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	existingProject, err := h.projects.FindByID(ctx, objectID)
+	if err != nil {
+		respondProjectLookupError(c, objectID, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&existingProject); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validProjectDates(existingProject) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	// Ensure ID remains the same
+	existingProject.ID = objectID
+
+	if err := h.projects.Update(ctx, &existingProject); err != nil {
+		log.Printf("Error updating project with ID %s: %v", objectID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, existingProject)
+}
+
+// DeleteProject removes a project.
+// Projects that still have tasks cannot be deleted.
+func (h *Handlers) DeleteProject(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectWrite)
+	defer cancel()
+
+	objectID, ok := parseObjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.projects.FindByID(ctx, objectID); err != nil {
+		respondProjectLookupError(c, objectID, err)
+		return
+	}
+
+	taskCount, err := h.tasks.CountByProject(ctx, objectID)
+	if err != nil {
+		log.Printf("Error counting tasks for project %s: %v", objectID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	if taskCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Project still has tasks", "task_count": taskCount})
+		return
+	}
+
+	if err := h.projects.Delete(ctx, objectID); err != nil {
+		log.Printf("Error deleting project with ID %s: %v", objectID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// GetProjectTasks returns all tasks that belong to a project
+func (h *Handlers) GetProjectTasks(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpProjectRead)
+	defer cancel()
+
+	objectID, ok := parseObjectIDParam(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.projects.FindByID(ctx, objectID); err != nil {
+		respondProjectLookupError(c, objectID, err)
+		return
+	}
+
+	tasks, err := h.tasks.FindByProject(ctx, objectID)
+	if err != nil {
+		log.Printf("Error fetching tasks for project %s: %v", objectID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// validateTaskProject checks that the project a task points at exists.
+// Tasks without a project are allowed. It writes the error response and
+// returns false when the task must be rejected.
+func (h *Handlers) validateTaskProject(ctx context.Context, c *gin.Context, task models.Task) bool {
+	if task.ProjectID.IsZero() {
+		return true
+	}
+
+	exists, err := h.projects.Exists(ctx, task.ProjectID)
+	if err != nil {
+		log.Printf("Error checking project %s: %v", task.ProjectID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify project"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project does not exist"})
+		return false
+	}
+	return true
+}
+
+// parseObjectIDParam parses the :id path parameter, writing a 400 response if invalid
+func parseObjectIDParam(c *gin.Context) (primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
+
+// respondProjectLookupError maps a project lookup error to an HTTP response
+func respondProjectLookupError(c *gin.Context, id primitive.ObjectID, err error) {
+	if errors.Is(err, repositories.ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	log.Printf("Error fetching project with ID %s: %v", id.Hex(), err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project"})
+}
+
+// validProjectDates reports whether the project's date range is consistent
+func validProjectDates(p models.Project) bool {
+	return p.StartDate.IsZero() || p.EndDate.IsZero() || !p.EndDate.Before(p.StartDate)
+}
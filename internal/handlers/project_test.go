@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serve runs a request through a router with the project and task handlers
+func serve(h *Handlers, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/tasks", h.CreateTask)
+	r.GET("/api/tasks", h.GetTasks)
+	r.DELETE("/api/projects/:id", h.DeleteProject)
+	r.GET("/api/projects/:id/tasks", h.GetProjectTasks)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestCreateTaskRequiresExistingProject tests that tasks cannot reference unknown projects
+func TestCreateTaskRequiresExistingProject(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	h := New(Dependencies{Repositories: repos})
+
+	w := serve(h, "POST", "/api/tasks", `{"title":"Visita de obra","priority":"Low","status":"To-Do","project_id":"`+primitive.NewObjectID().Hex()+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	project := models.Project{Name: "Casa Usaquén"}
+	require.NoError(t, repos.Projects.Create(context.Background(), &project))
+	w = serve(h, "POST", "/api/tasks", `{"title":"Visita de obra","priority":"Low","status":"To-Do","project_id":"`+project.ID.Hex()+`"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = serve(h, "GET", "/api/projects/"+project.ID.Hex()+"/tasks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var tasks []models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+	assert.Len(t, tasks, 1)
+}
+
+// TestDeleteProjectWithTasks tests that projects with tasks are not deleted
+func TestDeleteProjectWithTasks(t *testing.T) {
+	repos := repositories.NewMemoryRepositories()
+	h := New(Dependencies{Repositories: repos})
+
+	project := models.Project{Name: "Casa Usaquén"}
+	require.NoError(t, repos.Projects.Create(context.Background(), &project))
+	task := models.Task{Title: "Visita de obra", ProjectID: project.ID}
+	require.NoError(t, repos.Tasks.Create(context.Background(), &task))
+
+	w := serve(h, "DELETE", "/api/projects/"+project.ID.Hex(), "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	require.NoError(t, repos.Tasks.Delete(context.Background(), task.ID))
+	w = serve(h, "DELETE", "/api/projects/"+project.ID.Hex(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h, "DELETE", "/api/projects/"+project.ID.Hex(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
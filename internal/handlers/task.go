@@ -4,32 +4,167 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/config"
 	"github.com/lyffseba/ana/internal/models"
 	"github.com/lyffseba/ana/internal/repositories"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// taskRepo is the repository for task operations
-var taskRepo = repositories.NewTaskRepository()
+// timeouts holds the deadlines applied to repository calls
+var timeouts = config.DefaultTimeouts()
 
-// GetTasks returns all tasks
-func GetTasks(c *gin.Context) {
-	tasks, err := taskRepo.FindAll()
+// SetTimeouts sets the per-operation deadlines used by the handlers
+func SetTimeouts(t config.TimeoutConfig) {
+	timeouts = t
+}
+
+// operationContext derives the context for an operation from the request,
+// so a disconnected client cancels the work and the configured deadline applies
+func operationContext(c *gin.Context, op string) (context.Context, context.CancelFunc) {
+	return timeouts.WithDeadline(c.Request.Context(), op)
+}
+
+// GetTasks returns one page of tasks.
+// Supported query parameters: status, priority (comma-separated or repeated),
+// project_id, due_from, due_to (YYYY-MM-DD or RFC 3339), q (title search),
+// sort (due_date, created_at, updated_at or title; prefix "-" for descending),
+// limit and cursor (the next_cursor of a previous page).
+func (h *Handlers) GetTasks(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskRead)
+	defer cancel()
+
+	query, err := parseTaskQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.tasks.Find(ctx, query)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) || errors.Is(err, repositories.ErrEmptyDueRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Printf("Error fetching tasks: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, page)
+}
+
+// parseTaskQuery builds a TaskQuery from the request's query parameters
+func parseTaskQuery(c *gin.Context) (repositories.TaskQuery, error) {
+	var query repositories.TaskQuery
+
+	query.Status = splitQueryList(c.QueryArray("status"))
+	for _, status := range query.Status {
+		if !validTaskStatuses[status] {
+			return query, fmt.Errorf("invalid status: %s", status)
+		}
+	}
+	query.Priority = splitQueryList(c.QueryArray("priority"))
+	for _, priority := range query.Priority {
+		if !validTaskPriorities[priority] {
+			return query, fmt.Errorf("invalid priority: %s", priority)
+		}
+	}
+
+	if projectID := c.Query("project_id"); projectID != "" {
+		id, err := primitive.ObjectIDFromHex(projectID)
+		if err != nil {
+			return query, fmt.Errorf("invalid project_id: %s", projectID)
+		}
+		query.ProjectID = id
+	}
+
+	if dueFrom := c.Query("due_from"); dueFrom != "" {
+		t, _, err := parseDateParam(dueFrom)
+		if err != nil {
+			return query, fmt.Errorf("invalid due_from: %s", dueFrom)
+		}
+		query.DueFrom = t
+	}
+	if dueTo := c.Query("due_to"); dueTo != "" {
+		t, dateOnly, err := parseDateParam(dueTo)
+		if err != nil {
+			return query, fmt.Errorf("invalid due_to: %s", dueTo)
+		}
+		// A plain date includes the whole day
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		query.DueBefore = t
+	}
+
+	query.Search = strings.TrimSpace(c.Query("q"))
+
+	if sortExpr := c.Query("sort"); sortExpr != "" {
+		field, descending, err := repositories.ParseTaskSort(sortExpr)
+		if err != nil {
+			return query, err
+		}
+		query.SortBy = field
+		query.Descending = descending
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, fmt.Errorf("invalid limit: %s", limit)
+		}
+		query.Limit = n
+	}
+
+	query.Cursor = c.Query("cursor")
+	return query, nil
+}
+
+var (
+	validTaskStatuses   = map[string]bool{"To-Do": true, "In-Progress": true, "Done": true}
+	validTaskPriorities = map[string]bool{"Low": true, "Medium": true, "High": true}
+)
+
+// splitQueryList flattens repeated and comma-separated query values
+func splitQueryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// parseDateParam parses a YYYY-MM-DD date, in Bogotá whatever the server's
+// time zone, or an RFC 3339 timestamp. The boolean reports whether the value
+// was a plain date.
+func parseDateParam(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, tools.Bogota); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
 }
 
 // GetTaskByID returns a specific task by ID
-func GetTaskByID(c *gin.Context) {
+func (h *Handlers) GetTaskByID(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskRead)
+	defer cancel()
+
 	idStr := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -37,7 +172,7 @@ func GetTaskByID(c *gin.Context) {
 		return
 	}
 
-	task, err := taskRepo.FindByID(objectID)
+	task, err := h.tasks.FindByID(ctx, objectID)
 	if err != nil {
 		log.Printf("Error fetching task with ID %s: %v", idStr, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
@@ -48,15 +183,22 @@ func GetTaskByID(c *gin.Context) {
 }
 
 // CreateTask creates a new task
-func CreateTask(c *gin.Context) {
+func (h *Handlers) CreateTask(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskWrite)
+	defer cancel()
+
 	var newTask models.Task
 	if err := c.ShouldBindJSON(&newTask); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !h.validateTaskProject(ctx, c, newTask) {
+		return
+	}
+
 	// Save to database using repository
-	if err := taskRepo.Create(&newTask); err != nil {
+	if err := h.tasks.Create(ctx, &newTask); err != nil {
 		log.Printf("Error creating task: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
@@ -66,7 +208,10 @@ func CreateTask(c *gin.Context) {
 }
 
 // UpdateTask updates an existing task
-func UpdateTask(c *gin.Context) {
+func (h *Handlers) UpdateTask(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskWrite)
+	defer cancel()
+
 	idStr := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -75,7 +220,7 @@ func UpdateTask(c *gin.Context) {
 	}
 
 	// First find the existing task
-	existingTask, err := taskRepo.FindByID(objectID)
+	existingTask, err := h.tasks.FindByID(ctx, objectID)
 	if err != nil {
 		log.Printf("Error finding task to update with ID %s: %v", idStr, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
@@ -91,8 +236,12 @@ func UpdateTask(c *gin.Context) {
 	// Ensure ID remains the same
 	existingTask.ID = objectID
 
+	if !h.validateTaskProject(ctx, c, existingTask) {
+		return
+	}
+
 	// Update in the database
-	if err := taskRepo.Update(&existingTask); err != nil {
+	if err := h.tasks.Update(ctx, &existingTask); err != nil {
 		log.Printf("Error updating task with ID %s: %v", idStr, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
@@ -102,7 +251,10 @@ func UpdateTask(c *gin.Context) {
 }
 
 // DeleteTask removes a task
-func DeleteTask(c *gin.Context) {
+func (h *Handlers) DeleteTask(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskWrite)
+	defer cancel()
+
 	idStr := c.Param("id")
 	objectID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -111,7 +263,7 @@ func DeleteTask(c *gin.Context) {
 	}
 
 	// Check if task exists
-	_, err = taskRepo.FindByID(objectID)
+	_, err = h.tasks.FindByID(ctx, objectID)
 	if err != nil {
 		log.Printf("Error finding task to delete with ID %s: %v", idStr, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
@@ -119,7 +271,7 @@ func DeleteTask(c *gin.Context) {
 	}
 
 	// Delete from database
-	if err := taskRepo.Delete(objectID); err != nil {
+	if err := h.tasks.Delete(ctx, objectID); err != nil {
 		log.Printf("Error deleting task with ID %s: %v", idStr, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
@@ -129,8 +281,11 @@ func DeleteTask(c *gin.Context) {
 }
 
 // GetTasksDueToday returns all tasks due today
-func GetTasksDueToday(c *gin.Context) {
-	todaysTasks, err := taskRepo.FindTasksDueToday()
+func (h *Handlers) GetTasksDueToday(c *gin.Context) {
+	ctx, cancel := operationContext(c, config.OpTaskRead)
+	defer cancel()
+
+	todaysTasks, err := h.tasks.FindTasksDueToday(ctx)
 	if err != nil {
 		log.Printf("Error fetching today's tasks: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve today's tasks"})
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai/tools"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newQueryContext builds a gin context for a GET request with the given query string
+func newQueryContext(rawQuery string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/tasks?"+rawQuery, nil)
+	return c
+}
+
+// TestParseTaskQuery tests the mapping of query parameters to a TaskQuery
+func TestParseTaskQuery(t *testing.T) {
+	c := newQueryContext("status=To-Do,In-Progress&priority=High&priority=Medium&project_id=64b7f0c2a1b2c3d4e5f60718&due_from=2025-06-01&due_to=2025-06-30&q=planos&sort=-created_at&limit=20&cursor=abc")
+	query, err := parseTaskQuery(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"To-Do", "In-Progress"}, query.Status)
+	assert.Equal(t, []string{"High", "Medium"}, query.Priority)
+	assert.Equal(t, "64b7f0c2a1b2c3d4e5f60718", query.ProjectID.Hex())
+	// Plain dates are days in Bogotá, whatever the server's time zone
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, tools.Bogota), query.DueFrom)
+	assert.Equal(t, time.Date(2025, 6, 1, 5, 0, 0, 0, time.UTC), query.DueFrom.UTC())
+	// due_to is inclusive of the whole day
+	assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, tools.Bogota), query.DueBefore)
+	assert.Equal(t, "planos", query.Search)
+	assert.Equal(t, repositories.SortByCreatedAt, query.SortBy)
+	assert.True(t, query.Descending)
+	assert.Equal(t, 20, query.Limit)
+	assert.Equal(t, "abc", query.Cursor)
+}
+
+// TestParseTaskQueryErrors tests rejection of invalid parameters
+func TestParseTaskQueryErrors(t *testing.T) {
+	for _, raw := range []string{
+		"status=Pending",
+		"priority=Urgent",
+		"project_id=123",
+		"due_from=mañana",
+		"sort=priority",
+		"limit=-1",
+	} {
+		t.Run(raw, func(t *testing.T) {
+			_, err := parseTaskQuery(newQueryContext(raw))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestGetTasksEmptyDueRange tests that a due_to before due_from is a bad request
+func TestGetTasksEmptyDueRange(t *testing.T) {
+	h := New(Dependencies{})
+
+	w := serve(h, "GET", "/api/tasks?due_from=2025-06-30&due_to=2025-06-01", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"due date range is empty"}`, w.Body.String())
+
+	w = serve(h, "GET", "/api/tasks?due_from=2025-06-01&due_to=2025-06-01", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
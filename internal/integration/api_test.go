@@ -4,62 +4,391 @@
 package integration
 
 import (
-    "testing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/processors"
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/handlers"
+	"github.com/lyffseba/ana/internal/lifecycle"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/server"
+	"github.com/lyffseba/ana/internal/session"
+	"github.com/lyffseba/ana/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// TODO: Integration tests are disabled until config/logger types and Handler are updated to match actual API implementation.
-// func TestAPIIntegration(t *testing.T) {
-//     // Create test configuration
-//     cfg := &config.Config{
-//         Server: config.ServerConfig{
-//             Port: 8080,
-//         },
-//         AI: config.AIConfig{
-//             Cerebras: config.CerebrasConfig{
-//                 Endpoint: "http://test-cerebras",
-//                 APIKey:   "test-key",
-//             },
-//         },
-//         Logging: config.LoggingConfig{
-//             Level:  "debug",
-//             Format: "json",
-//             Output: "stdout",
-//         },
-//     }
-
-//     // Create logger
-//     logger, err := logging.NewLogger(&cfg.Logging)
-//     require.NoError(t, err)
-
-//     // Create API
-//     apiServer, err := api.NewAPI(cfg, logger)
-//     require.NoError(t, err)
-
-//     // Create test server
-//     server := httptest.NewServer(apiServer.Handler())
-//     defer server.Close()
-
-//     // Test cases
-//     tests := []struct {
-//         name           string
-//         method         string
-//         path           string
-//         body           interface{}
-//         expectedStatus int
-//         expectedBody   interface{}
-//     }{
-//         {
-//             name:           "health check",
-//             method:         "GET",
-//             path:          "/health",
-//             expectedStatus: http.StatusOK,
-//             expectedBody: map[string]string{
-//                 "status": "healthy",
-//             },
-//         },
-//         {
-//     }
+// echoProcessor answers each request with its text in upper case after a
+// delay, so WebSocket answers overlap
+type echoProcessor struct {
+	mu       sync.Mutex
+	requests int64
+}
+
+func (p *echoProcessor) Process(ctx context.Context, input []byte) ([]byte, error) {
+	var request processors.Request
+	if err := json.Unmarshal(input, &request); err != nil || request.Text == "" {
+		return nil, processors.ErrInvalidInput
+	}
+	p.mu.Lock()
+	p.requests++
+	p.mu.Unlock()
+	select {
+	case <-time.After(20 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return json.Marshal(processors.Response{Text: strings.ToUpper(request.Text), Model: "echo"})
+}
+
+func (p *echoProcessor) Train(ctx context.Context, data []byte) error {
+	return errors.New("not supported")
+}
+
+func (p *echoProcessor) Evaluate(ctx context.Context) (*processors.EvaluationResult, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *echoProcessor) GetMetrics() *processors.Metrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &processors.Metrics{RequestCount: p.requests}
+}
+
+func (p *echoProcessor) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = 0
+}
+
+// newTestServer starts the server with an echo processor and a model served
+// by a fake OpenAI-compatible endpoint whose usage is recorded in ledger;
+// manager and ledger may be nil
+func newTestServer(t *testing.T, manager *lifecycle.Manager, ledger *usage.Ledger) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "chatcmpl-1", "model": "test-model", "choices": [{"message": {"role": "assistant", "content": "Hola"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}}`)
+	}))
+	t.Cleanup(llm.Close)
+	cfg := &config.Config{AI: config.AIConfig{LLM: config.LLMConfig{
+		Providers: map[string]config.ProviderConfig{"local": {Type: config.ProviderOpenAI, Endpoint: llm.URL + "/v1/"}},
+		Models:    map[string]config.ModelConfig{"extract": {Provider: "local", Model: "test-model"}},
+	}}}
+	factory, err := ai.NewModelFactoryFromConfig(cfg.AI.ResolvedLLM())
+	require.NoError(t, err)
+	t.Cleanup(func() { factory.Close() })
+	service := ai.NewAIService(cfg, factory)
+	require.NoError(t, service.InitializeModels(context.Background()))
+
+	model, err := factory.ProviderModel("local", config.ModelConfig{})
+	require.NoError(t, err)
+	processorManager := processors.NewProcessorManager()
+	processorManager.RegisterProcessor("echo", &echoProcessor{})
+	processorManager.RegisterProcessor("local", processors.NewCerebrasProcessor(&processors.Config{ModelID: "test-model"}, model))
+
+	srv := httptest.NewServer(server.New(server.Dependencies{
+		Models:     factory,
+		Usage:      ledger,
+		AIService:  service,
+		Processors: processorManager,
+		Lifecycle:  manager,
+
+		AllowedOrigins: []string{"https://ana.world"},
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// do sends a request with a JSON body and returns the status and body
+func do(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+// TestAPIIntegration tests the routes of the server under /api/v1 and the
+// /api alias
+func TestAPIIntegration(t *testing.T) {
+	srv := newTestServer(t, nil, nil)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"health check", "GET", "/api/v1/health", "", http.StatusOK, `"status"`},
+		{"readiness", "GET", "/ready", "", http.StatusOK, `{"status":"ready"}`},
+		{"tasks", "GET", "/api/v1/tasks", "", http.StatusOK, `"tasks"`},
+		{"tasks alias", "GET", "/api/tasks", "", http.StatusOK, `"tasks"`},
+		{"models", "GET", "/api/v1/ai/models", "", http.StatusOK, `"name":"extract"`},
+		{"process", "POST", "/api/v1/ai/process", `{"model": "extract", "input": {"prompt": "Saluda"}}`, http.StatusOK, `"output":"Hola"`},
+		{"process unknown model", "POST", "/api/v1/ai/process", `{"model": "missing", "input": {"prompt": "x"}}`, http.StatusNotFound, `"error"`},
+		{"process invalid", "POST", "/api/v1/ai/process", `{`, http.StatusBadRequest, `{"error":"Invalid request format"}`},
+		{"processor", "POST", "/api/v1/ai/processors/process", `{"processor": "echo", "input": "hola"}`, http.StatusOK, `"text":"HOLA"`},
+		{"processor missing fields", "POST", "/api/v1/ai/processors/process", `{"processor": "echo"}`, http.StatusBadRequest, `{"error":"Missing required fields"}`},
+		{"processor metrics", "GET", "/api/v1/ai/processors/metrics", "", http.StatusOK, `"echo"`},
+		{"processor metrics reset needs admin", "POST", "/api/v1/ai/processors/metrics/reset", "", http.StatusForbidden, `"error"`},
+		{"unknown endpoint", "GET", "/api/v1/missing", "", http.StatusNotFound, `{"error":"API endpoint not found"}`},
+	}
+	for _, tt := range tests {
+		status, body := do(t, tt.method, srv.URL+tt.path, tt.body)
+		assert.Equal(t, tt.expectedStatus, status, tt.name)
+		assert.Contains(t, body, tt.expectedBody, tt.name)
+	}
+
+	// Requests are recorded once, labelled with their route pattern
+	_, body := do(t, "GET", srv.URL+"/metrics", "")
+	assert.Contains(t, body, `ana_http_requests_total{method="GET",path="/api/v1/tasks",status="200"} 1`)
+	assert.Contains(t, body, `ana_http_requests_total{method="GET",path="unmatched",status="404"}`)
+}
+
+// TestProcessUsageIntegration tests that /ai/process records its tokens in
+// the usage ledger and is refused once a budget is used up
+func TestProcessUsageIntegration(t *testing.T) {
+	ledger := usage.NewLedger(repositories.NewMemoryUsageRepository())
+	srv := newTestServer(t, nil, ledger)
+	require.NoError(t, ledger.SaveBudget(context.Background(), &models.UsageBudget{
+		Scope:  models.BudgetScopeGlobal,
+		Period: models.BudgetPeriodDaily,
+		Limit:  6,
+	}))
+
+	status, body := do(t, "POST", srv.URL+"/api/v1/ai/process", `{"model": "extract", "input": {"prompt": "Saluda"}}`)
+	assert.Equal(t, http.StatusOK, status, body)
+	report, err := ledger.Report(context.Background(), usage.ReportQuery{From: time.Now().AddDate(0, 0, -1), To: time.Now().AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), report.Total.TotalTokens)
+	assert.Equal(t, int64(1), report.Total.Requests)
+
+	status, body = do(t, "POST", srv.URL+"/api/v1/ai/process", `{"model": "extract", "input": {"prompt": "Saluda"}}`)
+	assert.Equal(t, http.StatusTooManyRequests, status, body)
+}
+
+// TestProcessorUsageIntegration tests that processor requests, over HTTP and
+// WebSockets, are counted against the usage budgets
+func TestProcessorUsageIntegration(t *testing.T) {
+	ledger := usage.NewLedger(repositories.NewMemoryUsageRepository())
+	srv := newTestServer(t, nil, ledger)
+	require.NoError(t, ledger.SaveBudget(context.Background(), &models.UsageBudget{
+		Scope:  models.BudgetScopeGlobal,
+		Period: models.BudgetPeriodDaily,
+		Limit:  6,
+	}))
+
+	status, body := do(t, "POST", srv.URL+"/api/v1/ai/processors/process", `{"processor": "local", "input": "Saluda"}`)
+	assert.Equal(t, http.StatusOK, status, body)
+	report, err := ledger.Report(context.Background(), usage.ReportQuery{From: time.Now().AddDate(0, 0, -1), To: time.Now().AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), report.Total.TotalTokens)
+
+	status, body = do(t, "POST", srv.URL+"/api/v1/ai/processors/process", `{"processor": "local", "input": "Saluda"}`)
+	assert.Equal(t, http.StatusTooManyRequests, status, body)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/v1/ws", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+// TestWebSocketOrigin tests that only the server's own pages and the allowed
+// origins may open WebSockets
+func TestWebSocketOrigin(t *testing.T) {
+	srv := newTestServer(t, nil, nil)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws"
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{"same origin", srv.URL, http.StatusSwitchingProtocols},
+		{"allowed origin", "https://ana.world", http.StatusSwitchingProtocols},
+		{"no origin", "", http.StatusSwitchingProtocols},
+		{"other site", "https://evil.example", http.StatusForbidden},
+		{"allowed origin on another scheme", "http://ana.world", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+			if conn != nil {
+				conn.Close()
+			}
+			require.NotNil(t, resp, "dial error: %v", err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+// TestWebSocketIntegration tests processing messages over a WebSocket
 func TestWebSocketIntegration(t *testing.T) {
-    // Placeholder: WebSocket integration tests to be implemented
-    t.Skip("WebSocket tests to be implemented")
+	srv := newTestServer(t, nil, nil)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/v1/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Messages are processed concurrently and answered as they complete
+	inputs := []string{"uno", "dos", "tres"}
+	for _, input := range inputs {
+		require.NoError(t, conn.WriteJSON(map[string]string{"type": "process", "processor": "echo", "input": input}))
+	}
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+
+	var texts, errs []string
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for range len(inputs) + 1 {
+		var response struct {
+			Text  string `json:"text"`
+			Error string `json:"error"`
+		}
+		require.NoError(t, conn.ReadJSON(&response))
+		if response.Error != "" {
+			errs = append(errs, response.Error)
+		} else {
+			texts = append(texts, response.Text)
+		}
+	}
+	assert.ElementsMatch(t, []string{"UNO", "DOS", "TRES"}, texts)
+	assert.Equal(t, []string{"Invalid message format"}, errs)
+}
+
+// TestWebSocketShutdown tests that shutting down answers the messages being
+// processed before closing the connections, and refuses new ones
+func TestWebSocketShutdown(t *testing.T) {
+	manager := lifecycle.New()
+	manager.SetReady(true)
+	srv := newTestServer(t, manager, nil)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteJSON(map[string]string{"type": "process", "processor": "echo", "input": "uno"}))
+	time.Sleep(5 * time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- manager.Shutdown(context.Background())
+	}()
+
+	// Readiness is withdrawn at once
+	require.Eventually(t, func() bool {
+		status, _ := do(t, "GET", srv.URL+"/ready", "")
+		return status == http.StatusServiceUnavailable
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var response struct {
+		Text string `json:"text"`
+	}
+	require.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, "UNO", response.Text)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "got %v", err)
+	require.NoError(t, <-shutdown)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+// TestSessionIntegration tests that the task and AI routes need a signed-in
+// user once sessions are configured
+func TestSessionIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repositories.NewMemoryRepositories()
+	sessions := session.NewManager([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	handlers.SetAdminToken("secret")
+	t.Cleanup(func() { handlers.SetAdminToken("") })
+	srv := httptest.NewServer(server.New(server.Dependencies{Repositories: repos, Sessions: sessions}))
+	t.Cleanup(srv.Close)
+
+	user := &models.User{GoogleID: "1234567890", Email: "ana@example.com", Name: "Ana"}
+	require.NoError(t, repos.Users.SignIn(context.Background(), user))
+	w := httptest.NewRecorder()
+	sessions.Issue(w, httptest.NewRequest("GET", "/", nil), user.ID.Hex())
+	cookie := w.Result().Cookies()[0]
+
+	send := func(method, path, header, value string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	header := cookie.Name + "=" + cookie.Value
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		status int
+		body   string
+	}{
+		{"tasks without a session", "GET", "/api/v1/tasks", "", "", http.StatusUnauthorized, "Authentication required"},
+		{"ai without a session", "GET", "/api/v1/ai/conversations", "", "", http.StatusUnauthorized, "Authentication required"},
+		{"forged session", "GET", "/api/v1/tasks", "Cookie", header + "x", http.StatusUnauthorized, "Authentication required"},
+		{"tasks with a session", "GET", "/api/v1/tasks", "Cookie", header, http.StatusOK, "["},
+		{"tasks with the admin token", "GET", "/api/v1/tasks", "Authorization", "Bearer secret", http.StatusOK, "["},
+		{"me", "GET", "/api/v1/me", "Cookie", header, http.StatusOK, `"email":"ana@example.com"`},
+		{"me without a session", "GET", "/api/me", "", "", http.StatusUnauthorized, "Authentication required"},
+		{"health stays open", "GET", "/health", "", "", http.StatusOK, `"status"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := send(tt.method, tt.path, tt.header, tt.value)
+			assert.Equal(t, tt.status, status, body)
+			assert.Contains(t, body, tt.body)
+			assert.NotContains(t, body, "1234567890", "the Google ID is not exposed")
+		})
+	}
+
+	// Logging out clears the cookie
+	req, err := http.NewRequest("POST", srv.URL+"/api/v1/auth/logout", nil)
+	require.NoError(t, err)
+	req.AddCookie(cookie)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Len(t, resp.Cookies(), 1)
+	assert.Equal(t, session.CookieName, resp.Cookies()[0].Name)
+	assert.Negative(t, resp.Cookies()[0].MaxAge)
 }
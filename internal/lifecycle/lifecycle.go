@@ -0,0 +1,126 @@
+// Package lifecycle runs the HTTP server until it is told to stop, then
+// drains it and releases the server's resources in order
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options tune a graceful shutdown
+type Options struct {
+	// Delay is how long requests are still served after readiness is
+	// withdrawn, so load balancers stop sending traffic first
+	Delay time.Duration
+	// Timeout bounds the drain of in-flight requests, and separately the
+	// shutdown hooks. Requests still running after it are cut off.
+	Timeout time.Duration
+}
+
+// hook is a resource stopped on shutdown
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager tracks whether the server is ready for traffic and the hooks that
+// stop its resources. The zero value is not ready and has no hooks.
+type Manager struct {
+	mu    sync.Mutex
+	hooks []hook
+	ready atomic.Bool
+}
+
+// New creates a manager that is not ready yet
+func New() *Manager {
+	return &Manager{}
+}
+
+// OnShutdown registers stop to be called on shutdown. Hooks are called in
+// the reverse order of registration, like deferred calls, so a resource is
+// stopped before the ones it was built on, e.g. WebSocket connections before
+// the caches and the caches before the database.
+func (m *Manager) OnShutdown(name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// Ready reports whether the server accepts traffic
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// SetReady sets whether the server accepts traffic
+func (m *Manager) SetReady(ready bool) {
+	m.ready.Store(ready)
+}
+
+// Shutdown withdraws readiness and calls every hook, even when earlier ones
+// fail. The errors of the hooks are returned together.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.SetReady(false)
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Serve runs srv on ln and reports ready until ctx is done, e.g. on
+// SIGTERM. It then withdraws readiness, keeps serving for opts.Delay, waits
+// up to opts.Timeout for in-flight requests and finally calls the shutdown
+// hooks. The hooks also run when the server fails.
+func (m *Manager) Serve(ctx context.Context, srv *http.Server, ln net.Listener, opts Options) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+	m.SetReady(true)
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		errs = append(errs, fmt.Errorf("serving: %w", err))
+	case <-ctx.Done():
+		m.SetReady(false)
+		if opts.Delay > 0 {
+			time.Sleep(opts.Delay)
+		}
+		drainCtx, cancel := withTimeout(opts.Timeout)
+		if err := srv.Shutdown(drainCtx); err != nil {
+			errs = append(errs, fmt.Errorf("draining requests: %w", err))
+			srv.Close()
+		}
+		cancel()
+		<-serveErr
+	}
+
+	hooksCtx, cancel := withTimeout(opts.Timeout)
+	defer cancel()
+	errs = append(errs, m.Shutdown(hooksCtx))
+	return errors.Join(errs...)
+}
+
+// withTimeout returns a context that expires after timeout, or never when it
+// is zero
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -0,0 +1,122 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownHooks(t *testing.T) {
+	m := New()
+	var order []string
+	m.OnShutdown("database", func(context.Context) error {
+		order = append(order, "database")
+		return nil
+	})
+	m.OnShutdown("cache", func(context.Context) error {
+		order = append(order, "cache")
+		return errors.New("redis unreachable")
+	})
+	m.OnShutdown("websockets", func(context.Context) error {
+		order = append(order, "websockets")
+		return nil
+	})
+	m.SetReady(true)
+
+	err := m.Shutdown(context.Background())
+	assert.EqualError(t, err, "stopping cache: redis unreachable")
+	assert.Equal(t, []string{"websockets", "cache", "database"}, order, "hooks run in reverse order, even after a failure")
+	assert.False(t, m.Ready())
+
+	// Hooks run once
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.Len(t, order, 3)
+}
+
+func TestServeDrainsRequests(t *testing.T) {
+	m := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})}
+	var stopped bool
+	m.OnShutdown("jobs", func(context.Context) error {
+		stopped = true
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- m.Serve(ctx, srv, ln, Options{Delay: 50 * time.Millisecond, Timeout: 5 * time.Second})
+	}()
+
+	// A request is in flight when the server is told to stop
+	var wg sync.WaitGroup
+	var body string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = string(data)
+		}
+	}()
+	<-started
+	assert.True(t, m.Ready())
+	cancel()
+	require.Eventually(t, func() bool { return !m.Ready() }, time.Second, 5*time.Millisecond)
+
+	// It is answered before the hooks run
+	select {
+	case <-served:
+		t.Fatal("Serve returned with a request in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.False(t, stopped)
+	close(release)
+	require.NoError(t, <-served)
+	wg.Wait()
+	assert.Equal(t, "done", body)
+	assert.True(t, stopped)
+}
+
+func TestServeTimeout(t *testing.T) {
+	m := New()
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- m.Serve(ctx, srv, ln, Options{Timeout: 50 * time.Millisecond})
+	}()
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+	cancel()
+
+	err = <-served
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "requests still running after the timeout are cut off")
+}
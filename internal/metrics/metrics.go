@@ -5,6 +5,9 @@ package metrics
 
 import (
     "fmt"
+    "sync"
+
+    "github.com/lyffseba/ana/internal/monitoring"
     "github.com/prometheus/client_golang/prometheus"
     "github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -13,12 +16,16 @@ import (
 type Metrics struct {
     requestCounter   *prometheus.CounterVec
     requestDuration  *prometheus.HistogramVec
-    errorCounter     *prometheus.CounterVec
     aiProcessing     *prometheus.HistogramVec
     wsConnections    prometheus.Gauge
 }
 
-// NewMetrics creates a new metrics collector
+// Default returns the collector shared by the whole server. Its metrics are
+// registered with the default Prometheus registry, so it is created once.
+var Default = sync.OnceValue(NewMetrics)
+
+// NewMetrics creates a new metrics collector. It registers its metrics, so
+// it may be called only once per process; use Default.
 func NewMetrics() *Metrics {
     m := &Metrics{}
     
@@ -40,15 +47,6 @@ func NewMetrics() *Metrics {
         []string{"method", "path"},
     )
     
-    // Initialize error metrics
-    m.errorCounter = promauto.NewCounterVec(
-        prometheus.CounterOpts{
-            Name: "ana_errors_total",
-            Help: "Total number of errors",
-        },
-        []string{"type"},
-    )
-    
     // Initialize AI metrics
     m.aiProcessing = promauto.NewHistogramVec(
         prometheus.HistogramOpts{
@@ -76,9 +74,10 @@ func (m *Metrics) RecordRequest(method, path string, status int, duration float6
     m.requestDuration.WithLabelValues(method, path).Observe(duration)
 }
 
-// RecordError records an error
+// RecordError records an error in ana_errors_total, which is shared with
+// the monitoring package
 func (m *Metrics) RecordError(errorType string) {
-    m.errorCounter.WithLabelValues(errorType).Inc()
+    monitoring.RecordError("api", errorType)
 }
 
 // RecordAIProcessing records AI processing duration
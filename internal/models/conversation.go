@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Conversation is a thread of messages with the assistant (MongoDB)
+type Conversation struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// OwnerID is the session or user the conversation belongs to
+	OwnerID  string                `bson:"owner_id" json:"-"`
+	Title    string                `bson:"title" json:"title"`
+	Messages []ConversationMessage `bson:"messages" json:"messages,omitempty"`
+	// Summary condenses the first SummarizedCount messages, which are no
+	// longer sent to the model verbatim
+	Summary         string    `bson:"summary,omitempty" json:"summary,omitempty"`
+	SummarizedCount int       `bson:"summarized_count" json:"summarized_count"`
+	CreatedAt       time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ConversationMessage is one turn of a conversation
+type ConversationMessage struct {
+	Role      string    `bson:"role" json:"role"`
+	Content   string    `bson:"content" json:"content"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Document formats that can be ingested
+const (
+	DocumentKindMarkdown = "markdown"
+	DocumentKindPDF      = "pdf"
+	DocumentKindText     = "text"
+)
+
+// Document is a source the assistant can cite, such as a regulation (NSR-10,
+// POT de Bogotá) or a project brief. Documents of a project are only used to
+// answer questions about that project.
+type Document struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Title     string             `bson:"title" json:"title"`
+	Source    string             `bson:"source" json:"source"`
+	Kind      string             `bson:"kind" json:"kind"`
+	ProjectID primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	// Embedder names the model the chunks were embedded with; chunks of
+	// another model are not searched
+	Embedder  string    `bson:"embedder" json:"embedder"`
+	Chunks    int       `bson:"chunks" json:"chunks"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// DocumentChunk is a passage of a document and its embedding
+type DocumentChunk struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DocumentID primitive.ObjectID `bson:"document_id" json:"document_id"`
+	Index      int                `bson:"index" json:"index"`
+	// Page is the PDF page the passage starts on, or 0
+	Page int `bson:"page,omitempty" json:"page,omitempty"`
+	// Section is the Markdown heading the passage falls under, if any
+	Section   string    `bson:"section,omitempty" json:"section,omitempty"`
+	Text      string    `bson:"text" json:"text"`
+	Embedding []float32 `bson:"embedding" json:"-"`
+}
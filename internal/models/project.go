@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Project phases follow the usual lifecycle of an architecture commission
+const (
+	ProjectPhasePreDesign             = "Pre-Design"
+	ProjectPhaseSchematicDesign       = "Schematic-Design"
+	ProjectPhaseDesignDevelopment     = "Design-Development"
+	ProjectPhaseConstructionDocuments = "Construction-Documents"
+	ProjectPhasePermitting            = "Permitting"
+	ProjectPhaseConstruction          = "Construction"
+	ProjectPhaseCloseout              = "Closeout"
+)
+
+// Project statuses
+const (
+	ProjectStatusActive    = "Active"
+	ProjectStatusOnHold    = "On-Hold"
+	ProjectStatusCompleted = "Completed"
+	ProjectStatusCancelled = "Cancelled"
+)
+
+// Project represents an architecture project that groups tasks (MongoDB)
+type Project struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name" binding:"required"`
+	Client    string             `bson:"client" json:"client"`
+	Address   string             `bson:"address" json:"address"`
+	Phase     string             `bson:"phase" json:"phase" binding:"omitempty,oneof=Pre-Design Schematic-Design Design-Development Construction-Documents Permitting Construction Closeout"`
+	StartDate time.Time          `bson:"start_date" json:"start_date"`
+	EndDate   time.Time          `bson:"end_date" json:"end_date"`
+	Budget    float64            `bson:"budget" json:"budget" binding:"gte=0"`
+	Status    string             `bson:"status" json:"status" binding:"omitempty,oneof=Active On-Hold Completed Cancelled"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestProjectJSONMarshaling tests JSON round-tripping of Project
+func TestProjectJSONMarshaling(t *testing.T) {
+	original := Project{
+		ID:        primitive.NewObjectID(),
+		Name:      "Casa Usaquén",
+		Client:    "Familia Rojas",
+		Address:   "Carrera 7 # 120-20, Bogotá",
+		Phase:     ProjectPhasePermitting,
+		StartDate: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+		Budget:    850000000,
+		Status:    ProjectStatusActive,
+	}
+
+	data, err := json.Marshal(original)
+	assert.NoError(t, err)
+
+	var decoded Project
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original.ID, decoded.ID)
+	assert.Equal(t, original.Name, decoded.Name)
+	assert.Equal(t, original.Phase, decoded.Phase)
+	assert.Equal(t, original.Budget, decoded.Budget)
+	assert.True(t, original.EndDate.Equal(decoded.EndDate))
+}
+
+// TestTaskProjectReference tests how a task's project reference is encoded
+func TestTaskProjectReference(t *testing.T) {
+	// An empty project_id decodes as "no project"
+	var task Task
+	assert.NoError(t, json.Unmarshal([]byte(`{"title":"Visita de obra","project_id":""}`), &task))
+	assert.True(t, task.ProjectID.IsZero())
+
+	// Unassigned tasks do not store a project_id in MongoDB
+	raw, err := bson.Marshal(task)
+	assert.NoError(t, err)
+	_, lookupErr := bson.Raw(raw).LookupErr("project_id")
+	assert.Error(t, lookupErr)
+
+	// Assigned tasks store the ObjectID
+	task.ProjectID = primitive.NewObjectID()
+	raw, err = bson.Marshal(task)
+	assert.NoError(t, err)
+	assert.Equal(t, task.ProjectID, bson.Raw(raw).Lookup("project_id").ObjectID())
+}
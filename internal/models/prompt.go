@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PromptTemplate is a version of a prompt template stored in the database.
+// Versions are never changed once stored, so a recorded version always
+// names the text that was sent.
+type PromptTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Version     int                `bson:"version" json:"version"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	// Text is the text/template source of the prompt
+	Text      string    `bson:"text" json:"text"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
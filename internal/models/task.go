@@ -15,7 +15,7 @@ type Task struct {
 	Description string             `bson:"description" json:"description"`
 	DueDate     time.Time          `bson:"due_date" json:"due_date"`
 	Priority    string             `bson:"priority" json:"priority" binding:"oneof=Low Medium High"`
-	ProjectID   int                `bson:"project_id" json:"project_id"`
+	ProjectID   primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`
 	Status      string             `bson:"status" json:"status" binding:"oneof=To-Do In-Progress Done"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at,omitempty"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at,omitempty"`
@@ -30,7 +30,6 @@ var MockTasks = []Task{
 		Description: "Discuss project requirements for the new residential building",
 		DueDate:     time.Now().AddDate(0, 0, 1), // Tomorrow
 		Priority:    "High",
-		ProjectID:   1,
 		Status:      "To-Do",
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
@@ -41,7 +40,6 @@ var MockTasks = []Task{
 		Description: "Complete the final version of blueprint documents",
 		DueDate:     time.Now().AddDate(0, 0, 3), // In 3 days
 		Priority:    "Medium",
-		ProjectID:   1,
 		Status:      "To-Do",
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
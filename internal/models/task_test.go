@@ -19,7 +19,7 @@ func createValidTask() Task {
 		Description: "This is a test task for unit testing",
 		DueDate:     time.Now().Add(24 * time.Hour), // Tomorrow
 		Priority:    "High",
-		ProjectID:   1,
+		ProjectID:   primitive.NewObjectID(),
 		Status:      "To-Do",
 	}
 }
@@ -45,7 +45,7 @@ func TestTaskFieldValidation(t *testing.T) {
 				Description: "Test Description",
 				DueDate:     time.Now().Add(24 * time.Hour),
 				Priority:    "Medium",
-				ProjectID:   1,
+				ProjectID:   primitive.NewObjectID(),
 				Status:      "To-Do",
 			},
 			expectedValid: false,
@@ -58,7 +58,7 @@ func TestTaskFieldValidation(t *testing.T) {
 				Description: "Test Description",
 				DueDate:     time.Now().Add(24 * time.Hour),
 				Priority:    "InvalidPriority",
-				ProjectID:   1,
+				ProjectID:   primitive.NewObjectID(),
 				Status:      "To-Do",
 			},
 			expectedValid: false,
@@ -71,7 +71,7 @@ func TestTaskFieldValidation(t *testing.T) {
 				Description: "Test Description",
 				DueDate:     time.Now().Add(24 * time.Hour),
 				Priority:    "Medium",
-				ProjectID:   1,
+				ProjectID:   primitive.NewObjectID(),
 				Status:      "InvalidStatus",
 			},
 			expectedValid: false,
@@ -239,9 +239,9 @@ func TestTaskEdgeCases(t *testing.T) {
 	task.DueDate = time.Time{}
 	assert.True(t, task.DueDate.IsZero(), "Zero DueDate should be valid")
 	
-	// Test with negative ProjectID (might be invalid in business logic)
+	// Test without a project (tasks may be unassigned)
 	task = createValidTask()
-	task.ProjectID = -1
-	assert.Equal(t, -1, task.ProjectID, "Task should store negative ProjectID (validation might reject)")
+	task.ProjectID = primitive.NilObjectID
+	assert.True(t, task.ProjectID.IsZero(), "Task should allow an empty ProjectID")
 }
 
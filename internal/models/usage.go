@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Usage budget scopes
+const (
+	BudgetScopeGlobal  = "global"
+	BudgetScopeUser    = "user"
+	BudgetScopeProject = "project"
+)
+
+// Usage budget periods; days and months are counted in UTC
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// UsageRecord is one model completion in the token ledger
+type UsageRecord struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// UserID is the session or user the completion was made for
+	UserID string `bson:"user_id" json:"user_id"`
+	// ProjectID is the project the question was about, if any
+	ProjectID        string `bson:"project_id" json:"project_id,omitempty"`
+	Provider         string `bson:"provider" json:"provider"`
+	Model            string `bson:"model" json:"model"`
+	PromptTokens     int    `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int    `bson:"completion_tokens" json:"completion_tokens"`
+	// Estimated is set when the provider did not report usage, as for
+	// streamed answers, and the counts were estimated from the text
+	Estimated bool `bson:"estimated" json:"estimated,omitempty"`
+	// Prompt is the prompt template version the request was made with,
+	// e.g. "assistant@2"
+	Prompt    string    `bson:"prompt,omitempty" json:"prompt,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// UsageTotal sums the ledger records of one UTC day for a user, project and model
+type UsageTotal struct {
+	Day              time.Time `bson:"day" json:"day"`
+	UserID           string    `bson:"user_id" json:"user_id"`
+	ProjectID        string    `bson:"project_id" json:"project_id,omitempty"`
+	Provider         string    `bson:"provider" json:"provider"`
+	Model            string    `bson:"model" json:"model"`
+	PromptTokens     int64     `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int64     `bson:"completion_tokens" json:"completion_tokens"`
+	Requests         int64     `bson:"requests" json:"requests"`
+}
+
+// UsageBudget limits the tokens a user, a project or everyone may use per
+// day or month. Requests are warned about once WarnRatio of the limit is
+// used and refused once the limit is reached.
+type UsageBudget struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Scope string             `bson:"scope" json:"scope" binding:"required,oneof=global user project"`
+	// Subject is the user or project ID; empty for the global scope
+	Subject   string    `bson:"subject" json:"subject"`
+	Period    string    `bson:"period" json:"period" binding:"required,oneof=daily monthly"`
+	Limit     int64     `bson:"limit" json:"limit" binding:"required,gt=0"`
+	WarnRatio float64   `bson:"warn_ratio" json:"warn_ratio" binding:"gte=0,lte=1"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User is an account, created the first time someone signs in with Google
+// and updated from their Google profile on every sign-in
+type User struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// GoogleID is the subject of the user's Google account
+	GoogleID    string    `bson:"google_id" json:"-"`
+	Email       string    `bson:"email" json:"email"`
+	Name        string    `bson:"name" json:"name"`
+	Picture     string    `bson:"picture,omitempty" json:"picture,omitempty"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+	LastLoginAt time.Time `bson:"last_login_at" json:"last_login_at"`
+}
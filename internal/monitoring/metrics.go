@@ -57,6 +57,15 @@ var (
 		[]string{"service", "cache_type"},
 	)
 
+	CacheEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ana",
+			Name:      "cache_evictions_total",
+			Help:      "Number of cache entries evicted (reason=capacity or expired)",
+		},
+		[]string{"service", "cache_type", "reason"},
+	)
+
 	// ErrorCounter tracks errors
 	ErrorCounter = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -72,11 +81,21 @@ var (
 		prometheus.GaugeOpts{
 			Namespace: "ana",
 			Name:      "circuit_breaker_state",
-			Help:      "Circuit breaker state (0=closed, 1=open)",
+			Help:      "Circuit breaker state (0=closed, 1=open, 2=half-open)",
 		},
 		[]string{"service"},
 	)
 
+	// ModelFallbacks tracks requests passed to the next model of a fallback chain
+	ModelFallbacks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ana",
+			Name:      "model_fallbacks_total",
+			Help:      "Number of requests passed from a model to its fallback (reason=rate_limited, unavailable, circuit_open or not_configured)",
+		},
+		[]string{"from", "to", "reason"},
+	)
+
 	// RateLimiterRejections tracks rate limiter rejections
 	RateLimiterRejections = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -125,6 +144,8 @@ type CircuitStats struct {
 	FailureCount  int       `json:"failure_count"`
 	LastFailure   time.Time `json:"last_failure,omitempty"`
 	ResetTimeout  string    `json:"reset_timeout,omitempty"`
+	OpenUntil     time.Time `json:"open_until,omitempty"`
+	Opens         int64     `json:"opens"`
 }
 
 // PerformanceStats represents performance statistics
@@ -206,6 +227,17 @@ func UpdateServiceStats(service string, stats ServiceStats) {
 	aggregatedStats[service] = stats
 }
 
+// UpdateCircuitStats replaces the circuit breaker part of a service's stats
+func UpdateCircuitStats(service string, circuit CircuitStats) {
+	aggregatedStatsMutex.Lock()
+	defer aggregatedStatsMutex.Unlock()
+
+	stats, _ := aggregatedStats[service].(ServiceStats)
+	stats.CircuitStats = circuit
+	stats.ErrorStats.CircuitBreakerOpens = circuit.Opens
+	aggregatedStats[service] = stats
+}
+
 // GetServiceStats retrieves the stats for a specific service
 func GetServiceStats(service string) (ServiceStats, bool) {
 	aggregatedStatsMutex.RLock()
@@ -282,20 +314,33 @@ func RecordCacheMiss(service, cacheType string) {
 	CacheMisses.WithLabelValues(service, cacheType).Inc()
 }
 
+// RecordCacheEviction records an entry removed from a cache
+func RecordCacheEviction(service, cacheType, reason string) {
+	CacheEvictions.WithLabelValues(service, cacheType, reason).Inc()
+}
+
 // RecordError records an error
 func RecordError(service, errorType string) {
 	ErrorCounter.WithLabelValues(service, errorType).Inc()
 }
 
-// SetCircuitBreakerState sets the circuit breaker state
-func SetCircuitBreakerState(service string, isOpen bool) {
+// SetCircuitBreakerState sets the circuit breaker state: closed, open or half_open
+func SetCircuitBreakerState(service string, state string) {
 	value := 0.0
-	if isOpen {
+	switch state {
+	case "open":
 		value = 1.0
+	case "half_open":
+		value = 2.0
 	}
 	CircuitBreakerState.WithLabelValues(service).Set(value)
 }
 
+// RecordModelFallback records a request passed from one model to the next
+func RecordModelFallback(from, to, reason string) {
+	ModelFallbacks.WithLabelValues(from, to, reason).Inc()
+}
+
 // RecordRateLimiterRejection records a rate limiter rejection
 func RecordRateLimiterRejection(service, endpoint string) {
 	RateLimiterRejections.WithLabelValues(service, endpoint).Inc()
@@ -172,8 +172,9 @@ func TestRecordFunctions(t *testing.T) {
 	RecordError("test-service", "timeout")
 	
 	// Test circuit breaker state
-	SetCircuitBreakerState("test-service", true)
-	SetCircuitBreakerState("test-service", false)
+	SetCircuitBreakerState("test-service", "open")
+	SetCircuitBreakerState("test-service", "half_open")
+	SetCircuitBreakerState("test-service", "closed")
 	
 	// Test rate limiter rejection
 	RecordRateLimiterRejection("test-service", "/test-endpoint")
@@ -0,0 +1,282 @@
+package prompts
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/repositories"
+)
+
+// ErrTemplateNotFound is returned for template names or versions that are not loaded
+var ErrTemplateNotFound = errors.New("prompt template not found")
+
+//go:embed templates/*.tmpl
+var builtinFiles embed.FS
+
+// Source loads prompt templates
+type Source interface {
+	// Name describes the source in logs
+	Name() string
+	// Load returns the source's templates; any invalid template fails the load
+	Load(ctx context.Context) ([]*Template, error)
+}
+
+// builtinSource loads the templates compiled into the binary
+type builtinSource struct{}
+
+func (builtinSource) Name() string { return "built-in templates" }
+
+func (builtinSource) Load(ctx context.Context) ([]*Template, error) {
+	return loadFS(builtinFiles, "templates", OriginBuiltin)
+}
+
+// DirSource loads the *.tmpl files of a directory. A missing directory has
+// no templates.
+type DirSource struct {
+	Dir string
+}
+
+// Name describes the source in logs
+func (s DirSource) Name() string { return "directory " + s.Dir }
+
+// Load parses the directory's templates
+func (s DirSource) Load(ctx context.Context) ([]*Template, error) {
+	if _, err := os.Stat(s.Dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return loadFS(os.DirFS(s.Dir), ".", OriginFile)
+}
+
+// loadFS parses the *.tmpl files of a directory in fsys
+func loadFS(fsys fs.FS, dir, origin string) ([]*Template, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]*Template, 0, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseFile(name, origin, data)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// RepositorySource loads the template versions stored in the database
+type RepositorySource struct {
+	Repo repositories.PromptRepository
+}
+
+// Name describes the source in logs
+func (s RepositorySource) Name() string { return "database" }
+
+// Load parses the stored versions
+func (s RepositorySource) Load(ctx context.Context) ([]*Template, error) {
+	stored, err := s.Repo.ListPromptTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]*Template, 0, len(stored))
+	for _, st := range stored {
+		t, err := Parse(st.Name, st.Version, st.Description, OriginDatabase, st.Text)
+		if err != nil {
+			return nil, fmt.Errorf("%s@%d: %w", st.Name, st.Version, err)
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Registry holds the loaded templates. The built-in templates are always
+// loaded; sources added to the registry override them, later sources
+// overriding earlier ones for the same name and version.
+type Registry struct {
+	sources []Source
+
+	mu       sync.RWMutex
+	versions map[string][]*Template
+	builtin  map[string]*Template
+	loadedAt time.Time
+}
+
+// NewRegistry creates a registry with the built-in templates. Call Reload
+// to load the given sources as well.
+func NewRegistry(sources ...Source) *Registry {
+	builtin, err := builtinSource{}.Load(context.Background())
+	if err != nil {
+		// The built-in templates are checked by the package's tests
+		panic(fmt.Sprintf("prompts: invalid built-in template: %v", err))
+	}
+	r := &Registry{
+		sources: sources,
+		builtin: make(map[string]*Template),
+	}
+	for _, t := range builtin {
+		r.builtin[t.Name] = t
+	}
+	r.versions = index(builtin)
+	r.loadedAt = time.Now()
+	return r
+}
+
+// index groups templates by name, ordered by version. For equal versions
+// the template listed last wins.
+func index(templates []*Template) map[string][]*Template {
+	byRef := make(map[string]*Template)
+	for _, t := range templates {
+		byRef[t.Ref()] = t
+	}
+	versions := make(map[string][]*Template)
+	for _, t := range byRef {
+		versions[t.Name] = append(versions[t.Name], t)
+	}
+	for _, list := range versions {
+		sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	}
+	return versions
+}
+
+// Reload reads every source again. If any source fails, the templates in
+// use are kept and the error returned, so a broken edit never reaches the models.
+func (r *Registry) Reload(ctx context.Context) error {
+	templates, err := builtinSource{}.Load(ctx)
+	if err != nil {
+		return err
+	}
+	for _, source := range r.sources {
+		loaded, err := source.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("loading prompt templates from %s: %w", source.Name(), err)
+		}
+		templates = append(templates, loaded...)
+	}
+	versions := index(templates)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, list := range versions {
+		current := list[len(list)-1]
+		if previous, ok := r.current(name); !ok || previous.Ref() != current.Ref() || previous.Text != current.Text {
+			log.Printf("Using prompt template %s from %s", current.Ref(), current.Origin)
+		}
+	}
+	r.versions = versions
+	r.loadedAt = time.Now()
+	return nil
+}
+
+// Watch reloads the templates every interval until ctx is done, so edited
+// files and new database versions are picked up without a restart
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error reloading prompt templates, keeping the current ones: %v", err)
+			}
+		}
+	}
+}
+
+// current returns the highest version of a template; the caller holds mu
+func (r *Registry) current(name string) (*Template, bool) {
+	list := r.versions[name]
+	if len(list) == 0 {
+		return nil, false
+	}
+	return list[len(list)-1], true
+}
+
+// Get returns the version of a template in use
+func (r *Registry) Get(name string) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current(name)
+}
+
+// Version returns a given version of a template
+func (r *Registry) Version(name string, version int) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.versions[name] {
+		if t.Version == version {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Versions returns every loaded version of a template, oldest first
+func (r *Registry) Versions(name string) []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Template{}, r.versions[name]...)
+}
+
+// List returns the version in use of every template, by name
+func (r *Registry) List() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	templates := make([]*Template, 0, len(r.versions))
+	for name := range r.versions {
+		t, _ := r.current(name)
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// LoadedAt returns when the templates were last loaded
+func (r *Registry) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
+
+// Rendered is a template filled in for a request
+type Rendered struct {
+	Text string
+	// Ref is the template version that produced the text, e.g. "assistant@2"
+	Ref string
+}
+
+// Render fills in the version in use of a template. Should it fail, the
+// built-in version is used instead so requests are still answered.
+func (r *Registry) Render(name string, vars Vars) (Rendered, error) {
+	t, ok := r.Get(name)
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	text, err := t.Execute(vars)
+	if err == nil {
+		return Rendered{Text: text, Ref: t.Ref()}, nil
+	}
+	builtin, ok := r.builtin[name]
+	if !ok || builtin == t {
+		return Rendered{}, err
+	}
+	log.Printf("Error rendering prompt template %s, using the built-in version: %v", t.Ref(), err)
+	if text, err = builtin.Execute(vars); err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{Text: text, Ref: builtin.Ref()}, nil
+}
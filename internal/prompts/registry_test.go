@@ -0,0 +1,122 @@
+package prompts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinTemplates tests that the templates used by the handlers exist and render
+func TestBuiltinTemplates(t *testing.T) {
+	registry := NewRegistry()
+	date := time.Date(2025, 6, 13, 10, 0, 0, 0, time.UTC)
+	for _, name := range []string{Assistant, AssistantNoThink, Vision, Agent} {
+		rendered, err := registry.Render(name, Vars{Project: "Casa Usaquén", Date: date})
+		require.NoError(t, err, name)
+		assert.Equal(t, name+"@1", rendered.Ref)
+		assert.Contains(t, rendered.Text, "Casa Usaquén", name)
+	}
+
+	rendered, err := registry.Render(Assistant, Vars{Date: date})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Text, "Responde siempre en español")
+	assert.Contains(t, rendered.Text, "Hoy es viernes 2025-06-13.")
+	assert.NotContains(t, rendered.Text, "se refiere al proyecto")
+
+	rendered, err = registry.Render(Agent, Vars{Language: "en", Date: date})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Text, "Responde siempre en inglés")
+
+	_, err = registry.Render("missing", Vars{})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+// TestParseFile tests reading the header of template files
+func TestParseFile(t *testing.T) {
+	tmpl, err := ParseFile("prompts/vision.tmpl", OriginFile, []byte("---\nversion: 3\ndescription: Planos\n---\nAnaliza el plano de {{.Project}}.\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "vision", tmpl.Name)
+	assert.Equal(t, 3, tmpl.Version)
+	assert.Equal(t, "Planos", tmpl.Description)
+	assert.Equal(t, "vision@3", tmpl.Ref())
+
+	tmpl, err = ParseFile("custom.tmpl", OriginFile, []byte("Sin encabezado"))
+	require.NoError(t, err)
+	assert.Equal(t, "custom@1", tmpl.Ref())
+
+	for _, text := range []string{
+		"---\nversion: 2\nSin cierre",
+		"{{.Project",
+		"{{.Unknown}}",
+		"{{weekday .Project}}",
+	} {
+		_, err := ParseFile("bad.tmpl", OriginFile, []byte(text))
+		assert.ErrorIs(t, err, ErrInvalidTemplate, text)
+	}
+}
+
+// TestRegistryReload tests overriding built-in templates from files and the
+// database and picking up changes without a restart
+func TestRegistryReload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := repositories.NewMemoryPromptRepository()
+	registry := NewRegistry(DirSource{Dir: dir}, RepositorySource{Repo: repo})
+	require.NoError(t, registry.Reload(ctx))
+	assert.Len(t, registry.List(), 4)
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("assistant.tmpl", "---\nversion: 2\n---\nEres el asistente de {{.User}}.")
+	require.NoError(t, registry.Reload(ctx))
+	rendered, err := registry.Render(Assistant, Vars{User: "laura"})
+	require.NoError(t, err)
+	assert.Equal(t, Rendered{Text: "Eres el asistente de laura.", Ref: "assistant@2"}, rendered)
+	assert.Len(t, registry.Versions(Assistant), 2)
+
+	// A broken edit keeps the templates in use
+	write("assistant.tmpl", "---\nversion: 3\n---\nEres {{.User")
+	assert.ErrorIs(t, registry.Reload(ctx), ErrInvalidTemplate)
+	current, ok := registry.Get(Assistant)
+	require.True(t, ok)
+	assert.Equal(t, "assistant@2", current.Ref())
+
+	// Database versions override files of the same version
+	write("assistant.tmpl", "---\nversion: 2\n---\nDesde archivo.")
+	require.NoError(t, repo.CreatePromptTemplate(ctx, &models.PromptTemplate{Name: Assistant, Version: 2, Text: "Desde la base de datos."}))
+	require.NoError(t, registry.Reload(ctx))
+	current, _ = registry.Get(Assistant)
+	assert.Equal(t, OriginDatabase, current.Origin)
+	first, ok := registry.Version(Assistant, 1)
+	require.True(t, ok)
+	assert.Equal(t, OriginBuiltin, first.Origin)
+}
+
+// TestRenderFallback tests falling back to the built-in version of a
+// template that fails for some variables
+func TestRenderFallback(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryPromptRepository()
+	registry := NewRegistry(RepositorySource{Repo: repo})
+	// Executes for the sample variables, which name a project, but not without one
+	require.NoError(t, repo.CreatePromptTemplate(ctx, &models.PromptTemplate{
+		Name: Vision, Version: 5, Text: `{{if .Project}}Proyecto {{.Project}}{{else}}{{template "missing"}}{{end}}`,
+	}))
+	require.NoError(t, registry.Reload(ctx))
+
+	rendered, err := registry.Render(Vision, Vars{Project: "Casa"})
+	require.NoError(t, err)
+	assert.Equal(t, "vision@5", rendered.Ref)
+
+	rendered, err = registry.Render(Vision, Vars{})
+	require.NoError(t, err)
+	assert.Equal(t, "vision@1", rendered.Ref)
+}
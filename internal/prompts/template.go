@@ -0,0 +1,167 @@
+// Package prompts keeps the named, versioned system prompts sent to the
+// models. Prompts are text/template templates filled in with the request's
+// project, user, language and date. Templates come from the built-in set,
+// a directory and the database, and are reloaded while the server runs;
+// the highest version of each name is used.
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the templates used by the handlers
+const (
+	Assistant        = "assistant"
+	AssistantNoThink = "assistant_no_think"
+	Vision           = "vision"
+	Agent            = "agent"
+)
+
+// Origins of a template
+const (
+	OriginBuiltin  = "builtin"
+	OriginFile     = "file"
+	OriginDatabase = "database"
+)
+
+// DefaultLanguage is the language answers are written in unless a request asks otherwise
+const DefaultLanguage = "es"
+
+// ErrInvalidTemplate is returned for templates that do not parse or execute
+var ErrInvalidTemplate = errors.New("invalid prompt template")
+
+// Vars are the variables available to templates
+type Vars struct {
+	// Project is the name of the project the request is about, if any
+	Project string `json:"project,omitempty"`
+	// User identifies who is asking
+	User string `json:"user,omitempty"`
+	// Language is the language of the answer, DefaultLanguage if empty
+	Language string `json:"language,omitempty"`
+	// Date is the current time, now if zero
+	Date time.Time `json:"date,omitempty"`
+}
+
+// withDefaults fills in the language and date of vars
+func (v Vars) withDefaults() Vars {
+	if v.Language == "" {
+		v.Language = DefaultLanguage
+	}
+	if v.Date.IsZero() {
+		v.Date = time.Now()
+	}
+	return v
+}
+
+// sampleVars are used to check that a template executes before it is used
+var sampleVars = Vars{Project: "Casa Chapinero", User: "preview", Language: DefaultLanguage, Date: time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC)}
+
+// spanishWeekdays names the days of the week
+var spanishWeekdays = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+
+// languageNames names the languages in Spanish, the language the templates are written in
+var languageNames = map[string]string{"es": "español", "en": "inglés", "pt": "portugués"}
+
+// funcs are the functions available to templates
+var funcs = template.FuncMap{
+	// weekday names the day of the week of a time in Spanish
+	"weekday": func(t time.Time) string { return spanishWeekdays[t.Weekday()] },
+	// date formats a time as YYYY-MM-DD
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	// language names a language code in Spanish, e.g. "es" as "español"
+	"language": func(code string) string {
+		if name, ok := languageNames[code]; ok {
+			return name
+		}
+		return code
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Template is a parsed version of a prompt template
+type Template struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Description string `json:"description,omitempty"`
+	// Origin is where the template was loaded from: builtin, file or database
+	Origin string `json:"origin"`
+	// Text is the template source
+	Text string `json:"text"`
+
+	tmpl *template.Template
+}
+
+// Parse parses a template and checks that it executes
+func Parse(name string, version int, description, origin, text string) (*Template, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing name", ErrInvalidTemplate)
+	}
+	if version < 1 {
+		return nil, fmt.Errorf("%w: %s: version must be positive", ErrInvalidTemplate, name)
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	t := &Template{Name: name, Version: version, Description: description, Origin: origin, Text: text, tmpl: tmpl}
+	if _, err := t.Execute(sampleVars); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Ref identifies the template version, e.g. "assistant@2"
+func (t *Template) Ref() string {
+	return fmt.Sprintf("%s@%d", t.Name, t.Version)
+}
+
+// Execute fills in the template
+func (t *Template) Execute(vars Vars) (string, error) {
+	var out bytes.Buffer
+	if err := t.tmpl.Execute(&out, vars.withDefaults()); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// frontMatter is the header of a template file
+type frontMatter struct {
+	Name        string `yaml:"name"`
+	Version     int    `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// ParseFile parses a template file. The file may start with a YAML header
+// between "---" lines setting its name, version and description; the name
+// defaults to the file name without extension and the version to 1.
+func ParseFile(filename, origin string, data []byte) (*Template, error) {
+	header := frontMatter{Version: 1}
+	text := string(data)
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		yamlText, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			return nil, fmt.Errorf("%w: %s: unterminated header", ErrInvalidTemplate, filename)
+		}
+		if err := yaml.Unmarshal([]byte(yamlText), &header); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, filename, err)
+		}
+		text = body
+	}
+	if header.Name == "" {
+		header.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	t, err := Parse(header.Name, header.Version, header.Description, origin, text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,138 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lyffseba/ana/internal/models"
+)
+
+// Chunk sizes in characters. Passages of this size hold an article or
+// numeral of a regulation while leaving room for several in one prompt.
+const (
+	DefaultChunkSize    = 1200
+	DefaultChunkOverlap = 200
+)
+
+// Section is a run of text read from a document: a PDF page or the text
+// under a Markdown heading. Chunks never span sections, so each keeps its
+// page and heading for references.
+type Section struct {
+	Page    int
+	Heading string
+	Text    string
+}
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd    = regexp.MustCompile(`[.;:!?]\s+`)
+)
+
+// ChunkSections splits sections into passages of at most size characters.
+// Paragraphs are kept whole where possible; consecutive passages of a
+// section share about overlap characters so no sentence loses its context.
+func ChunkSections(sections []Section, size, overlap int) []models.DocumentChunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	chunks := []models.DocumentChunk{}
+	for _, section := range sections {
+		for _, text := range packPieces(splitPieces(section.Text, size), size, overlap) {
+			chunks = append(chunks, models.DocumentChunk{
+				Index:   len(chunks),
+				Page:    section.Page,
+				Section: section.Heading,
+				Text:    text,
+			})
+		}
+	}
+	return chunks
+}
+
+// splitPieces splits text into paragraphs, and paragraphs longer than size
+// into sentences or, failing that, fixed-size runs
+func splitPieces(text string, size int) []string {
+	var pieces []string
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph == "" {
+			continue
+		}
+		if utf8.RuneCountInString(paragraph) <= size {
+			pieces = append(pieces, paragraph)
+			continue
+		}
+		start := 0
+		for _, end := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+			pieces = append(pieces, splitRunes(strings.TrimSpace(paragraph[start:end[1]]), size)...)
+			start = end[1]
+		}
+		if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+			pieces = append(pieces, splitRunes(rest, size)...)
+		}
+	}
+	return pieces
+}
+
+// splitRunes cuts text into runs of at most size characters
+func splitRunes(text string, size int) []string {
+	runes := []rune(text)
+	var parts []string
+	for len(runes) > size {
+		parts = append(parts, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return append(parts, string(runes))
+}
+
+// packPieces joins consecutive pieces into passages of at most size
+// characters, starting each passage after the first with the end of the
+// previous one
+func packPieces(pieces []string, size, overlap int) []string {
+	var passages []string
+	var current strings.Builder
+	length := 0
+	for _, piece := range pieces {
+		n := utf8.RuneCountInString(piece)
+		if length > 0 && length+1+n > size {
+			passage := current.String()
+			passages = append(passages, passage)
+			current.Reset()
+			length = 0
+			if tail := overlapTail(passage, overlap); tail != "" && utf8.RuneCountInString(tail)+1+n <= size {
+				current.WriteString(tail)
+				length = utf8.RuneCountInString(tail)
+			}
+		}
+		if length > 0 {
+			current.WriteString(" ")
+			length++
+		}
+		current.WriteString(piece)
+		length += n
+	}
+	if length > 0 {
+		passages = append(passages, current.String())
+	}
+	return passages
+}
+
+// overlapTail returns about the last n characters of text, starting at a word
+func overlapTail(text string, n int) string {
+	if n == 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= n {
+		return ""
+	}
+	tail := string(runes[len(runes)-n:])
+	if i := strings.IndexByte(tail, ' '); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return "…" + tail
+}
@@ -0,0 +1,84 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkSections(t *testing.T) {
+	t.Run("short sections stay whole", func(t *testing.T) {
+		chunks := ChunkSections([]Section{
+			{Page: 1, Text: "Primer párrafo.\n\nSegundo   párrafo\ncon salto."},
+			{Page: 2, Text: "Otra página."},
+		}, 200, 20)
+		require.Len(t, chunks, 2)
+		assert.Equal(t, "Primer párrafo. Segundo párrafo con salto.", chunks[0].Text)
+		assert.Equal(t, 1, chunks[0].Page)
+		assert.Equal(t, 1, chunks[1].Index)
+		assert.Equal(t, 2, chunks[1].Page)
+	})
+
+	t.Run("long text is split with overlap", func(t *testing.T) {
+		var paragraphs []string
+		for i := 0; i < 20; i++ {
+			paragraphs = append(paragraphs, strings.Repeat("palabra ", 10)+"fin.")
+		}
+		chunks := ChunkSections([]Section{{Heading: "A.2", Text: strings.Join(paragraphs, "\n\n")}}, 200, 40)
+		require.Greater(t, len(chunks), 1)
+		for i, chunk := range chunks {
+			assert.LessOrEqual(t, utf8.RuneCountInString(chunk.Text), 200)
+			assert.Equal(t, "A.2", chunk.Section)
+			if i > 0 {
+				assert.True(t, strings.HasPrefix(chunk.Text, "…"), "chunk %d starts with the end of the previous one", i)
+			}
+		}
+	})
+
+	t.Run("long paragraphs are split at sentences", func(t *testing.T) {
+		sentence := strings.Repeat("x", 60) + ". "
+		chunks := ChunkSections([]Section{{Text: strings.Repeat(sentence, 10)}}, 130, 0)
+		require.Len(t, chunks, 5)
+		assert.Equal(t, strings.TrimSpace(sentence+sentence), chunks[0].Text)
+	})
+
+	t.Run("runs without breaks are cut", func(t *testing.T) {
+		chunks := ChunkSections([]Section{{Text: strings.Repeat("ñ", 250)}}, 100, 0)
+		require.Len(t, chunks, 3)
+		assert.Equal(t, 50, utf8.RuneCountInString(chunks[2].Text))
+	})
+}
+
+func TestReadMarkdown(t *testing.T) {
+	sections := ReadMarkdown([]byte(`Introducción sin título.
+
+# Título A
+## A.2 Zonas de amenaza sísmica
+Bogotá está en zona de amenaza intermedia.
+
+### A.2.3 Coeficientes
+Aa = 0.15
+
+# Título B
+Texto de B.
+`))
+	require.Len(t, sections, 4)
+	assert.Equal(t, "", sections[0].Heading)
+	assert.Equal(t, "Título A > A.2 Zonas de amenaza sísmica", sections[1].Heading)
+	assert.Contains(t, sections[1].Text, "amenaza intermedia")
+	assert.Equal(t, "Título A > A.2 Zonas de amenaza sísmica > A.2.3 Coeficientes", sections[2].Heading)
+	assert.Equal(t, "Título B", sections[3].Heading)
+}
+
+func TestDocumentKind(t *testing.T) {
+	assert.Equal(t, "markdown", DocumentKind("NSR-10.MD"))
+	assert.Equal(t, "pdf", DocumentKind("pot.pdf"))
+	assert.Equal(t, "text", DocumentKind("notas.txt"))
+	assert.Equal(t, "", DocumentKind("plano.dwg"))
+
+	_, err := ReadPDF([]byte("not a pdf"))
+	assert.ErrorIs(t, err, ErrUnreadableDocument)
+}
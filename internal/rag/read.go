@@ -0,0 +1,116 @@
+package rag
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/lyffseba/ana/internal/models"
+)
+
+// DocumentKind returns the kind of document a file name refers to, or ""
+// if it cannot be ingested
+func DocumentKind(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return models.DocumentKindMarkdown
+	case ".pdf":
+		return models.DocumentKindPDF
+	case ".txt":
+		return models.DocumentKindText
+	default:
+		return ""
+	}
+}
+
+// ReadSections extracts the text of a document of the given kind
+func ReadSections(kind string, data []byte) ([]Section, error) {
+	switch kind {
+	case models.DocumentKindMarkdown:
+		return ReadMarkdown(data), nil
+	case models.DocumentKindPDF:
+		return ReadPDF(data)
+	case models.DocumentKindText:
+		return []Section{{Text: string(data)}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, kind)
+	}
+}
+
+// ReadMarkdown splits a Markdown document at its headings. Each section's
+// heading is the path of headings above it, e.g. "Título A > A.2".
+func ReadMarkdown(data []byte) []Section {
+	var (
+		sections []Section
+		headings []string
+		text     strings.Builder
+	)
+	flush := func() {
+		if strings.TrimSpace(text.String()) != "" {
+			sections = append(sections, Section{Heading: strings.Join(headings, " > "), Text: text.String()})
+		}
+		text.Reset()
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if level > 0 && level <= 6 && strings.HasPrefix(trimmed[level:], " ") {
+			flush()
+			if level-1 < len(headings) {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, strings.TrimSpace(trimmed[level:]))
+			headings = compactHeadings(headings)
+			continue
+		}
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	flush()
+	return sections
+}
+
+// compactHeadings drops the empty levels of a document that skips levels
+func compactHeadings(headings []string) []string {
+	compact := headings[:0]
+	for _, heading := range headings {
+		if heading != "" {
+			compact = append(compact, heading)
+		}
+	}
+	return compact
+}
+
+// ReadPDF extracts the text of each page of a PDF. Scanned PDFs without a
+// text layer yield no sections.
+func ReadPDF(data []byte) (sections []Section, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			sections, err = nil, fmt.Errorf("%w: %v", ErrUnreadableDocument, r)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadableDocument, err)
+	}
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: page %d: %v", ErrUnreadableDocument, i, err)
+		}
+		if strings.TrimSpace(text) != "" {
+			sections = append(sections, Section{Page: i, Text: text})
+		}
+	}
+	return sections, nil
+}
@@ -0,0 +1,288 @@
+// Package rag grounds the assistant's answers in ingested documents. It
+// reads PDF, Markdown and text files, splits them into passages, embeds the
+// passages and keeps them in an in-memory vector index backed by a document
+// repository, so searches need no external vector database.
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that are not PDF, Markdown or text
+	ErrUnsupportedFormat = errors.New("unsupported document format")
+	// ErrUnreadableDocument is returned when a file cannot be parsed
+	ErrUnreadableDocument = errors.New("unreadable document")
+	// ErrEmptyDocument is returned when a file has no extractable text, such
+	// as a scanned PDF
+	ErrEmptyDocument = errors.New("document has no text")
+)
+
+const (
+	// DefaultK is the number of passages returned by a search by default
+	DefaultK = 4
+	// embedBatchSize is the number of passages embedded per request
+	embedBatchSize = 32
+)
+
+// entry is an indexed passage
+type entry struct {
+	document *models.Document
+	chunk    models.DocumentChunk
+}
+
+// Store ingests documents and searches their passages
+type Store struct {
+	repo     repositories.DocumentRepository
+	embedder ai.Embedder
+
+	mu        sync.RWMutex
+	documents map[primitive.ObjectID]*models.Document
+	index     []entry
+}
+
+// NewStore creates an empty store persisting documents to repo and
+// embedding passages with embedder
+func NewStore(repo repositories.DocumentRepository, embedder ai.Embedder) *Store {
+	return &Store{
+		repo:      repo,
+		embedder:  embedder,
+		documents: make(map[primitive.ObjectID]*models.Document),
+	}
+}
+
+// Embedder returns the embedder passages are indexed with
+func (s *Store) Embedder() ai.Embedder {
+	return s.embedder
+}
+
+// Load fills the index from the repository. Documents embedded with another
+// model are skipped and must be ingested again to be searched.
+func (s *Store) Load(ctx context.Context) error {
+	documents, err := s.repo.ListDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	chunks, err := s.repo.Chunks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load document chunks: %w", err)
+	}
+
+	loaded := make(map[primitive.ObjectID]*models.Document, len(documents))
+	for i := range documents {
+		document := &documents[i]
+		if document.Embedder != s.embedder.Name() {
+			log.Printf("Skipping document %q: embedded with %s, not %s", document.Title, document.Embedder, s.embedder.Name())
+			continue
+		}
+		loaded[document.ID] = document
+	}
+	var index []entry
+	for _, chunk := range chunks {
+		if document, ok := loaded[chunk.DocumentID]; ok {
+			index = append(index, entry{document: document, chunk: chunk})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.documents = loaded
+	s.index = index
+	return nil
+}
+
+// IngestRequest describes a file to ingest
+type IngestRequest struct {
+	// Title defaults to the file name
+	Title    string
+	Filename string
+	// ProjectID restricts the document to questions about one project;
+	// documents without a project, such as regulations, apply to all
+	ProjectID primitive.ObjectID
+	Data      []byte
+}
+
+// Ingest reads, chunks, embeds and stores a file, making it searchable
+func (s *Store) Ingest(ctx context.Context, req IngestRequest) (*models.Document, error) {
+	kind := DocumentKind(req.Filename)
+	if kind == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, req.Filename)
+	}
+	sections, err := ReadSections(kind, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := ChunkSections(sections, DefaultChunkSize, DefaultChunkOverlap)
+	if len(chunks) == 0 {
+		return nil, ErrEmptyDocument
+	}
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(chunks))
+		texts := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			texts = append(texts, passageText(chunk))
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed passages: %w", err)
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Embedding = vector
+		}
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = req.Filename
+	}
+	document := &models.Document{
+		Title:     title,
+		Source:    req.Filename,
+		Kind:      kind,
+		ProjectID: req.ProjectID,
+		Embedder:  s.embedder.Name(),
+	}
+	if err := s.repo.CreateDocument(ctx, document, chunks); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.documents[document.ID] = document
+	for _, chunk := range chunks {
+		s.index = append(s.index, entry{document: document, chunk: chunk})
+	}
+	return document, nil
+}
+
+// passageText is the text embedded for a passage; the heading adds the
+// context a passage often lacks on its own
+func passageText(chunk models.DocumentChunk) string {
+	if chunk.Section == "" {
+		return chunk.Text
+	}
+	return chunk.Section + "\n" + chunk.Text
+}
+
+// Documents returns the indexed documents, newest first
+func (s *Store) Documents() []models.Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	documents := make([]models.Document, 0, len(s.documents))
+	for _, document := range s.documents {
+		documents = append(documents, *document)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].CreatedAt.After(documents[j].CreatedAt)
+	})
+	return documents
+}
+
+// Delete removes a document from the repository and the index
+func (s *Store) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := s.repo.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.documents, id)
+	index := s.index[:0]
+	for _, e := range s.index {
+		if e.document.ID != id {
+			index = append(index, e)
+		}
+	}
+	s.index = index
+	return nil
+}
+
+// SearchOptions narrows a search
+type SearchOptions struct {
+	// K is the maximum number of passages returned, DefaultK if zero
+	K int
+	// ProjectID adds the documents of a project to the documents without one
+	ProjectID primitive.ObjectID
+	// MinScore drops passages less similar to the query
+	MinScore float64
+}
+
+// Passage is a search result
+type Passage struct {
+	DocumentID primitive.ObjectID `json:"document_id"`
+	Title      string             `json:"title"`
+	Source     string             `json:"source"`
+	Page       int                `json:"page,omitempty"`
+	Section    string             `json:"section,omitempty"`
+	Text       string             `json:"text"`
+	Score      float64            `json:"score"`
+}
+
+// Reference locates the passage for a citation, e.g. "NSR-10, p. 12" or
+// "POT Bogotá, § Usos del suelo"
+func (p Passage) Reference() string {
+	switch {
+	case p.Page > 0:
+		return fmt.Sprintf("%s, p. %d", p.Title, p.Page)
+	case p.Section != "":
+		return fmt.Sprintf("%s, § %s", p.Title, p.Section)
+	default:
+		return p.Title
+	}
+}
+
+// Search returns the passages most similar to query, best first
+func (s *Store) Search(ctx context.Context, query string, opts SearchOptions) ([]Passage, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	k := opts.K
+	if k <= 0 {
+		k = DefaultK
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for one query", len(vectors))
+	}
+	queryVector := vectors[0]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var passages []Passage
+	for _, e := range s.index {
+		if !e.document.ProjectID.IsZero() && e.document.ProjectID != opts.ProjectID {
+			continue
+		}
+		score := ai.CosineSimilarity(queryVector, e.chunk.Embedding)
+		if score <= 0 || score < opts.MinScore {
+			continue
+		}
+		passages = append(passages, Passage{
+			DocumentID: e.document.ID,
+			Title:      e.document.Title,
+			Source:     e.document.Source,
+			Page:       e.chunk.Page,
+			Section:    e.chunk.Section,
+			Text:       e.chunk.Text,
+			Score:      score,
+		})
+	}
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].Score > passages[j].Score })
+	if len(passages) > k {
+		passages = passages[:k]
+	}
+	return passages, nil
+}
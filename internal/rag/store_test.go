@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const nsr10 = `# Título A
+## A.2 Zonas de amenaza sísmica
+Bogotá se encuentra en zona de amenaza sísmica intermedia. Los edificios deben
+diseñarse con los coeficientes de aceleración Aa y Av del apéndice A-4.
+
+# Título C
+## C.7 Recubrimiento del refuerzo
+El recubrimiento mínimo de concreto para el refuerzo de columnas es 40 mm.
+`
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryDocumentRepository()
+	store := NewStore(repo, ai.NewHashEmbedder(512))
+
+	regulation, err := store.Ingest(ctx, IngestRequest{Title: "NSR-10", Filename: "nsr10.md", Data: []byte(nsr10)})
+	require.NoError(t, err)
+	assert.Equal(t, "markdown", regulation.Kind)
+	assert.Equal(t, "hash-512", regulation.Embedder)
+	assert.Equal(t, 2, regulation.Chunks)
+
+	projectID := primitive.NewObjectID()
+	brief, err := store.Ingest(ctx, IngestRequest{Filename: "brief.txt", ProjectID: projectID,
+		Data: []byte("La casa Chapinero tiene columnas de concreto con recubrimiento especial.")})
+	require.NoError(t, err)
+	assert.Equal(t, "brief.txt", brief.Title)
+
+	t.Run("finds the relevant passage", func(t *testing.T) {
+		passages, err := store.Search(ctx, "¿Cuál es el recubrimiento mínimo del refuerzo de columnas?", SearchOptions{K: 1})
+		require.NoError(t, err)
+		require.Len(t, passages, 1)
+		assert.Equal(t, "NSR-10", passages[0].Title)
+		assert.Contains(t, passages[0].Text, "40 mm")
+		assert.Equal(t, "NSR-10, § Título C > C.7 Recubrimiento del refuerzo", passages[0].Reference())
+	})
+
+	t.Run("project documents only answer for their project", func(t *testing.T) {
+		passages, err := store.Search(ctx, "recubrimiento columnas concreto", SearchOptions{})
+		require.NoError(t, err)
+		for _, passage := range passages {
+			assert.NotEqual(t, brief.ID, passage.DocumentID)
+		}
+
+		passages, err = store.Search(ctx, "recubrimiento columnas concreto", SearchOptions{ProjectID: projectID})
+		require.NoError(t, err)
+		var titles []string
+		for _, passage := range passages {
+			titles = append(titles, passage.Title)
+		}
+		assert.Contains(t, titles, "brief.txt")
+	})
+
+	t.Run("unrelated queries find nothing", func(t *testing.T) {
+		passages, err := store.Search(ctx, "horario del gimnasio", SearchOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, passages)
+	})
+
+	t.Run("reloads from the repository", func(t *testing.T) {
+		reloaded := NewStore(repo, ai.NewHashEmbedder(512))
+		require.NoError(t, reloaded.Load(ctx))
+		assert.Len(t, reloaded.Documents(), 2)
+		passages, err := reloaded.Search(ctx, "amenaza sísmica Bogotá", SearchOptions{K: 1})
+		require.NoError(t, err)
+		require.Len(t, passages, 1)
+		assert.Contains(t, passages[0].Section, "A.2")
+
+		other := NewStore(repo, ai.NewHashEmbedder(256))
+		require.NoError(t, other.Load(ctx))
+		assert.Empty(t, other.Documents(), "documents of another embedder are skipped")
+	})
+
+	t.Run("deletes documents", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, regulation.ID))
+		passages, err := store.Search(ctx, "amenaza sísmica Bogotá", SearchOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, passages)
+		assert.ErrorIs(t, store.Delete(ctx, regulation.ID), repositories.ErrDocumentNotFound)
+	})
+
+	t.Run("rejects unsupported and empty files", func(t *testing.T) {
+		_, err := store.Ingest(ctx, IngestRequest{Filename: "plano.dwg", Data: []byte("x")})
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+		_, err = store.Ingest(ctx, IngestRequest{Filename: "vacío.md", Data: []byte("# Solo título\n")})
+		assert.ErrorIs(t, err, ErrEmptyDocument)
+	})
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/database"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackends returns a constructor for every backend available to the tests.
+// Memory always runs; MongoDB and PostgreSQL run when ANA_TEST_MONGODB_URI or
+// ANA_TEST_POSTGRES_DSN point at a disposable server.
+func testBackends(t *testing.T) map[string]func(t *testing.T) *Repositories {
+	backends := map[string]func(t *testing.T) *Repositories{
+		"memory": func(t *testing.T) *Repositories { return NewMemoryRepositories() },
+	}
+	if uri := os.Getenv("ANA_TEST_MONGODB_URI"); uri != "" {
+		backends["mongo"] = func(t *testing.T) *Repositories {
+			return openTestBackend(t, config.DatabaseConfig{
+				Driver:   config.DatabaseDriverMongo,
+				URI:      uri,
+				Database: fmt.Sprintf("ana_test_%d", time.Now().UnixNano()),
+			})
+		}
+	}
+	if dsn := os.Getenv("ANA_TEST_POSTGRES_DSN"); dsn != "" {
+		backends["postgres"] = func(t *testing.T) *Repositories {
+			return openTestBackend(t, config.DatabaseConfig{
+				Driver:         config.DatabaseDriverPostgres,
+				URI:            dsn,
+				MigrationsPath: "../../db/migrations",
+			})
+		}
+	}
+	return backends
+}
+
+// openTestBackend opens a backend and removes everything it stored when the
+// test ends: the MongoDB database is dropped and every PostgreSQL table but
+// schema_migrations is emptied
+func openTestBackend(t *testing.T, cfg config.DatabaseConfig) *Repositories {
+	repos, err := Open(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ctx := context.Background()
+		repos.Close(ctx)
+		if err := clearTestBackend(ctx, cfg); err != nil {
+			t.Errorf("Failed to clear the %s test backend: %v", cfg.Driver, err)
+		}
+	})
+	return repos
+}
+
+// clearTestBackend removes the data of every repository from a backend
+func clearTestBackend(ctx context.Context, cfg config.DatabaseConfig) error {
+	if cfg.Driver == config.DatabaseDriverMongo {
+		client, err := database.ConnectMongo(ctx, cfg.URI)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(ctx)
+		return client.Database(cfg.Database).Drop(ctx)
+	}
+
+	db, err := database.OpenPostgres(ctx, cfg.URI)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rows, err := db.QueryContext(ctx, `SELECT quote_ident(table_name) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(tables) == 0 {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" CASCADE")
+	return err
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrConversationNotFound is returned when a conversation does not exist
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationRepository handles storage operations for assistant conversations.
+// Implementations exist for memory, MongoDB and PostgreSQL.
+type ConversationRepository interface {
+	// ListByOwner retrieves the owner's conversations, most recently updated
+	// first. Messages are not loaded.
+	ListByOwner(ctx context.Context, ownerID string) ([]models.Conversation, error)
+	// FindByID retrieves a conversation, returning ErrConversationNotFound if it does not exist
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Conversation, error)
+	// Create stores a new conversation, assigning its ID and timestamps
+	Create(ctx context.Context, conversation *models.Conversation) error
+	// Update replaces an existing conversation, returning ErrConversationNotFound if it does not exist
+	Update(ctx context.Context, conversation *models.Conversation) error
+	// Delete removes a conversation, returning ErrConversationNotFound if it does not exist
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// prepareNewConversation assigns the ID and timestamps of a conversation about to be created
+func prepareNewConversation(conversation *models.Conversation) {
+	if conversation.ID.IsZero() {
+		conversation.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	conversation.CreatedAt = now
+	conversation.UpdatedAt = now
+	if conversation.Messages == nil {
+		conversation.Messages = []models.ConversationMessage{}
+	}
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestConversationRepositoryContract checks conversation storage on every backend
+func TestConversationRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			conversations := open(t).Conversations
+			owner := primitive.NewObjectID().Hex()
+
+			first := models.Conversation{OwnerID: owner, Title: "Normativa NSR-10"}
+			require.NoError(t, conversations.Create(ctx, &first))
+			t.Cleanup(func() { _ = conversations.Delete(context.Background(), first.ID) })
+			second := models.Conversation{OwnerID: owner, Title: "Presupuesto", Messages: []models.ConversationMessage{
+				{Role: "user", Content: "¿Costo por m²?", CreatedAt: time.Now()},
+			}}
+			require.NoError(t, conversations.Create(ctx, &second))
+			t.Cleanup(func() { _ = conversations.Delete(context.Background(), second.ID) })
+			other := models.Conversation{OwnerID: "someone-else", Title: "Ajena"}
+			require.NoError(t, conversations.Create(ctx, &other))
+			t.Cleanup(func() { _ = conversations.Delete(context.Background(), other.ID) })
+
+			first.Messages = append(first.Messages,
+				models.ConversationMessage{Role: "user", Content: "¿Qué es la NSR-10?", CreatedAt: time.Now()},
+				models.ConversationMessage{Role: "assistant", Content: "La norma sismo resistente.", CreatedAt: time.Now()},
+			)
+			first.Summary = "Resumen"
+			first.SummarizedCount = 1
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(t, conversations.Update(ctx, &first))
+
+			list, err := conversations.ListByOwner(ctx, owner)
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+			assert.Equal(t, first.ID, list[0].ID, "most recently updated first")
+			assert.Empty(t, list[0].Messages, "listing does not load messages")
+
+			found, err := conversations.FindByID(ctx, first.ID)
+			require.NoError(t, err)
+			assert.Equal(t, owner, found.OwnerID)
+			require.Len(t, found.Messages, 2)
+			assert.Equal(t, "assistant", found.Messages[1].Role)
+			assert.Equal(t, "Resumen", found.Summary)
+			assert.Equal(t, 1, found.SummarizedCount)
+
+			missing := primitive.NewObjectID()
+			_, err = conversations.FindByID(ctx, missing)
+			assert.ErrorIs(t, err, ErrConversationNotFound)
+			assert.ErrorIs(t, conversations.Update(ctx, &models.Conversation{ID: missing}), ErrConversationNotFound)
+
+			require.NoError(t, conversations.Delete(ctx, second.ID))
+			assert.ErrorIs(t, conversations.Delete(ctx, second.ID), ErrConversationNotFound)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDocumentNotFound is returned when a document does not exist
+var ErrDocumentNotFound = errors.New("document not found")
+
+// DocumentRepository stores ingested documents and their embedded chunks.
+// Searching is left to the caller's vector index, which loads the chunks.
+// Implementations exist for memory, MongoDB and PostgreSQL.
+type DocumentRepository interface {
+	// ListDocuments retrieves every document, newest first
+	ListDocuments(ctx context.Context) ([]models.Document, error)
+	// CreateDocument stores a document and its chunks, assigning their IDs
+	// and the document's creation time
+	CreateDocument(ctx context.Context, document *models.Document, chunks []models.DocumentChunk) error
+	// DeleteDocument removes a document and its chunks, returning
+	// ErrDocumentNotFound if it does not exist
+	DeleteDocument(ctx context.Context, id primitive.ObjectID) error
+	// Chunks retrieves the chunks of every document
+	Chunks(ctx context.Context) ([]models.DocumentChunk, error)
+}
+
+// prepareNewDocument assigns the IDs and time of a document about to be
+// stored and links its chunks to it
+func prepareNewDocument(document *models.Document, chunks []models.DocumentChunk) {
+	if document.ID.IsZero() {
+		document.ID = primitive.NewObjectID()
+	}
+	document.CreatedAt = time.Now()
+	document.Chunks = len(chunks)
+	for i := range chunks {
+		if chunks[i].ID.IsZero() {
+			chunks[i].ID = primitive.NewObjectID()
+		}
+		chunks[i].DocumentID = document.ID
+	}
+}
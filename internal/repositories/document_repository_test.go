@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDocumentRepositoryContract runs the same document checks against every backend
+func TestDocumentRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			documents := open(t).Documents
+
+			document := models.Document{Title: "NSR-10 Título A", Source: "nsr10-a.pdf", Kind: models.DocumentKindPDF, Embedder: "hash-512"}
+			chunks := []models.DocumentChunk{
+				{Index: 0, Page: 1, Text: "Requisitos generales de diseño sismo resistente", Embedding: []float32{0.6, 0.8}},
+				{Index: 1, Page: 2, Section: "A.2", Text: "Zonas de amenaza sísmica", Embedding: []float32{1, 0}},
+			}
+			require.NoError(t, documents.CreateDocument(ctx, &document, chunks))
+			t.Cleanup(func() { _ = documents.DeleteDocument(context.Background(), document.ID) })
+			assert.False(t, document.ID.IsZero())
+			assert.Equal(t, 2, document.Chunks)
+
+			listed, err := documents.ListDocuments(ctx)
+			require.NoError(t, err)
+			require.Len(t, listed, 1)
+			assert.Equal(t, "NSR-10 Título A", listed[0].Title)
+			assert.True(t, listed[0].ProjectID.IsZero())
+
+			stored, err := documents.Chunks(ctx)
+			require.NoError(t, err)
+			require.Len(t, stored, 2)
+			for _, chunk := range stored {
+				assert.Equal(t, document.ID, chunk.DocumentID)
+				if chunk.Index == 1 {
+					assert.Equal(t, "A.2", chunk.Section)
+					assert.Equal(t, []float32{1, 0}, chunk.Embedding)
+				}
+			}
+
+			require.NoError(t, documents.DeleteDocument(ctx, document.ID))
+			assert.ErrorIs(t, documents.DeleteDocument(ctx, document.ID), ErrDocumentNotFound)
+			stored, err = documents.Chunks(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, stored)
+		})
+	}
+}
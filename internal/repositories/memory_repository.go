@@ -0,0 +1,636 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+)
+
+// MemoryTaskRepository keeps tasks in memory. It is used for tests and for
+// running the server without a database; data is lost on restart.
+type MemoryTaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[primitive.ObjectID]models.Task
+}
+
+// NewMemoryTaskRepository creates an empty in-memory task repository
+func NewMemoryTaskRepository() *MemoryTaskRepository {
+	return &MemoryTaskRepository{tasks: make(map[primitive.ObjectID]models.Task)}
+}
+
+// FindAll retrieves all tasks in creation order
+func (r *MemoryTaskRepository) FindAll(ctx context.Context) ([]models.Task, error) {
+	return r.collect(func(models.Task) bool { return true }), nil
+}
+
+// FindByID retrieves a task by its ObjectID
+func (r *MemoryTaskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	task, ok := r.tasks[id]
+	if !ok {
+		return models.Task{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// Create adds a new task
+func (r *MemoryTaskRepository) Create(ctx context.Context, task *models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prepareNewTask(task)
+	r.tasks[task.ID] = *task
+	return nil
+}
+
+// Update replaces an existing task
+func (r *MemoryTaskRepository) Update(ctx context.Context, task *models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[task.ID]; !ok {
+		return ErrTaskNotFound
+	}
+	task.UpdatedAt = time.Now()
+	r.tasks[task.ID] = *task
+	return nil
+}
+
+// Delete removes a task by ObjectID
+func (r *MemoryTaskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[id]; !ok {
+		return ErrTaskNotFound
+	}
+	delete(r.tasks, id)
+	return nil
+}
+
+// FindTasksDueToday retrieves all tasks due on the current day
+func (r *MemoryTaskRepository) FindTasksDueToday(ctx context.Context) ([]models.Task, error) {
+	return findTasksDueToday(ctx, r)
+}
+
+// Find retrieves one page of tasks matching the query
+func (r *MemoryTaskRepository) Find(ctx context.Context, query TaskQuery) (TaskPage, error) {
+	q, err := query.normalize()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	var cur *taskCursor
+	if q.Cursor != "" {
+		decoded, err := decodeTaskCursor(q.Cursor, q.SortBy)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		cur = &decoded
+	}
+
+	tasks := r.collect(func(t models.Task) bool {
+		if !q.matches(t) {
+			return false
+		}
+		if cur == nil {
+			return true
+		}
+		c := compareTaskToCursor(t, q.SortBy, *cur)
+		if q.Descending {
+			return c < 0
+		}
+		return c > 0
+	})
+	sort.Slice(tasks, func(i, j int) bool {
+		c := compareTasks(tasks[i], tasks[j], q.SortBy)
+		if q.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+	if len(tasks) > q.Limit+1 {
+		tasks = tasks[:q.Limit+1]
+	}
+	return q.buildPage(tasks), nil
+}
+
+// FindByProject retrieves all tasks that belong to the given project
+func (r *MemoryTaskRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID) ([]models.Task, error) {
+	return r.collect(func(t models.Task) bool { return t.ProjectID == projectID }), nil
+}
+
+// CountByProject returns the number of tasks that belong to the given project
+func (r *MemoryTaskRepository) CountByProject(ctx context.Context, projectID primitive.ObjectID) (int64, error) {
+	tasks, _ := r.FindByProject(ctx, projectID)
+	return int64(len(tasks)), nil
+}
+
+// collect returns the tasks accepted by keep, ordered by ID (creation order)
+func (r *MemoryTaskRepository) collect(keep func(models.Task) bool) []models.Task {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tasks := []models.Task{}
+	for _, t := range r.tasks {
+		if keep(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID.Hex() < tasks[j].ID.Hex() })
+	return tasks
+}
+
+// matches reports whether a task satisfies the query filters (not the cursor)
+func (q TaskQuery) matches(t models.Task) bool {
+	if len(q.Status) > 0 && !containsString(q.Status, t.Status) {
+		return false
+	}
+	if len(q.Priority) > 0 && !containsString(q.Priority, t.Priority) {
+		return false
+	}
+	if !q.ProjectID.IsZero() && t.ProjectID != q.ProjectID {
+		return false
+	}
+	if !q.DueFrom.IsZero() && t.DueDate.Before(q.DueFrom) {
+		return false
+	}
+	if !q.DueBefore.IsZero() && !t.DueDate.Before(q.DueBefore) {
+		return false
+	}
+	if q.Search != "" && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(q.Search)) {
+		return false
+	}
+	return true
+}
+
+// compareTasks orders two tasks by the sort field, then by ID
+func compareTasks(a, b models.Task, field TaskSortField) int {
+	var c int
+	if field == SortByTitle {
+		c = strings.Compare(a.Title, b.Title)
+	} else {
+		c = taskSortTime(a, field).Compare(taskSortTime(b, field))
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID.Hex(), b.ID.Hex())
+}
+
+// compareTaskToCursor orders a task relative to a cursor position
+func compareTaskToCursor(t models.Task, field TaskSortField, cur taskCursor) int {
+	var c int
+	if field == SortByTitle {
+		c = strings.Compare(t.Title, cur.value.(string))
+	} else {
+		c = taskSortTime(t, field).Compare(cur.value.(time.Time))
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(t.ID.Hex(), cur.id.Hex())
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryProjectRepository keeps projects in memory
+type MemoryProjectRepository struct {
+	mu       sync.RWMutex
+	projects map[primitive.ObjectID]models.Project
+}
+
+// NewMemoryProjectRepository creates an empty in-memory project repository
+func NewMemoryProjectRepository() *MemoryProjectRepository {
+	return &MemoryProjectRepository{projects: make(map[primitive.ObjectID]models.Project)}
+}
+
+// FindAll retrieves all projects ordered by name
+func (r *MemoryProjectRepository) FindAll(ctx context.Context) ([]models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	projects := make([]models.Project, 0, len(r.projects))
+	for _, p := range r.projects {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].ID.Hex() < projects[j].ID.Hex()
+	})
+	return projects, nil
+}
+
+// FindByID retrieves a project by its ObjectID
+func (r *MemoryProjectRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	project, ok := r.projects[id]
+	if !ok {
+		return models.Project{}, ErrProjectNotFound
+	}
+	return project, nil
+}
+
+// Exists reports whether a project with the given ObjectID exists
+func (r *MemoryProjectRepository) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.projects[id]
+	return ok, nil
+}
+
+// Create adds a new project
+func (r *MemoryProjectRepository) Create(ctx context.Context, project *models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prepareNewProject(project)
+	r.projects[project.ID] = *project
+	return nil
+}
+
+// Update replaces an existing project
+func (r *MemoryProjectRepository) Update(ctx context.Context, project *models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[project.ID]; !ok {
+		return ErrProjectNotFound
+	}
+	project.UpdatedAt = time.Now()
+	r.projects[project.ID] = *project
+	return nil
+}
+
+// Delete removes a project by ObjectID
+func (r *MemoryProjectRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.projects[id]; !ok {
+		return ErrProjectNotFound
+	}
+	delete(r.projects, id)
+	return nil
+}
+
+// MemoryConversationRepository keeps conversations in memory
+type MemoryConversationRepository struct {
+	mu            sync.RWMutex
+	conversations map[primitive.ObjectID]models.Conversation
+}
+
+// NewMemoryConversationRepository creates an empty in-memory conversation repository
+func NewMemoryConversationRepository() *MemoryConversationRepository {
+	return &MemoryConversationRepository{conversations: make(map[primitive.ObjectID]models.Conversation)}
+}
+
+// ListByOwner retrieves the owner's conversations without their messages
+func (r *MemoryConversationRepository) ListByOwner(ctx context.Context, ownerID string) ([]models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conversations := []models.Conversation{}
+	for _, conv := range r.conversations {
+		if conv.OwnerID == ownerID {
+			conv.Messages = nil
+			conversations = append(conversations, conv)
+		}
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		if !conversations[i].UpdatedAt.Equal(conversations[j].UpdatedAt) {
+			return conversations[i].UpdatedAt.After(conversations[j].UpdatedAt)
+		}
+		return conversations[i].ID.Hex() > conversations[j].ID.Hex()
+	})
+	return conversations, nil
+}
+
+// FindByID retrieves a conversation by its ObjectID
+func (r *MemoryConversationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conv, ok := r.conversations[id]
+	if !ok {
+		return models.Conversation{}, ErrConversationNotFound
+	}
+	return copyConversation(conv), nil
+}
+
+// Create adds a new conversation
+func (r *MemoryConversationRepository) Create(ctx context.Context, conversation *models.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prepareNewConversation(conversation)
+	r.conversations[conversation.ID] = copyConversation(*conversation)
+	return nil
+}
+
+// Update replaces an existing conversation
+func (r *MemoryConversationRepository) Update(ctx context.Context, conversation *models.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conversations[conversation.ID]; !ok {
+		return ErrConversationNotFound
+	}
+	conversation.UpdatedAt = time.Now()
+	r.conversations[conversation.ID] = copyConversation(*conversation)
+	return nil
+}
+
+// Delete removes a conversation by ObjectID
+func (r *MemoryConversationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conversations[id]; !ok {
+		return ErrConversationNotFound
+	}
+	delete(r.conversations, id)
+	return nil
+}
+
+// copyConversation copies the message slice so callers cannot modify stored data
+func copyConversation(conv models.Conversation) models.Conversation {
+	conv.Messages = append([]models.ConversationMessage{}, conv.Messages...)
+	return conv
+}
+
+// MemoryUsageRepository keeps the token ledger and budgets in memory
+type MemoryUsageRepository struct {
+	mu      sync.RWMutex
+	records []models.UsageRecord
+	budgets map[primitive.ObjectID]models.UsageBudget
+}
+
+// NewMemoryUsageRepository creates an empty in-memory usage repository
+func NewMemoryUsageRepository() *MemoryUsageRepository {
+	return &MemoryUsageRepository{budgets: make(map[primitive.ObjectID]models.UsageBudget)}
+}
+
+// Record appends a completion to the ledger
+func (r *MemoryUsageRepository) Record(ctx context.Context, record *models.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prepareUsageRecord(record)
+	r.records = append(r.records, *record)
+	return nil
+}
+
+// Totals sums the selected records per day, user, project, provider and model
+func (r *MemoryUsageRepository) Totals(ctx context.Context, query UsageQuery) ([]models.UsageTotal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	type key struct {
+		day                                time.Time
+		userID, projectID, provider, model string
+	}
+	index := make(map[key]int)
+	totals := []models.UsageTotal{}
+	for _, rec := range r.records {
+		if !query.matches(rec) {
+			continue
+		}
+		k := key{usageDay(rec.CreatedAt), rec.UserID, rec.ProjectID, rec.Provider, rec.Model}
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, models.UsageTotal{
+				Day: k.day, UserID: k.userID, ProjectID: k.projectID, Provider: k.provider, Model: k.model,
+			})
+		}
+		totals[i].PromptTokens += int64(rec.PromptTokens)
+		totals[i].CompletionTokens += int64(rec.CompletionTokens)
+		totals[i].Requests++
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Day.Before(totals[j].Day) })
+	return totals, nil
+}
+
+// ListBudgets retrieves every budget
+func (r *MemoryUsageRepository) ListBudgets(ctx context.Context) ([]models.UsageBudget, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	budgets := []models.UsageBudget{}
+	for _, budget := range r.budgets {
+		budgets = append(budgets, budget)
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].CreatedAt.Before(budgets[j].CreatedAt) })
+	return budgets, nil
+}
+
+// SaveBudget creates or replaces the budget for a scope, subject and period
+func (r *MemoryUsageRepository) SaveBudget(ctx context.Context, budget *models.UsageBudget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	budget.ID = primitive.NewObjectID()
+	budget.CreatedAt = now
+	for id, existing := range r.budgets {
+		if existing.Scope == budget.Scope && existing.Subject == budget.Subject && existing.Period == budget.Period {
+			budget.ID = id
+			budget.CreatedAt = existing.CreatedAt
+		}
+	}
+	budget.UpdatedAt = now
+	r.budgets[budget.ID] = *budget
+	return nil
+}
+
+// DeleteBudget removes a budget by ObjectID
+func (r *MemoryUsageRepository) DeleteBudget(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.budgets[id]; !ok {
+		return ErrBudgetNotFound
+	}
+	delete(r.budgets, id)
+	return nil
+}
+
+// MemoryDocumentRepository keeps documents and their chunks in memory
+type MemoryDocumentRepository struct {
+	mu        sync.RWMutex
+	documents map[primitive.ObjectID]models.Document
+	chunks    map[primitive.ObjectID][]models.DocumentChunk
+}
+
+// NewMemoryDocumentRepository creates an empty in-memory document repository
+func NewMemoryDocumentRepository() *MemoryDocumentRepository {
+	return &MemoryDocumentRepository{
+		documents: make(map[primitive.ObjectID]models.Document),
+		chunks:    make(map[primitive.ObjectID][]models.DocumentChunk),
+	}
+}
+
+// ListDocuments retrieves every document, newest first
+func (r *MemoryDocumentRepository) ListDocuments(ctx context.Context) ([]models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	documents := []models.Document{}
+	for _, document := range r.documents {
+		documents = append(documents, document)
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].CreatedAt.After(documents[j].CreatedAt) })
+	return documents, nil
+}
+
+// CreateDocument stores a document and its chunks
+func (r *MemoryDocumentRepository) CreateDocument(ctx context.Context, document *models.Document, chunks []models.DocumentChunk) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prepareNewDocument(document, chunks)
+	r.documents[document.ID] = *document
+	r.chunks[document.ID] = append([]models.DocumentChunk{}, chunks...)
+	return nil
+}
+
+// DeleteDocument removes a document and its chunks
+func (r *MemoryDocumentRepository) DeleteDocument(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.documents[id]; !ok {
+		return ErrDocumentNotFound
+	}
+	delete(r.documents, id)
+	delete(r.chunks, id)
+	return nil
+}
+
+// Chunks retrieves the chunks of every document
+func (r *MemoryDocumentRepository) Chunks(ctx context.Context) ([]models.DocumentChunk, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	chunks := []models.DocumentChunk{}
+	for _, documentChunks := range r.chunks {
+		chunks = append(chunks, documentChunks...)
+	}
+	return chunks, nil
+}
+
+// MemoryPromptRepository keeps prompt template versions in memory
+type MemoryPromptRepository struct {
+	mu        sync.RWMutex
+	templates []models.PromptTemplate
+}
+
+// NewMemoryPromptRepository creates an empty in-memory prompt repository
+func NewMemoryPromptRepository() *MemoryPromptRepository {
+	return &MemoryPromptRepository{}
+}
+
+// ListPromptTemplates retrieves every stored version, by name and version
+func (r *MemoryPromptRepository) ListPromptTemplates(ctx context.Context) ([]models.PromptTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	templates := append([]models.PromptTemplate{}, r.templates...)
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].Version < templates[j].Version
+	})
+	return templates, nil
+}
+
+// CreatePromptTemplate stores a new version
+func (r *MemoryPromptRepository) CreatePromptTemplate(ctx context.Context, template *models.PromptTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.templates {
+		if existing.Name == template.Name && existing.Version == template.Version {
+			return ErrPromptVersionExists
+		}
+	}
+	prepareNewPromptTemplate(template)
+	r.templates = append(r.templates, *template)
+	return nil
+}
+
+// MemoryUserRepository keeps user accounts in memory
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[primitive.ObjectID]models.User)}
+}
+
+// FindByID retrieves a user by ID
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SignIn creates or updates the user of a Google account
+func (r *MemoryUserRepository) SignIn(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	stored := models.User{ID: primitive.NewObjectID(), GoogleID: user.GoogleID, CreatedAt: now}
+	for _, existing := range r.users {
+		if existing.GoogleID == user.GoogleID {
+			stored = existing
+			break
+		}
+	}
+	stored.Email, stored.Name, stored.Picture = user.Email, user.Name, user.Picture
+	stored.UpdatedAt, stored.LastLoginAt = now, now
+	r.users[stored.ID] = stored
+	*user = stored
+	return nil
+}
+
+// MemoryTokenStore keeps tokens in memory. Nothing is written at rest, so
+// they are not encrypted.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[primitive.ObjectID]oauth2.Token
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[primitive.ObjectID]oauth2.Token)}
+}
+
+// Get retrieves a copy of the token of a user
+func (s *MemoryTokenStore) Get(ctx context.Context, userID primitive.ObjectID) (*oauth2.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// Save stores a copy of the token of a user
+func (s *MemoryTokenStore) Save(ctx context.Context, userID primitive.ObjectID, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[userID] = *token
+	return nil
+}
+
+// Delete removes the token of a user
+func (s *MemoryTokenStore) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, userID)
+	return nil
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoConversationRepository stores conversations in a MongoDB collection
+type MongoConversationRepository struct {
+	coll *mongo.Collection
+}
+
+// NewMongoConversationRepository creates a conversation repository backed by
+// the "conversations" collection of the given database
+func NewMongoConversationRepository(db *mongo.Database) *MongoConversationRepository {
+	return &MongoConversationRepository{coll: db.Collection("conversations")}
+}
+
+// EnsureIndexes creates the index used to list an owner's conversations
+func (r *MongoConversationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "updated_at", Value: -1}},
+	})
+	return err
+}
+
+// ListByOwner retrieves the owner's conversations without their messages
+func (r *MongoConversationRepository) ListByOwner(ctx context.Context, ownerID string) ([]models.Conversation, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"messages": 0})
+	cur, err := r.coll.Find(ctx, bson.M{"owner_id": ownerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	conversations := []models.Conversation{}
+	for cur.Next(ctx) {
+		var conv models.Conversation
+		if err := cur.Decode(&conv); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conv)
+	}
+	return conversations, cur.Err()
+}
+
+// FindByID retrieves a conversation by its ObjectID
+func (r *MongoConversationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Conversation, error) {
+	var conv models.Conversation
+	err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&conv)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return conv, ErrConversationNotFound
+	}
+	return conv, err
+}
+
+// Create adds a new conversation to MongoDB
+func (r *MongoConversationRepository) Create(ctx context.Context, conversation *models.Conversation) error {
+	prepareNewConversation(conversation)
+	_, err := r.coll.InsertOne(ctx, conversation)
+	return err
+}
+
+// Update replaces an existing conversation in MongoDB
+func (r *MongoConversationRepository) Update(ctx context.Context, conversation *models.Conversation) error {
+	conversation.UpdatedAt = time.Now()
+	res, err := r.coll.ReplaceOne(ctx, bson.M{"_id": conversation.ID}, conversation)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
+
+// Delete removes a conversation from MongoDB by ObjectID
+func (r *MongoConversationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrConversationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDocumentRepository stores documents in the "documents" collection
+// and their chunks in "document_chunks"
+type MongoDocumentRepository struct {
+	documents *mongo.Collection
+	chunks    *mongo.Collection
+}
+
+// NewMongoDocumentRepository creates a document repository backed by the given database
+func NewMongoDocumentRepository(db *mongo.Database) *MongoDocumentRepository {
+	return &MongoDocumentRepository{
+		documents: db.Collection("documents"),
+		chunks:    db.Collection("document_chunks"),
+	}
+}
+
+// EnsureIndexes creates the index used to remove a document's chunks
+func (r *MongoDocumentRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.chunks.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "document_id", Value: 1}}})
+	return err
+}
+
+// ListDocuments retrieves every document, newest first
+func (r *MongoDocumentRepository) ListDocuments(ctx context.Context) ([]models.Document, error) {
+	cur, err := r.documents.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	documents := []models.Document{}
+	if err := cur.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// CreateDocument stores a document and its chunks. The chunks are written
+// first so a failure never leaves a document without its passages.
+func (r *MongoDocumentRepository) CreateDocument(ctx context.Context, document *models.Document, chunks []models.DocumentChunk) error {
+	prepareNewDocument(document, chunks)
+	if len(chunks) > 0 {
+		docs := make([]interface{}, len(chunks))
+		for i, chunk := range chunks {
+			docs[i] = chunk
+		}
+		if _, err := r.chunks.InsertMany(ctx, docs); err != nil {
+			_, _ = r.chunks.DeleteMany(ctx, bson.M{"document_id": document.ID})
+			return err
+		}
+	}
+	if _, err := r.documents.InsertOne(ctx, document); err != nil {
+		_, _ = r.chunks.DeleteMany(ctx, bson.M{"document_id": document.ID})
+		return err
+	}
+	return nil
+}
+
+// DeleteDocument removes a document and its chunks
+func (r *MongoDocumentRepository) DeleteDocument(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.documents.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrDocumentNotFound
+	}
+	_, err = r.chunks.DeleteMany(ctx, bson.M{"document_id": id})
+	return err
+}
+
+// Chunks retrieves the chunks of every document
+func (r *MongoDocumentRepository) Chunks(ctx context.Context) ([]models.DocumentChunk, error) {
+	cur, err := r.chunks.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	chunks := []models.DocumentChunk{}
+	if err := cur.All(ctx, &chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoProjectRepository stores projects in a MongoDB collection
+type MongoProjectRepository struct {
+	coll *mongo.Collection
+}
+
+// NewMongoProjectRepository creates a project repository backed by the
+// "projects" collection of the given database
+func NewMongoProjectRepository(db *mongo.Database) *MongoProjectRepository {
+	return &MongoProjectRepository{coll: db.Collection("projects")}
+}
+
+// FindAll retrieves all projects ordered by name
+func (r *MongoProjectRepository) FindAll(ctx context.Context) ([]models.Project, error) {
+	cur, err := r.coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	projects := []models.Project{}
+	for cur.Next(ctx) {
+		var p models.Project
+		if err := cur.Decode(&p); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, cur.Err()
+}
+
+// FindByID retrieves a project by its ObjectID.
+// It returns ErrProjectNotFound when no project has that ID.
+func (r *MongoProjectRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Project, error) {
+	var project models.Project
+	err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&project)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return project, ErrProjectNotFound
+	}
+	return project, err
+}
+
+// Exists reports whether a project with the given ObjectID exists
+func (r *MongoProjectRepository) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	n, err := r.coll.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Create adds a new project to MongoDB
+func (r *MongoProjectRepository) Create(ctx context.Context, project *models.Project) error {
+	prepareNewProject(project)
+	_, err := r.coll.InsertOne(ctx, project)
+	return err
+}
+
+// Update modifies an existing project in MongoDB
+func (r *MongoProjectRepository) Update(ctx context.Context, project *models.Project) error {
+	project.UpdatedAt = time.Now()
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": project.ID}, bson.M{"$set": project})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// Delete removes a project from MongoDB by ObjectID
+func (r *MongoProjectRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
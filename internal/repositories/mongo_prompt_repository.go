@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoPromptRepository stores prompt template versions in the "prompt_templates" collection
+type MongoPromptRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPromptRepository creates a prompt repository backed by the given database
+func NewMongoPromptRepository(db *mongo.Database) *MongoPromptRepository {
+	return &MongoPromptRepository{collection: db.Collection("prompt_templates")}
+}
+
+// EnsureIndexes creates the unique index on name and version
+func (r *MongoPromptRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// ListPromptTemplates retrieves every stored version, by name and version
+func (r *MongoPromptRepository) ListPromptTemplates(ctx context.Context) ([]models.PromptTemplate, error) {
+	cur, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	templates := []models.PromptTemplate{}
+	if err := cur.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// CreatePromptTemplate stores a new version
+func (r *MongoPromptRepository) CreatePromptTemplate(ctx context.Context, template *models.PromptTemplate) error {
+	prepareNewPromptTemplate(template)
+	if _, err := r.collection.InsertOne(ctx, template); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrPromptVersionExists
+		}
+		return err
+	}
+	return nil
+}
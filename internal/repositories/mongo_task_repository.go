@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoTaskRepository stores tasks in a MongoDB collection
+type MongoTaskRepository struct {
+	coll *mongo.Collection
+}
+
+// NewMongoTaskRepository creates a task repository backed by the "tasks"
+// collection of the given database
+func NewMongoTaskRepository(db *mongo.Database) *MongoTaskRepository {
+	return &MongoTaskRepository{coll: db.Collection("tasks")}
+}
+
+// FindAll retrieves all tasks from MongoDB
+func (r *MongoTaskRepository) FindAll(ctx context.Context) ([]models.Task, error) {
+	return r.findTasks(ctx, bson.D{})
+}
+
+// FindByID retrieves a task by its ObjectID
+func (r *MongoTaskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Task, error) {
+	var task models.Task
+	err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return task, ErrTaskNotFound
+	}
+	return task, err
+}
+
+// Create adds a new task to MongoDB
+func (r *MongoTaskRepository) Create(ctx context.Context, task *models.Task) error {
+	prepareNewTask(task)
+	_, err := r.coll.InsertOne(ctx, task)
+	return err
+}
+
+// Update modifies an existing task in MongoDB
+func (r *MongoTaskRepository) Update(ctx context.Context, task *models.Task) error {
+	task.UpdatedAt = time.Now()
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": task.ID}, bson.M{"$set": task})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// Delete removes a task from MongoDB by ObjectID
+func (r *MongoTaskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// FindTasksDueToday retrieves all tasks due on the current day
+func (r *MongoTaskRepository) FindTasksDueToday(ctx context.Context) ([]models.Task, error) {
+	return findTasksDueToday(ctx, r)
+}
+
+// Find retrieves one page of tasks matching the query
+func (r *MongoTaskRepository) Find(ctx context.Context, query TaskQuery) (TaskPage, error) {
+	q, err := query.normalize()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	filter, err := q.filter()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	cur, err := r.coll.Find(ctx, filter, q.findOptions())
+	if err != nil {
+		return TaskPage{}, err
+	}
+	defer cur.Close(ctx)
+	var tasks []models.Task
+	if err := cur.All(ctx, &tasks); err != nil {
+		return TaskPage{}, err
+	}
+	return q.buildPage(tasks), nil
+}
+
+// EnsureIndexes creates the indexes used by Find
+func (r *MongoTaskRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, taskIndexes)
+	return err
+}
+
+// FindByProject retrieves all tasks that belong to the given project
+func (r *MongoTaskRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID) ([]models.Task, error) {
+	return r.findTasks(ctx, bson.M{"project_id": projectID})
+}
+
+// CountByProject returns the number of tasks that belong to the given project
+func (r *MongoTaskRepository) CountByProject(ctx context.Context, projectID primitive.ObjectID) (int64, error) {
+	return r.coll.CountDocuments(ctx, bson.M{"project_id": projectID})
+}
+
+// findTasks decodes every task matching the filter
+func (r *MongoTaskRepository) findTasks(ctx context.Context, filter interface{}) ([]models.Task, error) {
+	cur, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	tasks := []models.Task{}
+	for cur.Next(ctx) {
+		var t models.Task
+		if err := cur.Decode(&t); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, cur.Err()
+}
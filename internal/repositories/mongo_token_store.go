@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/oauth2"
+)
+
+// MongoTokenStore keeps encrypted tokens in the "oauth_tokens" collection,
+// one document per user keyed by the user's ID
+type MongoTokenStore struct {
+	collection *mongo.Collection
+	cipher     *TokenCipher
+}
+
+// storedToken is the document of an encrypted token
+type storedToken struct {
+	UserID    primitive.ObjectID `bson:"_id"`
+	Token     []byte             `bson:"token"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// NewMongoTokenStore creates a token store backed by the given database
+func NewMongoTokenStore(db *mongo.Database, cipher *TokenCipher) *MongoTokenStore {
+	return &MongoTokenStore{collection: db.Collection("oauth_tokens"), cipher: cipher}
+}
+
+// Get retrieves and decrypts the token of a user
+func (s *MongoTokenStore) Get(ctx context.Context, userID primitive.ObjectID) (*oauth2.Token, error) {
+	var stored storedToken
+	err := s.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Open(userID, stored.Token)
+}
+
+// Save encrypts and stores the token of a user
+func (s *MongoTokenStore) Save(ctx context.Context, userID primitive.ObjectID, token *oauth2.Token) error {
+	sealed, err := s.cipher.Seal(userID, token)
+	if err != nil {
+		return err
+	}
+	_, err = s.collection.ReplaceOne(ctx, bson.M{"_id": userID},
+		storedToken{UserID: userID, Token: sealed, UpdatedAt: time.Now()},
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+// Delete removes the token of a user
+func (s *MongoTokenStore) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUsageRepository stores the token ledger in the "ai_usage" collection
+// and budgets in "ai_budgets"
+type MongoUsageRepository struct {
+	usage   *mongo.Collection
+	budgets *mongo.Collection
+}
+
+// NewMongoUsageRepository creates a usage repository backed by the given database
+func NewMongoUsageRepository(db *mongo.Database) *MongoUsageRepository {
+	return &MongoUsageRepository{
+		usage:   db.Collection("ai_usage"),
+		budgets: db.Collection("ai_budgets"),
+	}
+}
+
+// EnsureIndexes creates the indexes used to sum usage and to find a budget
+// by scope, subject and period
+func (r *MongoUsageRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.usage.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.budgets.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "scope", Value: 1}, {Key: "subject", Value: 1}, {Key: "period", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Record appends a completion to the ledger
+func (r *MongoUsageRepository) Record(ctx context.Context, record *models.UsageRecord) error {
+	prepareUsageRecord(record)
+	_, err := r.usage.InsertOne(ctx, record)
+	return err
+}
+
+// Totals sums the selected records per day, user, project, provider and model
+func (r *MongoUsageRepository) Totals(ctx context.Context, query UsageQuery) ([]models.UsageTotal, error) {
+	match := bson.M{}
+	if query.UserID != "" {
+		match["user_id"] = query.UserID
+	}
+	if query.ProjectID != "" {
+		match["project_id"] = query.ProjectID
+	}
+	created := bson.M{}
+	if !query.Since.IsZero() {
+		created["$gte"] = query.Since
+	}
+	if !query.Until.IsZero() {
+		created["$lt"] = query.Until
+	}
+	if len(created) > 0 {
+		match["created_at"] = created
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"day":        bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+				"user_id":    "$user_id",
+				"project_id": "$project_id",
+				"provider":   "$provider",
+				"model":      "$model",
+			},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"requests":          bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.day", Value: 1}}}},
+	}
+	cur, err := r.usage.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	totals := []models.UsageTotal{}
+	for cur.Next(ctx) {
+		var row struct {
+			Group struct {
+				Day       string `bson:"day"`
+				UserID    string `bson:"user_id"`
+				ProjectID string `bson:"project_id"`
+				Provider  string `bson:"provider"`
+				Model     string `bson:"model"`
+			} `bson:"_id"`
+			PromptTokens     int64 `bson:"prompt_tokens"`
+			CompletionTokens int64 `bson:"completion_tokens"`
+			Requests         int64 `bson:"requests"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			return nil, err
+		}
+		day, err := time.Parse("2006-01-02", row.Group.Day)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, models.UsageTotal{
+			Day:              day,
+			UserID:           row.Group.UserID,
+			ProjectID:        row.Group.ProjectID,
+			Provider:         row.Group.Provider,
+			Model:            row.Group.Model,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			Requests:         row.Requests,
+		})
+	}
+	return totals, cur.Err()
+}
+
+// ListBudgets retrieves every budget
+func (r *MongoUsageRepository) ListBudgets(ctx context.Context) ([]models.UsageBudget, error) {
+	cur, err := r.budgets.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	budgets := []models.UsageBudget{}
+	if err := cur.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// SaveBudget creates or replaces the budget for a scope, subject and period
+func (r *MongoUsageRepository) SaveBudget(ctx context.Context, budget *models.UsageBudget) error {
+	now := time.Now()
+	filter := bson.M{"scope": budget.Scope, "subject": budget.Subject, "period": budget.Period}
+	update := bson.M{
+		"$set": bson.M{
+			"limit":      budget.Limit,
+			"warn_ratio": budget.WarnRatio,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID(), "created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.budgets.FindOneAndUpdate(ctx, filter, update, opts).Decode(budget)
+}
+
+// DeleteBudget removes a budget by ObjectID
+func (r *MongoUsageRepository) DeleteBudget(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.budgets.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
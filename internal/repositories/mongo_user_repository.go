@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUserRepository stores user accounts in the "users" collection
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository creates a user repository backed by the given database
+func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{collection: db.Collection("users")}
+}
+
+// EnsureIndexes creates the unique index on the Google account
+func (r *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "google_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// FindByID retrieves a user by ID
+func (r *MongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, err
+}
+
+// SignIn creates or updates the user of a Google account
+func (r *MongoUserRepository) SignIn(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"email":         user.Email,
+			"name":          user.Name,
+			"picture":       user.Picture,
+			"updated_at":    now,
+			"last_login_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"google_id": user.GoogleID}, update, opts).Decode(user)
+}
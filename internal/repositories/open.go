@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lyffseba/ana/internal/config"
+	"github.com/lyffseba/ana/internal/database"
+)
+
+// Repositories groups the repositories of one storage backend
+type Repositories struct {
+	Tasks         TaskRepository
+	Projects      ProjectRepository
+	Conversations ConversationRepository
+	Usage         UsageRepository
+	Documents     DocumentRepository
+	Prompts       PromptRepository
+	Users         UserRepository
+
+	// tokens creates the backend's token store
+	tokens func(cipher *TokenCipher) TokenStore
+	close  func(ctx context.Context) error
+}
+
+// Tokens returns the backend's store of Google tokens, encrypted at rest
+// with cipher
+func (r *Repositories) Tokens(cipher *TokenCipher) TokenStore {
+	return r.tokens(cipher)
+}
+
+// Close releases the backend's connections
+func (r *Repositories) Close(ctx context.Context) error {
+	if r.close == nil {
+		return nil
+	}
+	return r.close(ctx)
+}
+
+// NewMemoryRepositories creates empty in-memory repositories
+func NewMemoryRepositories() *Repositories {
+	tokens := NewMemoryTokenStore()
+	return &Repositories{
+		Tasks:         NewMemoryTaskRepository(),
+		Projects:      NewMemoryProjectRepository(),
+		Conversations: NewMemoryConversationRepository(),
+		Usage:         NewMemoryUsageRepository(),
+		Documents:     NewMemoryDocumentRepository(),
+		Prompts:       NewMemoryPromptRepository(),
+		Users:         NewMemoryUserRepository(),
+		tokens:        func(*TokenCipher) TokenStore { return tokens },
+	}
+}
+
+// Open connects to the backend selected by cfg.Driver and returns its
+// repositories. MongoDB indexes are created and PostgreSQL migrations are
+// applied before returning.
+func Open(ctx context.Context, cfg config.DatabaseConfig) (*Repositories, error) {
+	switch cfg.Driver {
+	case config.DatabaseDriverMemory:
+		return NewMemoryRepositories(), nil
+
+	case "", config.DatabaseDriverMongo:
+		client, err := database.ConnectMongo(ctx, cfg.URI)
+		if err != nil {
+			return nil, err
+		}
+		dbName := cfg.Database
+		if dbName == "" {
+			dbName = "ana_world"
+		}
+		db := client.Database(dbName)
+		tasks := NewMongoTaskRepository(db)
+		if err := tasks.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating task indexes: %w", err)
+		}
+		conversations := NewMongoConversationRepository(db)
+		if err := conversations.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating conversation indexes: %w", err)
+		}
+		usage := NewMongoUsageRepository(db)
+		if err := usage.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating usage indexes: %w", err)
+		}
+		documents := NewMongoDocumentRepository(db)
+		if err := documents.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating document indexes: %w", err)
+		}
+		prompts := NewMongoPromptRepository(db)
+		if err := prompts.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating prompt template indexes: %w", err)
+		}
+		users := NewMongoUserRepository(db)
+		if err := users.EnsureIndexes(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, fmt.Errorf("creating user indexes: %w", err)
+		}
+		return &Repositories{
+			Tasks:         tasks,
+			Projects:      NewMongoProjectRepository(db),
+			Conversations: conversations,
+			Usage:         usage,
+			Documents:     documents,
+			Prompts:       prompts,
+			Users:         users,
+			tokens:        func(cipher *TokenCipher) TokenStore { return NewMongoTokenStore(db, cipher) },
+			close:         client.Disconnect,
+		}, nil
+
+	case config.DatabaseDriverPostgres:
+		dsn := cfg.URI
+		if dsn == "" {
+			dsn = cfg.PostgresDSN()
+		}
+		db, err := database.OpenPostgres(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err := database.Migrate(ctx, db, cfg.MigrationsPath); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return &Repositories{
+			Tasks:         NewPostgresTaskRepository(db),
+			Projects:      NewPostgresProjectRepository(db),
+			Conversations: NewPostgresConversationRepository(db),
+			Usage:         NewPostgresUsageRepository(db),
+			Documents:     NewPostgresDocumentRepository(db),
+			Prompts:       NewPostgresPromptRepository(db),
+			Users:         NewPostgresUserRepository(db),
+			tokens:        func(cipher *TokenCipher) TokenStore { return NewPostgresTokenStore(db, cipher) },
+			close:         func(context.Context) error { return db.Close() },
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+}
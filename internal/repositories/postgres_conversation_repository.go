@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresConversationRepository stores conversations in the PostgreSQL "conversations" table
+type PostgresConversationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresConversationRepository creates a conversation repository using the given connection pool
+func NewPostgresConversationRepository(db *sql.DB) *PostgresConversationRepository {
+	return &PostgresConversationRepository{db: db}
+}
+
+// ListByOwner retrieves the owner's conversations without their messages
+func (r *PostgresConversationRepository) ListByOwner(ctx context.Context, ownerID string) ([]models.Conversation, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, owner_id, title, '[]'::jsonb, summary, summarized_count, created_at, updated_at
+		FROM conversations WHERE owner_id = $1 ORDER BY updated_at DESC, id DESC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	conversations := []models.Conversation{}
+	for rows.Next() {
+		conv, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conv.Messages = nil
+		conversations = append(conversations, conv)
+	}
+	return conversations, rows.Err()
+}
+
+// FindByID retrieves a conversation by its ObjectID
+func (r *PostgresConversationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Conversation, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT id, owner_id, title, messages, summary, summarized_count, created_at, updated_at
+		FROM conversations WHERE id = $1`, id.Hex())
+	conv, err := scanConversation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return conv, ErrConversationNotFound
+	}
+	return conv, err
+}
+
+// Create adds a new conversation to PostgreSQL
+func (r *PostgresConversationRepository) Create(ctx context.Context, conversation *models.Conversation) error {
+	prepareNewConversation(conversation)
+	messages, err := json.Marshal(conversation.Messages)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO conversations (id, owner_id, title, messages, summary, summarized_count, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		conversation.ID.Hex(), conversation.OwnerID, conversation.Title, messages,
+		conversation.Summary, conversation.SummarizedCount,
+		conversation.CreatedAt.UTC(), conversation.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+// Update replaces an existing conversation in PostgreSQL
+func (r *PostgresConversationRepository) Update(ctx context.Context, conversation *models.Conversation) error {
+	conversation.UpdatedAt = time.Now()
+	if conversation.Messages == nil {
+		conversation.Messages = []models.ConversationMessage{}
+	}
+	messages, err := json.Marshal(conversation.Messages)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE conversations SET owner_id = $2, title = $3, messages = $4, summary = $5,
+			summarized_count = $6, updated_at = $7
+		WHERE id = $1`,
+		conversation.ID.Hex(), conversation.OwnerID, conversation.Title, messages,
+		conversation.Summary, conversation.SummarizedCount, conversation.UpdatedAt.UTC(),
+	)
+	return checkAffected(res, err, ErrConversationNotFound)
+}
+
+// Delete removes a conversation from PostgreSQL by ObjectID
+func (r *PostgresConversationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM conversations WHERE id = $1", id.Hex())
+	return checkAffected(res, err, ErrConversationNotFound)
+}
+
+// scanConversation reads a conversation row including its messages column
+func scanConversation(row rowScanner) (models.Conversation, error) {
+	var (
+		conv     models.Conversation
+		id       string
+		messages []byte
+	)
+	err := row.Scan(&id, &conv.OwnerID, &conv.Title, &messages, &conv.Summary,
+		&conv.SummarizedCount, &conv.CreatedAt, &conv.UpdatedAt)
+	if err != nil {
+		return conv, err
+	}
+	if conv.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+		return conv, fmt.Errorf("invalid conversation id %q: %w", id, err)
+	}
+	if err := json.Unmarshal(messages, &conv.Messages); err != nil {
+		return conv, fmt.Errorf("decoding messages of conversation %s: %w", id, err)
+	}
+	return conv, nil
+}
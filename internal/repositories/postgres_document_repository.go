@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresDocumentRepository stores documents in the PostgreSQL "documents"
+// table and their chunks in "document_chunks"
+type PostgresDocumentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDocumentRepository creates a document repository using the given connection pool
+func NewPostgresDocumentRepository(db *sql.DB) *PostgresDocumentRepository {
+	return &PostgresDocumentRepository{db: db}
+}
+
+// ListDocuments retrieves every document, newest first
+func (r *PostgresDocumentRepository) ListDocuments(ctx context.Context) ([]models.Document, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, title, source, kind, project_id, embedder, chunk_count, created_at
+		FROM documents ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []models.Document{}
+	for rows.Next() {
+		var (
+			document  models.Document
+			id        string
+			projectID sql.NullString
+		)
+		err := rows.Scan(&id, &document.Title, &document.Source, &document.Kind, &projectID,
+			&document.Embedder, &document.Chunks, &document.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if document.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+			return nil, fmt.Errorf("invalid document id %q: %w", id, err)
+		}
+		if projectID.Valid {
+			if document.ProjectID, err = primitive.ObjectIDFromHex(strings.TrimSpace(projectID.String)); err != nil {
+				return nil, fmt.Errorf("invalid project id %q: %w", projectID.String, err)
+			}
+		}
+		documents = append(documents, document)
+	}
+	return documents, rows.Err()
+}
+
+// CreateDocument stores a document and its chunks in one transaction
+func (r *PostgresDocumentRepository) CreateDocument(ctx context.Context, document *models.Document, chunks []models.DocumentChunk) error {
+	prepareNewDocument(document, chunks)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO documents (id, title, source, kind, project_id, embedder, chunk_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		document.ID.Hex(), document.Title, document.Source, document.Kind, nullObjectID(document.ProjectID),
+		document.Embedder, document.Chunks, document.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO document_chunks (id, document_id, chunk_index, page, section, text, embedding)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			chunk.ID.Hex(), document.ID.Hex(), chunk.Index, chunk.Page, chunk.Section, chunk.Text,
+			pq.Float32Array(chunk.Embedding),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteDocument removes a document; its chunks are removed by the foreign key
+func (r *PostgresDocumentRepository) DeleteDocument(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM documents WHERE id = $1", id.Hex())
+	return checkAffected(res, err, ErrDocumentNotFound)
+}
+
+// Chunks retrieves the chunks of every document
+func (r *PostgresDocumentRepository) Chunks(ctx context.Context) ([]models.DocumentChunk, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, document_id, chunk_index, page, section, text, embedding
+		FROM document_chunks ORDER BY document_id, chunk_index`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []models.DocumentChunk{}
+	for rows.Next() {
+		var (
+			chunk          models.DocumentChunk
+			id, documentID string
+			embedding      pq.Float32Array
+		)
+		err := rows.Scan(&id, &documentID, &chunk.Index, &chunk.Page, &chunk.Section, &chunk.Text, &embedding)
+		if err != nil {
+			return nil, err
+		}
+		if chunk.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+			return nil, fmt.Errorf("invalid chunk id %q: %w", id, err)
+		}
+		if chunk.DocumentID, err = primitive.ObjectIDFromHex(strings.TrimSpace(documentID)); err != nil {
+			return nil, fmt.Errorf("invalid document id %q: %w", documentID, err)
+		}
+		chunk.Embedding = embedding
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// projectColumns is the column list shared by every project SELECT
+const projectColumns = "id, name, client, address, phase, start_date, end_date, budget, status, created_at, updated_at"
+
+// PostgresProjectRepository stores projects in the PostgreSQL "projects" table
+type PostgresProjectRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresProjectRepository creates a project repository using the given connection pool
+func NewPostgresProjectRepository(db *sql.DB) *PostgresProjectRepository {
+	return &PostgresProjectRepository{db: db}
+}
+
+// FindAll retrieves all projects ordered by name
+func (r *PostgresProjectRepository) FindAll(ctx context.Context) ([]models.Project, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+projectColumns+" FROM projects ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// FindByID retrieves a project by its ObjectID
+func (r *PostgresProjectRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Project, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE id = $1", id.Hex())
+	project, err := scanProject(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return project, ErrProjectNotFound
+	}
+	return project, err
+}
+
+// Exists reports whether a project with the given ObjectID exists
+func (r *PostgresProjectRepository) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1)", id.Hex()).Scan(&exists)
+	return exists, err
+}
+
+// Create adds a new project to PostgreSQL
+func (r *PostgresProjectRepository) Create(ctx context.Context, project *models.Project) error {
+	prepareNewProject(project)
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO projects (`+projectColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		project.ID.Hex(), project.Name, project.Client, project.Address, project.Phase,
+		nullTime(project.StartDate), nullTime(project.EndDate), project.Budget, project.Status,
+		project.CreatedAt.UTC(), project.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+// Update modifies an existing project in PostgreSQL
+func (r *PostgresProjectRepository) Update(ctx context.Context, project *models.Project) error {
+	project.UpdatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE projects SET name = $2, client = $3, address = $4, phase = $5,
+			start_date = $6, end_date = $7, budget = $8, status = $9, updated_at = $10
+		WHERE id = $1`,
+		project.ID.Hex(), project.Name, project.Client, project.Address, project.Phase,
+		nullTime(project.StartDate), nullTime(project.EndDate), project.Budget, project.Status,
+		project.UpdatedAt.UTC(),
+	)
+	return checkAffected(res, err, ErrProjectNotFound)
+}
+
+// Delete removes a project from PostgreSQL by ObjectID
+func (r *PostgresProjectRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM projects WHERE id = $1", id.Hex())
+	return checkAffected(res, err, ErrProjectNotFound)
+}
+
+// scanProject reads a row selected with projectColumns
+func scanProject(row rowScanner) (models.Project, error) {
+	var (
+		project                        models.Project
+		id                             string
+		client, address, phase, status sql.NullString
+		startDate, endDate             sql.NullTime
+		createdAt, updatedAt           sql.NullTime
+	)
+	err := row.Scan(&id, &project.Name, &client, &address, &phase, &startDate, &endDate,
+		&project.Budget, &status, &createdAt, &updatedAt)
+	if err != nil {
+		return project, err
+	}
+	if project.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+		return project, fmt.Errorf("invalid project id %q: %w", id, err)
+	}
+	project.Client = client.String
+	project.Address = address.String
+	project.Phase = phase.String
+	project.Status = status.String
+	project.StartDate = startDate.Time
+	project.EndDate = endDate.Time
+	project.CreatedAt = createdAt.Time
+	project.UpdatedAt = updatedAt.Time
+	return project, nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresPromptRepository stores prompt template versions in the
+// PostgreSQL "prompt_templates" table
+type PostgresPromptRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresPromptRepository creates a prompt repository using the given connection pool
+func NewPostgresPromptRepository(db *sql.DB) *PostgresPromptRepository {
+	return &PostgresPromptRepository{db: db}
+}
+
+// ListPromptTemplates retrieves every stored version, by name and version
+func (r *PostgresPromptRepository) ListPromptTemplates(ctx context.Context) ([]models.PromptTemplate, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, version, description, text, created_at
+		FROM prompt_templates ORDER BY name, version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.PromptTemplate{}
+	for rows.Next() {
+		var (
+			template models.PromptTemplate
+			id       string
+		)
+		if err := rows.Scan(&id, &template.Name, &template.Version, &template.Description, &template.Text, &template.CreatedAt); err != nil {
+			return nil, err
+		}
+		if template.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+			return nil, fmt.Errorf("invalid prompt template id %q: %w", id, err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// CreatePromptTemplate stores a new version
+func (r *PostgresPromptRepository) CreatePromptTemplate(ctx context.Context, template *models.PromptTemplate) error {
+	prepareNewPromptTemplate(template)
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO prompt_templates (id, name, version, description, text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (name, version) DO NOTHING`,
+		template.ID.Hex(), template.Name, template.Version, template.Description, template.Text, template.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrPromptVersionExists
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taskColumns is the column list shared by every task SELECT
+const taskColumns = "id, title, description, due_date, priority, project_id, status, created_at, updated_at"
+
+// PostgresTaskRepository stores tasks in the PostgreSQL "tasks" table.
+// Times are stored in UTC because the columns have no time zone.
+type PostgresTaskRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTaskRepository creates a task repository using the given connection pool
+func NewPostgresTaskRepository(db *sql.DB) *PostgresTaskRepository {
+	return &PostgresTaskRepository{db: db}
+}
+
+// FindAll retrieves all tasks from PostgreSQL
+func (r *PostgresTaskRepository) FindAll(ctx context.Context) ([]models.Task, error) {
+	return r.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks ORDER BY id")
+}
+
+// FindByID retrieves a task by its ObjectID
+func (r *PostgresTaskRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Task, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1", id.Hex())
+	task, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return task, ErrTaskNotFound
+	}
+	return task, err
+}
+
+// Create adds a new task to PostgreSQL
+func (r *PostgresTaskRepository) Create(ctx context.Context, task *models.Task) error {
+	prepareNewTask(task)
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO tasks (`+taskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		task.ID.Hex(), task.Title, task.Description, task.DueDate.UTC(), task.Priority,
+		nullObjectID(task.ProjectID), task.Status, task.CreatedAt.UTC(), task.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+// Update modifies an existing task in PostgreSQL
+func (r *PostgresTaskRepository) Update(ctx context.Context, task *models.Task) error {
+	task.UpdatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE tasks SET title = $2, description = $3, due_date = $4, priority = $5,
+			project_id = $6, status = $7, updated_at = $8
+		WHERE id = $1`,
+		task.ID.Hex(), task.Title, task.Description, task.DueDate.UTC(), task.Priority,
+		nullObjectID(task.ProjectID), task.Status, task.UpdatedAt.UTC(),
+	)
+	return checkAffected(res, err, ErrTaskNotFound)
+}
+
+// Delete removes a task from PostgreSQL by ObjectID
+func (r *PostgresTaskRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM tasks WHERE id = $1", id.Hex())
+	return checkAffected(res, err, ErrTaskNotFound)
+}
+
+// FindTasksDueToday retrieves all tasks due on the current day
+func (r *PostgresTaskRepository) FindTasksDueToday(ctx context.Context) ([]models.Task, error) {
+	return findTasksDueToday(ctx, r)
+}
+
+// Find retrieves one page of tasks matching the query using keyset pagination
+func (r *PostgresTaskRepository) Find(ctx context.Context, query TaskQuery) (TaskPage, error) {
+	q, err := query.normalize()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	where, args, err := q.sqlWhere()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	dir := "ASC"
+	if q.Descending {
+		dir = "DESC"
+	}
+	args = append(args, q.Limit+1)
+	stmt := fmt.Sprintf("SELECT %s FROM tasks%s ORDER BY %s %s, id %s LIMIT $%d",
+		taskColumns, where, q.SortBy, dir, dir, len(args))
+	tasks, err := r.queryTasks(ctx, stmt, args...)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	return q.buildPage(tasks), nil
+}
+
+// FindByProject retrieves all tasks that belong to the given project
+func (r *PostgresTaskRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID) ([]models.Task, error) {
+	return r.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks WHERE project_id = $1 ORDER BY id", projectID.Hex())
+}
+
+// CountByProject returns the number of tasks that belong to the given project
+func (r *PostgresTaskRepository) CountByProject(ctx context.Context, projectID primitive.ObjectID) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE project_id = $1", projectID.Hex()).Scan(&n)
+	return n, err
+}
+
+// queryTasks runs a task SELECT and scans every row
+func (r *PostgresTaskRepository) queryTasks(ctx context.Context, stmt string, args ...interface{}) ([]models.Task, error) {
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// sqlWhere builds the WHERE clause and arguments for the query, including the cursor position
+func (q TaskQuery) sqlWhere() (string, []interface{}, error) {
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Status) > 0 {
+		conds = append(conds, "status = ANY("+arg(pq.Array(q.Status))+")")
+	}
+	if len(q.Priority) > 0 {
+		conds = append(conds, "priority = ANY("+arg(pq.Array(q.Priority))+")")
+	}
+	if !q.ProjectID.IsZero() {
+		conds = append(conds, "project_id = "+arg(q.ProjectID.Hex()))
+	}
+	if !q.DueFrom.IsZero() {
+		conds = append(conds, "due_date >= "+arg(q.DueFrom.UTC()))
+	}
+	if !q.DueBefore.IsZero() {
+		conds = append(conds, "due_date < "+arg(q.DueBefore.UTC()))
+	}
+	if q.Search != "" {
+		conds = append(conds, "title ILIKE "+arg("%"+likeEscaper.Replace(q.Search)+"%"))
+	}
+	if q.Cursor != "" {
+		cur, err := decodeTaskCursor(q.Cursor, q.SortBy)
+		if err != nil {
+			return "", nil, err
+		}
+		value := cur.value
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC()
+		}
+		op := ">"
+		if q.Descending {
+			op = "<"
+		}
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", q.SortBy, op, arg(value), arg(cur.id.Hex())))
+	}
+
+	if len(conds) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// likeEscaper escapes LIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask reads a row selected with taskColumns
+func scanTask(row rowScanner) (models.Task, error) {
+	var (
+		task                          models.Task
+		id                            string
+		description, priority, status sql.NullString
+		projectID                     sql.NullString
+	)
+	err := row.Scan(&id, &task.Title, &description, &task.DueDate, &priority, &projectID,
+		&status, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return task, err
+	}
+	if task.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+		return task, fmt.Errorf("invalid task id %q: %w", id, err)
+	}
+	if projectID.Valid {
+		if task.ProjectID, err = primitive.ObjectIDFromHex(strings.TrimSpace(projectID.String)); err != nil {
+			return task, fmt.Errorf("invalid project id %q: %w", projectID.String, err)
+		}
+	}
+	task.Description = description.String
+	task.Priority = priority.String
+	task.Status = status.String
+	return task, nil
+}
+
+// nullObjectID stores a zero ObjectID as NULL
+func nullObjectID(id primitive.ObjectID) sql.NullString {
+	if id.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.Hex(), Valid: true}
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// checkAffected returns notFound when a statement matched no rows
+func checkAffected(res sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
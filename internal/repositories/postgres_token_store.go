@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+)
+
+// PostgresTokenStore keeps encrypted tokens in the PostgreSQL "oauth_tokens" table
+type PostgresTokenStore struct {
+	db     *sql.DB
+	cipher *TokenCipher
+}
+
+// NewPostgresTokenStore creates a token store using the given connection pool
+func NewPostgresTokenStore(db *sql.DB, cipher *TokenCipher) *PostgresTokenStore {
+	return &PostgresTokenStore{db: db, cipher: cipher}
+}
+
+// Get retrieves and decrypts the token of a user
+func (s *PostgresTokenStore) Get(ctx context.Context, userID primitive.ObjectID) (*oauth2.Token, error) {
+	var sealed []byte
+	err := s.db.QueryRowContext(ctx, `SELECT token FROM oauth_tokens WHERE user_id = $1`, userID.Hex()).Scan(&sealed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Open(userID, sealed)
+}
+
+// Save encrypts and stores the token of a user
+func (s *PostgresTokenStore) Save(ctx context.Context, userID primitive.ObjectID, token *oauth2.Token) error {
+	sealed, err := s.cipher.Seal(userID, token)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO oauth_tokens (user_id, token, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, updated_at = EXCLUDED.updated_at`,
+		userID.Hex(), sealed, time.Now(),
+	)
+	return err
+}
+
+// Delete removes the token of a user
+func (s *PostgresTokenStore) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM oauth_tokens WHERE user_id = $1`, userID.Hex())
+	return err
+}
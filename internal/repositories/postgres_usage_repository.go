@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresUsageRepository stores the token ledger in the PostgreSQL
+// "ai_usage" table and budgets in "ai_budgets"
+type PostgresUsageRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUsageRepository creates a usage repository using the given connection pool
+func NewPostgresUsageRepository(db *sql.DB) *PostgresUsageRepository {
+	return &PostgresUsageRepository{db: db}
+}
+
+// Record appends a completion to the ledger
+func (r *PostgresUsageRepository) Record(ctx context.Context, record *models.UsageRecord) error {
+	prepareUsageRecord(record)
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO ai_usage (id, user_id, project_id, provider, model, prompt_tokens, completion_tokens, estimated, prompt, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		record.ID.Hex(), record.UserID, record.ProjectID, record.Provider, record.Model,
+		record.PromptTokens, record.CompletionTokens, record.Estimated, record.Prompt, record.CreatedAt,
+	)
+	return err
+}
+
+// Totals sums the selected records per day, user, project, provider and model
+func (r *PostgresUsageRepository) Totals(ctx context.Context, query UsageQuery) ([]models.UsageTotal, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if query.UserID != "" {
+		where("user_id = $%d", query.UserID)
+	}
+	if query.ProjectID != "" {
+		where("project_id = $%d", query.ProjectID)
+	}
+	if !query.Since.IsZero() {
+		where("created_at >= $%d", query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		where("created_at < $%d", query.Until.UTC())
+	}
+	filter := ""
+	if len(conditions) > 0 {
+		filter = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT date_trunc('day', created_at), user_id, project_id, provider, model,
+			SUM(prompt_tokens), SUM(completion_tokens), COUNT(*)
+		FROM ai_usage `+filter+`
+		GROUP BY 1, 2, 3, 4, 5 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []models.UsageTotal{}
+	for rows.Next() {
+		var total models.UsageTotal
+		err := rows.Scan(&total.Day, &total.UserID, &total.ProjectID, &total.Provider, &total.Model,
+			&total.PromptTokens, &total.CompletionTokens, &total.Requests)
+		if err != nil {
+			return nil, err
+		}
+		total.Day = usageDay(total.Day)
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// ListBudgets retrieves every budget
+func (r *PostgresUsageRepository) ListBudgets(ctx context.Context) ([]models.UsageBudget, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, scope, subject, period, token_limit, warn_ratio, created_at, updated_at
+		FROM ai_budgets ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	budgets := []models.UsageBudget{}
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// SaveBudget creates or replaces the budget for a scope, subject and period
+func (r *PostgresUsageRepository) SaveBudget(ctx context.Context, budget *models.UsageBudget) error {
+	now := time.Now().UTC()
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO ai_budgets (id, scope, subject, period, token_limit, warn_ratio, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (scope, subject, period) DO UPDATE
+			SET token_limit = EXCLUDED.token_limit, warn_ratio = EXCLUDED.warn_ratio, updated_at = EXCLUDED.updated_at
+		RETURNING id, scope, subject, period, token_limit, warn_ratio, created_at, updated_at`,
+		primitive.NewObjectID().Hex(), budget.Scope, budget.Subject, budget.Period,
+		budget.Limit, budget.WarnRatio, now,
+	)
+	saved, err := scanBudget(row)
+	if err != nil {
+		return err
+	}
+	*budget = saved
+	return nil
+}
+
+// DeleteBudget removes a budget by ObjectID
+func (r *PostgresUsageRepository) DeleteBudget(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM ai_budgets WHERE id = $1", id.Hex())
+	return checkAffected(res, err, ErrBudgetNotFound)
+}
+
+// scanBudget reads a budget row
+func scanBudget(row rowScanner) (models.UsageBudget, error) {
+	var (
+		budget models.UsageBudget
+		id     string
+	)
+	err := row.Scan(&id, &budget.Scope, &budget.Subject, &budget.Period, &budget.Limit,
+		&budget.WarnRatio, &budget.CreatedAt, &budget.UpdatedAt)
+	if err != nil {
+		return budget, err
+	}
+	if budget.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+		return budget, fmt.Errorf("invalid budget id %q: %w", id, err)
+	}
+	return budget, nil
+}
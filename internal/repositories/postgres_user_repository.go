@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresUserRepository stores user accounts in the PostgreSQL "users" table
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUserRepository creates a user repository using the given connection pool
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+// FindByID retrieves a user by ID
+func (r *PostgresUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	user := models.User{ID: id}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT google_id, email, name, picture, created_at, updated_at, last_login_at
+		FROM users WHERE id = $1`, id.Hex(),
+	).Scan(&user.GoogleID, &user.Email, &user.Name, &user.Picture, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, err
+}
+
+// SignIn creates or updates the user of a Google account
+func (r *PostgresUserRepository) SignIn(ctx context.Context, user *models.User) error {
+	now := time.Now()
+	var id string
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO users (id, google_id, email, name, picture, created_at, updated_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $6)
+		ON CONFLICT (google_id) DO UPDATE SET
+			email = EXCLUDED.email, name = EXCLUDED.name, picture = EXCLUDED.picture,
+			updated_at = EXCLUDED.updated_at, last_login_at = EXCLUDED.last_login_at
+		RETURNING id, created_at, updated_at, last_login_at`,
+		primitive.NewObjectID().Hex(), user.GoogleID, user.Email, user.Name, user.Picture, now,
+	).Scan(&id, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt)
+	if err != nil {
+		return err
+	}
+	if user.ID, err = primitive.ObjectIDFromHex(strings.TrimSpace(id)); err != nil {
+		return fmt.Errorf("invalid user id %q: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrProjectNotFound is returned when a project does not exist
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectRepository handles storage operations for projects.
+// Implementations exist for memory, MongoDB and PostgreSQL.
+type ProjectRepository interface {
+	// FindAll retrieves all projects ordered by name
+	FindAll(ctx context.Context) ([]models.Project, error)
+	// FindByID retrieves a project, returning ErrProjectNotFound if it does not exist
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Project, error)
+	// Exists reports whether a project with the given ID exists
+	Exists(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// Create stores a new project, assigning its ID and timestamps
+	Create(ctx context.Context, project *models.Project) error
+	// Update replaces an existing project, returning ErrProjectNotFound if it does not exist
+	Update(ctx context.Context, project *models.Project) error
+	// Delete removes a project, returning ErrProjectNotFound if it does not exist
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// prepareNewProject assigns the ID and timestamps of a project about to be created
+func prepareNewProject(project *models.Project) {
+	if project.ID.IsZero() {
+		project.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	project.CreatedAt = now
+	project.UpdatedAt = now
+}
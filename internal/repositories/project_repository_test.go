@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProjectRepositoryContract checks project storage on every backend
+func TestProjectRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			projects := open(t).Projects
+
+			b := models.Project{Name: "B Torre", Budget: 1500000.5, Status: models.ProjectStatusActive}
+			a := models.Project{Name: "A Casa", StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+			require.NoError(t, projects.Create(ctx, &b))
+			require.NoError(t, projects.Create(ctx, &a))
+
+			all, err := projects.FindAll(ctx)
+			require.NoError(t, err)
+			require.Len(t, all, 2)
+			assert.Equal(t, "A Casa", all[0].Name)
+			assert.Equal(t, 1500000.5, all[1].Budget)
+
+			exists, err := projects.Exists(ctx, a.ID)
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			a.Phase = models.ProjectPhasePermitting
+			require.NoError(t, projects.Update(ctx, &a))
+			found, err := projects.FindByID(ctx, a.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.ProjectPhasePermitting, found.Phase)
+			assert.True(t, a.StartDate.Equal(found.StartDate))
+
+			require.NoError(t, projects.Delete(ctx, a.ID))
+			exists, err = projects.Exists(ctx, a.ID)
+			require.NoError(t, err)
+			assert.False(t, exists)
+			_, err = projects.FindByID(ctx, a.ID)
+			assert.ErrorIs(t, err, ErrProjectNotFound)
+			assert.ErrorIs(t, projects.Delete(ctx, a.ID), ErrProjectNotFound)
+		})
+	}
+}
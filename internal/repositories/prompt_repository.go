@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrPromptVersionExists is returned when storing a prompt template version
+// that is already stored
+var ErrPromptVersionExists = errors.New("prompt template version already exists")
+
+// PromptRepository stores versions of prompt templates. Implementations
+// exist for memory, MongoDB and PostgreSQL.
+type PromptRepository interface {
+	// ListPromptTemplates retrieves every stored version, by name and version
+	ListPromptTemplates(ctx context.Context) ([]models.PromptTemplate, error)
+	// CreatePromptTemplate stores a new version, assigning its ID and
+	// creation time. It returns ErrPromptVersionExists if the name and
+	// version are taken.
+	CreatePromptTemplate(ctx context.Context, template *models.PromptTemplate) error
+}
+
+// prepareNewPromptTemplate assigns the ID and time of a template version about to be stored
+func prepareNewPromptTemplate(template *models.PromptTemplate) {
+	if template.ID.IsZero() {
+		template.ID = primitive.NewObjectID()
+	}
+	template.CreatedAt = time.Now()
+}
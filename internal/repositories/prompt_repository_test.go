@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPromptRepositoryContract runs the prompt repository contract against every backend
+func TestPromptRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			prompts := open(t).Prompts
+			// Versions cannot be deleted, so each run uses its own name
+			promptName := fmt.Sprintf("test_%d", time.Now().UnixNano())
+
+			second := models.PromptTemplate{Name: promptName, Version: 2, Description: "Más breve", Text: "Hola {{.User}}"}
+			require.NoError(t, prompts.CreatePromptTemplate(ctx, &second))
+			assert.False(t, second.ID.IsZero())
+			assert.False(t, second.CreatedAt.IsZero())
+			require.NoError(t, prompts.CreatePromptTemplate(ctx, &models.PromptTemplate{Name: promptName, Version: 1, Text: "Hola"}))
+			assert.ErrorIs(t, prompts.CreatePromptTemplate(ctx, &models.PromptTemplate{Name: promptName, Version: 2, Text: "Otro"}), ErrPromptVersionExists)
+
+			listed, err := prompts.ListPromptTemplates(ctx)
+			require.NoError(t, err)
+			var versions []models.PromptTemplate
+			for _, template := range listed {
+				if template.Name == promptName {
+					versions = append(versions, template)
+				}
+			}
+			require.Len(t, versions, 2)
+			assert.Equal(t, 1, versions[0].Version)
+			assert.Equal(t, "Hola {{.User}}", versions[1].Text)
+			assert.Equal(t, "Más breve", versions[1].Description)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultTaskPageSize is used when a query does not set a limit
+	DefaultTaskPageSize = 50
+	// MaxTaskPageSize caps the number of tasks returned in one page
+	MaxTaskPageSize = 200
+)
+
+// TaskSortField is a task field that results can be ordered by
+type TaskSortField string
+
+// Supported sort fields
+const (
+	SortByDueDate   TaskSortField = "due_date"
+	SortByCreatedAt TaskSortField = "created_at"
+	SortByUpdatedAt TaskSortField = "updated_at"
+	SortByTitle     TaskSortField = "title"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrEmptyDueRange is returned when a query's due date range ends before it starts
+var ErrEmptyDueRange = errors.New("due date range is empty")
+
+// TaskQuery describes a filtered, sorted and paginated task lookup.
+// Zero values mean "no filter" so callers only set what they need.
+type TaskQuery struct {
+	Status    []string
+	Priority  []string
+	ProjectID primitive.ObjectID
+	// DueFrom is inclusive and DueBefore is exclusive
+	DueFrom   time.Time
+	DueBefore time.Time
+	// Search matches the title case-insensitively
+	Search     string
+	SortBy     TaskSortField
+	Descending bool
+	Limit      int
+	Cursor     string
+}
+
+// TaskPage is one page of task results
+type TaskPage struct {
+	Tasks      []models.Task `json:"tasks"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// ParseTaskSort parses a sort expression such as "due_date" or "-created_at"
+func ParseTaskSort(expr string) (TaskSortField, bool, error) {
+	descending := strings.HasPrefix(expr, "-")
+	field := TaskSortField(strings.TrimPrefix(expr, "-"))
+	switch field {
+	case SortByDueDate, SortByCreatedAt, SortByUpdatedAt, SortByTitle:
+		return field, descending, nil
+	default:
+		return "", false, fmt.Errorf("unsupported sort field: %s", field)
+	}
+}
+
+// normalize fills defaults and validates the query
+func (q TaskQuery) normalize() (TaskQuery, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortByDueDate
+	}
+	if _, _, err := ParseTaskSort(string(q.SortBy)); err != nil {
+		return q, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultTaskPageSize
+	}
+	if q.Limit > MaxTaskPageSize {
+		q.Limit = MaxTaskPageSize
+	}
+	if !q.DueFrom.IsZero() && !q.DueBefore.IsZero() && !q.DueBefore.After(q.DueFrom) {
+		return q, ErrEmptyDueRange
+	}
+	return q, nil
+}
+
+// filter builds the MongoDB filter for the query, including the cursor position
+func (q TaskQuery) filter() (bson.D, error) {
+	conds := bson.A{}
+	if len(q.Status) > 0 {
+		conds = append(conds, bson.M{"status": bson.M{"$in": q.Status}})
+	}
+	if len(q.Priority) > 0 {
+		conds = append(conds, bson.M{"priority": bson.M{"$in": q.Priority}})
+	}
+	if !q.ProjectID.IsZero() {
+		conds = append(conds, bson.M{"project_id": q.ProjectID})
+	}
+	if !q.DueFrom.IsZero() || !q.DueBefore.IsZero() {
+		due := bson.M{}
+		if !q.DueFrom.IsZero() {
+			due["$gte"] = q.DueFrom
+		}
+		if !q.DueBefore.IsZero() {
+			due["$lt"] = q.DueBefore
+		}
+		conds = append(conds, bson.M{"due_date": due})
+	}
+	if q.Search != "" {
+		conds = append(conds, bson.M{"title": primitive.Regex{Pattern: regexp.QuoteMeta(q.Search), Options: "i"}})
+	}
+	if q.Cursor != "" {
+		cur, err := decodeTaskCursor(q.Cursor, q.SortBy)
+		if err != nil {
+			return nil, err
+		}
+		op := "$gt"
+		if q.Descending {
+			op = "$lt"
+		}
+		field := string(q.SortBy)
+		conds = append(conds, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: cur.value}},
+			bson.M{field: cur.value, "_id": bson.M{op: cur.id}},
+		}})
+	}
+
+	if len(conds) == 0 {
+		return bson.D{}, nil
+	}
+	return bson.D{{Key: "$and", Value: conds}}, nil
+}
+
+// findOptions builds the sort and limit options. One extra document is
+// fetched to know whether another page exists.
+func (q TaskQuery) findOptions() *options.FindOptions {
+	dir := 1
+	if q.Descending {
+		dir = -1
+	}
+	return options.Find().
+		SetSort(bson.D{{Key: string(q.SortBy), Value: dir}, {Key: "_id", Value: dir}}).
+		SetLimit(int64(q.Limit + 1))
+}
+
+// buildPage trims the look-ahead document and computes the next cursor
+func (q TaskQuery) buildPage(tasks []models.Task) TaskPage {
+	page := TaskPage{Tasks: tasks}
+	if page.Tasks == nil {
+		page.Tasks = []models.Task{}
+	}
+	if len(page.Tasks) > q.Limit {
+		page.Tasks = page.Tasks[:q.Limit]
+		page.NextCursor = encodeTaskCursor(page.Tasks[len(page.Tasks)-1], q.SortBy)
+	}
+	return page
+}
+
+// taskCursor is the decoded position of the last task of a page
+type taskCursor struct {
+	value interface{}
+	id    primitive.ObjectID
+}
+
+// cursorPayload is the wire form of a cursor
+type cursorPayload struct {
+	Field string `json:"f"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// encodeTaskCursor encodes the sort key and ID of a task as an opaque cursor
+func encodeTaskCursor(task models.Task, field TaskSortField) string {
+	payload := cursorPayload{Field: string(field), ID: task.ID.Hex()}
+	switch field {
+	case SortByTitle:
+		payload.Value = task.Title
+	default:
+		payload.Value = taskSortTime(task, field).UTC().Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor decodes a cursor produced by encodeTaskCursor for the same sort field
+func decodeTaskCursor(cursor string, field TaskSortField) (taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return taskCursor{}, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Field != string(field) {
+		return taskCursor{}, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(payload.ID)
+	if err != nil {
+		return taskCursor{}, ErrInvalidCursor
+	}
+	if field == SortByTitle {
+		return taskCursor{value: payload.Value, id: id}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, payload.Value)
+	if err != nil {
+		return taskCursor{}, ErrInvalidCursor
+	}
+	return taskCursor{value: t, id: id}, nil
+}
+
+// taskSortTime returns the time value a task is sorted by
+func taskSortTime(task models.Task, field TaskSortField) time.Time {
+	switch field {
+	case SortByCreatedAt:
+		return task.CreatedAt
+	case SortByUpdatedAt:
+		return task.UpdatedAt
+	default:
+		return task.DueDate
+	}
+}
+
+// taskIndexes are the indexes backing TaskQuery filters and sort orders
+var taskIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "due_date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "due_date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "priority", Value: 1}, {Key: "due_date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "due_date", Value: 1}, {Key: "_id", Value: 1}}},
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestParseTaskSort tests sort expression parsing
+func TestParseTaskSort(t *testing.T) {
+	field, desc, err := ParseTaskSort("-created_at")
+	require.NoError(t, err)
+	assert.Equal(t, SortByCreatedAt, field)
+	assert.True(t, desc)
+
+	field, desc, err = ParseTaskSort("title")
+	require.NoError(t, err)
+	assert.Equal(t, SortByTitle, field)
+	assert.False(t, desc)
+
+	_, _, err = ParseTaskSort("priority")
+	assert.Error(t, err)
+}
+
+// TestTaskQueryNormalize tests defaults and validation
+func TestTaskQueryNormalize(t *testing.T) {
+	q, err := TaskQuery{}.normalize()
+	require.NoError(t, err)
+	assert.Equal(t, SortByDueDate, q.SortBy)
+	assert.Equal(t, DefaultTaskPageSize, q.Limit)
+
+	q, err = TaskQuery{Limit: 10000}.normalize()
+	require.NoError(t, err)
+	assert.Equal(t, MaxTaskPageSize, q.Limit)
+
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err = TaskQuery{DueFrom: day, DueBefore: day}.normalize()
+	assert.Error(t, err)
+}
+
+// TestTaskCursorRoundTrip tests that cursors decode to the encoded position
+func TestTaskCursorRoundTrip(t *testing.T) {
+	task := models.Task{
+		ID:      primitive.NewObjectID(),
+		Title:   "Revisar planos",
+		DueDate: time.Date(2025, 6, 3, 15, 0, 0, 0, time.UTC),
+	}
+
+	cur, err := decodeTaskCursor(encodeTaskCursor(task, SortByDueDate), SortByDueDate)
+	require.NoError(t, err)
+	assert.Equal(t, task.ID, cur.id)
+	assert.True(t, task.DueDate.Equal(cur.value.(time.Time)))
+
+	cur, err = decodeTaskCursor(encodeTaskCursor(task, SortByTitle), SortByTitle)
+	require.NoError(t, err)
+	assert.Equal(t, "Revisar planos", cur.value)
+
+	// A cursor is bound to the sort field it was produced for
+	_, err = decodeTaskCursor(encodeTaskCursor(task, SortByTitle), SortByDueDate)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = decodeTaskCursor("not-a-cursor", SortByDueDate)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestTaskQueryFilter tests the generated MongoDB filter
+func TestTaskQueryFilter(t *testing.T) {
+	projectID := primitive.NewObjectID()
+	q, err := TaskQuery{
+		Status:    []string{"To-Do", "In-Progress"},
+		ProjectID: projectID,
+		Search:    "planos (v2)",
+	}.normalize()
+	require.NoError(t, err)
+
+	filter, err := q.filter()
+	require.NoError(t, err)
+	conds := filter.Map()["$and"].(bson.A)
+	require.Len(t, conds, 3)
+	assert.Equal(t, bson.M{"status": bson.M{"$in": []string{"To-Do", "In-Progress"}}}, conds[0])
+	assert.Equal(t, bson.M{"project_id": projectID}, conds[1])
+	assert.Equal(t, bson.M{"title": primitive.Regex{Pattern: `planos \(v2\)`, Options: "i"}}, conds[2])
+
+	empty, err := TaskQuery{}.filter()
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// TestTaskQueryBuildPage tests look-ahead trimming and next cursor generation
+func TestTaskQueryBuildPage(t *testing.T) {
+	q, err := TaskQuery{Limit: 2}.normalize()
+	require.NoError(t, err)
+
+	tasks := []models.Task{
+		{ID: primitive.NewObjectID(), DueDate: time.Now()},
+		{ID: primitive.NewObjectID(), DueDate: time.Now().Add(time.Hour)},
+		{ID: primitive.NewObjectID(), DueDate: time.Now().Add(2 * time.Hour)},
+	}
+	page := q.buildPage(tasks)
+	assert.Len(t, page.Tasks, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page = q.buildPage(tasks[:2])
+	assert.Len(t, page.Tasks, 2)
+	assert.Empty(t, page.NextCursor)
+
+	page = q.buildPage(nil)
+	assert.NotNil(t, page.Tasks)
+}
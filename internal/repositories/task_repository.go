@@ -6,106 +6,71 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"github.com/lyffseba/ana/internal/database"
 	"github.com/lyffseba/ana/internal/models"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// TaskRepository handles database operations for tasks
-// (MongoDB implementation)
-type TaskRepository struct{}
+// ErrTaskNotFound is returned when a task does not exist
+var ErrTaskNotFound = errors.New("task not found")
 
-// NewTaskRepository creates a new instance of TaskRepository
-func NewTaskRepository() *TaskRepository {
-	return &TaskRepository{}
+// TaskRepository handles storage operations for tasks.
+// Implementations exist for memory, MongoDB and PostgreSQL.
+type TaskRepository interface {
+	// FindAll retrieves every task
+	FindAll(ctx context.Context) ([]models.Task, error)
+	// FindByID retrieves a task, returning ErrTaskNotFound if it does not exist
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Task, error)
+	// Create stores a new task, assigning its ID and timestamps
+	Create(ctx context.Context, task *models.Task) error
+	// Update replaces an existing task, returning ErrTaskNotFound if it does not exist
+	Update(ctx context.Context, task *models.Task) error
+	// Delete removes a task, returning ErrTaskNotFound if it does not exist
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// FindTasksDueToday retrieves all tasks due on the current day
+	FindTasksDueToday(ctx context.Context) ([]models.Task, error)
+	// Find retrieves one page of tasks matching the query
+	Find(ctx context.Context, query TaskQuery) (TaskPage, error)
+	// FindByProject retrieves all tasks that belong to the given project
+	FindByProject(ctx context.Context, projectID primitive.ObjectID) ([]models.Task, error)
+	// CountByProject returns the number of tasks that belong to the given project
+	CountByProject(ctx context.Context, projectID primitive.ObjectID) (int64, error)
 }
 
-// FindAll retrieves all tasks from MongoDB
-func (r *TaskRepository) FindAll() ([]models.Task, error) {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	cur, err := coll.Find(ctx, bson.D{})
-	if err != nil {
-		return nil, err
-	}
-	defer cur.Close(ctx)
-	var tasks []models.Task
-	for cur.Next(ctx) {
-		var t models.Task
-		if err := cur.Decode(&t); err != nil {
+// FindAllMatching follows the cursor through every page of the query
+func FindAllMatching(ctx context.Context, repo TaskRepository, query TaskQuery) ([]models.Task, error) {
+	query.Limit = MaxTaskPageSize
+	tasks := []models.Task{}
+	for {
+		page, err := repo.Find(ctx, query)
+		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, t)
+		tasks = append(tasks, page.Tasks...)
+		if page.NextCursor == "" {
+			return tasks, nil
+		}
+		query.Cursor = page.NextCursor
 	}
-	return tasks, cur.Err()
 }
 
-// FindByID retrieves a task by its ObjectID
-func (r *TaskRepository) FindByID(id primitive.ObjectID) (models.Task, error) {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	var task models.Task
-	err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&task)
-	return task, err
+// findTasksDueToday implements FindTasksDueToday on top of Find
+func findTasksDueToday(ctx context.Context, repo TaskRepository) ([]models.Task, error) {
+	today := time.Now()
+	start := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	return FindAllMatching(ctx, repo, TaskQuery{DueFrom: start, DueBefore: start.AddDate(0, 0, 1)})
 }
 
-// Create adds a new task to MongoDB
-func (r *TaskRepository) Create(task *models.Task) error {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// prepareNewTask assigns the ID and timestamps of a task about to be created
+func prepareNewTask(task *models.Task) {
 	if task.ID.IsZero() {
 		task.ID = primitive.NewObjectID()
 	}
-	_, err := coll.InsertOne(ctx, task)
-	return err
-}
-
-// Update modifies an existing task in MongoDB
-func (r *TaskRepository) Update(task *models.Task) error {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	_, err := coll.UpdateOne(ctx, bson.M{"_id": task.ID}, bson.M{"$set": task})
-	return err
-}
-
-// Delete removes a task from MongoDB by ObjectID
-func (r *TaskRepository) Delete(id primitive.ObjectID) error {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	_, err := coll.DeleteOne(ctx, bson.M{"_id": id})
-	return err
-}
-
-// FindTasksDueToday retrieves all tasks due on the current day
-func (r *TaskRepository) FindTasksDueToday() ([]models.Task, error) {
-	coll := database.GetCollection("", "tasks")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	today := time.Now()
-	start := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
-	end := start.AddDate(0, 0, 1)
-	filter := bson.M{"due_date": bson.M{"$gte": start, "$lt": end}}
-	cur, err := coll.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cur.Close(ctx)
-	var tasks []models.Task
-	for cur.Next(ctx) {
-		var t models.Task
-		if err := cur.Decode(&t); err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, t)
+	now := time.Now()
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
 	}
-	return tasks, cur.Err()
+	task.UpdatedAt = now
 }
-
@@ -1,11 +1,119 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// TestTaskRepositorySetup checks if the test database can be set up (placeholder, needs real test logic)
-func TestTaskRepositoryPlaceholder(t *testing.T) {
-	// Placeholder test to keep file valid
-	t.Log("Placeholder test for TaskRepository. Real tests to be implemented.")
+// TestTaskRepositoryContract checks that every backend behaves the same way
+func TestTaskRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := open(t)
+			tasks := repos.Tasks
+
+			project := models.Project{Name: "Casa Usaquén"}
+			require.NoError(t, repos.Projects.Create(ctx, &project))
+
+			due := time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+			task := models.Task{Title: "Revisar planos", DueDate: due, Priority: "High", Status: "To-Do", ProjectID: project.ID}
+			require.NoError(t, tasks.Create(ctx, &task))
+			assert.False(t, task.ID.IsZero())
+			assert.False(t, task.CreatedAt.IsZero())
+
+			found, err := tasks.FindByID(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Revisar planos", found.Title)
+			assert.Equal(t, project.ID, found.ProjectID)
+			assert.True(t, due.Equal(found.DueDate))
+
+			found.Status = "Done"
+			require.NoError(t, tasks.Update(ctx, &found))
+			found, err = tasks.FindByID(ctx, task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Done", found.Status)
+
+			byProject, err := tasks.FindByProject(ctx, project.ID)
+			require.NoError(t, err)
+			assert.Len(t, byProject, 1)
+			count, err := tasks.CountByProject(ctx, project.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+
+			missing := primitive.NewObjectID()
+			_, err = tasks.FindByID(ctx, missing)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+			assert.ErrorIs(t, tasks.Update(ctx, &models.Task{ID: missing, Title: "x"}), ErrTaskNotFound)
+			assert.ErrorIs(t, tasks.Delete(ctx, missing), ErrTaskNotFound)
+
+			require.NoError(t, tasks.Delete(ctx, task.ID))
+			_, err = tasks.FindByID(ctx, task.ID)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+		})
+	}
+}
+
+// TestTaskRepositoryFindPagination checks filtering, sorting and cursor paging on every backend
+func TestTaskRepositoryFindPagination(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tasks := open(t).Tasks
+
+			base := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+			for i := 0; i < 7; i++ {
+				status := "To-Do"
+				if i%2 == 1 {
+					status = "Done"
+				}
+				task := models.Task{
+					Title:    fmt.Sprintf("Plano %d", i),
+					DueDate:  base.AddDate(0, 0, i/2), // pairs share a due date
+					Priority: "Medium",
+					Status:   status,
+				}
+				require.NoError(t, tasks.Create(ctx, &task))
+			}
+
+			query := TaskQuery{SortBy: SortByDueDate, Descending: true, Limit: 2}
+			var titles []string
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, 10, "pagination does not terminate")
+				page, err := tasks.Find(ctx, query)
+				require.NoError(t, err)
+				for _, task := range page.Tasks {
+					titles = append(titles, task.Title)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			require.Len(t, titles, 7)
+			assert.Equal(t, "Plano 6", titles[0])
+
+			page, err := tasks.Find(ctx, TaskQuery{
+				Status:    []string{"Done"},
+				DueBefore: base.AddDate(0, 0, 2),
+				Search:    "PLANO",
+				SortBy:    SortByTitle,
+			})
+			require.NoError(t, err)
+			require.Len(t, page.Tasks, 2)
+			assert.Equal(t, "Plano 1", page.Tasks[0].Title)
+			assert.Equal(t, "Plano 3", page.Tasks[1].Title)
+			assert.Empty(t, page.NextCursor)
+
+			_, err = tasks.Find(ctx, TaskQuery{Cursor: "not-a-cursor"})
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
 }
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+)
+
+// ErrTokenNotFound is returned when a user has not connected their Google account
+var ErrTokenNotFound = errors.New("token not found")
+
+// ErrTokenUnreadable is returned for tokens that cannot be decrypted, e.g.
+// because they were sealed with another key
+var ErrTokenUnreadable = errors.New("token cannot be decrypted")
+
+// TokenStore keeps the Google OAuth token of each user. The MongoDB and
+// PostgreSQL stores encrypt tokens at rest with a TokenCipher; the memory
+// store keeps them as they are.
+type TokenStore interface {
+	// Get retrieves the token of a user, or returns ErrTokenNotFound
+	Get(ctx context.Context, userID primitive.ObjectID) (*oauth2.Token, error)
+	// Save creates or replaces the token of a user
+	Save(ctx context.Context, userID primitive.ObjectID, token *oauth2.Token) error
+	// Delete removes the token of a user; it is not an error if there is none
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// TokenCipher encrypts tokens with AES-256-GCM. Each ciphertext is bound to
+// its user, so a token copied to another user's record cannot be decrypted.
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher creates a cipher from a 32-byte key
+func NewTokenCipher(key []byte) (*TokenCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("token key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// Seal encrypts the token of a user; the random nonce is prepended
+func (c *TokenCipher) Seal(userID primitive.ObjectID, token *oauth2.Token) ([]byte, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, userID[:]), nil
+}
+
+// Open decrypts a token sealed for a user
+func (c *TokenCipher) Open(userID primitive.ObjectID, data []byte) (*oauth2.Token, error) {
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrTokenUnreadable)
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, userID[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenUnreadable, err)
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenUnreadable, err)
+	}
+	return &token, nil
+}
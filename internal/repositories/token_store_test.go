@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenCipher(t *testing.T) {
+	c, err := NewTokenCipher([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	userID := primitive.NewObjectID()
+
+	sealed, err := c.Seal(userID, &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"})
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, []byte("refresh-1")), "the token is encrypted")
+	token, err := c.Open(userID, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+
+	again, err := c.Seal(userID, &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal uses a new nonce")
+
+	// Another user's record
+	_, err = c.Open(primitive.NewObjectID(), sealed)
+	assert.ErrorIs(t, err, ErrTokenUnreadable)
+
+	// Another key
+	other, err := NewTokenCipher([]byte("another secret key of 32 bytes!!"))
+	require.NoError(t, err)
+	_, err = other.Open(userID, sealed)
+	assert.ErrorIs(t, err, ErrTokenUnreadable)
+
+	// Tampered with or truncated
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	_, err = c.Open(userID, tampered)
+	assert.ErrorIs(t, err, ErrTokenUnreadable)
+	_, err = c.Open(userID, sealed[:4])
+	assert.ErrorIs(t, err, ErrTokenUnreadable)
+
+	_, err = NewTokenCipher([]byte("short"))
+	assert.Error(t, err)
+}
+
+// TestTokenStoreContract checks that every backend stores tokens the same way
+func TestTokenStoreContract(t *testing.T) {
+	cipher, err := NewTokenCipher([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := open(t)
+			tokens := repos.Tokens(cipher)
+			user := models.User{GoogleID: fmt.Sprintf("google-%d", time.Now().UnixNano())}
+			require.NoError(t, repos.Users.SignIn(ctx, &user))
+
+			_, err := tokens.Get(ctx, user.ID)
+			assert.ErrorIs(t, err, ErrTokenNotFound)
+
+			expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+			require.NoError(t, tokens.Save(ctx, user.ID, &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", TokenType: "Bearer", Expiry: expiry}))
+			token, err := tokens.Get(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "access-1", token.AccessToken)
+			assert.Equal(t, "refresh-1", token.RefreshToken)
+			assert.True(t, expiry.Equal(token.Expiry))
+
+			// Saving again replaces the token
+			require.NoError(t, tokens.Save(ctx, user.ID, &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-2"}))
+			token, err = tokens.Get(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "refresh-2", token.RefreshToken)
+
+			require.NoError(t, tokens.Delete(ctx, user.ID))
+			_, err = tokens.Get(ctx, user.ID)
+			assert.ErrorIs(t, err, ErrTokenNotFound)
+			require.NoError(t, tokens.Delete(ctx, user.ID), "deleting a missing token is not an error")
+		})
+	}
+}
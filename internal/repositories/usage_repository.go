@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrBudgetNotFound is returned when a usage budget does not exist
+var ErrBudgetNotFound = errors.New("usage budget not found")
+
+// UsageQuery selects ledger records. Empty fields match everything; Since is
+// inclusive and Until exclusive.
+type UsageQuery struct {
+	UserID    string
+	ProjectID string
+	Since     time.Time
+	Until     time.Time
+}
+
+// matches reports whether a record is selected by the query
+func (q UsageQuery) matches(r models.UsageRecord) bool {
+	return (q.UserID == "" || r.UserID == q.UserID) &&
+		(q.ProjectID == "" || r.ProjectID == q.ProjectID) &&
+		(q.Since.IsZero() || !r.CreatedAt.Before(q.Since)) &&
+		(q.Until.IsZero() || r.CreatedAt.Before(q.Until))
+}
+
+// UsageRepository stores the token ledger and the budgets that limit it.
+// Implementations exist for memory, MongoDB and PostgreSQL.
+type UsageRepository interface {
+	// Record appends a completion to the ledger, assigning its ID and, if
+	// unset, its time
+	Record(ctx context.Context, record *models.UsageRecord) error
+	// Totals sums the selected records per UTC day, user, project, provider
+	// and model, oldest day first
+	Totals(ctx context.Context, query UsageQuery) ([]models.UsageTotal, error)
+
+	// ListBudgets retrieves every budget
+	ListBudgets(ctx context.Context) ([]models.UsageBudget, error)
+	// SaveBudget creates the budget, or replaces the one with the same scope,
+	// subject and period, and sets its ID and timestamps
+	SaveBudget(ctx context.Context, budget *models.UsageBudget) error
+	// DeleteBudget removes a budget, returning ErrBudgetNotFound if it does not exist
+	DeleteBudget(ctx context.Context, id primitive.ObjectID) error
+}
+
+// prepareUsageRecord assigns the ID and time of a record about to be stored
+func prepareUsageRecord(record *models.UsageRecord) {
+	if record.ID.IsZero() {
+		record.ID = primitive.NewObjectID()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	// Stored times are compared and bucketed in UTC
+	record.CreatedAt = record.CreatedAt.UTC()
+}
+
+// usageDay returns the start of the UTC day t falls on
+func usageDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
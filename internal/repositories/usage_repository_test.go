@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestUsageRepositoryContract checks the token ledger and budgets on every backend
+func TestUsageRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			usage := open(t).Usage
+			user := primitive.NewObjectID().Hex()
+			project := primitive.NewObjectID().Hex()
+			today := time.Now().UTC()
+			yesterday := today.AddDate(0, 0, -1)
+
+			for _, rec := range []models.UsageRecord{
+				{UserID: user, ProjectID: project, Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 100, CompletionTokens: 20, CreatedAt: yesterday},
+				{UserID: user, ProjectID: project, Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 50, CompletionTokens: 10},
+				{UserID: user, ProjectID: project, Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 30, CompletionTokens: 5},
+				{UserID: user, Provider: "ollama", Model: "llama3.1:8b", PromptTokens: 7, CompletionTokens: 3, Estimated: true},
+				{UserID: "someone-else", Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 1000},
+			} {
+				rec := rec
+				require.NoError(t, usage.Record(ctx, &rec))
+				assert.False(t, rec.ID.IsZero())
+			}
+
+			totals, err := usage.Totals(ctx, UsageQuery{UserID: user})
+			require.NoError(t, err)
+			require.Len(t, totals, 3)
+			assert.Equal(t, usageDay(yesterday), totals[0].Day, "oldest day first")
+			assert.Equal(t, int64(100), totals[0].PromptTokens)
+			var cerebrasToday models.UsageTotal
+			for _, total := range totals[1:] {
+				if total.Provider == "cerebras" {
+					cerebrasToday = total
+				}
+			}
+			assert.Equal(t, int64(80), cerebrasToday.PromptTokens)
+			assert.Equal(t, int64(15), cerebrasToday.CompletionTokens)
+			assert.Equal(t, int64(2), cerebrasToday.Requests)
+			assert.Equal(t, project, cerebrasToday.ProjectID)
+
+			totals, err = usage.Totals(ctx, UsageQuery{ProjectID: project, Since: usageDay(today)})
+			require.NoError(t, err)
+			require.Len(t, totals, 1)
+			assert.Equal(t, int64(80), totals[0].PromptTokens)
+
+			daily := models.UsageBudget{Scope: models.BudgetScopeUser, Subject: user, Period: models.BudgetPeriodDaily, Limit: 1000, WarnRatio: 0.8}
+			require.NoError(t, usage.SaveBudget(ctx, &daily))
+			t.Cleanup(func() { _ = usage.DeleteBudget(context.Background(), daily.ID) })
+			assert.False(t, daily.ID.IsZero())
+
+			// Saving the same scope, subject and period replaces the budget
+			replacement := models.UsageBudget{Scope: models.BudgetScopeUser, Subject: user, Period: models.BudgetPeriodDaily, Limit: 2000}
+			require.NoError(t, usage.SaveBudget(ctx, &replacement))
+			assert.Equal(t, daily.ID, replacement.ID)
+
+			budgets, err := usage.ListBudgets(ctx)
+			require.NoError(t, err)
+			var found []models.UsageBudget
+			for _, budget := range budgets {
+				if budget.Subject == user {
+					found = append(found, budget)
+				}
+			}
+			require.Len(t, found, 1)
+			assert.Equal(t, int64(2000), found[0].Limit)
+
+			require.NoError(t, usage.DeleteBudget(ctx, daily.ID))
+			assert.ErrorIs(t, usage.DeleteBudget(ctx, daily.ID), ErrBudgetNotFound)
+		})
+	}
+}
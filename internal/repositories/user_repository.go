@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lyffseba/ana/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// UserRepository stores user accounts. Implementations exist for memory,
+// MongoDB and PostgreSQL.
+type UserRepository interface {
+	// FindByID retrieves a user by ID, or returns ErrUserNotFound
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error)
+	// SignIn creates the user of a Google account signing in for the first
+	// time, or updates the profile and last sign-in of the existing one. The
+	// ID and times of user are set to the stored ones.
+	SignIn(ctx context.Context, user *models.User) error
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestUserRepositoryContract checks user sign-in and lookup on every backend
+func TestUserRepositoryContract(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			users := open(t).Users
+			// Users cannot be deleted, so each run uses its own account
+			googleID := fmt.Sprintf("google-%d", time.Now().UnixNano())
+
+			user := models.User{GoogleID: googleID, Email: "ana@example.com", Name: "Ana"}
+			require.NoError(t, users.SignIn(ctx, &user))
+			assert.False(t, user.ID.IsZero())
+			assert.False(t, user.CreatedAt.IsZero())
+
+			// Signing in again updates the profile of the same user
+			again := models.User{GoogleID: googleID, Email: "ana@example.com", Name: "Ana María", Picture: "https://example.com/ana.png"}
+			require.NoError(t, users.SignIn(ctx, &again))
+			assert.Equal(t, user.ID, again.ID)
+			assert.WithinDuration(t, user.CreatedAt, again.CreatedAt, time.Millisecond)
+
+			found, err := users.FindByID(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "Ana María", found.Name)
+			assert.Equal(t, "https://example.com/ana.png", found.Picture)
+			assert.Equal(t, googleID, found.GoogleID)
+
+			_, err = users.FindByID(ctx, primitive.NewObjectID())
+			assert.ErrorIs(t, err, ErrUserNotFound)
+		})
+	}
+}
@@ -8,35 +8,44 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	aiapi "github.com/lyffseba/ana/internal/api"
+	apihandlers "github.com/lyffseba/ana/internal/api/handlers"
 	"github.com/lyffseba/ana/internal/handlers"
+	"github.com/lyffseba/ana/internal/lifecycle"
+	"github.com/lyffseba/ana/internal/metrics"
 	"github.com/lyffseba/ana/internal/monitoring"
-	"github.com/lyffseba/ana/internal/googleauth"
 )
 
-// MetricsMiddleware adds request metrics collection
-func MetricsMiddleware() gin.HandlerFunc {
+// MetricsMiddleware records the duration and status of each request. Requests
+// are labelled with their route pattern, e.g. /api/v1/tasks/:id, so IDs do
+// not create new series.
+func MetricsMiddleware(m *metrics.Metrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip metrics collection for monitoring endpoints to avoid circular reporting
 		path := c.Request.URL.Path
-		if path == "/metrics" || path == "/health" || path == "/stats" {
+		if path == "/metrics" || path == "/health" || path == "/ready" || path == "/stats" {
 			c.Next()
 			return
 		}
 
 		// Start timer
 		start := time.Now()
-		
+
 		// Process request
 		c.Next()
-		
+
 		// Stop timer and collect metrics
 		duration := time.Since(start)
 		statusCode := c.Writer.Status()
-		endpoint := path
-		
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+
 		// Record metrics
 		monitoring.RecordRequestDuration("api", endpoint, statusCode, duration)
-		
+		m.RecordRequest(c.Request.Method, endpoint, statusCode, duration.Seconds())
+
 		// Record errors if any
 		if statusCode >= 400 {
 			monitoring.RecordError("api", http.StatusText(statusCode))
@@ -44,67 +53,179 @@ func MetricsMiddleware() gin.HandlerFunc {
 	}
 }
 
-// SetupRouter configures all the routes for the application
-func SetupRouter(authService *googleauth.OAuthService) *gin.Engine {
-	r := gin.Default()
-	
-	// Add metrics middleware
-	r.Use(MetricsMiddleware())
-
-	// Enable CORS
-	r.Use(func(c *gin.Context) {
+// CORSMiddleware allows the web app to call the API from any origin
+func CORSMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
 		}
 		c.Next()
-	})
+	}
+}
 
-	// API routes
-	api := r.Group("/api")
-	{
-		// Google OAuth 2.0 routes
+// registerAPI registers the API routes on api
+func registerAPI(api *gin.RouterGroup, h *handlers.Handlers, deps *Dependencies) {
+	api.GET("/health", monitoring.HealthCheckHandler)
+
+	// Google OAuth 2.0 routes
+	if deps.Auth != nil {
 		authGroup := api.Group("/auth/google")
 		{
-			authGroup.GET("/login", authService.HandleLogin)
-			authGroup.GET("/callback", authService.HandleCallback)
+			authGroup.GET("/login", deps.Auth.HandleLogin)
+			authGroup.GET("/callback", deps.Auth.HandleCallback)
 		}
+	}
+	api.POST("/auth/logout", h.Logout)
+
+	// Everything else needs a signed-in user or the admin token
+	api = api.Group("", h.RequireUser())
+	api.GET("/me", h.GetMe)
+	if deps.Auth != nil {
+		api.POST("/auth/google/disconnect", deps.Auth.HandleDisconnect)
+	}
+
+	// Task routes
+	tasks := api.Group("/tasks")
+	{
+		tasks.GET("", h.GetTasks)
+		tasks.GET("/:id", h.GetTaskByID)
+		tasks.POST("", h.CreateTask)
+		tasks.POST("/bulk", h.BulkCreateTasks)
+		tasks.PUT("/:id", h.UpdateTask)
+		tasks.DELETE("/:id", h.DeleteTask)
+	}
+
+	// Project routes
+	projects := api.Group("/projects")
+	{
+		projects.GET("", h.GetProjects)
+		projects.GET("/:id", h.GetProjectByID)
+		projects.POST("", h.CreateProject)
+		projects.PUT("/:id", h.UpdateProject)
+		projects.DELETE("/:id", h.DeleteProject)
+		projects.GET("/:id/tasks", h.GetProjectTasks)
+	}
+
+	// Agenda routes
+	agenda := api.Group("/agenda")
+	{
+		agenda.GET("/today", h.GetTasksDueToday)
+	}
 
-		// Task routes
-		tasks := api.Group("/tasks")
+	// AI Assistant routes
+	ai := api.Group("/ai")
+	{
+		// Cerebras AI assistant endpoint
+		ai.POST("/cerebras", h.GetCerebrasAIAssistance)
+
+		// Task assistant with tool calling; changes need confirmation
+		ai.POST("/agent", h.RunTaskAgent)
+		ai.POST("/agent/confirm/:id", h.ConfirmAgentAction)
+
+		// Proposes tasks found in pasted text; they are created with /api/tasks/bulk
+		ai.POST("/extract-tasks", h.ExtractTasks)
+
+		// Answers many independent queries at once, e.g. one per task
+		ai.POST("/batch", h.GenerateBatch)
+
+		// Assistant conversations of the current session
+		ai.GET("/conversations", h.ListConversations)
+		ai.GET("/conversations/:id", h.GetConversation)
+		ai.PATCH("/conversations/:id", h.RenameConversation)
+		ai.DELETE("/conversations/:id", h.DeleteConversation)
+
+		// Token usage of the current session, or of anyone for admins
+		ai.GET("/usage", h.GetAIUsage)
+
+		// Documents the assistant grounds its answers in; ingesting and
+		// deleting need the admin token
+		documents := ai.Group("/documents")
 		{
-			tasks.GET("", handlers.GetTasks)
-			tasks.GET("/:id", handlers.GetTaskByID)
-			tasks.POST("", handlers.CreateTask)
-			tasks.PUT("/:id", handlers.UpdateTask)
-			tasks.DELETE("/:id", handlers.DeleteTask)
+			documents.GET("", h.ListDocuments)
+			documents.GET("/search", h.SearchDocuments)
+			documents.POST("", handlers.RequireAdmin(), h.UploadDocument)
+			documents.DELETE("/:id", handlers.RequireAdmin(), h.DeleteDocument)
 		}
 
-		// Agenda routes
-		agenda := api.Group("/agenda")
+		// Usage budgets, managed with the admin token
+		budgets := ai.Group("/budgets", handlers.RequireAdmin())
 		{
-			agenda.GET("/today", handlers.GetTasksDueToday)
+			budgets.GET("", h.ListUsageBudgets)
+			budgets.PUT("", h.SaveUsageBudget)
+			budgets.DELETE("/:id", h.DeleteUsageBudget)
 		}
-		
-		// AI Assistant routes
-		ai := api.Group("/ai")
+
+		// System prompt templates, managed with the admin token
+		prompts := ai.Group("/prompts", handlers.RequireAdmin())
 		{
-			// Cerebras AI assistant endpoint
-			ai.POST("/cerebras", handlers.GetCerebrasAIAssistance)
+			prompts.GET("", h.ListPrompts)
+			prompts.POST("", h.CreatePrompt)
+			prompts.POST("/reload", h.ReloadPrompts)
+			prompts.GET("/:name", h.GetPromptVersions)
+			prompts.POST("/:name/preview", h.PreviewPrompt)
+		}
+
+		// Tasks run on a configured model by name, with per-task options,
+		// counted against the caller's usage budgets
+		if deps.AIService != nil {
+			service := aiapi.NewAIHandler(deps.AIService, deps.metrics())
+			ai.POST("/process", h.AccountUsage(), gin.WrapF(service.ProcessRequest))
+			ai.GET("/models", gin.WrapF(service.GetModels))
+			ai.GET("/health", gin.WrapF(service.HealthCheck))
+		}
+
+		// Registered processors, counted against the caller's usage budgets;
+		// resetting their metrics needs the admin token
+		if deps.Processors != nil {
+			processors := apihandlers.NewAIHandler(deps.Processors, deps.metrics())
+			processorGroup := ai.Group("/processors")
+			{
+				processorGroup.POST("/process", h.AccountUsage(), gin.WrapF(processors.ProcessRequest))
+				processorGroup.GET("/metrics", gin.WrapF(processors.GetMetrics))
+				processorGroup.POST("/metrics/reset", handlers.RequireAdmin(), gin.WrapF(processors.ResetMetrics))
+			}
 		}
 	}
-	
-	// Monitoring routes
+
+	// Processor requests over a WebSocket, answered as they complete. The
+	// budgets are checked when the connection opens and every message is
+	// counted against them.
+	if deps.websockets != nil {
+		api.GET("/ws", h.AccountUsage(), gin.WrapF(deps.websockets.HandleConnection))
+	}
+
+	// Cerebras assistant and monitoring endpoints
+	h.RegisterCerebrasRoutes(api)
+}
+
+// registerMonitoring registers the Prometheus, health, readiness and stats
+// endpoints
+func registerMonitoring(r *gin.Engine, deps *Dependencies) {
 	monitoring.RegisterHealthEndpoint(r)
+	r.GET("/ready", readiness(deps.Lifecycle))
 	monitoring.RegisterMetricsEndpoint(r)
 	monitoring.RegisterStatsEndpoint(r)
-	
-	// Register handlers for Cerebras monitoring endpoints
-	handlers.RegisterCerebrasRoutes(r)
+}
 
+// readiness answers 503 while the server is starting or draining, so load
+// balancers only send traffic while it is ready
+func readiness(manager *lifecycle.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if manager != nil && !manager.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
+
+// registerWebApp serves the web app, falling back to index.html so the
+// client handles its own routes
+func registerWebApp(r *gin.Engine) {
 	// Handle frontend routes for development
 	// Specifically handle index.html and other static assets
 	r.GET("/", func(c *gin.Context) {
@@ -136,8 +257,6 @@ func SetupRouter(authService *googleauth.OAuthService) *gin.Engine {
 		// Default to serving index.html for client-side routing
 		c.File("./web/index.html")
 	})
-
-	return r
 }
 
 // fileExists checks if a file exists at the given path
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/ai/processors"
+	apihandlers "github.com/lyffseba/ana/internal/api/handlers"
+	"github.com/lyffseba/ana/internal/googleauth"
+	"github.com/lyffseba/ana/internal/handlers"
+	"github.com/lyffseba/ana/internal/lifecycle"
+	"github.com/lyffseba/ana/internal/metrics"
+	"github.com/lyffseba/ana/internal/prompts"
+	"github.com/lyffseba/ana/internal/rag"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/lyffseba/ana/internal/session"
+	"github.com/lyffseba/ana/internal/usage"
+)
+
+// Dependencies are the storage, AI clients and services the server's
+// handlers use. Nil fields leave the handlers' defaults in place, e.g. the
+// in-memory repositories, and the routes that need a service that is not
+// given are not registered.
+type Dependencies struct {
+	Repositories *repositories.Repositories
+	// Models serves the logical models the assistant handlers ask for
+	Models    *ai.ModelFactory
+	Usage     *usage.Ledger
+	Documents *rag.Store
+	// Prompts renders system prompts; new versions are stored in
+	// Repositories.Prompts, or in memory without repositories
+	Prompts *prompts.Registry
+	// AIService runs the tasks of /ai/process
+	AIService *ai.AIService
+	// Processors serve /ai/processors and the WebSocket endpoint
+	Processors *processors.ProcessorManager
+	// Metrics records requests and errors; metrics.Default() if nil
+	Metrics *metrics.Metrics
+	// Auth serves the Google OAuth routes; users who sign in are stored in
+	// Repositories.Users
+	Auth *googleauth.OAuthService
+	// Sessions signs users in; without it the task and AI routes are open
+	// to anyone
+	Sessions *session.Manager
+	// TokenCipher encrypts the Google tokens of the users who sign in, which
+	// are stored in Repositories; without it they are kept in memory
+	TokenCipher *repositories.TokenCipher
+	// Lifecycle reports readiness at /ready and closes the WebSocket
+	// connections on shutdown; the server is always ready without it
+	Lifecycle *lifecycle.Manager
+	// AllowedOrigins are the origins besides the server's own whose pages
+	// may open WebSockets
+	AllowedOrigins []string
+
+	// websockets serves /ws under every API prefix
+	websockets *apihandlers.WebSocketHandler
+}
+
+// metrics returns the collector requests are recorded in
+func (d *Dependencies) metrics() *metrics.Metrics {
+	if d.Metrics == nil {
+		return metrics.Default()
+	}
+	return d.Metrics
+}
+
+// handlers builds the handlers of the task, project and assistant routes
+// and connects the Google sign-in to the same users and sessions
+func (d *Dependencies) handlers() *handlers.Handlers {
+	if d.Auth != nil {
+		if repos := d.Repositories; repos != nil {
+			d.Auth.Users = repos.Users
+			if d.TokenCipher != nil {
+				d.Auth.Tokens = repos.Tokens(d.TokenCipher)
+			}
+		}
+		d.Auth.Sessions = d.Sessions
+	}
+	return handlers.New(handlers.Dependencies{
+		Repositories: d.Repositories,
+		Sessions:     d.Sessions,
+		Models:       d.Models,
+		Usage:        d.Usage,
+		Documents:    d.Documents,
+		Prompts:      d.Prompts,
+	})
+}
+
+// New builds the HTTP server: every API route under /api/v1, and under
+// /api for existing clients, behind one middleware chain, plus the
+// monitoring endpoints and the web app.
+func New(deps Dependencies) *gin.Engine {
+	h := deps.handlers()
+	if deps.Processors != nil {
+		deps.websockets = apihandlers.NewWebSocketHandler(deps.Processors, deps.metrics(), deps.AllowedOrigins...)
+		if deps.Lifecycle != nil {
+			deps.Lifecycle.OnShutdown("websockets", deps.websockets.Shutdown)
+		}
+	}
+
+	r := gin.New()
+	r.Use(gin.Logger(), gin.Recovery(), MetricsMiddleware(deps.metrics()), CORSMiddleware())
+
+	registerAPI(r.Group("/api/v1"), h, &deps)
+	registerAPI(r.Group("/api"), h, &deps)
+	registerMonitoring(r, &deps)
+	registerWebApp(r)
+	return r
+}
@@ -0,0 +1,93 @@
+// Package session issues and verifies the signed cookies that keep users
+// signed in
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieName is the cookie that holds the session
+const CookieName = "ana_user"
+
+// ErrNoSession is returned for requests without a valid session
+var ErrNoSession = errors.New("no valid session")
+
+// Manager signs sessions with a secret key. A session names a user and
+// expires after the manager's max age; it cannot be forged or extended
+// without the key.
+type Manager struct {
+	secret []byte
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewManager creates a manager signing with secret, whose sessions last maxAge
+func NewManager(secret []byte, maxAge time.Duration) *Manager {
+	return &Manager{secret: secret, maxAge: maxAge, now: time.Now}
+}
+
+// Issue sets the session cookie of userID on the response
+func (m *Manager) Issue(w http.ResponseWriter, r *http.Request, userID string) {
+	expires := m.now().Add(m.maxAge)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID + "|" + strconv.FormatInt(expires.Unix(), 10)))
+	http.SetCookie(w, m.cookie(r, payload+"."+m.sign(payload), int(m.maxAge.Seconds())))
+}
+
+// Clear removes the session cookie, signing the user out
+func (m *Manager) Clear(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, m.cookie(r, "", -1))
+}
+
+// UserID returns the user of the request's session, or ErrNoSession when
+// it has none or it is expired or tampered with
+func (m *Manager) UserID(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return "", ErrNoSession
+	}
+	payload, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(m.sign(payload))) {
+		return "", ErrNoSession
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrNoSession
+	}
+	userID, expiry, ok := strings.Cut(string(data), "|")
+	if !ok || userID == "" {
+		return "", ErrNoSession
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !m.now().Before(time.Unix(unix, 0)) {
+		return "", ErrNoSession
+	}
+	return userID, nil
+}
+
+// sign returns the signature of a session payload
+func (m *Manager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookie builds the session cookie; scripts cannot read it and it is only
+// sent over HTTPS when the request came over HTTPS
+func (m *Manager) cookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issued returns a request carrying the session cookie m issues for userID
+func issued(t *testing.T, m *Manager, userID string) (*http.Request, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Issue(w, httptest.NewRequest("GET", "/", nil), userID)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	return r, cookies[0]
+}
+
+func TestSession(t *testing.T) {
+	m := NewManager([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+
+	r, cookie := issued(t, m, "64b7f0c2a1b2c3d4e5f60718")
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	userID, err := m.UserID(r)
+	require.NoError(t, err)
+	assert.Equal(t, "64b7f0c2a1b2c3d4e5f60718", userID)
+
+	// Without a cookie
+	_, err = m.UserID(httptest.NewRequest("GET", "/", nil))
+	assert.ErrorIs(t, err, ErrNoSession)
+
+	// Signed with another key
+	other := NewManager([]byte("another secret key of 32 bytes!!"), time.Hour)
+	otherRequest, _ := issued(t, other, "64b7f0c2a1b2c3d4e5f60718")
+	_, err = m.UserID(otherRequest)
+	assert.ErrorIs(t, err, ErrNoSession)
+
+	// Tampered with
+	payload, signature, _ := strings.Cut(cookie.Value, ".")
+	tampered := httptest.NewRequest("GET", "/", nil)
+	tampered.AddCookie(&http.Cookie{Name: CookieName, Value: payload + "x." + signature})
+	_, err = m.UserID(tampered)
+	assert.ErrorIs(t, err, ErrNoSession)
+
+	// Expired
+	m.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = m.UserID(r)
+	assert.ErrorIs(t, err, ErrNoSession)
+}
+
+func TestClear(t *testing.T) {
+	m := NewManager([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	w := httptest.NewRecorder()
+	m.Clear(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, CookieName, cookies[0].Name)
+	assert.Equal(t, "", cookies[0].Value)
+	assert.Negative(t, cookies[0].MaxAge)
+}
@@ -0,0 +1,247 @@
+// Package usage keeps the ledger of tokens used by the AI assistant and
+// enforces the daily and monthly budgets set by administrators.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultWarnRatio is the share of a budget after which requests are warned
+// about, for budgets that do not set one
+const DefaultWarnRatio = 0.8
+
+// ErrMissingSubject is returned for a user or project budget without the
+// user or project it limits
+var ErrMissingSubject = errors.New("budget needs a subject")
+
+// recordTimeout bounds writing a completion to the ledger
+const recordTimeout = 5 * time.Second
+
+// Budget levels, from least to most severe
+const (
+	LevelOK       = "ok"
+	LevelWarning  = "warning"
+	LevelExceeded = "exceeded"
+)
+
+// Account identifies who a completion is for
+type Account struct {
+	UserID    string
+	ProjectID string
+}
+
+type accountKey struct{}
+
+// WithAccount returns a context whose completions are recorded against account
+func WithAccount(ctx context.Context, account Account) context.Context {
+	return context.WithValue(ctx, accountKey{}, account)
+}
+
+// AccountFrom returns the account set with WithAccount, if any
+func AccountFrom(ctx context.Context) (Account, bool) {
+	account, ok := ctx.Value(accountKey{}).(Account)
+	return account, ok
+}
+
+type promptKey struct{}
+
+// WithPrompt returns a context whose completions are recorded as made with
+// the given prompt template version, e.g. "assistant@2"
+func WithPrompt(ctx context.Context, prompt string) context.Context {
+	return context.WithValue(ctx, promptKey{}, prompt)
+}
+
+// PromptFrom returns the prompt template version set with WithPrompt, if any
+func PromptFrom(ctx context.Context) string {
+	prompt, _ := ctx.Value(promptKey{}).(string)
+	return prompt
+}
+
+// BudgetStatus is how much of a budget's current period has been used
+type BudgetStatus struct {
+	Budget models.UsageBudget `json:"budget"`
+	Used   int64              `json:"used"`
+	Level  string             `json:"level"`
+	// ResetsAt is when the budget's period ends
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// Ledger records token usage and checks it against budgets
+type Ledger struct {
+	repo repositories.UsageRepository
+	now  func() time.Time
+}
+
+// NewLedger creates a ledger stored in repo
+func NewLedger(repo repositories.UsageRepository) *Ledger {
+	return &Ledger{repo: repo, now: time.Now}
+}
+
+// Record adds a completion to the ledger, made now unless its time is set
+func (l *Ledger) Record(ctx context.Context, record models.UsageRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = l.now()
+	}
+	return l.repo.Record(ctx, &record)
+}
+
+// Recorder returns an ai.UsageRecorder that records completions against the
+// account of their request's context. Completions without an account, such
+// as background work, are not recorded.
+func (l *Ledger) Recorder() ai.UsageRecorder {
+	return func(ctx context.Context, usage ai.TokenUsage) {
+		account, ok := AccountFrom(ctx)
+		if !ok {
+			return
+		}
+		// The completion was paid for even if the request was cancelled since
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+		defer cancel()
+		err := l.Record(ctx, models.UsageRecord{
+			UserID:           account.UserID,
+			ProjectID:        account.ProjectID,
+			Provider:         usage.Provider,
+			Model:            usage.Model,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			Estimated:        usage.Estimated,
+			Prompt:           PromptFrom(ctx),
+		})
+		if err != nil {
+			log.Printf("Error recording token usage for %s: %v", account.UserID, err)
+		}
+	}
+}
+
+// Budgets returns every budget
+func (l *Ledger) Budgets(ctx context.Context) ([]models.UsageBudget, error) {
+	return l.repo.ListBudgets(ctx)
+}
+
+// SaveBudget creates or replaces a budget. The global budget has no subject;
+// user and project budgets need one.
+func (l *Ledger) SaveBudget(ctx context.Context, budget *models.UsageBudget) error {
+	if budget.Scope == models.BudgetScopeGlobal {
+		budget.Subject = ""
+	} else if budget.Subject == "" {
+		return fmt.Errorf("%s %w", budget.Scope, ErrMissingSubject)
+	}
+	if budget.WarnRatio == 0 {
+		budget.WarnRatio = DefaultWarnRatio
+	}
+	return l.repo.SaveBudget(ctx, budget)
+}
+
+// DeleteBudget removes a budget, returning repositories.ErrBudgetNotFound
+// if it does not exist
+func (l *Ledger) DeleteBudget(ctx context.Context, id primitive.ObjectID) error {
+	return l.repo.DeleteBudget(ctx, id)
+}
+
+// Check returns the status of the most used budget that applies to account:
+// the global budgets and those of its user and project. The level is
+// LevelOK when no budget applies.
+func (l *Ledger) Check(ctx context.Context, account Account) (BudgetStatus, error) {
+	statuses, err := l.Statuses(ctx, account)
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	worst := BudgetStatus{Level: LevelOK}
+	for _, status := range statuses {
+		if worst.Budget.Limit == 0 || status.ratio() > worst.ratio() {
+			worst = status
+		}
+	}
+	return worst, nil
+}
+
+// Statuses returns the status of every budget that applies to account
+func (l *Ledger) Statuses(ctx context.Context, account Account) ([]BudgetStatus, error) {
+	budgets, err := l.repo.ListBudgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := l.now().UTC()
+	statuses := []BudgetStatus{}
+	for _, budget := range budgets {
+		query, ok := budgetQuery(budget, account)
+		if !ok {
+			continue
+		}
+		query.Since, query.Until = periodBounds(budget.Period, now)
+		totals, err := l.repo.Totals(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		var used int64
+		for _, total := range totals {
+			used += total.PromptTokens + total.CompletionTokens
+		}
+		statuses = append(statuses, BudgetStatus{
+			Budget:   budget,
+			Used:     used,
+			Level:    budgetLevel(budget, used),
+			ResetsAt: query.Until,
+		})
+	}
+	return statuses, nil
+}
+
+// ratio is the share of the budget used
+func (s BudgetStatus) ratio() float64 {
+	if s.Budget.Limit == 0 {
+		return 0
+	}
+	return float64(s.Used) / float64(s.Budget.Limit)
+}
+
+// budgetQuery selects the usage a budget counts for account, or returns
+// false if the budget does not apply to it
+func budgetQuery(budget models.UsageBudget, account Account) (repositories.UsageQuery, bool) {
+	switch budget.Scope {
+	case models.BudgetScopeGlobal:
+		return repositories.UsageQuery{}, true
+	case models.BudgetScopeUser:
+		return repositories.UsageQuery{UserID: budget.Subject}, budget.Subject == account.UserID
+	case models.BudgetScopeProject:
+		return repositories.UsageQuery{ProjectID: budget.Subject}, account.ProjectID != "" && budget.Subject == account.ProjectID
+	default:
+		return repositories.UsageQuery{}, false
+	}
+}
+
+// budgetLevel rates used tokens against a budget
+func budgetLevel(budget models.UsageBudget, used int64) string {
+	warnRatio := budget.WarnRatio
+	if warnRatio == 0 {
+		warnRatio = DefaultWarnRatio
+	}
+	switch {
+	case used >= budget.Limit:
+		return LevelExceeded
+	case float64(used) >= warnRatio*float64(budget.Limit):
+		return LevelWarning
+	default:
+		return LevelOK
+	}
+}
+
+// periodBounds returns the UTC day or month now falls in
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	y, m, d := now.UTC().Date()
+	if period == models.BudgetPeriodMonthly {
+		start := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,126 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lyffseba/ana/internal/ai"
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLedger returns an in-memory ledger whose clock reads now
+func newTestLedger(now time.Time) *Ledger {
+	ledger := NewLedger(repositories.NewMemoryUsageRepository())
+	ledger.now = func() time.Time { return now }
+	return ledger
+}
+
+// TestLedgerRecorder tests recording completions against the request's account
+func TestLedgerRecorder(t *testing.T) {
+	ctx := context.Background()
+	ledger := newTestLedger(time.Now())
+	record := ledger.Recorder()
+
+	// Without an account nothing is recorded
+	record(ctx, ai.TokenUsage{Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 10})
+
+	cancelled, cancel := context.WithCancel(WithAccount(ctx, Account{UserID: "ana", ProjectID: "p1"}))
+	cancel()
+	record(cancelled, ai.TokenUsage{Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 10, CompletionTokens: 5, Estimated: true})
+
+	report, err := ledger.Report(ctx, ReportQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, Totals{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Requests: 1}, report.Total)
+	require.Len(t, report.Users, 1)
+	assert.Equal(t, "ana", report.Users[0].ID)
+	require.Len(t, report.Projects, 1)
+	assert.Equal(t, "p1", report.Projects[0].ID)
+}
+
+// TestLedgerBudgets tests warning and blocking as budgets are used up
+func TestLedgerBudgets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 5, 28, 15, 0, 0, 0, time.UTC)
+	ledger := newTestLedger(now)
+	account := Account{UserID: "ana", ProjectID: "p1"}
+
+	require.Error(t, ledger.SaveBudget(ctx, &models.UsageBudget{Scope: models.BudgetScopeUser, Period: models.BudgetPeriodDaily, Limit: 100}),
+		"a user budget needs a subject")
+	daily := models.UsageBudget{Scope: models.BudgetScopeUser, Subject: "ana", Period: models.BudgetPeriodDaily, Limit: 100}
+	require.NoError(t, ledger.SaveBudget(ctx, &daily))
+	assert.Equal(t, DefaultWarnRatio, daily.WarnRatio)
+	monthly := models.UsageBudget{Scope: models.BudgetScopeProject, Subject: "p1", Period: models.BudgetPeriodMonthly, Limit: 1000, WarnRatio: 0.4}
+	require.NoError(t, ledger.SaveBudget(ctx, &monthly))
+	other := models.UsageBudget{Scope: models.BudgetScopeUser, Subject: "someone-else", Period: models.BudgetPeriodDaily, Limit: 1}
+	require.NoError(t, ledger.SaveBudget(ctx, &other))
+
+	status, err := ledger.Check(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, LevelOK, status.Level)
+
+	// Yesterday's usage counts toward the month but not the day
+	require.NoError(t, ledger.Record(ctx, models.UsageRecord{UserID: "ana", ProjectID: "p1", PromptTokens: 450, CreatedAt: now.AddDate(0, 0, -1)}))
+	require.NoError(t, ledger.Record(ctx, models.UsageRecord{UserID: "ana", PromptTokens: 70, CompletionTokens: 15}))
+	status, err = ledger.Check(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarning, status.Level)
+	assert.Equal(t, "ana", status.Budget.Subject)
+	assert.Equal(t, int64(85), status.Used)
+	assert.Equal(t, time.Date(2025, 5, 29, 0, 0, 0, 0, time.UTC), status.ResetsAt)
+
+	require.NoError(t, ledger.Record(ctx, models.UsageRecord{UserID: "ana", CompletionTokens: 20}))
+	status, err = ledger.Check(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, LevelExceeded, status.Level)
+
+	statuses, err := ledger.Statuses(ctx, account)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+
+	// The project budget alone only warns
+	require.NoError(t, ledger.DeleteBudget(ctx, daily.ID))
+	status, err = ledger.Check(ctx, account)
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarning, status.Level)
+	assert.Equal(t, int64(450), status.Used)
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), status.ResetsAt)
+	assert.ErrorIs(t, ledger.DeleteBudget(ctx, daily.ID), repositories.ErrBudgetNotFound)
+}
+
+// TestLedgerReport tests summarising usage by day and month
+func TestLedgerReport(t *testing.T) {
+	ctx := context.Background()
+	ledger := newTestLedger(time.Now())
+	for _, rec := range []models.UsageRecord{
+		{UserID: "ana", ProjectID: "p1", Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 100, CompletionTokens: 10, CreatedAt: time.Date(2025, 4, 30, 12, 0, 0, 0, time.UTC)},
+		{UserID: "ana", ProjectID: "p1", Provider: "cerebras", Model: "qwen-3-32b", PromptTokens: 50, CompletionTokens: 5, CreatedAt: time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC)},
+		{UserID: "luis", Provider: "ollama", Model: "llama3.1:8b", PromptTokens: 20, CompletionTokens: 2, CreatedAt: time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)},
+		{UserID: "luis", Provider: "ollama", Model: "llama3.1:8b", PromptTokens: 1, CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		require.NoError(t, ledger.Record(ctx, rec))
+	}
+
+	report, err := ledger.Report(ctx, ReportQuery{To: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.Equal(t, IntervalDay, report.Interval)
+	assert.Equal(t, int64(187), report.Total.TotalTokens)
+	require.Len(t, report.Periods, 2)
+	assert.Equal(t, time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC), report.Periods[1].Start)
+	assert.Len(t, report.Periods[1].Models, 2)
+	assert.Equal(t, int64(2), report.Periods[1].Requests)
+	require.Len(t, report.Users, 2)
+	assert.Equal(t, "ana", report.Users[0].ID, "largest user first")
+
+	report, err = ledger.Report(ctx, ReportQuery{UserID: "ana", Interval: IntervalMonth})
+	require.NoError(t, err)
+	require.Len(t, report.Periods, 2)
+	assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), report.Periods[1].Start)
+	assert.Equal(t, int64(55), report.Periods[1].TotalTokens)
+
+	_, err = ledger.Report(ctx, ReportQuery{Interval: "week"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lyffseba/ana/internal/models"
+	"github.com/lyffseba/ana/internal/repositories"
+)
+
+// Report intervals
+const (
+	IntervalDay   = "day"
+	IntervalMonth = "month"
+)
+
+// Totals sums token usage
+type Totals struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	Requests         int64 `json:"requests"`
+}
+
+// add counts a ledger total
+func (t *Totals) add(total models.UsageTotal) {
+	t.PromptTokens += total.PromptTokens
+	t.CompletionTokens += total.CompletionTokens
+	t.TotalTokens += total.PromptTokens + total.CompletionTokens
+	t.Requests += total.Requests
+}
+
+// ModelUsage is the usage of one provider model
+type ModelUsage struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Totals
+}
+
+// PeriodUsage is the usage of one day or month, by model
+type PeriodUsage struct {
+	Start  time.Time    `json:"start"`
+	Models []ModelUsage `json:"models"`
+	Totals
+}
+
+// SubjectUsage is the usage of one user or project
+type SubjectUsage struct {
+	ID string `json:"id"`
+	Totals
+}
+
+// ReportQuery selects the usage a report covers. Empty user and project
+// IDs match everyone; From is inclusive and To exclusive.
+type ReportQuery struct {
+	UserID    string
+	ProjectID string
+	From      time.Time
+	To        time.Time
+	// Interval groups the report by IntervalDay or IntervalMonth
+	Interval string
+}
+
+// Report summarises token usage over time
+type Report struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Interval string         `json:"interval"`
+	Total    Totals         `json:"total"`
+	Periods  []PeriodUsage  `json:"periods"`
+	Users    []SubjectUsage `json:"users"`
+	Projects []SubjectUsage `json:"projects"`
+}
+
+// Report summarises the usage selected by query per day or month, per model,
+// user and project. Periods without usage are left out.
+func (l *Ledger) Report(ctx context.Context, query ReportQuery) (Report, error) {
+	if query.Interval == "" {
+		query.Interval = IntervalDay
+	}
+	if query.Interval != IntervalDay && query.Interval != IntervalMonth {
+		return Report{}, fmt.Errorf("invalid interval %q: use %s or %s", query.Interval, IntervalDay, IntervalMonth)
+	}
+	totals, err := l.repo.Totals(ctx, repositories.UsageQuery{
+		UserID:    query.UserID,
+		ProjectID: query.ProjectID,
+		Since:     query.From,
+		Until:     query.To,
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		From:     query.From,
+		To:       query.To,
+		Interval: query.Interval,
+		Periods:  []PeriodUsage{},
+	}
+	periods := make(map[time.Time]*PeriodUsage)
+	users := make(map[string]*Totals)
+	projects := make(map[string]*Totals)
+	for _, total := range totals {
+		report.Total.add(total)
+
+		start := total.Day
+		if query.Interval == IntervalMonth {
+			start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		period, ok := periods[start]
+		if !ok {
+			period = &PeriodUsage{Start: start}
+			periods[start] = period
+		}
+		period.add(total)
+		period.Models = addModel(period.Models, total)
+
+		subjectTotals(users, total.UserID).add(total)
+		if total.ProjectID != "" {
+			subjectTotals(projects, total.ProjectID).add(total)
+		}
+	}
+
+	for _, period := range periods {
+		report.Periods = append(report.Periods, *period)
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Start.Before(report.Periods[j].Start) })
+	report.Users = sortedSubjects(users)
+	report.Projects = sortedSubjects(projects)
+	return report, nil
+}
+
+// addModel counts total against its model in usage
+func addModel(usage []ModelUsage, total models.UsageTotal) []ModelUsage {
+	for i := range usage {
+		if usage[i].Provider == total.Provider && usage[i].Model == total.Model {
+			usage[i].add(total)
+			return usage
+		}
+	}
+	m := ModelUsage{Provider: total.Provider, Model: total.Model}
+	m.add(total)
+	return append(usage, m)
+}
+
+// subjectTotals returns the totals of id, adding them if needed
+func subjectTotals(subjects map[string]*Totals, id string) *Totals {
+	t, ok := subjects[id]
+	if !ok {
+		t = &Totals{}
+		subjects[id] = t
+	}
+	return t
+}
+
+// sortedSubjects lists subjects by total tokens, largest first
+func sortedSubjects(subjects map[string]*Totals) []SubjectUsage {
+	list := make([]SubjectUsage, 0, len(subjects))
+	for id, totals := range subjects {
+		list = append(list, SubjectUsage{ID: id, Totals: *totals})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TotalTokens != list[j].TotalTokens {
+			return list[i].TotalTokens > list[j].TotalTokens
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}